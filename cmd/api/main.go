@@ -1,12 +1,31 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"smart-waste-management/internal/container"
+	"smart-waste-management/internal/containertype"
+	"smart-waste-management/internal/dispatch"
+	"smart-waste-management/internal/facility"
+	"smart-waste-management/internal/forecast"
+	"smart-waste-management/internal/lorawan"
+	"smart-waste-management/internal/optimizer"
 	"smart-waste-management/internal/platform/database"
+	"smart-waste-management/internal/platform/idempotency"
+	"smart-waste-management/internal/platform/mqtt"
+	"smart-waste-management/internal/routing"
+	"smart-waste-management/internal/stream"
+	"smart-waste-management/internal/threshold"
+	"smart-waste-management/internal/tiles"
+	"smart-waste-management/internal/timewindow"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
+	_ "time/tzdata" // La imagen final no incluye la base de datos de zonas horarias de las franjas de recogida.
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -57,11 +76,91 @@ func main() {
 	// 3. "Cablear" las dependencias (Dependency Injection)
 	// La cadena es: DB -> Repositorio -> Servicio -> Handler
 	containerRepository := container.NewPostgresRepository(db)
-	containerService := container.NewService(containerRepository)
+	ingestConfig := container.IngestConfig{
+		QueueSize: envInt("INGEST_QUEUE_SIZE", container.DefaultIngestConfig().QueueSize),
+		Workers:   envInt("INGEST_WORKERS", container.DefaultIngestConfig().Workers),
+	}
+	// El historial de lecturas alimenta los modelos de predicción de llenado.
+	forecastService := forecast.NewService(containerRepository)
+	// Depósitos y puntos de descarga: las rutas salen del depósito, descargan y vuelven a él.
+	facilityRepository := facility.NewPostgresRepository(db)
+	facilityService := facility.NewService(facilityRepository)
+	facilityHandler := facility.NewHandler(facilityService)
+	// Red viaria opcional: distancias por carretera al planificar y trazado de las rutas exportadas.
+	var distances optimizer.DistanceProvider = optimizer.Haversine{}
+	var paths dispatch.PathTracer
+	if roads := roadNetwork(); roads != nil {
+		distances, paths = roads, roads
+	}
+	// Rutas guardadas: el servicio de contenedores las guarda al generarlas y el de ejecución
+	// registra las recogidas de las paradas junto con su resultado.
+	dispatchRepository := dispatch.NewPostgresRepository(db)
+	// Canal en tiempo real: el servicio de contenedores publica los cambios de estado de cada lectura.
+	streamHub := stream.NewHub(envInt("STREAM_BUFFER_SIZE", stream.DefaultBufferSize))
+	streamHandler := stream.NewHandler(streamHub, envList("STREAM_ALLOWED_ORIGINS"))
+	containerService := container.NewService(containerRepository, forecastService, facilityService, distances, dispatchRepository, streamHub, ingestConfig)
 	containerHandler := container.NewHandler(containerService)
+	dispatchService := dispatch.NewService(dispatchRepository, distances, paths)
+	dispatchHandler := dispatch.NewHandler(dispatchService)
+
+	// Módulo LoRaWAN: resuelve el DevEUI, decodifica el payload y entrega la lectura al servicio de contenedores.
+	lorawanRepository := lorawan.NewPostgresRepository(db)
+	lorawanService := lorawan.NewService(lorawanRepository, lorawan.DefaultRegistry(), containerService)
+	lorawanHandler := lorawan.NewHandler(lorawanService, os.Getenv("LORAWAN_WEBHOOK_TOKEN"))
+
+	// Tipos de contenedor (modelo, volumen y sistema de elevación)
+	containerTypeRepository := containertype.NewPostgresRepository(db)
+	containerTypeService := containertype.NewService(containerTypeRepository)
+	containerTypeHandler := containertype.NewHandler(containerTypeService)
+
+	// Perfiles de umbrales de estado configurables por contenedor
+	thresholdRepository := threshold.NewPostgresRepository(db)
+	thresholdService := threshold.NewService(thresholdRepository)
+	thresholdHandler := threshold.NewHandler(thresholdService)
+
+	// Perfiles de franjas horarias de recogida por zona
+	timeWindowRepository := timewindow.NewPostgresRepository(db)
+	timeWindowService := timewindow.NewService(timeWindowRepository)
+	timeWindowHandler := timewindow.NewHandler(timeWindowService)
+
+	// Teselas vectoriales de contenedores para los mapas
+	tilesRepository := tiles.NewPostgresRepository(db)
+	tilesService := tiles.NewService(tilesRepository)
+	tilesHandler := tiles.NewHandler(tilesService, envDuration("TILE_CACHE_MAX_AGE", tiles.DefaultMaxAge))
+
+	// 3b. Adaptador MQTT opcional: solo se arranca si hay un broker configurado.
+	var mqttSubscriber *mqtt.Subscriber
+	if mqttConfig, enabled := mqtt.ConfigFromEnv(); enabled {
+		mqttSubscriber, err = mqtt.NewSubscriber(mqttConfig, containerService)
+		if err != nil {
+			log.Fatalf("FATAL: Configuración MQTT inválida: %v", err)
+		}
+		if err := mqttSubscriber.Start(); err != nil {
+			log.Printf("Advertencia: %v", err)
+		}
+	} else {
+		log.Println("Info: MQTT_BROKER_URL no definida, la ingesta por MQTT está deshabilitada")
+	}
+
+	// Claves de idempotencia para que los reintentos de los gateways no dupliquen escrituras.
+	idempotencyStore := idempotency.NewPostgresStore(db)
+	idempotencyTTL := envDuration("IDEMPOTENCY_TTL", idempotency.DefaultTTL)
+	janitorCtx, stopJanitor := context.WithCancel(context.Background())
+	defer stopJanitor()
+	go idempotency.RunJanitor(janitorCtx, idempotencyStore, time.Hour)
 
 	// 4. Configurar el router de Gin
-	router := setupRouter(containerHandler)
+	router := setupRouter(idempotency.Middleware(idempotencyStore, idempotencyTTL),
+		containerHandler,     // Módulo de contenedores
+		containerTypeHandler, // Tipos de contenedor
+		dispatchHandler,      // Ejecución de las rutas guardadas
+		facilityHandler,      // Depósitos y puntos de descarga
+		lorawanHandler,       // Webhooks de los servidores de red LoRaWAN y gestión de sensores
+		streamHandler,        // Cambios de estado en tiempo real (SSE y WebSocket)
+		thresholdHandler,     // Perfiles de umbrales de estado
+		tilesHandler,         // Teselas vectoriales (MVT) de contenedores
+		timeWindowHandler,    // Perfiles de franjas horarias de recogida
+	)
 
 	// 5. Arrancar el servidor HTTP
 	apiPort := os.Getenv("API_PORT")
@@ -75,17 +174,112 @@ func main() {
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
+	// Las conexiones en tiempo real no terminan solas: al parar el servidor se cierran para que
+	// Shutdown no espere a que caduquen.
+	server.RegisterOnShutdown(streamHub.Close)
 
 	log.Printf("🚀 Servidor escuchando en el puerto %s", apiPort)
 	log.Printf("📘 Documentación de la API disponible en http://localhost:%s/swagger/index.html", apiPort)
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("FATAL: No se pudo iniciar el servidor: %v", err)
+	// 6. Arrancamos el servidor en una goroutine para poder escuchar las señales de parada.
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("FATAL: No se pudo iniciar el servidor: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Señal de parada recibida, deteniendo el servidor...")
+
+	// 7. Parada ordenada: primero dejamos de aceptar peticiones y después vaciamos la cola
+	// de ingesta, para no perder lecturas que ya se respondieron con 202 Accepted, y esperamos
+	// a los recálculos de estados en curso.
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelShutdown()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error al detener el servidor HTTP: %v", err)
+	}
+	if mqttSubscriber != nil {
+		mqttSubscriber.Stop()
+	}
+	if err := containerService.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error al vaciar la cola de ingesta: %v", err)
 	}
+	if err := thresholdService.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error al detener los recálculos de estados: %v", err)
+	}
+	log.Println("Servidor detenido correctamente")
+}
+
+// roadNetwork carga la red viaria del extracto OSM indicado en OSM_PBF_PATH para calcular las
+// rutas con distancias por carretera y trazar su recorrido. Devuelve nil si no hay extracto o si
+// no se puede cargar; en ese caso se usa la distancia en línea recta.
+func roadNetwork() *routing.Provider {
+	path := os.Getenv("OSM_PBF_PATH")
+	if path == "" {
+		log.Println("Info: OSM_PBF_PATH no definida, las rutas se calculan con distancias en línea recta")
+		return nil
+	}
+	start := time.Now()
+	graph, err := routing.LoadPBF(path)
+	if err != nil {
+		log.Printf("Advertencia: no se pudo cargar la red viaria, las rutas se calculan con distancias en línea recta: %v", err)
+		return nil
+	}
+	log.Printf("Red viaria cargada de %s: %d nodos y %d tramos en %s", path, graph.Nodes(), graph.Edges(), time.Since(start).Round(time.Millisecond))
+	return routing.NewProvider(graph)
+}
+
+// envDuration lee una variable de entorno con formato de duración de Go (ej. "24h"),
+// devolviendo el valor por defecto si no existe o no es válida.
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Advertencia: valor inválido para %s (%q), se usará %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// envInt lee una variable de entorno entera, devolviendo el valor por defecto si no existe o no es válida.
+func envInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Advertencia: valor inválido para %s (%q), se usará %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// envList lee una variable de entorno con una lista de valores separados por comas.
+func envList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// routeRegistrar es cualquier handler de módulo capaz de registrar sus rutas en un grupo.
+type routeRegistrar interface {
+	RegisterRoutes(router *gin.RouterGroup)
 }
 
 // setupRouter configura el router de Gin y registra todas las rutas.
-func setupRouter(containerHandler *container.Handler) *gin.Engine {
+func setupRouter(idempotencyMiddleware gin.HandlerFunc, handlers ...routeRegistrar) *gin.Engine {
 	// gin.SetMode(gin.ReleaseMode) // Descomentar para producción
 	router := gin.Default()
 
@@ -98,10 +292,13 @@ func setupRouter(containerHandler *container.Handler) *gin.Engine {
 	})
 
 	// Grupo de rutas para la v1 de la API
-	v1 := router.Group("/api/v1")
+	// Todas las rutas de escritura de la v1 aceptan la cabecera Idempotency-Key.
+	v1 := router.Group("/api/v1", idempotencyMiddleware)
 	{
-		// Registramos las rutas del módulo de contenedores
-		containerHandler.RegisterRoutes(v1)
+		// Registramos las rutas de cada módulo
+		for _, h := range handlers {
+			h.RegisterRoutes(v1)
+		}
 	}
 
 	// Ruta para la documentación de Swagger
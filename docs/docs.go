@@ -24,23 +24,23 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/containers": {
+        "/container-types": {
             "get": {
-                "description": "Devuelve una lista de todos los contenedores registrados con su estado actual.",
+                "description": "Devuelve todos los modelos de contenedor registrados con su volumen y sistema de elevación.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Containers"
+                    "ContainerTypes"
                 ],
-                "summary": "Obtiene todos los contenedores",
+                "summary": "Obtiene los tipos de contenedor",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/domain.Container"
+                                "$ref": "#/definitions/domain.ContainerType"
                             }
                         }
                     },
@@ -56,7 +56,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Registra un nuevo contenedor en el sistema con su ubicación y capacidad.",
+                "description": "Registra un modelo de contenedor (volumen y sistema de elevación) que se puede asignar a los contenedores.",
                 "consumes": [
                     "application/json"
                 ],
@@ -64,25 +64,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Containers"
+                    "ContainerTypes"
                 ],
-                "summary": "Crea un nuevo contenedor",
+                "summary": "Crea un tipo de contenedor",
                 "parameters": [
                     {
-                        "description": "Datos del contenedor a crear",
-                        "name": "container",
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Datos del tipo de contenedor",
+                        "name": "type",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/container.UpsertContainerRequest"
+                            "$ref": "#/definitions/containertype.UpsertTypeRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Contenedor creado exitosamente",
+                        "description": "Tipo de contenedor creado",
                         "schema": {
-                            "$ref": "#/definitions/domain.Container"
+                            "$ref": "#/definitions/domain.ContainerType"
                         }
                     },
                     "400": {
@@ -94,6 +100,15 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Error interno del servidor",
                         "schema": {
@@ -106,20 +121,19 @@ const docTemplate = `{
                 }
             }
         },
-        "/containers/{id}": {
+        "/container-types/{id}": {
             "get": {
-                "description": "Devuelve la información detallada de un único contenedor.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Containers"
+                    "ContainerTypes"
                 ],
-                "summary": "Obtiene un contenedor por su ID",
+                "summary": "Obtiene un tipo de contenedor por su ID",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID del Contenedor (UUID)",
+                        "description": "ID del tipo de contenedor (UUID)",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -129,11 +143,11 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/domain.Container"
+                            "$ref": "#/definitions/domain.ContainerType"
                         }
                     },
                     "404": {
-                        "description": "Contenedor no encontrado",
+                        "description": "Tipo de contenedor no encontrado",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -153,7 +167,6 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Actualiza la ubicación y/o la capacidad de un contenedor existente.",
                 "consumes": [
                     "application/json"
                 ],
@@ -161,30 +174,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Containers"
+                    "ContainerTypes"
                 ],
-                "summary": "Actualiza un contenedor",
+                "summary": "Actualiza un tipo de contenedor",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID del Contenedor (UUID)",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID del tipo de contenedor (UUID)",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Nuevos datos del contenedor",
-                        "name": "container",
+                        "description": "Nuevos datos del tipo de contenedor",
+                        "name": "type",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/container.UpsertContainerRequest"
+                            "$ref": "#/definitions/containertype.UpsertTypeRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Contenedor actualizado exitosamente",
+                        "description": "Tipo de contenedor actualizado",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -201,6 +220,24 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "404": {
+                        "description": "Tipo de contenedor no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Error interno del servidor",
                         "schema": {
@@ -213,15 +250,21 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Elimina un contenedor y todas sus lecturas asociadas del sistema.",
+                "description": "Elimina el tipo. No se puede eliminar mientras haya contenedores que lo usen.",
                 "tags": [
-                    "Containers"
+                    "ContainerTypes"
                 ],
-                "summary": "Elimina un contenedor",
+                "summary": "Elimina un tipo de contenedor",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID del Contenedor (UUID)",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID del tipo de contenedor (UUID)",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -231,6 +274,15 @@ const docTemplate = `{
                     "204": {
                         "description": "Sin contenido"
                     },
+                    "409": {
+                        "description": "El tipo está asignado a algún contenedor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Error interno del servidor",
                         "schema": {
@@ -243,38 +295,165 @@ const docTemplate = `{
                 }
             }
         },
-        "/containers/{id}/readings": {
+        "/containers": {
             "get": {
-                "description": "Devuelve una lista de las últimas N lecturas de sensor para un contenedor específico.",
+                "description": "Devuelve una página de los contenedores registrados con su estado actual, el total de contenedores que cumplen los filtros y el cursor ('next_cursor') de la página siguiente, que es null en la última. Para recorrer el listado se repite la consulta con 'cursor' y los mismos filtros y orden.\nSe puede filtrar por fracción, estado, rango de nivel de llenado y de capacidad, y por 'updated_before' para encontrar sensores que han dejado de informar.\nCon 'bbox' devuelve solo los contenedores de la zona visible del mapa. Con 'near' y 'radius_m' devuelve los que están a menos de 'radius_m' metros, por defecto del más cercano al más lejano y con su 'distance_meters'.\nCon 'Accept: application/geo+json' (o 'format=geojson') devuelve una FeatureCollection GeoJSON con un punto por contenedor y su estado, nivel de llenado y fechas como propiedades; 'next_cursor' y 'total' van como miembros de la colección.",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/geo+json"
                 ],
                 "tags": [
                     "Containers"
                 ],
-                "summary": "Obtiene el historial de lecturas de un contenedor",
+                "summary": "Obtiene los contenedores, paginados",
                 "parameters": [
                     {
+                        "enum": [
+                            "json",
+                            "geojson"
+                        ],
                         "type": "string",
-                        "description": "ID del Contenedor (UUID)",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Formato de la respuesta; tiene prioridad sobre la cabecera Accept",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "organic",
+                            "paper",
+                            "packaging",
+                            "glass",
+                            "residual",
+                            "textile"
+                        ],
+                        "type": "string",
+                        "description": "Fracción de residuo",
+                        "name": "fraction",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "low",
+                                "medium",
+                                "high"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Estado del contenedor (se puede repetir)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Nivel de llenado mínimo (0-100)",
+                        "name": "min_fill_level",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Nivel de llenado máximo (0-100)",
+                        "name": "max_fill_level",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Capacidad mínima en litros",
+                        "name": "min_capacity",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Capacidad máxima en litros",
+                        "name": "max_capacity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Solo los contenedores sin lecturas desde esta fecha (RFC 3339), incluidos los que nunca han enviado ninguna",
+                        "name": "updated_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "-3.72,40.40,-3.68,40.43",
+                        "description": "Rectángulo minLon,minLat,maxLon,maxLat",
+                        "name": "bbox",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "40.4168,-3.7038",
+                        "description": "Punto lat,lon de la búsqueda por radio",
+                        "name": "near",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Radio de la búsqueda en metros (obligatorio con 'near', máximo 50000)",
+                        "name": "radius_m",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "last_updated",
+                            "fill_level",
+                            "capacity",
+                            "distance"
+                        ],
+                        "type": "string",
+                        "description": "Campo de ordenación; por defecto 'distance' en las búsquedas por radio y 'created_at' en el resto. 'distance' solo se admite con 'near'. Con 'last_updated', los contenedores sin lecturas van como los más antiguos",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sentido del orden; por defecto 'asc' para 'distance' y 'desc' para el resto",
+                        "name": "order",
+                        "in": "query"
                     },
                     {
+                        "maximum": 1000,
+                        "minimum": 1,
                         "type": "integer",
-                        "description": "Número máximo de lecturas a devolver (por defecto 50)",
+                        "default": 100,
+                        "description": "Tamaño de página",
                         "name": "limit",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Valor de 'next_cursor' de la página anterior",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.Reading"
+                            "$ref": "#/definitions/container.ContainerPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Filtro, orden, tamaño de página o cursor inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -288,11 +467,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/readings": {
+            },
             "post": {
-                "description": "Registra el nivel de llenado de un contenedor en un momento dado.",
+                "description": "Registra un nuevo contenedor en el sistema con su ubicación y capacidad.",
                 "consumes": [
                     "application/json"
                 ],
@@ -300,23 +477,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Ingest"
+                    "Containers"
                 ],
-                "summary": "Crea una nueva lectura de sensor",
+                "summary": "Crea un nuevo contenedor",
                 "parameters": [
                     {
-                        "description": "Datos de la lectura",
-                        "name": "reading",
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Datos del contenedor a crear",
+                        "name": "container",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/domain.Reading"
+                            "$ref": "#/definitions/container.UpsertContainerRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Lectura aceptada para procesamiento",
+                    "201": {
+                        "description": "Contenedor creado exitosamente",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Container"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o datos incorrectos",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -324,8 +513,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Petición inválida o datos incorrectos",
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -345,9 +534,65 @@ const docTemplate = `{
                 }
             }
         },
-        "/routes": {
-            "post": {
-                "description": "Calcula una ruta óptima para visitar contenedores basados en su estado.",
+        "/containers/{id}": {
+            "get": {
+                "description": "Devuelve la información detallada de un único contenedor.\nCon 'Accept: application/geo+json' (o 'format=geojson') devuelve una Feature GeoJSON con la ubicación como punto.",
+                "produces": [
+                    "application/json",
+                    "application/geo+json"
+                ],
+                "tags": [
+                    "Containers"
+                ],
+                "summary": "Obtiene un contenedor por su ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID del Contenedor (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "geojson"
+                        ],
+                        "type": "string",
+                        "description": "Formato de la respuesta; tiene prioridad sobre la cabecera Accept",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Container"
+                        }
+                    },
+                    "404": {
+                        "description": "Contenedor no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Actualiza la ubicación y/o la capacidad de un contenedor existente.",
                 "consumes": [
                     "application/json"
                 ],
@@ -355,27 +600,40 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Routes"
+                    "Containers"
                 ],
-                "summary": "Genera una ruta de recogida",
+                "summary": "Actualiza un contenedor",
                 "parameters": [
                     {
-                        "description": "Parámetros para la generación de la ruta",
-                        "name": "routeRequest",
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID del Contenedor (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Nuevos datos del contenedor",
+                        "name": "container",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/container.RouteRequest"
+                            "$ref": "#/definitions/container.UpsertContainerRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "La ruta optimizada como una lista ordenada de contenedores",
+                        "description": "Contenedor actualizado exitosamente",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/domain.Container"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -388,6 +646,15 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Error interno del servidor",
                         "schema": {
@@ -398,119 +665,4384 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "container.RouteRequest": {
-            "type": "object",
-            "required": [
-                "start_point",
-                "statuses"
+            },
+            "delete": {
+                "description": "Elimina un contenedor y todas sus lecturas asociadas del sistema.",
+                "tags": [
+                    "Containers"
+                ],
+                "summary": "Elimina un contenedor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID del Contenedor (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Sin contenido"
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/containers/{id}/collections": {
+            "get": {
+                "description": "Devuelve las últimas recogidas del contenedor, tanto registradas como inferidas de una caída brusca del nivel de llenado.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Collections"
+                ],
+                "summary": "Obtiene el historial de recogidas de un contenedor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID del Contenedor (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Collection"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Registra que un camión ha vaciado el contenedor. El estado del contenedor se reinicia (nivel 0, 'low') salvo que ya tenga una lectura posterior.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Collections"
+                ],
+                "summary": "Registra una recogida",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID del Contenedor (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Datos de la recogida",
+                        "name": "collection",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/container.CollectionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Recogida registrada",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Collection"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o datos incorrectos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Contenedor no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/containers/{id}/forecast": {
+            "get": {
+                "description": "Estima la tasa de llenado, con estacionalidad por día de la semana y hora, a partir de las lecturas de las últimas semanas y predice cuándo llegará al 100%, con un intervalo de confianza.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Containers"
+                ],
+                "summary": "Predice cuándo se llenará un contenedor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID del Contenedor (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Forecast"
+                        }
+                    },
+                    "404": {
+                        "description": "Contenedor no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Historial de lecturas insuficiente",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/containers/{id}/readings": {
+            "get": {
+                "description": "Devuelve una página de las lecturas de sensor de un contenedor, de la más reciente a la más antigua, opcionalmente limitadas a [from, to). La página siguiente se pide con 'cursor' ('next_cursor' es null en la última página).\nCon 'bucket' (15m, 1h o 1d) las lecturas se agregan en la base de datos y se devuelve, por cada intervalo con lecturas, el nivel de llenado mínimo, máximo, medio y último y el número de lecturas (ReadingBucketPage). Los intervalos están alineados con la medianoche UTC; los de los extremos solo incluyen las lecturas dentro de [from, to).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Containers"
+                ],
+                "summary": "Obtiene el historial de lecturas de un contenedor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID del Contenedor (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Lecturas desde esta fecha, incluida (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Lecturas hasta esta fecha, excluida (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "15m",
+                            "1h",
+                            "1d"
+                        ],
+                        "type": "string",
+                        "description": "Agrega las lecturas en intervalos",
+                        "name": "bucket",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 5000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Tamaño de página (por defecto 50 lecturas o 1000 intervalos)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Valor de 'next_cursor' de la página anterior",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Lecturas (sin 'bucket')",
+                        "schema": {
+                            "$ref": "#/definitions/container.ReadingPage"
+                        }
+                    },
+                    "400": {
+                        "description": "ID, intervalo de fechas, agregación, tamaño de página o cursor inválidos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/facilities": {
+            "get": {
+                "description": "Devuelve las instalaciones fijas que usan las rutas: depósitos de camiones, estaciones de transferencia y vertederos.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Facilities"
+                ],
+                "summary": "Obtiene los depósitos y puntos de descarga",
+                "parameters": [
+                    {
+                        "enum": [
+                            "depot",
+                            "transfer_station",
+                            "landfill"
+                        ],
+                        "type": "string",
+                        "description": "Filtra por tipo de instalación",
+                        "name": "kind",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Facility"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Tipo de instalación desconocido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Registra una instalación fija. Las rutas salen de un depósito y vuelven a él, y descargan en las estaciones de transferencia o vertederos que admiten su fracción.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Facilities"
+                ],
+                "summary": "Crea un depósito o punto de descarga",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Datos de la instalación",
+                        "name": "facility",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/facility.UpsertFacilityRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Instalación creada",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Facility"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o datos incorrectos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/facilities/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Facilities"
+                ],
+                "summary": "Obtiene una instalación por su ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID de la instalación (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Facility"
+                        }
+                    },
+                    "404": {
+                        "description": "Instalación no encontrada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Facilities"
+                ],
+                "summary": "Actualiza una instalación",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID de la instalación (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Nuevos datos de la instalación",
+                        "name": "facility",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/facility.UpsertFacilityRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Instalación actualizada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o datos incorrectos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Instalación no encontrada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "Facilities"
+                ],
+                "summary": "Elimina una instalación",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID de la instalación (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Sin contenido"
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ingest/stats": {
+            "get": {
+                "description": "Devuelve la profundidad de la cola de ingesta, su capacidad y la latencia de los workers.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Ingest"
+                ],
+                "summary": "Obtiene las métricas de ingesta",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/container.IngestStats"
+                        }
+                    }
+                }
+            }
+        },
+        "/lorawan/decoders": {
+            "get": {
+                "description": "Devuelve los modelos de sensor para los que existe un decodificador de payload.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "LoRaWAN"
+                ],
+                "summary": "Obtiene los decodificadores disponibles",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lorawan/devices": {
+            "get": {
+                "description": "Devuelve todos los sensores LoRaWAN registrados, con el contenedor asociado y su última telemetría.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "LoRaWAN"
+                ],
+                "summary": "Obtiene los dispositivos LoRaWAN",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.SensorDevice"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Asocia un DevEUI a un contenedor y a un decodificador. Si el DevEUI ya existe, se actualiza.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "LoRaWAN"
+                ],
+                "summary": "Registra un dispositivo LoRaWAN",
+                "parameters": [
+                    {
+                        "description": "Datos del dispositivo",
+                        "name": "device",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/lorawan.RegisterDeviceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/domain.SensorDevice"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o decodificador desconocido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lorawan/devices/{dev_eui}": {
+            "delete": {
+                "description": "Elimina la asociación de un DevEUI con su contenedor.",
+                "tags": [
+                    "LoRaWAN"
+                ],
+                "summary": "Elimina un dispositivo LoRaWAN",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "DevEUI del dispositivo",
+                        "name": "dev_eui",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Sin contenido"
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lorawan/uplinks/chirpstack": {
+            "post": {
+                "description": "Webhook para la integración HTTP de ChirpStack (v4, codificación JSON). Los eventos distintos de 'up' se ignoran.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "LoRaWAN"
+                ],
+                "summary": "Recibe un uplink de ChirpStack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Secreto compartido del webhook (si está configurado)",
+                        "name": "X-Webhook-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tipo de evento enviado por ChirpStack (up, join, status...)",
+                        "name": "event",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Evento de uplink de ChirpStack",
+                        "name": "uplink",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/lorawan.ChirpStackUplink"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Uplink procesado",
+                        "schema": {
+                            "$ref": "#/definitions/lorawan.UplinkResult"
+                        }
+                    },
+                    "204": {
+                        "description": "Evento distinto de 'up', ignorado"
+                    },
+                    "400": {
+                        "description": "Petición inválida",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Token de webhook inválido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "DevEUI no registrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Payload no decodificable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lorawan/uplinks/ttn": {
+            "post": {
+                "description": "Webhook para The Things Stack (v3). Resuelve el contenedor a partir del DevEUI, decodifica 'frm_payload' con el decodificador del modelo de sensor y registra la lectura de llenado.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "LoRaWAN"
+                ],
+                "summary": "Recibe un uplink de The Things Stack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Secreto compartido del webhook (si está configurado)",
+                        "name": "X-Webhook-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Mensaje de uplink de The Things Stack",
+                        "name": "uplink",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/lorawan.TTNUplink"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Uplink procesado",
+                        "schema": {
+                            "$ref": "#/definitions/lorawan.UplinkResult"
+                        }
+                    },
+                    "204": {
+                        "description": "Evento sin uplink, ignorado"
+                    },
+                    "400": {
+                        "description": "Petición inválida",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Token de webhook inválido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "DevEUI no registrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Payload no decodificable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/readings": {
+            "post": {
+                "description": "Acepta el nivel de llenado de un contenedor en un momento dado y lo encola para su procesamiento asíncrono.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Ingest"
+                ],
+                "summary": "Crea una nueva lectura de sensor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Datos de la lectura",
+                        "name": "reading",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.Reading"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Lectura aceptada para procesamiento",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o datos incorrectos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Contenedor no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Cola de ingesta llena; reintentar tras Retry-After",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/readings/batch": {
+            "post": {
+                "description": "Registra de una vez hasta 10000 lecturas (p. ej. las acumuladas por un gateway sin conexión). Cada lectura se valida de forma individual y la respuesta indica si fue aceptada o rechazada.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Ingest"
+                ],
+                "summary": "Crea un lote de lecturas de sensor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Lote de lecturas",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/container.BatchReadingsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Resultado del lote, con el detalle por lectura",
+                        "schema": {
+                            "$ref": "#/definitions/container.BatchReadingsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o datos incorrectos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "El lote supera el tamaño máximo permitido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/routes": {
+            "get": {
+                "description": "Devuelve las rutas generadas con POST /routes, de la salida planificada más reciente a la más antigua, sin sus paradas.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Routes"
+                ],
+                "summary": "Obtiene las rutas guardadas",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "planned",
+                                "in_progress",
+                                "completed"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Filtra por estado (se puede repetir)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filtra por camión",
+                        "name": "vehicle",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filtra por conductor",
+                        "name": "driver",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Salida planificada a partir de esta fecha (RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Salida planificada antes de esta fecha (RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.DispatchedRoute"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Filtro inválido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Calcula una ruta óptima para visitar contenedores basados en su estado y, opcionalmente, en su fracción.\nCon 'forecast', incluye también los contenedores que se prevé que superen el umbral a la hora de la visita o antes de la siguiente ruta. Cada parada indica el motivo de su selección.\nLa ruta del vecino más cercano se mejora con 2-opt y Or-opt; la respuesta incluye la distancia antes y después de la mejora.\nCon 'shift', 'service_minutes' y 'average_speed_kmh' se calcula la hora de llegada (ETA) a cada parada respetando las franjas horarias de los contenedores; las paradas que no caben en su franja o en el turno se devuelven en 'unassigned' con su motivo.\nCon 'fleet', las paradas se reparten entre los camiones según su carga estimada (capacidad y nivel de llenado) y se devuelve una ruta cerrada desde el depósito por camión; las paradas que no caben quedan en 'unassigned'.\nLas rutas se guardan en estado 'planned' para su ejecución (ver GET /routes/{id}) y la respuesta incluye su 'id'; con 'dry_run' solo se calculan.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Routes"
+                ],
+                "summary": "Genera una ruta de recogida",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Parámetros para la generación de la ruta",
+                        "name": "routeRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/container.RouteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Con 'dry_run', las rutas calculadas sin guardar",
+                        "schema": {
+                            "$ref": "#/definitions/domain.RoutePlan"
+                        }
+                    },
+                    "201": {
+                        "description": "Una ruta guardada por camión con sus paradas en orden, su carga y su distancia",
+                        "schema": {
+                            "$ref": "#/definitions/domain.RoutePlan"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o datos incorrectos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/routes/{id}": {
+            "get": {
+                "description": "Devuelve la ruta con sus paradas en el orden planificado, el resultado de cada una y la comparación entre lo planificado y lo ejecutado.\nCon 'format' (o la cabecera Accept) la ruta se descarga como GeoJSON (application/geo+json), GPX (application/gpx+xml) o KML (application/vnd.google-earth.kml+xml): una entidad por parada con su secuencia y los datos del contenedor, y el trazado desde el punto de salida, por carretera si hay una red viaria cargada.",
+                "produces": [
+                    "application/json",
+                    "application/geo+json",
+                    "application/gpx+xml",
+                    "application/vnd.google-earth.kml+xml"
+                ],
+                "tags": [
+                    "Routes"
+                ],
+                "summary": "Obtiene una ruta guardada",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID de la ruta (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "geojson",
+                            "gpx",
+                            "kml"
+                        ],
+                        "type": "string",
+                        "description": "Formato de descarga; tiene prioridad sobre la cabecera Accept",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DispatchedRoute"
+                        }
+                    },
+                    "400": {
+                        "description": "Formato no soportado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Ruta no encontrada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/routes/{id}/assignment": {
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Routes"
+                ],
+                "summary": "Asigna el camión y el conductor de una ruta",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID de la ruta (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Camión y conductor",
+                        "name": "assignment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dispatch.AssignRouteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DispatchedRoute"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o datos incorrectos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Ruta no encontrada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "La ruta ya está terminada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/routes/{id}/complete": {
+            "post": {
+                "description": "Pasa una ruta en curso a 'completed'. Las paradas sin resultado quedan como 'pending' y cuentan como no visitadas en la comparación.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Routes"
+                ],
+                "summary": "Termina una ruta",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID de la ruta (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Hora real de fin",
+                        "name": "transition",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dispatch.RouteTransitionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DispatchedRoute"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o datos incorrectos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Ruta no encontrada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "La ruta no está en curso",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/routes/{id}/reroute": {
+            "post": {
+                "description": "Añade a una ruta en curso los contenedores que se han vuelto urgentes (por defecto, en estado 'high') y que no están en otra ruta pendiente. Cada contenedor se inserta en la posición de las paradas pendientes que menos alarga la ruta desde la posición del camión, sin superar su capacidad en ningún viaje (entre descargas). La capacidad es la de la flota con la que se planificó la ruta; 'capacity_liters' y 'capacity_kg' la sustituyen.\nDevuelve las paradas añadidas, las que cambian de posición y los kilómetros de más. Con 'dry_run' los cambios no se guardan. Las paradas añadidas no tienen ETA planificada.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Routes"
+                ],
+                "summary": "Re-planifica una ruta en curso",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID de la ruta (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Posición del camión y capacidad",
+                        "name": "reroute",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dispatch.RerouteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Reroute"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o datos incorrectos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Ruta no encontrada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "La ruta no está en curso o ha cambiado mientras se re-planificaba",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/routes/{id}/start": {
+            "post": {
+                "description": "Pasa una ruta planificada a 'in_progress'. Marcar la primera parada también la inicia.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Routes"
+                ],
+                "summary": "Inicia una ruta",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID de la ruta (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Hora real de inicio",
+                        "name": "transition",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dispatch.RouteTransitionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DispatchedRoute"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o datos incorrectos",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Ruta no encontrada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "La ruta no está planificada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/routes/{id}/stops/{stop_id}": {
+            "put": {
+                "description": "El conductor marca un contenedor como recogido ('collected'), no recogido ('skipped', con el motivo 'blocked' o 'inaccessible') o desbordado ('overflowing').\nSi el contenedor se vació (recogido o desbordado) se registra la recogida y el contenedor vuelve a estado bajo. Cada parada se marca una sola vez.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Routes"
+                ],
+                "summary": "Marca el resultado de una parada",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID de la ruta (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID de la parada (UUID)",
+                        "name": "stop_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Resultado de la parada",
+                        "name": "result",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dispatch.StopUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.DispatchedStop"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o resultado incoherente",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Ruta o parada no encontrada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "La parada ya tiene resultado o la ruta está terminada",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/stream/containers": {
+            "get": {
+                "description": "Abre un canal con un evento por cada lectura que cambia el nivel de llenado o el estado de un contenedor, con el nivel y el estado anteriores y nuevos, su ubicación y su fracción.\nPor defecto responde con Server-Sent Events ('text/event-stream'): cada cambio es un evento 'container' con el cambio en JSON, y cada 25 s se envía un comentario de latido. Si la petición es un upgrade a WebSocket, cada cambio es un mensaje de texto {\"event\": \"container\", \"data\": {...}}.\nCada cliente tiene un buffer propio: si no consume los eventos a tiempo se cierra su canal (evento o mensaje 'close' con el motivo) para no frenar la ingesta; el cliente debe reconectar y volver a leer el estado con GET /containers.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Stream"
+                ],
+                "summary": "Recibe en tiempo real los cambios de estado de los contenedores",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "ID de contenedor (se puede repetir)",
+                        "name": "container_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "-3.72,40.40,-3.68,40.43",
+                        "description": "Rectángulo minLon,minLat,maxLon,maxLat",
+                        "name": "bbox",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "organic",
+                            "paper",
+                            "packaging",
+                            "glass",
+                            "residual",
+                            "textile"
+                        ],
+                        "type": "string",
+                        "description": "Fracción de residuo",
+                        "name": "fraction",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Solo los cambios de estado, sin los que solo cambian el nivel de llenado",
+                        "name": "transitions_only",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "low",
+                                "medium",
+                                "high"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Solo los cambios de estado que salen de este estado (se puede repetir)",
+                        "name": "from_status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "low",
+                                "medium",
+                                "high"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Solo los cambios de estado que llegan a este estado (se puede repetir)",
+                        "name": "to_status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Flujo de eventos 'container'",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ContainerUpdate"
+                        }
+                    },
+                    "400": {
+                        "description": "Filtro inválido",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "El servidor se está deteniendo",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/threshold-profiles": {
+            "get": {
+                "description": "Devuelve todos los perfiles de umbrales de estado definidos.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Thresholds"
+                ],
+                "summary": "Obtiene los perfiles de umbrales",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.ThresholdProfile"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Define a partir de qué nivel de llenado un contenedor pasa a 'medium' y a 'high'.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Thresholds"
+                ],
+                "summary": "Crea un perfil de umbrales",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Datos del perfil",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/threshold.UpsertProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Perfil creado",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ThresholdProfile"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o umbrales incoherentes",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/threshold-profiles/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Thresholds"
+                ],
+                "summary": "Obtiene un perfil de umbrales por su ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID del perfil (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ThresholdProfile"
+                        }
+                    },
+                    "404": {
+                        "description": "Perfil no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Actualiza los umbrales del perfil. El estado de los contenedores que lo usan se recalcula en segundo plano.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Thresholds"
+                ],
+                "summary": "Actualiza un perfil de umbrales",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID del perfil (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Nuevos datos del perfil",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/threshold.UpsertProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Perfil actualizado; recálculo de estados en curso",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o umbrales incoherentes",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Perfil no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Elimina el perfil. Sus contenedores vuelven a los umbrales por defecto y su estado se recalcula.",
+                "tags": [
+                    "Thresholds"
+                ],
+                "summary": "Elimina un perfil de umbrales",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID del perfil (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Sin contenido"
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/threshold-profiles/{id}/recompute": {
+            "post": {
+                "description": "Re-deriva de forma síncrona el estado de todos los contenedores que usan el perfil a partir de su último nivel de llenado.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Thresholds"
+                ],
+                "summary": "Recalcula el estado de los contenedores de un perfil",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID del perfil (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Número de contenedores cuyo estado cambió",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Perfil no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tiles/containers/{z}/{x}/{y}.mvt": {
+            "get": {
+                "description": "Devuelve la tesela {z}/{x}/{y} en formato Mapbox Vector Tile, con una capa 'containers'. Hasta el zoom 14 los contenedores se agrupan en una rejilla de 8×8 celdas por tesela: cada punto es un grupo con 'point_count', 'high_count', 'medium_count', 'low_count', 'avg_fill_level', el peor 'status' y el mayor 'fill_level' ('cluster' = true; los grupos de un contenedor llevan su 'id').\nA partir del zoom 15 cada contenedor es un punto con 'id', 'status', 'fill_level', 'fraction', 'capacity_liters' y 'last_updated'. La respuesta lleva ETag y Cache-Control para poder poner una caché de teselas delante; con 'If-None-Match' responde 304 si la tesela no ha cambiado.",
+                "produces": [
+                    "application/vnd.mapbox-vector-tile"
+                ],
+                "tags": [
+                    "Tiles"
+                ],
+                "summary": "Obtiene una tesela vectorial de contenedores",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Nivel de zoom (0-22)",
+                        "name": "z",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Columna de la tesela",
+                        "name": "x",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fila de la tesela, con la extensión .mvt (p. ej. 1547.mvt)",
+                        "name": "y",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "organic",
+                            "paper",
+                            "packaging",
+                            "glass",
+                            "residual",
+                            "textile"
+                        ],
+                        "type": "string",
+                        "description": "Fracción de residuo",
+                        "name": "fraction",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag de la tesela que ya tiene el cliente",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tesela MVT (vacía si no hay contenedores)",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "La tesela no ha cambiado",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Coordenadas o fracción inválidas",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/time-window-profiles": {
+            "get": {
+                "description": "Devuelve las franjas de recogida definidas por zona.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "TimeWindows"
+                ],
+                "summary": "Obtiene los perfiles de franjas horarias",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.TimeWindowProfile"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Define en qué franjas diarias (hora local) se pueden recoger los contenedores de una zona. Se asigna a los contenedores con 'time_window_profile_id'.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "TimeWindows"
+                ],
+                "summary": "Crea un perfil de franjas horarias",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Nombre y franjas del perfil",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/timewindow.UpsertProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Perfil creado",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TimeWindowProfile"
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o franjas incorrectas",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/time-window-profiles/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "TimeWindows"
+                ],
+                "summary": "Obtiene un perfil de franjas horarias por su ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID del perfil (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.TimeWindowProfile"
+                        }
+                    },
+                    "404": {
+                        "description": "Perfil no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Los cambios se aplican a las rutas que se generen a partir de ese momento.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "TimeWindows"
+                ],
+                "summary": "Actualiza un perfil de franjas horarias",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID del perfil (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Nuevos datos del perfil",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/timewindow.UpsertProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Perfil actualizado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Petición inválida o franjas incorrectas",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Perfil no encontrado",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key reutilizada con una petición distinta",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Los contenedores que lo usaban pasan a poder recogerse a cualquier hora.",
+                "tags": [
+                    "TimeWindows"
+                ],
+                "summary": "Elimina un perfil de franjas horarias",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Clave para reintentar la petición de forma segura",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID del perfil (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Sin contenido"
+                    },
+                    "500": {
+                        "description": "Error interno del servidor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "container.BatchItemResult": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "boolean"
+                },
+                "container_id": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "outcome": {
+                    "description": "Outcome indica, para las lecturas aceptadas, si se aplicaron, se guardaron solo como historial o se descartaron por duplicadas.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.ReadingOutcome"
+                        }
+                    ]
+                }
+            }
+        },
+        "container.BatchReadingsRequest": {
+            "type": "object",
+            "required": [
+                "readings"
+            ],
+            "properties": {
+                "readings": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/domain.Reading"
+                    }
+                }
+            }
+        },
+        "container.BatchReadingsResponse": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/container.BatchItemResult"
+                    }
+                }
+            }
+        },
+        "container.CollectionRequest": {
+            "type": "object",
+            "required": [
+                "collected_by"
+            ],
+            "properties": {
+                "collected_at": {
+                    "description": "CollectedAt es el momento de la recogida; si se omite, se usa la hora actual.",
+                    "type": "string"
+                },
+                "collected_by": {
+                    "description": "CollectedBy identifica al operario o camión que hizo la recogida.",
+                    "type": "string"
+                },
+                "estimated_volume_liters": {
+                    "description": "EstimatedVolumeLiters es el volumen recogido; si se omite, se estima a partir del nivel de llenado previo.",
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "container.ContainerPage": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Container"
+                    }
+                },
+                "next_cursor": {
+                    "description": "NextCursor se pasa como 'cursor' para pedir la página siguiente; es null en la última página.",
+                    "type": "string"
+                },
+                "total": {
+                    "description": "Total es el número de contenedores que cumplen el filtro, sumando todas las páginas.",
+                    "type": "integer"
+                }
+            }
+        },
+        "container.FleetRequest": {
+            "type": "object",
+            "required": [
+                "vehicles"
+            ],
+            "properties": {
+                "capacity_kg": {
+                    "type": "number"
+                },
+                "capacity_liters": {
+                    "description": "Capacidad de carga de cada camión en litros y/o en kilos.",
+                    "type": "number"
+                },
+                "vehicles": {
+                    "type": "integer"
+                }
+            }
+        },
+        "container.ForecastRouteRequest": {
+            "type": "object",
+            "required": [
+                "next_run_at"
+            ],
+            "properties": {
+                "fill_threshold": {
+                    "description": "FillThreshold es el llenado previsto (en %) a partir del cual se recoge; por defecto 90.",
+                    "type": "integer",
+                    "maximum": 100
+                },
+                "next_run_at": {
+                    "description": "NextRunAt es la hora de la siguiente ruta: se recoge todo lo que se prevé que supere el umbral antes de ella.",
+                    "type": "string"
+                },
+                "visit_at": {
+                    "description": "VisitAt es la hora prevista de la visita; si se omite, se usa la hora actual.",
+                    "type": "string"
+                }
+            }
+        },
+        "container.IngestStats": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "description": "Desglose de las lecturas procesadas según su efecto.",
+                    "type": "integer"
+                },
+                "avg_latency_ms": {
+                    "type": "number"
+                },
+                "avg_wait_ms": {
+                    "type": "number"
+                },
+                "duplicates": {
+                    "type": "integer"
+                },
+                "enqueued": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "history_only": {
+                    "type": "integer"
+                },
+                "last_latency_ms": {
+                    "type": "number"
+                },
+                "processed": {
+                    "type": "integer"
+                },
+                "queue_capacity": {
+                    "type": "integer"
+                },
+                "queue_depth": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "container.OptimizationRequest": {
+            "type": "object",
+            "properties": {
+                "algorithms": {
+                    "description": "Algorithms son las fases de mejora (\"2opt\", \"oropt\"). Una lista vacía deja la ruta del vecino más cercano.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/optimizer.Algorithm"
+                    }
+                },
+                "time_budget_ms": {
+                    "description": "TimeBudgetMs limita el tiempo de mejora en milisegundos.",
+                    "type": "integer",
+                    "maximum": 60000
+                }
+            }
+        },
+        "container.ReadingBucketPage": {
+            "type": "object",
+            "properties": {
+                "bucket": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.ReadingBucket"
+                    }
+                },
+                "next_cursor": {
+                    "description": "NextCursor se pasa como 'cursor' para pedir la página siguiente; es null en la última página.",
+                    "type": "string"
+                }
+            }
+        },
+        "container.ReadingPage": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Reading"
+                    }
+                },
+                "next_cursor": {
+                    "description": "NextCursor se pasa como 'cursor' para pedir la página siguiente; es null en la última página.",
+                    "type": "string"
+                }
+            }
+        },
+        "container.RouteRequest": {
+            "type": "object",
+            "properties": {
+                "average_speed_kmh": {
+                    "description": "AverageSpeedKmh es la velocidad media de los camiones; por defecto 25 km/h.",
+                    "type": "number",
+                    "maximum": 130
+                },
+                "depot_id": {
+                    "description": "DepotID es el depósito del que salen y al que vuelven los camiones.",
+                    "type": "string"
+                },
+                "dry_run": {
+                    "description": "DryRun calcula las rutas sin guardarlas; por defecto se guardan para su ejecución.",
+                    "type": "boolean"
+                },
+                "fleet": {
+                    "description": "Fleet reparte las paradas entre varios camiones; si se omite, se genera una única ruta.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/container.FleetRequest"
+                        }
+                    ]
+                },
+                "forecast": {
+                    "description": "Forecast selecciona además los contenedores por su llenado previsto.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/container.ForecastRouteRequest"
+                        }
+                    ]
+                },
+                "fraction": {
+                    "description": "Fraction limita la ruta a una fracción de residuo. Si se omite, se incluyen todas.",
+                    "enum": [
+                        "organic",
+                        "paper",
+                        "packaging",
+                        "glass",
+                        "residual",
+                        "textile"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Fraction"
+                        }
+                    ]
+                },
+                "optimization": {
+                    "description": "Optimization configura la mejora local de la ruta; si se omite, se aplican 2-opt y Or-opt durante un máximo de 2 s.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/container.OptimizationRequest"
+                        }
+                    ]
+                },
+                "service_minutes": {
+                    "description": "ServiceMinutes es el tiempo de recogida de cada contenedor; por defecto 2 minutos.",
+                    "type": "number",
+                    "maximum": 60
+                },
+                "shift": {
+                    "description": "Shift es el turno de los camiones; si se omite, las rutas empiezan ahora y no tienen hora límite.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/container.ShiftRequest"
+                        }
+                    ]
+                },
+                "start_point": {
+                    "description": "StartPoint es el punto de salida. Es obligatorio salvo que se indique 'depot_id'.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Point"
+                        }
+                    ]
+                },
+                "statuses": {
+                    "description": "Statuses selecciona los contenedores por su estado actual. Es obligatorio salvo que se indique 'forecast'.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Status"
+                    }
+                },
+                "time_zone": {
+                    "description": "TimeZone es la zona horaria (IANA) de las franjas horarias de los contenedores; por defecto Europe/Madrid.",
+                    "type": "string",
+                    "example": "Europe/Madrid"
+                }
+            }
+        },
+        "container.ShiftRequest": {
+            "type": "object",
+            "required": [
+                "end",
+                "start"
+            ],
+            "properties": {
+                "end": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "container.UpsertContainerRequest": {
+            "type": "object",
+            "required": [
+                "capacity_liters",
+                "latitude",
+                "longitude"
+            ],
+            "properties": {
+                "capacity_liters": {
+                    "type": "integer"
+                },
+                "container_type_id": {
+                    "type": "string"
+                },
+                "fraction": {
+                    "description": "Fraction es la fracción de residuo; si se omite, el contenedor es de la fracción resto.",
+                    "enum": [
+                        "organic",
+                        "paper",
+                        "packaging",
+                        "glass",
+                        "residual",
+                        "textile"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Fraction"
+                        }
+                    ]
+                },
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                },
+                "threshold_profile_id": {
+                    "description": "ThresholdProfileID asigna un perfil de umbrales; si se omite, se usan los umbrales por defecto.",
+                    "type": "string"
+                },
+                "time_window_profile_id": {
+                    "description": "TimeWindowProfileID asigna las franjas horarias de recogida de su zona; si se omite, se recoge a cualquier hora.",
+                    "type": "string"
+                }
+            }
+        },
+        "containertype.UpsertTypeRequest": {
+            "type": "object",
+            "required": [
+                "lift_mechanism",
+                "model",
+                "volume_liters"
+            ],
+            "properties": {
+                "lift_mechanism": {
+                    "enum": [
+                        "rear_loader",
+                        "side_loader",
+                        "front_loader",
+                        "crane"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.LiftMechanism"
+                        }
+                    ]
+                },
+                "model": {
+                    "type": "string"
+                },
+                "volume_liters": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dispatch.AssignRouteRequest": {
+            "type": "object",
+            "properties": {
+                "driver": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 1,
+                    "example": "conductor-17"
+                },
+                "vehicle": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "minLength": 1,
+                    "example": "1234-KLM"
+                }
+            }
+        },
+        "dispatch.RerouteRequest": {
+            "type": "object",
+            "required": [
+                "position"
+            ],
+            "properties": {
+                "capacity_kg": {
+                    "type": "number"
+                },
+                "capacity_liters": {
+                    "description": "Capacidad del camión en litros y/o en kilos; si se omite, se usa la de la flota con la que se\nplanificó la ruta (y, si se planificó sin flota, no se limita la carga).",
+                    "type": "number"
+                },
+                "dry_run": {
+                    "description": "DryRun calcula los cambios sin guardarlos.",
+                    "type": "boolean"
+                },
+                "max_detour_km": {
+                    "description": "MaxDetourKm es lo máximo que puede alargar la ruta cada contenedor añadido; si se omite, no hay límite.",
+                    "type": "number"
+                },
+                "position": {
+                    "description": "Position es la posición actual del camión.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Point"
+                        }
+                    ]
+                },
+                "statuses": {
+                    "description": "Statuses son los estados de los contenedores que se consideran urgentes; por defecto, 'high'.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Status"
+                    }
+                }
+            }
+        },
+        "dispatch.RouteTransitionRequest": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "description": "At es la hora del cambio; si se omite, se usa la hora actual.",
+                    "type": "string"
+                }
+            }
+        },
+        "dispatch.StopUpdateRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "notes": {
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "skip_reason": {
+                    "description": "SkipReason es obligatorio si la parada no se recogió.",
+                    "enum": [
+                        "blocked",
+                        "inaccessible"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.SkipReason"
+                        }
+                    ]
+                },
+                "status": {
+                    "enum": [
+                        "collected",
+                        "skipped",
+                        "overflowing"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.StopStatus"
+                        }
+                    ]
+                },
+                "visited_at": {
+                    "description": "VisitedAt es la hora de la visita; si se omite, se usa la hora actual.",
+                    "type": "string"
+                },
+                "volume_liters": {
+                    "description": "VolumeLiters es el volumen recogido; si se omite, se estima a partir del nivel de llenado previo.",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Collection": {
+            "type": "object",
+            "properties": {
+                "collected_at": {
+                    "type": "string"
+                },
+                "collected_by": {
+                    "description": "CollectedBy identifica al operario o camión que hizo la recogida. Es nil en las recogidas inferidas.",
+                    "type": "string"
+                },
+                "container_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "estimated_volume_liters": {
+                    "description": "EstimatedVolumeLiters es el volumen recogido, informado por el operario o estimado\na partir del nivel de llenado previo y de la capacidad del contenedor.",
+                    "type": "integer"
+                },
+                "fill_level_before": {
+                    "description": "FillLevelBefore es el nivel de llenado (%) que tenía el contenedor antes de vaciarse.",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "inferred": {
+                    "description": "Inferred indica que la recogida no se registró explícitamente, sino que se dedujo\nde una caída brusca del nivel de llenado.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "domain.Container": {
+            "type": "object",
+            "properties": {
+                "capacity_liters": {
+                    "type": "integer"
+                },
+                "container_type_id": {
+                    "description": "ContainerTypeID referencia el modelo de contenedor (volumen y sistema de elevación), si se conoce.",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "--- CAMPOS ACTUALIZADOS ---\nEstos campos son gestionados por la base de datos y son cruciales para el tracking.",
+                    "type": "string"
+                },
+                "distance_meters": {
+                    "description": "DistanceMeters es la distancia al punto de una búsqueda por radio. No se persiste.",
+                    "type": "number"
+                },
+                "forecast": {
+                    "description": "Forecast es la predicción de llenado calculada a partir del historial. No se persiste.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Forecast"
+                        }
+                    ]
+                },
+                "fraction": {
+                    "description": "Fraction es la fracción de residuo que recoge el contenedor.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Fraction"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_fill_level": {
+                    "type": "integer"
+                },
+                "last_updated": {
+                    "type": "string"
+                },
+                "location": {
+                    "$ref": "#/definitions/domain.Point"
+                },
+                "status": {
+                    "description": "omitempty porque no se establece al crear",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Status"
+                        }
+                    ]
+                },
+                "threshold_profile_id": {
+                    "description": "ThresholdProfileID referencia el perfil de umbrales del contenedor. Si es nil se usan los umbrales por defecto.",
+                    "type": "string"
+                },
+                "time_window_profile_id": {
+                    "description": "TimeWindowProfileID referencia las franjas horarias en las que se puede recoger. Si es nil, a cualquier hora.",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ContainerType": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lift_mechanism": {
+                    "$ref": "#/definitions/domain.LiftMechanism"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "volume_liters": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.ContainerUpdate": {
+            "type": "object",
+            "properties": {
+                "container_id": {
+                    "type": "string"
+                },
+                "fill_level": {
+                    "type": "integer"
+                },
+                "fraction": {
+                    "$ref": "#/definitions/domain.Fraction"
+                },
+                "location": {
+                    "$ref": "#/definitions/domain.Point"
+                },
+                "previous_fill_level": {
+                    "type": "integer"
+                },
+                "previous_status": {
+                    "$ref": "#/definitions/domain.Status"
+                },
+                "recorded_at": {
+                    "description": "RecordedAt es la marca de tiempo de la lectura aplicada.",
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/domain.Status"
+                }
+            }
+        },
+        "domain.DispatchedRoute": {
+            "type": "object",
+            "properties": {
+                "added_distance_km": {
+                    "type": "number"
+                },
+                "capacity_kg": {
+                    "type": "number"
+                },
+                "capacity_liters": {
+                    "description": "Capacidad del camión en litros y en kilos con la que se planificó la ruta. Cero significa sin límite.",
+                    "type": "number"
+                },
+                "comparison": {
+                    "description": "Comparison compara lo planificado con lo ejecutado. No se incluye en los listados.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.RouteComparison"
+                        }
+                    ]
+                },
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "depot_id": {
+                    "type": "string"
+                },
+                "driver": {
+                    "type": "string"
+                },
+                "fraction": {
+                    "$ref": "#/definitions/domain.Fraction"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "planned_distance_km": {
+                    "type": "number"
+                },
+                "planned_duration_minutes": {
+                    "type": "number"
+                },
+                "planned_end_at": {
+                    "type": "string"
+                },
+                "planned_load_kg": {
+                    "type": "number"
+                },
+                "planned_load_liters": {
+                    "type": "number"
+                },
+                "planned_start_at": {
+                    "description": "Datos planificados al generar la ruta.",
+                    "type": "string"
+                },
+                "rerouted_at": {
+                    "description": "ReroutedAt es la última vez que se añadieron paradas a la ruta en curso y AddedDistanceKm, lo\nque se ha alargado la ruta en total por ello.",
+                    "type": "string"
+                },
+                "start_point": {
+                    "description": "StartPoint es el punto de salida de la ruta (el depósito, si sale de uno).",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Point"
+                        }
+                    ]
+                },
+                "started_at": {
+                    "description": "StartedAt y CompletedAt son el inicio y el fin reales de la ruta.",
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/domain.RouteStatus"
+                },
+                "stops": {
+                    "description": "Stops son las paradas en el orden planificado. No se incluyen en los listados.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.DispatchedStop"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "vehicle": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.DispatchedStop": {
+            "type": "object",
+            "properties": {
+                "added_at": {
+                    "description": "AddedAt es la hora a la que se añadió la parada al re-planificar la ruta en curso; falta en las\nparadas planificadas al generarla. Las paradas añadidas no tienen ETA planificada.",
+                    "type": "string"
+                },
+                "collected_volume_liters": {
+                    "type": "integer"
+                },
+                "collection_id": {
+                    "description": "CollectionID es la recogida registrada al vaciar el contenedor y CollectedVolumeLiters, su volumen.",
+                    "type": "string"
+                },
+                "container": {
+                    "description": "Container y FacilityName describen el contenedor o el punto de descarga tal como están ahora.\nFaltan si se han eliminado.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.StopContainerDetails"
+                        }
+                    ]
+                },
+                "container_id": {
+                    "type": "string"
+                },
+                "delay_minutes": {
+                    "type": "number"
+                },
+                "estimated_load_kg": {
+                    "type": "number"
+                },
+                "estimated_load_liters": {
+                    "type": "number"
+                },
+                "facility_id": {
+                    "type": "string"
+                },
+                "facility_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "$ref": "#/definitions/domain.StopKind"
+                },
+                "location": {
+                    "$ref": "#/definitions/domain.Point"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "planned_eta": {
+                    "description": "Datos planificados de la parada.",
+                    "type": "string"
+                },
+                "sequence": {
+                    "description": "Sequence es la posición planificada de la parada en la ruta, empezando por 1.",
+                    "type": "integer"
+                },
+                "skip_reason": {
+                    "$ref": "#/definitions/domain.SkipReason"
+                },
+                "status": {
+                    "description": "Resultado de la parada. Las descargas se quedan siempre en 'pending'.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.StopStatus"
+                        }
+                    ]
+                },
+                "visited_at": {
+                    "description": "VisitedAt es la hora real de la visita y DelayMinutes, el retraso respecto a la ETA planificada\n(negativo si se llegó antes).",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.Facility": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "fractions": {
+                    "description": "Fractions son las fracciones que admite un punto de descarga. Vacío significa que admite todas.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Fraction"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "$ref": "#/definitions/domain.FacilityKind"
+                },
+                "location": {
+                    "$ref": "#/definitions/domain.Point"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.FacilityKind": {
+            "type": "string",
+            "enum": [
+                "depot",
+                "transfer_station",
+                "landfill"
+            ],
+            "x-enum-varnames": [
+                "FacilityDepot",
+                "FacilityTransferStation",
+                "FacilityLandfill"
+            ]
+        },
+        "domain.Forecast": {
+            "type": "object",
+            "properties": {
+                "confidence_level": {
+                    "type": "number"
+                },
+                "container_id": {
+                    "type": "string"
+                },
+                "fill_rate_per_hour": {
+                    "description": "FillRatePerHour es la tasa media de llenado (puntos porcentuales por hora), sin estacionalidad.",
+                    "type": "number"
+                },
+                "full_at_earliest": {
+                    "description": "FullAtEarliest y FullAtLatest delimitan el intervalo de confianza de PredictedFullAt.",
+                    "type": "string"
+                },
+                "full_at_latest": {
+                    "type": "string"
+                },
+                "generated_at": {
+                    "type": "string"
+                },
+                "hours_to_full": {
+                    "type": "number"
+                },
+                "predicted_fill_level": {
+                    "description": "PredictedFillLevel es el nivel de llenado estimado en el momento de generar la predicción.",
+                    "type": "integer"
+                },
+                "predicted_full_at": {
+                    "description": "PredictedFullAt es el momento estimado en que el contenedor alcanzará el 100%.",
+                    "type": "string"
+                },
+                "sample_size": {
+                    "description": "SampleSize es el número de intervalos entre lecturas usados para ajustar el modelo.",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Fraction": {
+            "type": "string",
+            "enum": [
+                "organic",
+                "paper",
+                "packaging",
+                "glass",
+                "residual",
+                "textile",
+                "residual"
+            ],
+            "x-enum-comments": {
+                "FractionPackaging": "Plásticos, latas y briks."
+            },
+            "x-enum-varnames": [
+                "FractionOrganic",
+                "FractionPaper",
+                "FractionPackaging",
+                "FractionGlass",
+                "FractionResidual",
+                "FractionTextile",
+                "DefaultFraction"
+            ]
+        },
+        "domain.LiftMechanism": {
+            "type": "string",
+            "enum": [
+                "rear_loader",
+                "side_loader",
+                "front_loader",
+                "crane"
+            ],
+            "x-enum-comments": {
+                "LiftCrane": "Grúa con gancho (iglús y soterrados).",
+                "LiftFrontLoader": "Carga frontal.",
+                "LiftRearLoader": "Carga trasera (contenedores de 2 o 4 ruedas).",
+                "LiftSideLoader": "Carga lateral automatizada."
+            },
+            "x-enum-varnames": [
+                "LiftRearLoader",
+                "LiftSideLoader",
+                "LiftFrontLoader",
+                "LiftCrane"
+            ]
+        },
+        "domain.Point": {
+            "type": "object",
+            "properties": {
+                "latitude": {
+                    "type": "number"
+                },
+                "longitude": {
+                    "type": "number"
+                }
+            }
+        },
+        "domain.Reading": {
+            "type": "object",
+            "properties": {
+                "container_id": {
+                    "type": "string"
+                },
+                "fill_level": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ReadingBucket": {
+            "type": "object",
+            "properties": {
+                "avg_fill_level": {
+                    "type": "number"
+                },
+                "end": {
+                    "type": "string"
+                },
+                "last_fill_level": {
+                    "type": "integer"
+                },
+                "max_fill_level": {
+                    "type": "integer"
+                },
+                "min_fill_level": {
+                    "type": "integer"
+                },
+                "readings": {
+                    "description": "Readings es el número de lecturas del intervalo.",
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.ReadingOutcome": {
+            "type": "string",
+            "enum": [
+                "applied",
+                "history",
+                "discarded"
             ],
+            "x-enum-varnames": [
+                "OutcomeApplied",
+                "OutcomeHistory",
+                "OutcomeDiscarded"
+            ]
+        },
+        "domain.Reroute": {
+            "type": "object",
+            "properties": {
+                "added_stops": {
+                    "description": "AddedStops son las paradas añadidas, con su posición en la ruta.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.DispatchedStop"
+                    }
+                },
+                "applied": {
+                    "description": "Applied indica si los cambios se han guardado en la ruta; es falso en modo de prueba o si no\nse ha añadido ninguna parada.",
+                    "type": "boolean"
+                },
+                "extra_distance_km": {
+                    "type": "number"
+                },
+                "position": {
+                    "description": "Position es la posición del camión desde la que se re-planifica.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Point"
+                        }
+                    ]
+                },
+                "remaining_distance_after_km": {
+                    "type": "number"
+                },
+                "remaining_distance_before_km": {
+                    "description": "Distancia que queda desde la posición del camión (regreso al depósito incluido) antes y después\nde añadir las paradas, y la diferencia.",
+                    "type": "number"
+                },
+                "remaining_stops": {
+                    "description": "RemainingStops son las paradas pendientes en el nuevo orden, incluidas las añadidas.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.DispatchedStop"
+                    }
+                },
+                "route_id": {
+                    "type": "string"
+                },
+                "sequence_changes": {
+                    "description": "SequenceChanges son las paradas pendientes que cambian de posición en la ruta.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.SequenceChange"
+                    }
+                },
+                "unassigned": {
+                    "description": "Unassigned son los contenedores urgentes que no se han podido añadir, con el motivo.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.RerouteCandidate"
+                    }
+                }
+            }
+        },
+        "domain.RerouteCandidate": {
+            "type": "object",
+            "properties": {
+                "container_id": {
+                    "type": "string"
+                },
+                "estimated_load_kg": {
+                    "type": "number"
+                },
+                "estimated_load_liters": {
+                    "type": "number"
+                },
+                "last_fill_level": {
+                    "type": "integer"
+                },
+                "location": {
+                    "$ref": "#/definitions/domain.Point"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/domain.Status"
+                }
+            }
+        },
+        "domain.Route": {
+            "type": "object",
             "properties": {
+                "capacity_kg": {
+                    "type": "number"
+                },
+                "capacity_liters": {
+                    "description": "Capacidad del camión en litros y en kilos, si la ruta se ha planificado con flota. Cero\nsignifica sin límite.",
+                    "type": "number"
+                },
+                "depot": {
+                    "description": "Depot es el depósito del que sale y al que vuelve el camión, si la ruta sale de uno.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Facility"
+                        }
+                    ]
+                },
+                "distance_km": {
+                    "description": "DistanceKm es la longitud final de la ruta, desde el punto de salida hasta la última parada\ny, si la ruta es cerrada, de vuelta al depósito.",
+                    "type": "number"
+                },
+                "duration_minutes": {
+                    "description": "DurationMinutes es la duración estimada: conducción, esperas, recogida de cada contenedor, descargas y regreso.",
+                    "type": "number"
+                },
+                "end_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID es el identificador de la ruta guardada; vacío si la ruta no se ha guardado.",
+                    "type": "string"
+                },
+                "initial_distance_km": {
+                    "description": "InitialDistanceKm es la longitud de la ruta construida con el vecino más cercano (o, con flota,\ncon el algoritmo de ahorros), antes de la mejora local.",
+                    "type": "number"
+                },
+                "load_kg": {
+                    "type": "number"
+                },
+                "load_liters": {
+                    "description": "Carga total estimada que recoge el camión a lo largo de la ruta.",
+                    "type": "number"
+                },
+                "optimization": {
+                    "$ref": "#/definitions/domain.RouteOptimization"
+                },
+                "return_distance_km": {
+                    "description": "ReturnDistanceKm es la parte de DistanceKm que corresponde al regreso al depósito.",
+                    "type": "number"
+                },
+                "start_at": {
+                    "description": "StartAt y EndAt son la salida del camión y el final estimado de la ruta (regreso incluido).",
+                    "type": "string"
+                },
                 "start_point": {
+                    "description": "StartPoint es el punto de salida de la ruta: el depósito o el punto de salida indicado.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Point"
+                        }
+                    ]
+                },
+                "stops": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.RouteStop"
+                    }
+                },
+                "vehicle": {
+                    "description": "Vehicle numera los camiones de la flota, empezando por 1.",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.RouteComparison": {
+            "type": "object",
+            "properties": {
+                "actual_duration_minutes": {
+                    "type": "number"
+                },
+                "added_stops": {
+                    "type": "integer"
+                },
+                "average_delay_minutes": {
+                    "description": "AverageDelayMinutes es el retraso medio de las paradas visitadas respecto a su ETA.",
+                    "type": "number"
+                },
+                "collected_stops": {
+                    "type": "integer"
+                },
+                "collected_volume_liters": {
+                    "type": "integer"
+                },
+                "completion_rate": {
+                    "description": "CompletionRate es el porcentaje de paradas planificadas en las que se vació el contenedor.",
+                    "type": "number"
+                },
+                "overflowing_stops": {
+                    "type": "integer"
+                },
+                "pending_stops": {
+                    "type": "integer"
+                },
+                "planned_duration_minutes": {
+                    "description": "Duración planificada frente a la real (solo si la ruta ha terminado).",
+                    "type": "number"
+                },
+                "planned_load_liters": {
+                    "description": "Carga estimada al planificar frente al volumen de las recogidas registradas.",
+                    "type": "number"
+                },
+                "planned_stops": {
+                    "description": "Número de paradas de contenedor planificadas (incluidas las añadidas en ruta) y resultado de cada una.",
+                    "type": "integer"
+                },
+                "skipped_stops": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.RouteOptimization": {
+            "type": "object",
+            "properties": {
+                "algorithms": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "elapsed_ms": {
+                    "type": "integer"
+                },
+                "iterations": {
+                    "description": "Iterations es el número de movimientos de mejora aplicados.",
+                    "type": "integer"
+                },
+                "time_budget_exhausted": {
+                    "description": "TimeBudgetExhausted indica que la mejora se detuvo por el límite de tiempo y no en un óptimo local.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "domain.RoutePlan": {
+            "type": "object",
+            "properties": {
+                "routes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Route"
+                    }
+                },
+                "total_distance_km": {
+                    "type": "number"
+                },
+                "total_duration_minutes": {
+                    "type": "number"
+                },
+                "unassigned": {
+                    "description": "Unassigned son las paradas seleccionadas que no caben en ninguna ruta (por capacidad, por sus\nfranjas horarias o por el fin de turno), cada una con su motivo.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.RouteStop"
+                    }
+                }
+            }
+        },
+        "domain.RouteStatus": {
+            "type": "string",
+            "enum": [
+                "planned",
+                "in_progress",
+                "completed"
+            ],
+            "x-enum-varnames": [
+                "RouteStatusPlanned",
+                "RouteStatusInProgress",
+                "RouteStatusCompleted"
+            ]
+        },
+        "domain.RouteStop": {
+            "type": "object",
+            "properties": {
+                "capacity_liters": {
+                    "type": "integer"
+                },
+                "container_type_id": {
+                    "description": "ContainerTypeID referencia el modelo de contenedor (volumen y sistema de elevación), si se conoce.",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "--- CAMPOS ACTUALIZADOS ---\nEstos campos son gestionados por la base de datos y son cruciales para el tracking.",
+                    "type": "string"
+                },
+                "distance_meters": {
+                    "description": "DistanceMeters es la distancia al punto de una búsqueda por radio. No se persiste.",
+                    "type": "number"
+                },
+                "estimated_load_kg": {
+                    "type": "number"
+                },
+                "estimated_load_liters": {
+                    "description": "Carga estimada que aporta la parada al camión, a partir de la capacidad y del nivel de llenado.\nEn las descargas, es la carga que se descarga.",
+                    "type": "number"
+                },
+                "eta": {
+                    "description": "ETA es la hora estimada de llegada a la parada y WaitMinutes, la espera hasta que se abre su franja.",
+                    "type": "string"
+                },
+                "facility": {
+                    "description": "Facility es el punto de descarga; solo en las descargas.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Facility"
+                        }
+                    ]
+                },
+                "forecast": {
+                    "description": "Forecast es la predicción de llenado calculada a partir del historial. No se persiste.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Forecast"
+                        }
+                    ]
+                },
+                "fraction": {
+                    "description": "Fraction es la fracción de residuo que recoge el contenedor.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Fraction"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "$ref": "#/definitions/domain.StopKind"
+                },
+                "last_fill_level": {
+                    "type": "integer"
+                },
+                "last_updated": {
+                    "type": "string"
+                },
+                "location": {
                     "$ref": "#/definitions/domain.Point"
                 },
-                "statuses": {
+                "predicted_fill_at_next_run": {
+                    "type": "integer"
+                },
+                "predicted_fill_at_visit": {
+                    "description": "Nivel de llenado previsto a la hora de la visita y a la hora de la siguiente ruta,\nsolo en las rutas generadas a partir de predicciones.",
+                    "type": "integer"
+                },
+                "reason": {
+                    "$ref": "#/definitions/domain.SelectionReason"
+                },
+                "reason_detail": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "omitempty porque no se establece al crear",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Status"
+                        }
+                    ]
+                },
+                "threshold_profile_id": {
+                    "description": "ThresholdProfileID referencia el perfil de umbrales del contenedor. Si es nil se usan los umbrales por defecto.",
+                    "type": "string"
+                },
+                "time_window_profile_id": {
+                    "description": "TimeWindowProfileID referencia las franjas horarias en las que se puede recoger. Si es nil, a cualquier hora.",
+                    "type": "string"
+                },
+                "time_windows": {
+                    "description": "TimeWindows son las franjas horarias en las que se puede recoger el contenedor, si las tiene.",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/domain.Status"
+                        "$ref": "#/definitions/domain.TimeWindow"
                     }
+                },
+                "unassigned_reason": {
+                    "description": "UnassignedReason explica por qué la parada no se ha podido asignar a ninguna ruta.",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "wait_minutes": {
+                    "type": "number"
                 }
             }
         },
-        "container.UpsertContainerRequest": {
-            "type": "object",
-            "required": [
-                "capacity_liters",
-                "latitude",
-                "longitude"
+        "domain.SelectionReason": {
+            "type": "string",
+            "enum": [
+                "status",
+                "predicted_fill_at_visit",
+                "predicted_fill_before_next_run",
+                "current_fill_level"
             ],
+            "x-enum-varnames": [
+                "ReasonStatus",
+                "ReasonPredictedAtVisit",
+                "ReasonPredictedBeforeNextRun",
+                "ReasonCurrentLevel"
+            ]
+        },
+        "domain.SensorDevice": {
+            "type": "object",
             "properties": {
-                "capacity_liters": {
+                "container_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "decoder": {
+                    "type": "string"
+                },
+                "dev_eui": {
+                    "type": "string"
+                },
+                "empty_distance_mm": {
+                    "description": "Distancia (en mm) que mide el sensor ultrasónico con el contenedor vacío y lleno.",
                     "type": "integer"
                 },
-                "latitude": {
-                    "type": "number"
+                "full_distance_mm": {
+                    "type": "integer"
                 },
-                "longitude": {
+                "last_battery_mv": {
+                    "description": "Telemetría del propio dispositivo, actualizada con cada uplink.\nLa batería se guarda en mV o en porcentaje, según de cuál informe el modelo de sensor.",
+                    "type": "integer"
+                },
+                "last_battery_percent": {
+                    "type": "integer"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "last_temperature_c": {
                     "type": "number"
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "domain.Container": {
+        "domain.SequenceChange": {
+            "type": "object",
+            "properties": {
+                "container_id": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "integer"
+                },
+                "stop_id": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.SkipReason": {
+            "type": "string",
+            "enum": [
+                "blocked",
+                "inaccessible"
+            ],
+            "x-enum-varnames": [
+                "SkipBlocked",
+                "SkipInaccessible"
+            ]
+        },
+        "domain.Status": {
+            "type": "string",
+            "enum": [
+                "low",
+                "medium",
+                "high"
+            ],
+            "x-enum-varnames": [
+                "StatusLow",
+                "StatusMedium",
+                "StatusHigh"
+            ]
+        },
+        "domain.StopContainerDetails": {
             "type": "object",
             "properties": {
                 "capacity_liters": {
                     "type": "integer"
                 },
+                "container_type_id": {
+                    "type": "string"
+                },
+                "fraction": {
+                    "$ref": "#/definitions/domain.Fraction"
+                },
+                "last_fill_level": {
+                    "type": "integer"
+                },
+                "status": {
+                    "$ref": "#/definitions/domain.Status"
+                }
+            }
+        },
+        "domain.StopKind": {
+            "type": "string",
+            "enum": [
+                "container",
+                "unload"
+            ],
+            "x-enum-varnames": [
+                "StopContainer",
+                "StopUnload"
+            ]
+        },
+        "domain.StopStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "collected",
+                "skipped",
+                "overflowing"
+            ],
+            "x-enum-varnames": [
+                "StopPending",
+                "StopCollected",
+                "StopSkipped",
+                "StopOverflowing"
+            ]
+        },
+        "domain.ThresholdProfile": {
+            "type": "object",
+            "properties": {
                 "created_at": {
-                    "description": "--- CAMPOS ACTUALIZADOS ---\nEstos campos son gestionados por la base de datos y son cruciales para el tracking.",
                     "type": "string"
                 },
+                "high_at": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "string"
                 },
-                "last_fill_level": {
+                "medium_at": {
                     "type": "integer"
                 },
-                "last_updated": {
+                "name": {
                     "type": "string"
                 },
-                "location": {
-                    "$ref": "#/definitions/domain.Point"
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.TimeWindow": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "string",
+                    "example": "08:00"
                 },
-                "status": {
-                    "description": "omitempty porque no se establece al crear",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/domain.Status"
-                        }
-                    ]
+                "start": {
+                    "type": "string",
+                    "example": "06:00"
+                }
+            }
+        },
+        "domain.TimeWindowProfile": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "windows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.TimeWindow"
+                    }
                 }
             }
         },
-        "domain.Point": {
+        "facility.UpsertFacilityRequest": {
             "type": "object",
+            "required": [
+                "kind",
+                "latitude",
+                "longitude",
+                "name"
+            ],
             "properties": {
+                "fractions": {
+                    "description": "Fractions son las fracciones que admite un punto de descarga; si se omite, admite todas.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Fraction"
+                    }
+                },
+                "kind": {
+                    "enum": [
+                        "depot",
+                        "transfer_station",
+                        "landfill"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.FacilityKind"
+                        }
+                    ]
+                },
                 "latitude": {
                     "type": "number"
                 },
                 "longitude": {
                     "type": "number"
+                },
+                "name": {
+                    "type": "string"
                 }
             }
         },
-        "domain.Reading": {
+        "lorawan.ChirpStackUplink": {
             "type": "object",
             "properties": {
-                "container_id": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "deviceInfo": {
+                    "type": "object",
+                    "properties": {
+                        "devEui": {
+                            "type": "string"
+                        },
+                        "deviceName": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "fPort": {
+                    "type": "integer"
+                },
+                "time": {
                     "type": "string"
+                }
+            }
+        },
+        "lorawan.Measurement": {
+            "type": "object",
+            "properties": {
+                "battery_mv": {
+                    "description": "Los sensores informan de la batería en mV o en porcentaje, según el modelo.",
+                    "type": "integer"
+                },
+                "battery_percent": {
+                    "type": "integer"
+                },
+                "distance_mm": {
+                    "type": "integer"
                 },
                 "fill_level": {
+                    "description": "Para sensores que ya calculan el porcentaje.",
                     "type": "integer"
                 },
-                "timestamp": {
+                "temperature_c": {
+                    "type": "number"
+                }
+            }
+        },
+        "lorawan.RegisterDeviceRequest": {
+            "type": "object",
+            "required": [
+                "container_id",
+                "decoder",
+                "dev_eui",
+                "empty_distance_mm"
+            ],
+            "properties": {
+                "container_id": {
+                    "type": "string"
+                },
+                "decoder": {
+                    "type": "string"
+                },
+                "dev_eui": {
                     "type": "string"
+                },
+                "empty_distance_mm": {
+                    "type": "integer"
+                },
+                "full_distance_mm": {
+                    "type": "integer",
+                    "minimum": 0
                 }
             }
         },
-        "domain.Status": {
+        "lorawan.TTNUplink": {
+            "type": "object",
+            "properties": {
+                "end_device_ids": {
+                    "type": "object",
+                    "properties": {
+                        "dev_eui": {
+                            "type": "string"
+                        },
+                        "device_id": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "received_at": {
+                    "type": "string"
+                },
+                "uplink_message": {
+                    "type": "object",
+                    "properties": {
+                        "f_port": {
+                            "type": "integer"
+                        },
+                        "frm_payload": {
+                            "type": "array",
+                            "items": {
+                                "type": "integer"
+                            }
+                        },
+                        "received_at": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "lorawan.UplinkResult": {
+            "type": "object",
+            "properties": {
+                "dev_eui": {
+                    "type": "string"
+                },
+                "measurement": {
+                    "$ref": "#/definitions/lorawan.Measurement"
+                },
+                "outcome": {
+                    "$ref": "#/definitions/domain.ReadingOutcome"
+                },
+                "reading": {
+                    "$ref": "#/definitions/domain.Reading"
+                }
+            }
+        },
+        "optimizer.Algorithm": {
             "type": "string",
             "enum": [
-                "low",
-                "medium",
-                "high"
+                "2opt",
+                "oropt"
             ],
             "x-enum-varnames": [
-                "StatusLow",
-                "StatusMedium",
-                "StatusHigh"
+                "TwoOpt",
+                "OrOpt"
             ]
+        },
+        "threshold.UpsertProfileRequest": {
+            "type": "object",
+            "required": [
+                "high_at",
+                "medium_at",
+                "name"
+            ],
+            "properties": {
+                "high_at": {
+                    "type": "integer",
+                    "maximum": 100
+                },
+                "medium_at": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "timewindow.UpsertProfileRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "windows"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "windows": {
+                    "description": "Windows son las franjas diarias (hora local, \"HH:MM\") en las que se permite recoger.",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/domain.TimeWindow"
+                    }
+                }
+            }
         }
     }
 }`
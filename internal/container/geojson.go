@@ -0,0 +1,98 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/platform/geojson"
+
+	"github.com/gin-gonic/gin"
+)
+
+// wantsGeoJSON indica si la petición pide GeoJSON, con '?format=geojson' o con la cabecera
+// 'Accept: application/geo+json'. Sin preferencia, se responde con el JSON propio de la API.
+func wantsGeoJSON(c *gin.Context) (bool, error) {
+	switch format := c.Query("format"); format {
+	case "":
+		return c.NegotiateFormat(gin.MIMEJSON, geojson.ContentType) == geojson.ContentType, nil
+	case "json", "geojson":
+		return format == "geojson", nil
+	default:
+		return false, fmt.Errorf("formato no soportado: %s. Usa 'json' o 'geojson'", format)
+	}
+}
+
+// containerFeature convierte un contenedor en una entidad GeoJSON con su ubicación como punto. Las
+// propiedades son planas (la predicción se resume en sus campos principales) para que las
+// herramientas GIS las muestren como columnas.
+func containerFeature(c domain.Container) geojson.Feature {
+	props := map[string]any{
+		"id":              c.ID,
+		"capacity_liters": c.CapacityLiters,
+		"fraction":        c.Fraction,
+		"status":          c.CurrentStatus,
+		"fill_level":      c.LastFillLevel,
+		"last_updated":    c.LastUpdatedAt,
+		"created_at":      c.CreatedAt,
+		"updated_at":      c.UpdatedAt,
+	}
+	optional := map[string]*string{
+		"container_type_id":      c.ContainerTypeID,
+		"threshold_profile_id":   c.ThresholdProfileID,
+		"time_window_profile_id": c.TimeWindowProfileID,
+	}
+	for name, value := range optional {
+		if value != nil {
+			props[name] = *value
+		}
+	}
+	if c.DistanceMeters != nil {
+		props["distance_meters"] = *c.DistanceMeters
+	}
+	if f := c.Forecast; f != nil {
+		props["fill_rate_per_hour"] = f.FillRatePerHour
+		props["predicted_fill_level"] = f.PredictedFillLevel
+		if f.PredictedFullAt != nil {
+			props["predicted_full_at"] = *f.PredictedFullAt
+		}
+	}
+	return geojson.NewFeature(c.ID, geojson.Point(c.Location), props)
+}
+
+// containerCollection convierte una lista de contenedores en una FeatureCollection, en el mismo orden.
+func containerCollection(containers []domain.Container) geojson.FeatureCollection {
+	features := make([]geojson.Feature, len(containers))
+	for i, c := range containers {
+		features[i] = containerFeature(c)
+	}
+	return geojson.NewFeatureCollection(features...)
+}
+
+// pageCollection es una página del listado como FeatureCollection. El cursor y el total van como
+// miembros adicionales de la colección, que RFC 7946 permite y los visores ignoran.
+type pageCollection struct {
+	geojson.FeatureCollection
+	NextCursor *string `json:"next_cursor"`
+	Total      int     `json:"total"`
+}
+
+// containerPageCollection convierte una página del listado en una FeatureCollection.
+func containerPageCollection(page ContainerPage) pageCollection {
+	return pageCollection{
+		FeatureCollection: containerCollection(page.Items),
+		NextCursor:        page.NextCursor,
+		Total:             page.Total,
+	}
+}
+
+// writeGeoJSON responde con el objeto GeoJSON y su tipo MIME.
+func writeGeoJSON(c *gin.Context, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		fmt.Printf("Error al codificar el GeoJSON: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo generar el GeoJSON"})
+		return
+	}
+	c.Data(http.StatusOK, geojson.ContentType, body)
+}
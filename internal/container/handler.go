@@ -1,9 +1,15 @@
 package container
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/forecast"
+	"smart-waste-management/internal/optimizer"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,16 +22,103 @@ type Handler struct {
 
 // RouteRequest define el cuerpo de la petición para generar una ruta.
 type RouteRequest struct {
-	StartPoint domain.Point    `json:"start_point" binding:"required"`
-	Statuses   []domain.Status `json:"statuses" binding:"required"`
+	// StartPoint es el punto de salida. Es obligatorio salvo que se indique 'depot_id'.
+	StartPoint *domain.Point `json:"start_point" binding:"required_without=DepotID"`
+	// DepotID es el depósito del que salen y al que vuelven los camiones.
+	DepotID string `json:"depot_id" binding:"omitempty,uuid"`
+	// Statuses selecciona los contenedores por su estado actual. Es obligatorio salvo que se indique 'forecast'.
+	Statuses []domain.Status `json:"statuses" binding:"required_without=Forecast"`
+	// Fraction limita la ruta a una fracción de residuo. Si se omite, se incluyen todas.
+	Fraction domain.Fraction `json:"fraction" binding:"omitempty,oneof=organic paper packaging glass residual textile"`
+	// Forecast selecciona además los contenedores por su llenado previsto.
+	Forecast *ForecastRouteRequest `json:"forecast"`
+	// Optimization configura la mejora local de la ruta; si se omite, se aplican 2-opt y Or-opt durante un máximo de 2 s.
+	Optimization *OptimizationRequest `json:"optimization"`
+	// Fleet reparte las paradas entre varios camiones; si se omite, se genera una única ruta.
+	Fleet *FleetRequest `json:"fleet"`
+	// Shift es el turno de los camiones; si se omite, las rutas empiezan ahora y no tienen hora límite.
+	Shift *ShiftRequest `json:"shift"`
+	// ServiceMinutes es el tiempo de recogida de cada contenedor; por defecto 2 minutos.
+	ServiceMinutes float64 `json:"service_minutes" binding:"omitempty,gt=0,lte=60"`
+	// AverageSpeedKmh es la velocidad media de los camiones; por defecto 25 km/h.
+	AverageSpeedKmh float64 `json:"average_speed_kmh" binding:"omitempty,gt=0,lte=130"`
+	// TimeZone es la zona horaria (IANA) de las franjas horarias de los contenedores; por defecto Europe/Madrid.
+	TimeZone string `json:"time_zone" example:"Europe/Madrid"`
+	// DryRun calcula las rutas sin guardarlas; por defecto se guardan para su ejecución.
+	DryRun bool `json:"dry_run"`
+}
+
+// ShiftRequest es el turno de los camiones: salen al inicio y deben haber vuelto antes del fin.
+type ShiftRequest struct {
+	Start time.Time `json:"start" binding:"required"`
+	End   time.Time `json:"end" binding:"required,gtfield=Start"`
+}
+
+// FleetRequest describe los camiones disponibles. Hay que indicar al menos una de las dos capacidades.
+type FleetRequest struct {
+	Vehicles int `json:"vehicles" binding:"required,gt=0"`
+	// Capacidad de carga de cada camión en litros y/o en kilos.
+	CapacityLiters float64 `json:"capacity_liters" binding:"omitempty,gt=0"`
+	CapacityKg     float64 `json:"capacity_kg" binding:"omitempty,gt=0"`
+}
+
+// OptimizationRequest configura la fase de mejora local de una ruta.
+type OptimizationRequest struct {
+	// Algorithms son las fases de mejora ("2opt", "oropt"). Una lista vacía deja la ruta del vecino más cercano.
+	Algorithms []optimizer.Algorithm `json:"algorithms" binding:"omitempty,dive,oneof=2opt oropt"`
+	// TimeBudgetMs limita el tiempo de mejora en milisegundos.
+	TimeBudgetMs int `json:"time_budget_ms" binding:"omitempty,gt=0,lte=60000"`
+}
+
+// ForecastRouteRequest define el horizonte de planificación de una ruta basada en predicciones.
+type ForecastRouteRequest struct {
+	// VisitAt es la hora prevista de la visita; si se omite, se usa la hora actual.
+	VisitAt time.Time `json:"visit_at"`
+	// NextRunAt es la hora de la siguiente ruta: se recoge todo lo que se prevé que supere el umbral antes de ella.
+	NextRunAt time.Time `json:"next_run_at" binding:"required"`
+	// FillThreshold es el llenado previsto (en %) a partir del cual se recoge; por defecto 90.
+	FillThreshold int `json:"fill_threshold" binding:"omitempty,gt=0,lte=100"`
+}
+
+// BatchReadingsRequest define el cuerpo de la petición para la ingesta de lecturas por lotes.
+type BatchReadingsRequest struct {
+	Readings []domain.Reading `json:"readings" binding:"required,min=1"`
+}
+
+// BatchReadingsResponse resume el resultado de un lote e incluye el detalle de cada lectura.
+type BatchReadingsResponse struct {
+	Accepted int               `json:"accepted"`
+	Rejected int               `json:"rejected"`
+	Results  []BatchItemResult `json:"results"`
 }
 
 type UpsertContainerRequest struct {
 	Latitude       float64 `json:"latitude" binding:"required,latitude"`
 	Longitude      float64 `json:"longitude" binding:"required,longitude"`
 	CapacityLiters int     `json:"capacity_liters" binding:"required,gt=0"`
+	// Fraction es la fracción de residuo; si se omite, el contenedor es de la fracción resto.
+	Fraction        domain.Fraction `json:"fraction" binding:"omitempty,oneof=organic paper packaging glass residual textile"`
+	ContainerTypeID *string         `json:"container_type_id" binding:"omitempty,uuid"`
+	// ThresholdProfileID asigna un perfil de umbrales; si se omite, se usan los umbrales por defecto.
+	ThresholdProfileID *string `json:"threshold_profile_id" binding:"omitempty,uuid"`
+	// TimeWindowProfileID asigna las franjas horarias de recogida de su zona; si se omite, se recoge a cualquier hora.
+	TimeWindowProfileID *string `json:"time_window_profile_id" binding:"omitempty,uuid"`
 }
 
+// CollectionRequest define el cuerpo de la petición para registrar una recogida.
+type CollectionRequest struct {
+	// CollectedBy identifica al operario o camión que hizo la recogida.
+	CollectedBy string `json:"collected_by" binding:"required"`
+	// CollectedAt es el momento de la recogida; si se omite, se usa la hora actual.
+	CollectedAt time.Time `json:"collected_at"`
+	// EstimatedVolumeLiters es el volumen recogido; si se omite, se estima a partir del nivel de llenado previo.
+	EstimatedVolumeLiters int `json:"estimated_volume_liters" binding:"omitempty,gte=0"`
+}
+
+// queueFullRetryAfter es el tiempo (en segundos) que se sugiere al cliente en la cabecera
+// Retry-After cuando la cola de ingesta está llena.
+const queueFullRetryAfter = 1
+
 // NewHandler crea una nueva instancia del handler.
 func NewHandler(s Service) *Handler {
 	return &Handler{
@@ -36,6 +129,8 @@ func NewHandler(s Service) *Handler {
 // RegisterRoutes registra todas las rutas de este handler en el router de Gin.
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	router.POST("/readings", h.CreateReading)
+	router.POST("/readings/batch", h.CreateReadingsBatch)
+	router.GET("/ingest/stats", h.GetIngestStats)
 	router.GET("/containers", h.GetContainers)
 	router.POST("/routes", h.CreateRoute)
 	router.POST("/containers", h.CreateContainer)
@@ -43,17 +138,24 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	router.PUT("/containers/:id", h.UpdateContainer)
 	router.DELETE("/containers/:id", h.DeleteContainer)
 	router.GET("/containers/:id/readings", h.GetReadingsByContainerID)
+	router.GET("/containers/:id/forecast", h.GetForecast)
+	router.POST("/containers/:id/collections", h.CreateCollection)
+	router.GET("/containers/:id/collections", h.GetCollectionsByContainerID)
 }
 
 // CreateReading maneja la creación de una nueva lectura de sensor.
 // @Summary      Crea una nueva lectura de sensor
-// @Description  Registra el nivel de llenado de un contenedor en un momento dado.
+// @Description  Acepta el nivel de llenado de un contenedor en un momento dado y lo encola para su procesamiento asíncrono.
 // @Tags         Ingest
 // @Accept       json
 // @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
 // @Param        reading  body      domain.Reading  true  "Datos de la lectura"
 // @Success      202  {object}  map[string]string "Lectura aceptada para procesamiento"
 // @Failure      400  {object}  map[string]string "Petición inválida o datos incorrectos"
+// @Failure      404  {object}  map[string]string "Contenedor no encontrado"
+// @Failure      503  {object}  map[string]string "Cola de ingesta llena; reintentar tras Retry-After"
+// @Failure      422  {object}  map[string]string "Idempotency-Key reutilizada con una petición distinta"
 // @Failure      500  {object}  map[string]string "Error interno del servidor"
 // @Router       /readings [post]
 func (h *Handler) CreateReading(c *gin.Context) {
@@ -65,50 +167,262 @@ func (h *Handler) CreateReading(c *gin.Context) {
 		return
 	}
 
-	// 2. Llamar a la capa de servicio para procesar la lógica de negocio.
-	err := h.service.ProcessNewReading(c.Request.Context(), reading)
+	// 2. Encolar la lectura. La persistencia la realizan los workers de ingesta en segundo plano.
+	err := h.service.EnqueueReading(c.Request.Context(), reading)
 	if err != nil {
-		// 4. Manejar errores del servicio.
-		// Aquí podríamos tener una lógica más sofisticada para mapear tipos de error a códigos de estado.
-		fmt.Printf("Error al procesar la lectura: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo procesar la lectura"})
+		// 3. Mapear los errores del servicio a códigos de estado.
+		switch {
+		case errors.Is(err, ErrInvalidReading):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrContainerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrQueueFull), errors.Is(err, ErrIngestClosed):
+			c.Header("Retry-After", strconv.Itoa(queueFullRetryAfter))
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "El sistema de ingesta está saturado, inténtelo más tarde"})
+		default:
+			fmt.Printf("Error al encolar la lectura: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo procesar la lectura"})
+		}
 		return
 	}
 
-	// 5. Enviar la respuesta. 202 Accepted es semánticamente correcto para una ingesta de datos asíncrona.
+	// 4. Enviar la respuesta. 202 Accepted es semánticamente correcto para una ingesta de datos asíncrona.
 	c.JSON(http.StatusAccepted, gin.H{"message": "Lectura aceptada"})
 }
 
+// GetIngestStats expone las métricas de la cola de ingesta asíncrona.
+// @Summary      Obtiene las métricas de ingesta
+// @Description  Devuelve la profundidad de la cola de ingesta, su capacidad y la latencia de los workers.
+// @Tags         Ingest
+// @Produce      json
+// @Success      200  {object}  IngestStats
+// @Router       /ingest/stats [get]
+func (h *Handler) GetIngestStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.IngestStats())
+}
+
+// CreateReadingsBatch maneja la ingesta de un lote de lecturas de sensor.
+// @Summary      Crea un lote de lecturas de sensor
+// @Description  Registra de una vez hasta 10000 lecturas (p. ej. las acumuladas por un gateway sin conexión). Cada lectura se valida de forma individual y la respuesta indica si fue aceptada o rechazada.
+// @Tags         Ingest
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        batch  body      BatchReadingsRequest   true  "Lote de lecturas"
+// @Success      202    {object}  BatchReadingsResponse  "Resultado del lote, con el detalle por lectura"
+// @Failure      400    {object}  map[string]string      "Petición inválida o datos incorrectos"
+// @Failure      413    {object}  map[string]string      "El lote supera el tamaño máximo permitido"
+// @Failure      422    {object}  map[string]string      "Idempotency-Key reutilizada con una petición distinta"
+// @Failure      500    {object}  map[string]string      "Error interno del servidor"
+// @Router       /readings/batch [post]
+func (h *Handler) CreateReadingsBatch(c *gin.Context) {
+	var req BatchReadingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cuerpo de la petición inválido: " + err.Error()})
+		return
+	}
+
+	if len(req.Readings) > MaxBatchSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("El lote no puede superar las %d lecturas", MaxBatchSize)})
+		return
+	}
+
+	results, err := h.service.ProcessReadingsBatch(c.Request.Context(), req.Readings)
+	if err != nil {
+		fmt.Printf("Error al procesar el lote de lecturas: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo procesar el lote de lecturas"})
+		return
+	}
+
+	resp := BatchReadingsResponse{Results: results}
+	for _, r := range results {
+		if r.Accepted {
+			resp.Accepted++
+		} else {
+			resp.Rejected++
+		}
+	}
+
+	c.JSON(http.StatusAccepted, resp)
+}
+
 // GetContainers maneja la obtención de todos los contenedores.
-// @Summary      Obtiene todos los contenedores
-// @Description  Devuelve una lista de todos los contenedores registrados con su estado actual.
+// @Summary      Obtiene los contenedores, paginados
+// @Description  Devuelve una página de los contenedores registrados con su estado actual, el total de contenedores que cumplen los filtros y el cursor ('next_cursor') de la página siguiente, que es null en la última. Para recorrer el listado se repite la consulta con 'cursor' y los mismos filtros y orden.
+// @Description  Se puede filtrar por fracción, estado, rango de nivel de llenado y de capacidad, y por 'updated_before' para encontrar sensores que han dejado de informar.
+// @Description  Con 'bbox' devuelve solo los contenedores de la zona visible del mapa. Con 'near' y 'radius_m' devuelve los que están a menos de 'radius_m' metros, por defecto del más cercano al más lejano y con su 'distance_meters'.
+// @Description  Con 'Accept: application/geo+json' (o 'format=geojson') devuelve una FeatureCollection GeoJSON con un punto por contenedor y su estado, nivel de llenado y fechas como propiedades; 'next_cursor' y 'total' van como miembros de la colección.
 // @Tags         Containers
 // @Produce      json
-// @Success      200  {object}  []domain.Container
+// @Produce      application/geo+json
+// @Param        format          query     string    false  "Formato de la respuesta; tiene prioridad sobre la cabecera Accept"  Enums(json, geojson)
+// @Param        fraction        query     string    false  "Fracción de residuo"  Enums(organic, paper, packaging, glass, residual, textile)
+// @Param        status          query     []string  false  "Estado del contenedor (se puede repetir)"  collectionFormat(multi) Enums(low, medium, high)
+// @Param        min_fill_level  query     int       false  "Nivel de llenado mínimo (0-100)"  minimum(0) maximum(100)
+// @Param        max_fill_level  query     int       false  "Nivel de llenado máximo (0-100)"  minimum(0) maximum(100)
+// @Param        min_capacity    query     int       false  "Capacidad mínima en litros"  minimum(1)
+// @Param        max_capacity    query     int       false  "Capacidad máxima en litros"  minimum(1)
+// @Param        updated_before  query     string    false  "Solo los contenedores sin lecturas desde esta fecha (RFC 3339), incluidos los que nunca han enviado ninguna"
+// @Param        bbox            query     string    false  "Rectángulo minLon,minLat,maxLon,maxLat"  example(-3.72,40.40,-3.68,40.43)
+// @Param        near            query     string    false  "Punto lat,lon de la búsqueda por radio"  example(40.4168,-3.7038)
+// @Param        radius_m        query     number    false  "Radio de la búsqueda en metros (obligatorio con 'near', máximo 50000)"
+// @Param        sort            query     string    false  "Campo de ordenación; por defecto 'distance' en las búsquedas por radio y 'created_at' en el resto. 'distance' solo se admite con 'near'. Con 'last_updated', los contenedores sin lecturas van como los más antiguos"  Enums(created_at, last_updated, fill_level, capacity, distance)
+// @Param        order           query     string    false  "Sentido del orden; por defecto 'asc' para 'distance' y 'desc' para el resto"  Enums(asc, desc)
+// @Param        limit           query     int       false  "Tamaño de página"  default(100) minimum(1) maximum(1000)
+// @Param        cursor          query     string    false  "Valor de 'next_cursor' de la página anterior"
+// @Success      200  {object}  ContainerPage
+// @Failure      400  {object}  map[string]string "Filtro, orden, tamaño de página o cursor inválidos"
 // @Failure      500  {object}  map[string]string "Error interno del servidor"
 // @Router       /containers [get]
 func (h *Handler) GetContainers(c *gin.Context) {
+	// 1. Validar los filtros y la página.
+	filter := ContainerFilter{Fraction: domain.Fraction(c.Query("fraction"))}
+	if filter.Fraction != "" && !filter.Fraction.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fracción desconocida: " + string(filter.Fraction)})
+		return
+	}
+	if err := parseGeoFilter(c, &filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := parseStateFilter(c, &filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page := PageRequest{Sort: SortField(c.Query("sort")), Order: c.Query("order"), Cursor: c.Query("cursor")}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("'limit' debe ser un número entre 1 y %d", MaxPageSize)})
+			return
+		}
+		page.Limit = limit
+	}
+	geoJSON, err := wantsGeoJSON(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 2. Llamar al servicio.
-	containers, err := h.service.GetAllContainers(c.Request.Context())
+	result, err := h.service.ListContainers(c.Request.Context(), filter, page)
+	if errors.Is(err, ErrInvalidPage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		fmt.Printf("Error al obtener los contenedores: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener la lista de contenedores"})
 		return
 	}
 
-	// 5. Enviar la respuesta.
-	c.JSON(http.StatusOK, containers)
+	// 3. Enviar la respuesta.
+	if geoJSON {
+		writeGeoJSON(c, containerPageCollection(result))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// parseStateFilter lee los filtros por estado de la consulta: 'status', los rangos de nivel de
+// llenado y de capacidad, y 'updated_before'.
+func parseStateFilter(c *gin.Context, filter *ContainerFilter) error {
+	for _, value := range c.QueryArray("status") {
+		status := domain.Status(value)
+		if !status.IsValid() {
+			return errors.New("estado desconocido: " + value)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	bounds := []struct {
+		param    string
+		min, max int
+		dst      **int
+	}{
+		{"min_fill_level", 0, 100, &filter.MinFillLevel},
+		{"max_fill_level", 0, 100, &filter.MaxFillLevel},
+		{"min_capacity", 1, math.MaxInt32, &filter.MinCapacity},
+		{"max_capacity", 1, math.MaxInt32, &filter.MaxCapacity},
+	}
+	for _, b := range bounds {
+		value := c.Query(b.param)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < b.min || n > b.max {
+			return fmt.Errorf("'%s' debe ser un número entre %d y %d", b.param, b.min, b.max)
+		}
+		*b.dst = &n
+	}
+	if filter.MinFillLevel != nil && filter.MaxFillLevel != nil && *filter.MinFillLevel > *filter.MaxFillLevel {
+		return errors.New("'min_fill_level' no puede ser mayor que 'max_fill_level'")
+	}
+	if filter.MinCapacity != nil && filter.MaxCapacity != nil && *filter.MinCapacity > *filter.MaxCapacity {
+		return errors.New("'min_capacity' no puede ser mayor que 'max_capacity'")
+	}
+
+	if value := c.Query("updated_before"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return errors.New("'updated_before' debe tener formato RFC 3339")
+		}
+		filter.UpdatedBefore = &t
+	}
+	return nil
+}
+
+// maxRadiusMeters limita las búsquedas por radio, que no deben sustituir al listado completo.
+const maxRadiusMeters = 50000
+
+// parseGeoFilter lee los filtros geográficos de la consulta: 'bbox' y 'near' con 'radius_m'.
+func parseGeoFilter(c *gin.Context, filter *ContainerFilter) error {
+	if value := c.Query("bbox"); value != "" {
+		bbox, err := domain.ParseBoundingBox(value)
+		if err != nil {
+			return fmt.Errorf("'bbox' %w", err)
+		}
+		filter.BBox = &bbox
+	}
+
+	near, radius := c.Query("near"), c.Query("radius_m")
+	switch {
+	case near == "" && radius == "":
+		return nil
+	case near == "" || radius == "":
+		return errors.New("'near' y 'radius_m' se deben indicar juntos")
+	}
+	point, err := domain.ParsePoint(near)
+	if err != nil {
+		return fmt.Errorf("'near' %w", err)
+	}
+	meters, err := strconv.ParseFloat(radius, 64)
+	if err != nil || meters <= 0 || meters > maxRadiusMeters {
+		return fmt.Errorf("'radius_m' debe ser un número de metros mayor que 0 y menor o igual que %d", maxRadiusMeters)
+	}
+	filter.Near = &point
+	filter.RadiusMeters = meters
+	return nil
 }
 
 // CreateRoute maneja la generación de una ruta de recogida optimizada.
 // @Summary      Genera una ruta de recogida
-// @Description  Calcula una ruta óptima para visitar contenedores basados en su estado.
+// @Description  Calcula una ruta óptima para visitar contenedores basados en su estado y, opcionalmente, en su fracción.
+// @Description  Con 'forecast', incluye también los contenedores que se prevé que superen el umbral a la hora de la visita o antes de la siguiente ruta. Cada parada indica el motivo de su selección.
+// @Description  La ruta del vecino más cercano se mejora con 2-opt y Or-opt; la respuesta incluye la distancia antes y después de la mejora.
+// @Description  Con 'shift', 'service_minutes' y 'average_speed_kmh' se calcula la hora de llegada (ETA) a cada parada respetando las franjas horarias de los contenedores; las paradas que no caben en su franja o en el turno se devuelven en 'unassigned' con su motivo.
+// @Description  Con 'fleet', las paradas se reparten entre los camiones según su carga estimada (capacidad y nivel de llenado) y se devuelve una ruta cerrada desde el depósito por camión; las paradas que no caben quedan en 'unassigned'.
+// @Description  Las rutas se guardan en estado 'planned' para su ejecución (ver GET /routes/{id}) y la respuesta incluye su 'id'; con 'dry_run' solo se calculan.
 // @Tags         Routes
 // @Accept       json
 // @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
 // @Param        routeRequest body      RouteRequest      true  "Parámetros para la generación de la ruta"
-// @Success      200          {object}  []domain.Container "La ruta optimizada como una lista ordenada de contenedores"
+// @Success      201          {object}  domain.RoutePlan  "Una ruta guardada por camión con sus paradas en orden, su carga y su distancia"
+// @Success      200          {object}  domain.RoutePlan  "Con 'dry_run', las rutas calculadas sin guardar"
 // @Failure      400          {object}  map[string]string "Petición inválida o datos incorrectos"
+// @Failure      422          {object}  map[string]string "Idempotency-Key reutilizada con una petición distinta"
 // @Failure      500          {object}  map[string]string "Error interno del servidor"
 // @Router       /routes [post]
 func (h *Handler) CreateRoute(c *gin.Context) {
@@ -119,14 +433,63 @@ func (h *Handler) CreateRoute(c *gin.Context) {
 		return
 	}
 
-	route, err := h.service.GenerateRoute(c.Request.Context(), req.StartPoint, req.Statuses)
+	opts := RouteOptions{
+		DepotID:  req.DepotID,
+		Statuses: req.Statuses,
+		Fraction: req.Fraction,
+		DryRun:   req.DryRun,
+	}
+	if req.StartPoint != nil {
+		opts.StartPoint = *req.StartPoint
+	}
+	if req.Optimization != nil {
+		opts.Algorithms = req.Optimization.Algorithms
+		opts.TimeBudget = time.Duration(req.Optimization.TimeBudgetMs) * time.Millisecond
+	}
+	if req.Forecast != nil {
+		opts.Forecast = &ForecastSelection{
+			VisitAt:       req.Forecast.VisitAt,
+			NextRunAt:     req.Forecast.NextRunAt,
+			FillThreshold: req.Forecast.FillThreshold,
+		}
+	}
+	if req.Shift != nil {
+		opts.Shift = &Shift{Start: req.Shift.Start, End: req.Shift.End}
+	}
+	opts.ServiceTime = time.Duration(req.ServiceMinutes * float64(time.Minute))
+	opts.AverageSpeedKmh = req.AverageSpeedKmh
+	if req.TimeZone != "" {
+		loc, err := time.LoadLocation(req.TimeZone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Zona horaria desconocida: " + req.TimeZone})
+			return
+		}
+		opts.Location = loc
+	}
+	if req.Fleet != nil {
+		opts.Fleet = &Fleet{
+			Vehicles:       req.Fleet.Vehicles,
+			CapacityLiters: req.Fleet.CapacityLiters,
+			CapacityKg:     req.Fleet.CapacityKg,
+		}
+	}
+
+	plan, err := h.service.GenerateRoute(c.Request.Context(), opts)
 	if err != nil {
+		if errors.Is(err, ErrInvalidRouteOptions) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		fmt.Printf("Error al generar la ruta: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo generar la ruta"})
 		return
 	}
 
-	c.JSON(http.StatusOK, route)
+	if req.DryRun {
+		c.JSON(http.StatusOK, plan)
+		return
+	}
+	c.JSON(http.StatusCreated, plan)
 }
 
 // @Summary      Crea un nuevo contenedor
@@ -134,9 +497,11 @@ func (h *Handler) CreateRoute(c *gin.Context) {
 // @Tags         Containers
 // @Accept       json
 // @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
 // @Param        container  body      UpsertContainerRequest  true  "Datos del contenedor a crear"
 // @Success      201        {object}  domain.Container        "Contenedor creado exitosamente"
 // @Failure      400        {object}  map[string]string       "Petición inválida o datos incorrectos"
+// @Failure      422        {object}  map[string]string       "Idempotency-Key reutilizada con una petición distinta"
 // @Failure      500        {object}  map[string]string       "Error interno del servidor"
 // @Router       /containers [post]
 func (h *Handler) CreateContainer(c *gin.Context) {
@@ -147,8 +512,12 @@ func (h *Handler) CreateContainer(c *gin.Context) {
 	}
 
 	newContainer := domain.Container{
-		Location:       domain.Point{Latitude: req.Latitude, Longitude: req.Longitude},
-		CapacityLiters: req.CapacityLiters,
+		Location:            domain.Point{Latitude: req.Latitude, Longitude: req.Longitude},
+		CapacityLiters:      req.CapacityLiters,
+		Fraction:            req.Fraction,
+		ContainerTypeID:     req.ContainerTypeID,
+		ThresholdProfileID:  req.ThresholdProfileID,
+		TimeWindowProfileID: req.TimeWindowProfileID,
 	}
 
 	created, err := h.service.CreateContainer(c.Request.Context(), newContainer)
@@ -162,24 +531,36 @@ func (h *Handler) CreateContainer(c *gin.Context) {
 
 // @Summary      Obtiene un contenedor por su ID
 // @Description  Devuelve la información detallada de un único contenedor.
+// @Description  Con 'Accept: application/geo+json' (o 'format=geojson') devuelve una Feature GeoJSON con la ubicación como punto.
 // @Tags         Containers
 // @Produce      json
+// @Produce      application/geo+json
 // @Param        id   path      string  true  "ID del Contenedor (UUID)"
+// @Param        format  query  string  false  "Formato de la respuesta; tiene prioridad sobre la cabecera Accept"  Enums(json, geojson)
 // @Success      200  {object}  domain.Container
 // @Failure      404  {object}  map[string]string  "Contenedor no encontrado"
 // @Failure      500  {object}  map[string]string  "Error interno del servidor"
 // @Router       /containers/{id} [get]
 func (h *Handler) GetContainerByID(c *gin.Context) {
 	id := c.Param("id")
+	geoJSON, err := wantsGeoJSON(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	container, err := h.service.GetContainerByID(c.Request.Context(), id)
 	if err != nil {
-		if err.Error() == "contenedor no encontrado" {
+		if errors.Is(err, ErrContainerNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al buscar el contenedor"})
 		}
 		return
 	}
+	if geoJSON {
+		writeGeoJSON(c, containerFeature(container))
+		return
+	}
 	c.JSON(http.StatusOK, container)
 }
 
@@ -188,10 +569,12 @@ func (h *Handler) GetContainerByID(c *gin.Context) {
 // @Tags         Containers
 // @Accept       json
 // @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
 // @Param        id         path      string                  true  "ID del Contenedor (UUID)"
 // @Param        container  body      UpsertContainerRequest  true  "Nuevos datos del contenedor"
 // @Success      200        {object}  map[string]string       "Contenedor actualizado exitosamente"
 // @Failure      400        {object}  map[string]string       "Petición inválida o datos incorrectos"
+// @Failure      422        {object}  map[string]string       "Idempotency-Key reutilizada con una petición distinta"
 // @Failure      500        {object}  map[string]string       "Error interno del servidor"
 // @Router       /containers/{id} [put]
 func (h *Handler) UpdateContainer(c *gin.Context) {
@@ -203,9 +586,13 @@ func (h *Handler) UpdateContainer(c *gin.Context) {
 	}
 
 	container := domain.Container{
-		ID:             id,
-		Location:       domain.Point{Latitude: req.Latitude, Longitude: req.Longitude},
-		CapacityLiters: req.CapacityLiters,
+		ID:                  id,
+		Location:            domain.Point{Latitude: req.Latitude, Longitude: req.Longitude},
+		CapacityLiters:      req.CapacityLiters,
+		Fraction:            req.Fraction,
+		ContainerTypeID:     req.ContainerTypeID,
+		ThresholdProfileID:  req.ThresholdProfileID,
+		TimeWindowProfileID: req.TimeWindowProfileID,
 	}
 
 	if err := h.service.UpdateContainer(c.Request.Context(), container); err != nil {
@@ -219,8 +606,10 @@ func (h *Handler) UpdateContainer(c *gin.Context) {
 // @Summary      Elimina un contenedor
 // @Description  Elimina un contenedor y todas sus lecturas asociadas del sistema.
 // @Tags         Containers
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
 // @Param        id   path      string  true  "ID del Contenedor (UUID)"
 // @Success      204  "Sin contenido"
+// @Failure      422  {object}  map[string]string "Idempotency-Key reutilizada con una petición distinta"
 // @Failure      500  {object}  map[string]string "Error interno del servidor"
 // @Router       /containers/{id} [delete]
 func (h *Handler) DeleteContainer(c *gin.Context) {
@@ -232,24 +621,148 @@ func (h *Handler) DeleteContainer(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// GetReadingsByContainerID maneja la consulta del historial de lecturas de un contenedor.
 // @Summary      Obtiene el historial de lecturas de un contenedor
-// @Description  Devuelve una lista de las últimas N lecturas de sensor para un contenedor específico.
+// @Description  Devuelve una página de las lecturas de sensor de un contenedor, de la más reciente a la más antigua, opcionalmente limitadas a [from, to). La página siguiente se pide con 'cursor' ('next_cursor' es null en la última página).
+// @Description  Con 'bucket' (15m, 1h o 1d) las lecturas se agregan en la base de datos y se devuelve, por cada intervalo con lecturas, el nivel de llenado mínimo, máximo, medio y último y el número de lecturas (ReadingBucketPage). Los intervalos están alineados con la medianoche UTC; los de los extremos solo incluyen las lecturas dentro de [from, to).
 // @Tags         Containers
 // @Produce      json
 // @Param        id      path      string  true   "ID del Contenedor (UUID)"
-// @Param        limit   query     int     false  "Número máximo de lecturas a devolver (por defecto 50)"
-// @Success      200     {object}  []domain.Reading
+// @Param        from    query     string  false  "Lecturas desde esta fecha, incluida (RFC 3339)"
+// @Param        to      query     string  false  "Lecturas hasta esta fecha, excluida (RFC 3339)"
+// @Param        bucket  query     string  false  "Agrega las lecturas en intervalos"  Enums(15m, 1h, 1d)
+// @Param        limit   query     int     false  "Tamaño de página (por defecto 50 lecturas o 1000 intervalos)"  minimum(1) maximum(5000)
+// @Param        cursor  query     string  false  "Valor de 'next_cursor' de la página anterior"
+// @Success      200     {object}  ReadingBucketPage  "Intervalos (con 'bucket')"
+// @Success      200     {object}  ReadingPage        "Lecturas (sin 'bucket')"
+// @Failure      400     {object}  map[string]string "ID, intervalo de fechas, agregación, tamaño de página o cursor inválidos"
 // @Failure      500     {object}  map[string]string "Error interno del servidor"
 // @Router       /containers/{id}/readings [get]
 func (h *Handler) GetReadingsByContainerID(c *gin.Context) {
 	id := c.Param("id")
-	// En un caso real, el límite podría venir como un query param:
-	// limitStr := c.DefaultQuery("limit", "50")
-	// limit, _ := strconv.Atoi(limitStr)
-	readings, err := h.service.GetReadingsForContainer(c.Request.Context(), id, 50)
-	if err != nil {
+	q := HistoryQuery{Bucket: c.Query("bucket"), Cursor: c.Query("cursor")}
+	for param, dst := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("El parámetro '%s' debe tener formato RFC 3339", param)})
+				return
+			}
+			*dst = t
+		}
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxHistoryLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("'limit' debe ser un número entre 1 y %d", MaxHistoryLimit)})
+			return
+		}
+		q.Limit = limit
+	}
+
+	var result any
+	var err error
+	if q.Bucket != "" {
+		result, err = h.service.GetReadingBuckets(c.Request.Context(), id, q)
+	} else {
+		result, err = h.service.GetReadings(c.Request.Context(), id, q)
+	}
+	switch {
+	case errors.Is(err, ErrInvalidHistoryQuery), errors.Is(err, ErrInvalidPage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		fmt.Printf("Error al obtener las lecturas del contenedor %s: %v\n", id, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las lecturas"})
+	default:
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// @Summary      Predice cuándo se llenará un contenedor
+// @Description  Estima la tasa de llenado, con estacionalidad por día de la semana y hora, a partir de las lecturas de las últimas semanas y predice cuándo llegará al 100%, con un intervalo de confianza.
+// @Tags         Containers
+// @Produce      json
+// @Param        id   path      string  true  "ID del Contenedor (UUID)"
+// @Success      200  {object}  domain.Forecast
+// @Failure      404  {object}  map[string]string "Contenedor no encontrado"
+// @Failure      422  {object}  map[string]string "Historial de lecturas insuficiente"
+// @Failure      500  {object}  map[string]string "Error interno del servidor"
+// @Router       /containers/{id}/forecast [get]
+func (h *Handler) GetForecast(c *gin.Context) {
+	f, err := h.service.GetForecast(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrContainerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, forecast.ErrInsufficientHistory):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			fmt.Printf("Error al calcular la predicción: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo calcular la predicción de llenado"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// @Summary      Registra una recogida
+// @Description  Registra que un camión ha vaciado el contenedor. El estado del contenedor se reinicia (nivel 0, 'low') salvo que ya tenga una lectura posterior.
+// @Tags         Collections
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id          path      string             true  "ID del Contenedor (UUID)"
+// @Param        collection  body      CollectionRequest  true  "Datos de la recogida"
+// @Success      201         {object}  domain.Collection  "Recogida registrada"
+// @Failure      400         {object}  map[string]string  "Petición inválida o datos incorrectos"
+// @Failure      404         {object}  map[string]string  "Contenedor no encontrado"
+// @Failure      422         {object}  map[string]string  "Idempotency-Key reutilizada con una petición distinta"
+// @Failure      500         {object}  map[string]string  "Error interno del servidor"
+// @Router       /containers/{id}/collections [post]
+func (h *Handler) CreateCollection(c *gin.Context) {
+	var req CollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collectedBy := req.CollectedBy
+	collection := domain.Collection{
+		ContainerID:           c.Param("id"),
+		CollectedAt:           req.CollectedAt,
+		CollectedBy:           &collectedBy,
+		EstimatedVolumeLiters: req.EstimatedVolumeLiters,
+	}
+
+	created, err := h.service.RecordCollection(c.Request.Context(), collection)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidCollection):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrContainerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			fmt.Printf("Error al registrar la recogida: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar la recogida"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// @Summary      Obtiene el historial de recogidas de un contenedor
+// @Description  Devuelve las últimas recogidas del contenedor, tanto registradas como inferidas de una caída brusca del nivel de llenado.
+// @Tags         Collections
+// @Produce      json
+// @Param        id   path      string  true  "ID del Contenedor (UUID)"
+// @Success      200  {object}  []domain.Collection
+// @Failure      500  {object}  map[string]string "Error interno del servidor"
+// @Router       /containers/{id}/collections [get]
+func (h *Handler) GetCollectionsByContainerID(c *gin.Context) {
+	collections, err := h.service.GetCollectionsForContainer(c.Request.Context(), c.Param("id"), 50)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener las recogidas"})
 		return
 	}
-	c.JSON(http.StatusOK, readings)
+	c.JSON(http.StatusOK, collections)
 }
@@ -0,0 +1,117 @@
+package container
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"smart-waste-management/internal/domain"
+	"time"
+)
+
+// ErrInvalidHistoryQuery se devuelve cuando el contenedor, el intervalo de fechas o la agregación
+// pedidos en el historial de lecturas no son válidos.
+var ErrInvalidHistoryQuery = errors.New("consulta del historial de lecturas inválida")
+
+// Tamaño de página del historial de lecturas: por defecto, de lecturas o de intervalos, y máximo.
+const (
+	DefaultReadingsLimit = 50
+	DefaultBucketsLimit  = 1000
+	MaxHistoryLimit      = 5000
+)
+
+// BucketSizes son los intervalos admitidos para agregar el historial de lecturas.
+var BucketSizes = map[string]time.Duration{
+	"15m": 15 * time.Minute,
+	"1h":  time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+// HistoryQuery pide una página del historial de lecturas de un contenedor, de la más reciente a
+// la más antigua.
+type HistoryQuery struct {
+	// From y To limitan las lecturas a las registradas en [From, To). Cero significa sin límite.
+	From time.Time
+	To   time.Time
+	// Bucket agrega las lecturas en intervalos ("15m", "1h" o "1d"); vacío devuelve las lecturas.
+	Bucket string
+	// Limit es el tamaño de la página; cero significa el valor por defecto.
+	Limit int
+	// Cursor es el 'next_cursor' de la página anterior; vacío para la primera página.
+	Cursor string
+}
+
+// ReadingRange es el intervalo de lecturas, ya validado, que consulta el repositorio.
+type ReadingRange struct {
+	// From y To limitan las lecturas a las registradas en [From, To). Cero significa sin límite.
+	From time.Time
+	To   time.Time
+	// Limit es el número máximo de filas devueltas.
+	Limit int
+}
+
+// ReadingPage es una página de lecturas.
+type ReadingPage struct {
+	Items []domain.Reading `json:"items"`
+	// NextCursor se pasa como 'cursor' para pedir la página siguiente; es null en la última página.
+	NextCursor *string `json:"next_cursor"`
+}
+
+// ReadingBucketPage es una página del historial agregado por intervalos. Solo incluye los
+// intervalos con alguna lectura.
+type ReadingBucketPage struct {
+	Bucket string                 `json:"bucket"`
+	Items  []domain.ReadingBucket `json:"items"`
+	// NextCursor se pasa como 'cursor' para pedir la página siguiente; es null en la última página.
+	NextCursor *string `json:"next_cursor"`
+}
+
+// historyCursor es la posición en el historial: la página siguiente empieza antes de Before. Lleva
+// la agregación para rechazarlo si se usa con otra.
+type historyCursor struct {
+	Bucket string    `json:"b,omitempty"`
+	Before time.Time `json:"t"`
+}
+
+// newReadingRange valida la consulta del historial y la traduce al intervalo que se consulta. El
+// cursor acota el final del intervalo.
+func newReadingRange(id string, q HistoryQuery, defaultLimit int) (ReadingRange, error) {
+	if !uuidPattern.MatchString(id) {
+		return ReadingRange{}, fmt.Errorf("%w: ID de contenedor inválido", ErrInvalidHistoryQuery)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return ReadingRange{}, fmt.Errorf("%w: 'from' debe ser anterior a 'to'", ErrInvalidHistoryQuery)
+	}
+	r := ReadingRange{From: q.From, To: q.To, Limit: q.Limit}
+	switch {
+	case q.Limit == 0:
+		r.Limit = defaultLimit
+	case q.Limit < 0 || q.Limit > MaxHistoryLimit:
+		return ReadingRange{}, fmt.Errorf("%w: el tamaño de página debe estar entre 1 y %d", ErrInvalidPage, MaxHistoryLimit)
+	}
+
+	if q.Cursor != "" {
+		var cursor historyCursor
+		data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+		if err == nil {
+			err = json.Unmarshal(data, &cursor)
+		}
+		if err != nil || cursor.Before.IsZero() {
+			return ReadingRange{}, fmt.Errorf("%w: cursor mal formado", ErrInvalidPage)
+		}
+		if cursor.Bucket != q.Bucket {
+			return ReadingRange{}, fmt.Errorf("%w: el cursor corresponde a otra agregación", ErrInvalidPage)
+		}
+		if r.To.IsZero() || cursor.Before.Before(r.To) {
+			r.To = cursor.Before
+		}
+	}
+	return r, nil
+}
+
+// encodeHistoryCursor devuelve el cursor de la página que empieza antes de 'before'.
+func encodeHistoryCursor(bucket string, before time.Time) *string {
+	data, _ := json.Marshal(historyCursor{Bucket: bucket, Before: before.UTC()})
+	cursor := base64.RawURLEncoding.EncodeToString(data)
+	return &cursor
+}
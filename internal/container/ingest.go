@@ -0,0 +1,211 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smart-waste-management/internal/domain"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Errores devueltos por la ruta de ingesta asíncrona.
+var (
+	// ErrQueueFull indica que el buffer de ingesta está lleno y la lectura no se ha aceptado (backpressure).
+	ErrQueueFull = errors.New("la cola de ingesta está llena")
+	// ErrIngestClosed indica que el servicio se está deteniendo y ya no acepta lecturas.
+	ErrIngestClosed = errors.New("la cola de ingesta está cerrada")
+)
+
+// readingProcessTimeout limita el tiempo que un worker dedica a persistir una única lectura.
+const readingProcessTimeout = 10 * time.Second
+
+// IngestConfig define el tamaño del buffer y el número de workers de la cola de ingesta.
+type IngestConfig struct {
+	QueueSize int
+	Workers   int
+}
+
+// DefaultIngestConfig devuelve una configuración razonable para un único nodo.
+func DefaultIngestConfig() IngestConfig {
+	return IngestConfig{
+		QueueSize: 1000,
+		Workers:   4,
+	}
+}
+
+// IngestStats expone el estado de la cola de ingesta para su monitorización.
+type IngestStats struct {
+	QueueDepth    int    `json:"queue_depth"`
+	QueueCapacity int    `json:"queue_capacity"`
+	Workers       int    `json:"workers"`
+	Enqueued      uint64 `json:"enqueued"`
+	Rejected      uint64 `json:"rejected"`
+	Processed     uint64 `json:"processed"`
+	Failed        uint64 `json:"failed"`
+	// Desglose de las lecturas procesadas según su efecto.
+	Applied       uint64  `json:"applied"`
+	HistoryOnly   uint64  `json:"history_only"`
+	Duplicates    uint64  `json:"duplicates"`
+	AvgWaitMs     float64 `json:"avg_wait_ms"`
+	AvgLatencyMs  float64 `json:"avg_latency_ms"`
+	LastLatencyMs float64 `json:"last_latency_ms"`
+}
+
+// queuedReading es un elemento de la cola junto con el instante en que fue aceptado.
+type queuedReading struct {
+	reading    domain.Reading
+	enqueuedAt time.Time
+}
+
+// ingestQueue es una cola en memoria con buffer acotado y un pool fijo de workers.
+// Cada worker entrega las lecturas a la función 'process' (la ruta síncrona del servicio).
+type ingestQueue struct {
+	jobs    chan queuedReading
+	process func(ctx context.Context, reading domain.Reading) (domain.ReadingOutcome, error)
+	workers int
+
+	// mu protege 'closed' para que nunca se envíe a un canal ya cerrado.
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+
+	enqueued       atomic.Uint64
+	rejected       atomic.Uint64
+	processed      atomic.Uint64
+	failed         atomic.Uint64
+	applied        atomic.Uint64
+	historyOnly    atomic.Uint64
+	duplicates     atomic.Uint64
+	totalWaitNs    atomic.Int64
+	totalLatencyNs atomic.Int64
+	lastLatencyNs  atomic.Int64
+}
+
+// newIngestQueue crea la cola y arranca sus workers.
+func newIngestQueue(cfg IngestConfig, process func(ctx context.Context, reading domain.Reading) (domain.ReadingOutcome, error)) *ingestQueue {
+	defaults := DefaultIngestConfig()
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaults.QueueSize
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaults.Workers
+	}
+
+	q := &ingestQueue{
+		jobs:    make(chan queuedReading, cfg.QueueSize),
+		process: process,
+		workers: cfg.Workers,
+	}
+
+	q.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go q.worker()
+	}
+	return q
+}
+
+// enqueue intenta añadir una lectura sin bloquear. Si el buffer está lleno devuelve ErrQueueFull.
+func (q *ingestQueue) enqueue(reading domain.Reading) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrIngestClosed
+	}
+
+	select {
+	case q.jobs <- queuedReading{reading: reading, enqueuedAt: time.Now()}:
+		q.enqueued.Add(1)
+		return nil
+	default:
+		q.rejected.Add(1)
+		return ErrQueueFull
+	}
+}
+
+// worker consume lecturas hasta que el canal se cierra y queda vacío.
+func (q *ingestQueue) worker() {
+	defer q.wg.Done()
+
+	for job := range q.jobs {
+		start := time.Now()
+		q.totalWaitNs.Add(int64(start.Sub(job.enqueuedAt)))
+
+		// Usamos un contexto propio: el de la petición HTTP ya ha terminado cuando el worker la procesa.
+		ctx, cancel := context.WithTimeout(context.Background(), readingProcessTimeout)
+		outcome, err := q.process(ctx, job.reading)
+		cancel()
+
+		latency := time.Since(start)
+		q.totalLatencyNs.Add(int64(latency))
+		q.lastLatencyNs.Store(int64(latency))
+
+		if err != nil {
+			q.failed.Add(1)
+			fmt.Printf("Error al procesar la lectura encolada del contenedor %s: %v\n", job.reading.ContainerID, err)
+			continue
+		}
+		q.processed.Add(1)
+		switch outcome {
+		case domain.OutcomeApplied:
+			q.applied.Add(1)
+		case domain.OutcomeHistory:
+			q.historyOnly.Add(1)
+		case domain.OutcomeDiscarded:
+			q.duplicates.Add(1)
+		}
+	}
+}
+
+// shutdown deja de aceptar lecturas y espera a que los workers vacíen la cola
+// o a que expire el contexto.
+func (q *ingestQueue) shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("la cola de ingesta no se vació a tiempo (%d lecturas pendientes): %w", len(q.jobs), ctx.Err())
+	}
+}
+
+// stats devuelve una instantánea de las métricas de la cola.
+func (q *ingestQueue) stats() IngestStats {
+	stats := IngestStats{
+		QueueDepth:    len(q.jobs),
+		QueueCapacity: cap(q.jobs),
+		Workers:       q.workers,
+		Enqueued:      q.enqueued.Load(),
+		Rejected:      q.rejected.Load(),
+		Processed:     q.processed.Load(),
+		Failed:        q.failed.Load(),
+		Applied:       q.applied.Load(),
+		HistoryOnly:   q.historyOnly.Load(),
+		Duplicates:    q.duplicates.Load(),
+		LastLatencyMs: nsToMs(q.lastLatencyNs.Load()),
+	}
+
+	if done := stats.Processed + stats.Failed; done > 0 {
+		stats.AvgWaitMs = nsToMs(q.totalWaitNs.Load()) / float64(done)
+		stats.AvgLatencyMs = nsToMs(q.totalLatencyNs.Load()) / float64(done)
+	}
+	return stats
+}
+
+func nsToMs(ns int64) float64 {
+	return float64(ns) / float64(time.Millisecond)
+}
@@ -0,0 +1,224 @@
+package container
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"smart-waste-management/internal/domain"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidPage se devuelve cuando el orden, el tamaño de página o el cursor no son válidos.
+var ErrInvalidPage = errors.New("paginación inválida")
+
+// Tamaño de página del listado de contenedores: por defecto y máximo.
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// SortField es un campo por el que se puede ordenar el listado de contenedores.
+type SortField string
+
+const (
+	SortCreatedAt   SortField = "created_at"
+	SortLastUpdated SortField = "last_updated"
+	SortFillLevel   SortField = "fill_level"
+	SortCapacity    SortField = "capacity"
+	// SortDistance ordena por la distancia al punto de la búsqueda por radio; solo se admite con ella.
+	SortDistance SortField = "distance"
+)
+
+// sortKey describe cómo se ordena por un campo: la columna, su tipo SQL (para leer el valor del
+// cursor, que viaja como texto) y cómo se obtiene ese valor de un contenedor.
+type sortKey struct {
+	// column es la expresión por la que se ordena. Para la distancia depende del punto de la
+	// búsqueda y la construye el repositorio.
+	column  string
+	sqlType string
+	value   func(c domain.Container) string
+}
+
+var sortKeys = map[SortField]sortKey{
+	SortCreatedAt: {"created_at", "timestamptz", func(c domain.Container) string {
+		return c.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}},
+	// Los contenedores que aún no han enviado ninguna lectura (last_updated_at es NULL) son los más
+	// antiguos.
+	SortLastUpdated: {"COALESCE(last_updated_at, '-infinity'::timestamptz)", "timestamptz", func(c domain.Container) string {
+		if c.LastUpdatedAt.IsZero() {
+			return "-infinity"
+		}
+		return c.LastUpdatedAt.UTC().Format(time.RFC3339Nano)
+	}},
+	SortFillLevel: {"last_fill_level", "int", func(c domain.Container) string {
+		return strconv.Itoa(c.LastFillLevel)
+	}},
+	SortCapacity: {"capacity_liters", "int", func(c domain.Container) string {
+		return strconv.Itoa(c.CapacityLiters)
+	}},
+	SortDistance: {"", "double precision", func(c domain.Container) string {
+		if c.DistanceMeters == nil {
+			return "0"
+		}
+		return strconv.FormatFloat(*c.DistanceMeters, 'f', -1, 64)
+	}},
+}
+
+// IsValid comprueba si el campo es uno de los admitidos.
+func (f SortField) IsValid() bool {
+	_, ok := sortKeys[f]
+	return ok
+}
+
+// PageRequest pide una página del listado de contenedores.
+type PageRequest struct {
+	// Sort es el campo de ordenación. Si está vacío se ordena por distancia en las búsquedas por
+	// radio y, si no, por fecha de alta.
+	Sort SortField
+	// Order es "asc" o "desc". Si está vacío, la distancia se ordena de menor a mayor y el resto de
+	// campos de mayor a menor.
+	Order string
+	// Limit es el tamaño de la página; cero significa DefaultPageSize.
+	Limit int
+	// Cursor es el 'next_cursor' de la página anterior; vacío para la primera página.
+	Cursor string
+}
+
+// ContainerPage es una página del listado de contenedores.
+type ContainerPage struct {
+	Items []domain.Container `json:"items"`
+	// NextCursor se pasa como 'cursor' para pedir la página siguiente; es null en la última página.
+	NextCursor *string `json:"next_cursor"`
+	// Total es el número de contenedores que cumplen el filtro, sumando todas las páginas.
+	Total int `json:"total"`
+}
+
+// PageQuery es una página ya validada, tal como la consulta el repositorio.
+type PageQuery struct {
+	Sort       SortField
+	Descending bool
+	// Limit es el número máximo de contenedores; cero significa sin límite.
+	Limit int
+	// After es la posición del último contenedor de la página anterior, si la hay.
+	After *Cursor
+}
+
+// Cursor es la posición en el listado (el valor del campo de ordenación y el ID del último
+// contenedor devuelto) con el orden y el filtro de la consulta, para rechazarlo si se usa con otra.
+type Cursor struct {
+	Sort       SortField `json:"s"`
+	Descending bool      `json:"d,omitempty"`
+	Filter     string    `json:"f"`
+	Value      string    `json:"v"`
+	ID         string    `json:"id"`
+}
+
+// defaultPage es el orden por defecto del listado: por distancia en las búsquedas por radio y,
+// si no, de los contenedores más recientes a los más antiguos.
+func defaultPage(filter ContainerFilter) PageQuery {
+	if filter.Near != nil {
+		return PageQuery{Sort: SortDistance}
+	}
+	return PageQuery{Sort: SortCreatedAt, Descending: true}
+}
+
+// newPageQuery valida la petición de página y decodifica su cursor.
+func newPageQuery(filter ContainerFilter, req PageRequest) (PageQuery, error) {
+	page := defaultPage(filter)
+	if req.Sort != "" {
+		if !req.Sort.IsValid() {
+			return PageQuery{}, fmt.Errorf("%w: no se puede ordenar por '%s'", ErrInvalidPage, req.Sort)
+		}
+		page.Sort, page.Descending = req.Sort, req.Sort != SortDistance
+	}
+	if page.Sort == SortDistance && filter.Near == nil {
+		return PageQuery{}, fmt.Errorf("%w: solo se puede ordenar por distancia en una búsqueda por radio", ErrInvalidPage)
+	}
+	switch req.Order {
+	case "":
+	case "asc", "desc":
+		page.Descending = req.Order == "desc"
+	default:
+		return PageQuery{}, fmt.Errorf("%w: el orden debe ser 'asc' o 'desc'", ErrInvalidPage)
+	}
+
+	switch {
+	case req.Limit == 0:
+		page.Limit = DefaultPageSize
+	case req.Limit < 0 || req.Limit > MaxPageSize:
+		return PageQuery{}, fmt.Errorf("%w: el tamaño de página debe estar entre 1 y %d", ErrInvalidPage, MaxPageSize)
+	default:
+		page.Limit = req.Limit
+	}
+
+	if req.Cursor != "" {
+		after, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return PageQuery{}, err
+		}
+		if after.Sort != page.Sort || after.Descending != page.Descending || after.Filter != filterFingerprint(filter) {
+			return PageQuery{}, fmt.Errorf("%w: el cursor corresponde a otra consulta", ErrInvalidPage)
+		}
+		page.After = &after
+	}
+	return page, nil
+}
+
+// nextCursor devuelve el cursor que sigue al contenedor 'last' en la consulta.
+func nextCursor(filter ContainerFilter, page PageQuery, last domain.Container) string {
+	cursor := Cursor{
+		Sort:       page.Sort,
+		Descending: page.Descending,
+		Filter:     filterFingerprint(filter),
+		Value:      sortKeys[page.Sort].value(last),
+		ID:         last.ID,
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor lee un cursor generado por nextCursor.
+func decodeCursor(value string) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil || !cursor.Sort.IsValid() || !uuidPattern.MatchString(cursor.ID) || !sortKeys[cursor.Sort].validValue(cursor.Value) {
+		return Cursor{}, fmt.Errorf("%w: cursor mal formado", ErrInvalidPage)
+	}
+	return cursor, nil
+}
+
+// validValue comprueba que el valor de un cursor es del tipo SQL del campo, para que un cursor
+// manipulado se rechace aquí y no al convertirlo en la consulta.
+func (k sortKey) validValue(v string) bool {
+	switch k.sqlType {
+	case "timestamptz":
+		if v == "-infinity" {
+			return true
+		}
+		_, err := time.Parse(time.RFC3339Nano, v)
+		return err == nil
+	case "int":
+		_, err := strconv.ParseInt(v, 10, 32)
+		return err == nil
+	case "double precision":
+		f, err := strconv.ParseFloat(v, 64)
+		return err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	}
+	return false
+}
+
+// filterFingerprint resume el filtro para comprobar que un cursor se usa con la misma consulta.
+func filterFingerprint(filter ContainerFilter) string {
+	data, _ := json.Marshal(filter)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
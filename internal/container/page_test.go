@@ -0,0 +1,63 @@
+package container
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const cursorID = "3f1c2a9e-8b7d-4c6e-9a5f-1d2e3c4b5a69"
+
+// encodeCursor codifica un cursor como lo hace nextCursor, con cualquier valor.
+func encodeCursor(t *testing.T, c Cursor) string {
+	t.Helper()
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("error al codificar el cursor: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestDecodeCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  Cursor
+		wantErr bool
+	}{
+		{name: "fecha de alta", cursor: Cursor{Sort: SortCreatedAt, Value: "2025-03-10T06:00:00.123456Z", ID: cursorID}},
+		{name: "contenedor sin lecturas", cursor: Cursor{Sort: SortLastUpdated, Value: "-infinity", ID: cursorID}},
+		{name: "nivel de llenado", cursor: Cursor{Sort: SortFillLevel, Value: "73", ID: cursorID}},
+		{name: "distancia", cursor: Cursor{Sort: SortDistance, Value: "152.75", ID: cursorID}},
+		{name: "fecha que no es una fecha", cursor: Cursor{Sort: SortCreatedAt, Value: "ayer", ID: cursorID}, wantErr: true},
+		{name: "-infinity como número", cursor: Cursor{Sort: SortCapacity, Value: "-infinity", ID: cursorID}, wantErr: true},
+		{name: "entero con decimales", cursor: Cursor{Sort: SortFillLevel, Value: "7.5", ID: cursorID}, wantErr: true},
+		{name: "entero fuera de rango", cursor: Cursor{Sort: SortCapacity, Value: "99999999999", ID: cursorID}, wantErr: true},
+		{name: "distancia no numérica", cursor: Cursor{Sort: SortDistance, Value: "NaN", ID: cursorID}, wantErr: true},
+		{name: "valor con SQL", cursor: Cursor{Sort: SortFillLevel, Value: "1; DROP TABLE containers", ID: cursorID}, wantErr: true},
+		{name: "campo desconocido", cursor: Cursor{Sort: "color", Value: "1", ID: cursorID}, wantErr: true},
+		{name: "ID que no es un UUID", cursor: Cursor{Sort: SortFillLevel, Value: "1", ID: "contenedor-1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(encodeCursor(t, tt.cursor))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPage) {
+					t.Fatalf("decodeCursor() error = %v, se esperaba ErrInvalidPage", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeCursor() error inesperado: %v", err)
+			}
+			if got != tt.cursor {
+				t.Errorf("decodeCursor() = %+v, se esperaba %+v", got, tt.cursor)
+			}
+		})
+	}
+
+	if _, err := decodeCursor("no es base64!"); !errors.Is(err, ErrInvalidPage) {
+		t.Errorf("decodeCursor() error = %v, se esperaba ErrInvalidPage", err)
+	}
+}
@@ -0,0 +1,378 @@
+package container
+
+import (
+	"context"
+	"math"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/optimizer"
+	"sort"
+	"time"
+)
+
+// Tiempos de referencia para estimar el horario de las rutas.
+const (
+	// DefaultAverageSpeedKmh es la velocidad media de un camión de recogida en ciudad si no se indica otra.
+	DefaultAverageSpeedKmh = 25.0
+	// DefaultServiceTime es el tiempo de maniobra y vaciado de un contenedor si no se indica otro.
+	DefaultServiceTime = 2 * time.Minute
+	// UnloadTime es el tiempo de pesaje y descarga en un punto de descarga.
+	UnloadTime = 20 * time.Minute
+)
+
+// routeSites son las instalaciones que intervienen en la planificación.
+type routeSites struct {
+	// depot es el depósito de salida y regreso; nil si la ruta sale de StartPoint.
+	depot *domain.Facility
+	// disposals son los puntos de descarga que admiten la fracción de la ruta.
+	disposals []domain.Facility
+}
+
+// network es la matriz de distancias de una planificación y la correspondencia entre sus nodos
+// y las paradas: el nodo 0 es el origen (depósito o punto de salida), los nodos 1..D son los
+// puntos de descarga y los siguientes, las paradas en el orden de 'stops'.
+type network struct {
+	matrix     optimizer.Matrix
+	stops      []domain.RouteStop
+	sites      routeSites
+	origin     domain.Point
+	closed     bool // Las rutas vuelven al origen.
+	algorithms []optimizer.Algorithm
+	// capacity es la capacidad de cada camión en [litros, kilos]; infinita sin flota.
+	capacity []float64
+	clock    clock
+}
+
+// newNetwork construye la red de una planificación con las distancias de 'distances'.
+func newNetwork(ctx context.Context, distances optimizer.DistanceProvider, opts RouteOptions, sites routeSites, stops []domain.RouteStop) (*network, error) {
+	n := &network{
+		stops:      stops,
+		sites:      sites,
+		origin:     opts.StartPoint,
+		algorithms: opts.algorithms(),
+		// Con depósito, los camiones vuelven a él. Con flota y sin depósito, StartPoint hace de depósito.
+		closed:   sites.depot != nil || opts.Fleet != nil,
+		capacity: []float64{math.Inf(1), math.Inf(1)},
+		clock:    opts.clock(),
+	}
+	if opts.Fleet != nil {
+		n.capacity = opts.Fleet.capacity()
+	}
+	if sites.depot != nil {
+		n.origin = sites.depot.Location
+	}
+
+	points := make([]domain.Point, 0, 1+len(sites.disposals)+len(stops))
+	points = append(points, n.origin)
+	for _, f := range sites.disposals {
+		points = append(points, f.Location)
+	}
+	for _, stop := range stops {
+		points = append(points, stop.Location)
+	}
+	matrix, err := distances.DistanceMatrix(ctx, points)
+	if err != nil {
+		return nil, err
+	}
+	n.matrix = matrix
+	return n, nil
+}
+
+func (n *network) stopNode(i int) int { return 1 + len(n.sites.disposals) + i }
+
+func (n *network) isDisposal(node int) bool { return node >= 1 && node <= len(n.sites.disposals) }
+
+func (n *network) stopOf(node int) domain.RouteStop { return n.stops[node-1-len(n.sites.disposals)] }
+
+// stopNodes devuelve los nodos de todas las paradas.
+func (n *network) stopNodes() []int {
+	nodes := make([]int, len(n.stops))
+	for i := range n.stops {
+		nodes[i] = n.stopNode(i)
+	}
+	return nodes
+}
+
+// nearestDisposal devuelve el punto de descarga más cercano a alguna parada del viaje, o -1 si no hay ninguno.
+func (n *network) nearestDisposal(trip []int) int {
+	best, bestDist := -1, math.Inf(1)
+	for d := 1; d <= len(n.sites.disposals); d++ {
+		for _, node := range trip {
+			if dist := n.matrix[node][d]; dist < bestDist {
+				best, bestDist = d, dist
+			}
+		}
+	}
+	return best
+}
+
+// runTrip mejora un viaje que sale del nodo 'from' y recoge las paradas 'trip' (en ese orden
+// inicial). El viaje termina en el punto de descarga más cercano o, si no hay ninguno, en el origen
+// (rutas cerradas) o en la última parada (rutas abiertas).
+func (n *network) runTrip(from int, trip []int, budget time.Duration) optimizer.Result {
+	tour := append([]int{from}, trip...)
+	end := n.nearestDisposal(trip)
+	if end < 0 && n.closed {
+		end = 0
+	}
+	if end >= 0 {
+		tour = append(tour, end)
+	}
+	return optimizer.Optimize(n.matrix, tour, optimizer.Options{
+		Algorithms: n.algorithms,
+		TimeBudget: budget,
+		FixedEnd:   end >= 0,
+	})
+}
+
+// algorithms devuelve las fases de mejora local efectivas.
+func (o RouteOptions) algorithms() []optimizer.Algorithm {
+	if o.Algorithms == nil {
+		return DefaultAlgorithms
+	}
+	return o.Algorithms
+}
+
+// budget devuelve el presupuesto de tiempo efectivo de la mejora local.
+func (o RouteOptions) budget() time.Duration {
+	if o.TimeBudget <= 0 {
+		return DefaultOptimizationBudget
+	}
+	return o.TimeBudget
+}
+
+// planRoute genera una única ruta con todas las paradas: las ordena con el vecino más cercano
+// desde el origen, descarga al final si hay un punto de descarga y vuelve al depósito si lo hay.
+func planRoute(n *network, opts RouteOptions) domain.RoutePlan {
+	trip := optimizer.NearestNeighbourOver(n.matrix, 0, n.stopNodes(), -1)[1:]
+	return n.finish([]*vehicleRoute{n.chainTrips([][]int{trip}, opts.budget())}, nil)
+}
+
+// planFleet reparte las paradas en viajes que caben en un camión con el algoritmo de ahorros
+// (Clarke-Wright). Si hay puntos de descarga, cada camión encadena varios viajes descargando al
+// final de cada uno, y los viajes se reparten para equilibrar la longitud de las rutas. Si no los
+// hay, cada camión hace un único viaje: se atienden los más cargados y el resto de paradas queda
+// sin asignar.
+func planFleet(n *network, opts RouteOptions) domain.RoutePlan {
+	fleet := *opts.Fleet
+
+	// La demanda de cada nodo es su carga estimada en [litros, kilos]; las instalaciones no tienen demanda.
+	demands := make([][]float64, len(n.matrix))
+	for node := range demands {
+		demands[node] = []float64{0, 0}
+	}
+	for i, stop := range n.stops {
+		demands[n.stopNode(i)] = []float64{stop.EstimatedLoadLiters, stop.EstimatedLoadKg}
+	}
+	trips, oversized := optimizer.Savings(n.matrix, 0, n.stopNodes(), demands, n.capacity)
+
+	var unassigned []unassignedStop
+	for _, node := range oversized {
+		unassigned = append(unassigned, unassignedStop{node: node, reason: "su carga estimada supera la capacidad de un camión"})
+	}
+
+	var vehicles [][][]int
+	if len(n.sites.disposals) > 0 {
+		vehicles = balanceTrips(n.matrix, trips, fleet.Vehicles)
+	} else {
+		// Sin descargas, cada camión hace un solo viaje: priorizamos los que más aprovechan su capacidad.
+		utilisation := func(trip []int) float64 {
+			var load [2]float64
+			for _, node := range trip {
+				load[0] += demands[node][0]
+				load[1] += demands[node][1]
+			}
+			return math.Max(load[0]/n.capacity[0], load[1]/n.capacity[1])
+		}
+		sort.SliceStable(trips, func(a, b int) bool { return utilisation(trips[a]) > utilisation(trips[b]) })
+		for i, trip := range trips {
+			if i < fleet.Vehicles {
+				vehicles = append(vehicles, [][]int{trip})
+				continue
+			}
+			for _, node := range trip {
+				unassigned = append(unassigned, unassignedStop{node: node, reason: "no cabe en la flota: no hay puntos de descarga y todos los camiones van llenos"})
+			}
+		}
+	}
+
+	// El presupuesto de mejora local se reparte a partes iguales entre los viajes.
+	budget := opts.budget()
+	if len(trips) > 0 {
+		budget /= time.Duration(len(trips))
+	}
+
+	routes := make([]*vehicleRoute, len(vehicles))
+	for i, vehicleTrips := range vehicles {
+		routes[i] = n.chainTrips(vehicleTrips, budget)
+	}
+	plan := n.finish(routes, unassigned)
+	for i := range plan.Routes {
+		plan.Routes[i].CapacityLiters, plan.Routes[i].CapacityKg = fleet.CapacityLiters, fleet.CapacityKg
+	}
+	return plan
+}
+
+// balanceTrips reparte los viajes entre 'vehicles' camiones asignando cada viaje, de más largo a
+// más corto, al camión con menos kilómetros acumulados. La longitud de un viaje se aproxima como
+// la del circuito cerrado desde el origen.
+func balanceTrips(m optimizer.Matrix, trips [][]int, vehicles int) [][][]int {
+	if len(trips) < vehicles {
+		vehicles = len(trips)
+	}
+	length := make([]float64, len(trips))
+	order := make([]int, len(trips))
+	for i, trip := range trips {
+		length[i] = optimizer.TourLength(m, append(append([]int{0}, trip...), 0))
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return length[order[a]] > length[order[b]] })
+
+	assigned := make([][][]int, vehicles)
+	load := make([]float64, vehicles)
+	for _, t := range order {
+		v := 0
+		for k := 1; k < vehicles; k++ {
+			if load[k] < load[v] {
+				v = k
+			}
+		}
+		assigned[v] = append(assigned[v], trips[t])
+		load[v] += length[t]
+	}
+	return assigned
+}
+
+// vehicleRoute es la secuencia de nodos de un camión: empieza en el origen (nodo 0), recorre
+// paradas y descargas y, si la ruta es cerrada, termina de nuevo en el origen.
+type vehicleRoute struct {
+	seq             []int
+	initialDistance float64
+	optimization    domain.RouteOptimization
+}
+
+// unassignedStop es una parada que no se ha podido asignar a ningún camión y el motivo.
+type unassignedStop struct {
+	node   int
+	reason string
+}
+
+// chainTrips encadena los viajes de un camión desde el origen y mejora cada uno con búsqueda local
+// (con 'tripBudget' de tiempo cada uno).
+func (n *network) chainTrips(trips [][]int, tripBudget time.Duration) *vehicleRoute {
+	started := time.Now()
+	vr := &vehicleRoute{
+		seq: []int{0},
+		optimization: domain.RouteOptimization{
+			Algorithms: make([]string, len(n.algorithms)),
+		},
+	}
+	for i, alg := range n.algorithms {
+		vr.optimization.Algorithms[i] = string(alg)
+	}
+
+	for _, trip := range trips {
+		result := n.runTrip(vr.seq[len(vr.seq)-1], trip, tripBudget)
+		vr.seq = append(vr.seq, result.Tour[1:]...)
+		vr.initialDistance += result.InitialDistance
+		vr.optimization.Iterations += result.Iterations
+		vr.optimization.TimeBudgetExhausted = vr.optimization.TimeBudgetExhausted || result.TimedOut
+	}
+
+	// Si el último viaje terminó en un punto de descarga, falta el regreso al depósito.
+	if last := vr.seq[len(vr.seq)-1]; n.closed && last != 0 {
+		vr.seq = append(vr.seq, 0)
+		vr.initialDistance += n.matrix[last][0]
+	}
+	vr.optimization.ElapsedMs = time.Since(started).Milliseconds()
+	return vr
+}
+
+// finish ajusta las rutas al horario (franjas horarias y turno) y construye el plan con el
+// horario de cada parada y las paradas que no se han podido asignar.
+func (n *network) finish(routes []*vehicleRoute, unassigned []unassignedStop) domain.RoutePlan {
+	unassigned = append(unassigned, n.schedule(routes)...)
+
+	plan := domain.RoutePlan{
+		Routes:     make([]domain.Route, 0, len(routes)),
+		Unassigned: make([]domain.RouteStop, 0, len(unassigned)),
+	}
+	for _, vr := range routes {
+		if !n.hasStops(vr.seq) {
+			continue
+		}
+		route := n.toRoute(vr)
+		route.Vehicle = len(plan.Routes) + 1
+		plan.Routes = append(plan.Routes, route)
+		plan.TotalDistanceKm += route.DistanceKm
+		plan.TotalDurationMinutes += route.DurationMinutes
+	}
+	for _, u := range unassigned {
+		stop := n.stopOf(u.node)
+		stop.UnassignedReason = u.reason
+		plan.Unassigned = append(plan.Unassigned, stop)
+	}
+	plan.TotalDistanceKm = roundKm(plan.TotalDistanceKm)
+	plan.TotalDurationMinutes = roundMinutes(plan.TotalDurationMinutes)
+	return plan
+}
+
+// toRoute construye la ruta de un camión con la hora de llegada a cada parada y a cada descarga.
+func (n *network) toRoute(vr *vehicleRoute) domain.Route {
+	timing := n.simulate(vr.seq, 0)
+	route := domain.Route{
+		Depot:             n.sites.depot,
+		StartPoint:        n.origin,
+		Stops:             make([]domain.RouteStop, 0, len(vr.seq)),
+		InitialDistanceKm: roundKm(vr.initialDistance),
+		DistanceKm:        roundKm(optimizer.TourLength(n.matrix, vr.seq)),
+		StartAt:           timing.departure[0],
+		EndAt:             timing.end,
+		DurationMinutes:   roundMinutes(timing.end.Sub(timing.departure[0]).Minutes()),
+		Optimization:      vr.optimization,
+	}
+	if last := len(vr.seq) - 1; n.closed && vr.seq[last] == 0 {
+		route.ReturnDistanceKm = roundKm(n.matrix[vr.seq[last-1]][0])
+	}
+
+	var tripLiters, tripKg float64
+	for k := 1; k < len(vr.seq); k++ {
+		node := vr.seq[k]
+		eta := timing.arrival[k]
+		switch {
+		case node == 0:
+			// Regreso al depósito: no es una parada.
+		case n.isDisposal(node):
+			facility := n.sites.disposals[node-1]
+			route.Stops = append(route.Stops, domain.RouteStop{
+				Kind:                domain.StopUnload,
+				Facility:            &facility,
+				EstimatedLoadLiters: math.Round(tripLiters*10) / 10,
+				EstimatedLoadKg:     math.Round(tripKg*10) / 10,
+				ETA:                 &eta,
+			})
+			tripLiters, tripKg = 0, 0
+		default:
+			stop := n.stopOf(node)
+			stop.ETA = &eta
+			stop.WaitMinutes = roundMinutes(timing.serviceStart[k].Sub(eta).Minutes())
+			route.Stops = append(route.Stops, stop)
+			tripLiters += stop.EstimatedLoadLiters
+			tripKg += stop.EstimatedLoadKg
+			route.LoadLiters += stop.EstimatedLoadLiters
+			route.LoadKg += stop.EstimatedLoadKg
+		}
+	}
+	route.LoadLiters = math.Round(route.LoadLiters*10) / 10
+	route.LoadKg = math.Round(route.LoadKg*10) / 10
+	return route
+}
+
+// roundKm redondea una distancia en kilómetros a metros.
+func roundKm(km float64) float64 {
+	return math.Round(km*1000) / 1000
+}
+
+// roundMinutes redondea una duración en minutos a décimas.
+func roundMinutes(minutes float64) float64 {
+	return math.Round(minutes*10) / 10
+}
@@ -0,0 +1,162 @@
+package container
+
+import (
+	"fmt"
+	"math"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/optimizer"
+	"testing"
+	"time"
+)
+
+// shiftStart es el inicio de turno de las planificaciones de prueba.
+var shiftStart = time.Date(2025, 3, 10, 6, 0, 0, 0, time.UTC)
+
+// lineNetwork construye una red sobre una recta: el origen está en el km 0, los puntos de descarga
+// en 'disposalsAt' y las paradas en 'stopsAt', cada una con 'liters' litros de carga. La distancia
+// entre dos nodos es la diferencia de sus posiciones.
+func lineNetwork(disposalsAt, stopsAt []float64, liters float64, capacity []float64) *network {
+	positions := append(append([]float64{0}, disposalsAt...), stopsAt...)
+	matrix := make(optimizer.Matrix, len(positions))
+	for i := range matrix {
+		matrix[i] = make([]float64, len(positions))
+		for j := range matrix[i] {
+			matrix[i][j] = math.Abs(positions[i] - positions[j])
+		}
+	}
+
+	n := &network{
+		matrix:     matrix,
+		closed:     true,
+		algorithms: []optimizer.Algorithm{},
+		capacity:   capacity,
+		clock:      clock{start: shiftStart, speedKmh: DefaultAverageSpeedKmh, service: DefaultServiceTime, loc: time.UTC},
+	}
+	for i := range disposalsAt {
+		n.sites.disposals = append(n.sites.disposals, domain.Facility{ID: fmt.Sprintf("descarga-%d", i+1), Kind: domain.FacilityLandfill})
+	}
+	for i := range stopsAt {
+		n.stops = append(n.stops, domain.RouteStop{
+			Kind:                domain.StopContainer,
+			Container:           &domain.Container{ID: fmt.Sprintf("contenedor-%d", i+1)},
+			EstimatedLoadLiters: liters,
+		})
+	}
+	return n
+}
+
+func TestPlanFleet(t *testing.T) {
+	tests := []struct {
+		name           string
+		disposalsAt    []float64
+		stopsAt        []float64
+		liters         float64
+		fleet          Fleet
+		wantRoutes     int
+		wantUnloads    int
+		wantUnassigned int
+		wantReason     string
+	}{
+		{
+			name:        "con descarga, la carga se reparte en varios viajes",
+			disposalsAt: []float64{5},
+			stopsAt:     []float64{1, 2, 3, 4},
+			liters:      400,
+			fleet:       Fleet{Vehicles: 1, CapacityLiters: 1000},
+			wantRoutes:  1,
+			wantUnloads: 2,
+		},
+		{
+			name:           "sin descarga, los viajes que no caben en la flota quedan sin asignar",
+			stopsAt:        []float64{1, 2, 3, 4, 5, 6},
+			liters:         400,
+			fleet:          Fleet{Vehicles: 2, CapacityLiters: 1000},
+			wantRoutes:     2,
+			wantUnassigned: 2,
+			wantReason:     "no cabe en la flota: no hay puntos de descarga y todos los camiones van llenos",
+		},
+		{
+			name:           "parada con más carga que un camión",
+			disposalsAt:    []float64{5},
+			stopsAt:        []float64{1},
+			liters:         1500,
+			fleet:          Fleet{Vehicles: 1, CapacityLiters: 1000},
+			wantUnassigned: 1,
+			wantReason:     "su carga estimada supera la capacidad de un camión",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := lineNetwork(tt.disposalsAt, tt.stopsAt, tt.liters, tt.fleet.capacity())
+			plan := planFleet(n, RouteOptions{Fleet: &tt.fleet, TimeBudget: time.Second})
+
+			if len(plan.Routes) != tt.wantRoutes {
+				t.Fatalf("se han generado %d rutas, se esperaban %d", len(plan.Routes), tt.wantRoutes)
+			}
+			if len(plan.Unassigned) != tt.wantUnassigned {
+				t.Fatalf("%d paradas sin asignar, se esperaban %d", len(plan.Unassigned), tt.wantUnassigned)
+			}
+			for _, stop := range plan.Unassigned {
+				if stop.UnassignedReason != tt.wantReason {
+					t.Errorf("motivo = %q, se esperaba %q", stop.UnassignedReason, tt.wantReason)
+				}
+			}
+
+			collected, unloads := 0, 0
+			for _, route := range plan.Routes {
+				if route.CapacityLiters != tt.fleet.CapacityLiters {
+					t.Errorf("CapacityLiters = %v, se esperaba %v", route.CapacityLiters, tt.fleet.CapacityLiters)
+				}
+				var load float64
+				for _, stop := range route.Stops {
+					switch stop.Kind {
+					case domain.StopUnload:
+						unloads++
+						if stop.EstimatedLoadLiters > tt.fleet.CapacityLiters {
+							t.Errorf("se descargan %v litros, más que la capacidad del camión", stop.EstimatedLoadLiters)
+						}
+						load = 0
+					default:
+						collected++
+						load += stop.EstimatedLoadLiters
+					}
+				}
+				if load > tt.fleet.CapacityLiters {
+					t.Errorf("el último viaje lleva %v litros, más que la capacidad del camión", load)
+				}
+			}
+			if unloads != tt.wantUnloads {
+				t.Errorf("%d descargas, se esperaban %d", unloads, tt.wantUnloads)
+			}
+			if collected+len(plan.Unassigned) != len(tt.stopsAt) {
+				t.Errorf("%d paradas recogidas y %d sin asignar de %d", collected, len(plan.Unassigned), len(tt.stopsAt))
+			}
+		})
+	}
+}
+
+func TestBalanceTrips(t *testing.T) {
+	// Nodos en los km 10, 6 y 4 de una recta: los circuitos cerrados miden 20, 12 y 8 km.
+	n := lineNetwork(nil, []float64{10, 6, 4}, 0, nil)
+	trips := [][]int{{3}, {1}, {2}}
+
+	tests := []struct {
+		name     string
+		vehicles int
+		want     [][][]int
+	}{
+		{name: "un camión hace todos los viajes, de más largo a más corto", vehicles: 1, want: [][][]int{{{1}, {2}, {3}}}},
+		{name: "dos camiones equilibran los kilómetros", vehicles: 2, want: [][][]int{{{1}}, {{2}, {3}}}},
+		{name: "más camiones que viajes", vehicles: 5, want: [][][]int{{{1}}, {{2}}, {{3}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := balanceTrips(n.matrix, trips, tt.vehicles)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("balanceTrips() = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
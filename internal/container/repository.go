@@ -2,31 +2,69 @@ package container
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"smart-waste-management/internal/domain"
 	"smart-waste-management/internal/platform/database"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrContainerNotFound se devuelve cuando no existe un contenedor con el ID indicado.
+var ErrContainerNotFound = errors.New("contenedor no encontrado")
+
+// foreignKeyViolation es el código de error de PostgreSQL de una clave foránea inexistente.
+const foreignKeyViolation = "23503"
+
 // Repository define la interfaz para las operaciones de persistencia de contenedores.
 // Usar una interfaz nos permitirá 'mockear' el repositorio fácilmente para las pruebas unitarias del servicio.
 type Repository interface {
-	// SaveReading guarda una nueva lectura y actualiza el estado del contenedor correspondiente.
-	SaveReading(ctx context.Context, reading domain.Reading) error
-	// FindAllContainers devuelve todos los contenedores con su estado actual.
-	FindAllContainers(ctx context.Context) ([]domain.Container, error)
+	// SaveReading guarda una nueva lectura y, si es la más reciente, actualiza el estado del contenedor.
+	// Si la lectura indica que el contenedor se ha vaciado, registra además una recogida inferida.
+	// Devuelve si la lectura se aplicó, se guardó solo como historial o se descartó por duplicada.
+	// Si la lectura se aplica, devuelve también el cambio del estado actual del contenedor.
+	SaveReading(ctx context.Context, reading domain.Reading) (domain.ReadingOutcome, *domain.ContainerUpdate, error)
+	// SaveReadings guarda un lote de lecturas con una única inserción y actualiza una sola vez
+	// el estado de cada contenedor afectado. Devuelve el resultado de cada lectura, en el mismo orden.
+	// Devuelve también el cambio del estado actual de cada contenedor actualizado.
+	SaveReadings(ctx context.Context, readings []domain.Reading) ([]domain.ReadingOutcome, []domain.ContainerUpdate, error)
+	// FindExistingContainerIDs devuelve el subconjunto de IDs que corresponden a contenedores existentes.
+	FindExistingContainerIDs(ctx context.Context, ids []string) (map[string]bool, error)
+	// FindAllContainers devuelve los contenedores que cumplen el filtro con su estado actual.
+	FindAllContainers(ctx context.Context, filter ContainerFilter) ([]domain.Container, error)
+	// FindContainerPage devuelve una página de los contenedores que cumplen el filtro.
+	FindContainerPage(ctx context.Context, filter ContainerFilter, page PageQuery) ([]domain.Container, error)
+	// CountContainers cuenta los contenedores que cumplen el filtro.
+	CountContainers(ctx context.Context, filter ContainerFilter) (int, error)
 	// FindContainerByID busca un único contenedor por su ID.
 	FindContainerByID(ctx context.Context, id string) (domain.Container, error)
-	// FindContainersByStatus busca contenedores por su estado actual y devuelve sus IDs y ubicaciones.
-	FindContainersByStatus(ctx context.Context, statuses []domain.Status) ([]domain.Container, error)
+	// FindContainersByStatus busca contenedores por su estado actual (y opcionalmente por fracción)
+	// y devuelve sus IDs, ubicaciones, fracción y el estado necesario para estimar la carga de cada parada.
+	FindContainersByStatus(ctx context.Context, statuses []domain.Status, fraction domain.Fraction) ([]domain.Container, error)
+
+	// FindTimeWindows devuelve las franjas horarias de recogida de los contenedores que tienen perfil de franjas.
+	FindTimeWindows(ctx context.Context, ids []string) (map[string]domain.TimeWindows, error)
 
 	CreateContainer(ctx context.Context, container domain.Container) (domain.Container, error)
 	UpdateContainer(ctx context.Context, container domain.Container) error
 	DeleteContainer(ctx context.Context, id string) error
-	FindReadingsByContainerID(ctx context.Context, id string, limit int) ([]domain.Reading, error)
+	// FindReadings devuelve las lecturas del contenedor en el intervalo, de la más reciente a la más antigua.
+	FindReadings(ctx context.Context, id string, rng ReadingRange) ([]domain.Reading, error)
+	// FindReadingBuckets devuelve las lecturas del contenedor en el intervalo agregadas en intervalos
+	// de 'size' (mínimo, máximo, media y último nivel de llenado), del más reciente al más antiguo.
+	FindReadingBuckets(ctx context.Context, id string, size time.Duration, rng ReadingRange) ([]domain.ReadingBucket, error)
+	// FindReadingsSince devuelve, agrupadas por contenedor y en orden cronológico, las lecturas posteriores a 'since'.
+	FindReadingsSince(ctx context.Context, ids []string, since time.Time) (map[string][]domain.Reading, error)
+
+	// RecordCollection registra una recogida y, si es posterior al estado actual, deja el contenedor vacío.
+	// Si no se indica el volumen recogido, se estima a partir del nivel de llenado previo.
+	RecordCollection(ctx context.Context, collection domain.Collection) (domain.Collection, error)
+	FindCollectionsByContainerID(ctx context.Context, id string, limit int) ([]domain.Collection, error)
 }
 
 // postgresRepository es la implementación concreta de la interfaz Repository para PostgreSQL.
@@ -42,53 +80,440 @@ func NewPostgresRepository(db *database.DB) Repository {
 	}
 }
 
+// querier es el subconjunto común de pgxpool.Pool y pgx.Tx que usan las consultas auxiliares.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// thresholdsFor obtiene los umbrales efectivos de cada contenedor: los de su perfil
+// o, si no tiene, los umbrales por defecto.
+func thresholdsFor(ctx context.Context, q querier, ids []string) (map[string]domain.Thresholds, error) {
+	query := `
+        SELECT c.id::text, tp.medium_at, tp.high_at
+        FROM containers c
+        LEFT JOIN threshold_profiles tp ON tp.id = c.threshold_profile_id
+        WHERE c.id IN (SELECT unnest($1::text[])::uuid)`
+
+	rows, err := q.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar los umbrales de los contenedores: %w", err)
+	}
+	defer rows.Close()
+
+	thresholds := make(map[string]domain.Thresholds, len(ids))
+	for rows.Next() {
+		var id string
+		var mediumAt, highAt *int
+		if err := rows.Scan(&id, &mediumAt, &highAt); err != nil {
+			return nil, fmt.Errorf("error al escanear los umbrales del contenedor: %w", err)
+		}
+		t := domain.DefaultThresholds
+		if mediumAt != nil && highAt != nil {
+			t = domain.Thresholds{MediumAt: *mediumAt, HighAt: *highAt}
+		}
+		thresholds[id] = t
+	}
+	return thresholds, rows.Err()
+}
+
+// effectiveThresholds devuelve los umbrales de un contenedor, o los por defecto si no se encontraron.
+func effectiveThresholds(thresholds map[string]domain.Thresholds, id string) domain.Thresholds {
+	if t, ok := thresholds[strings.ToLower(id)]; ok {
+		return t
+	}
+	return domain.DefaultThresholds
+}
+
 // SaveReading implementa la lógica para guardar una lectura en la base de datos.
 // Se ejecuta dentro de una transacción para garantizar la consistencia de los datos.
-func (r *postgresRepository) SaveReading(ctx context.Context, reading domain.Reading) error {
-	// Calculamos el nuevo estado basado en la lógica de dominio.
-	newStatus := domain.CalculateStatus(reading.FillLevel)
-
+// Las lecturas duplicadas se descartan gracias a la restricción única (container_id, recorded_at),
+// y las que llegan fuera de orden se guardan en el historial sin retroceder el estado actual.
+func (r *postgresRepository) SaveReading(ctx context.Context, reading domain.Reading) (domain.ReadingOutcome, *domain.ContainerUpdate, error) {
 	// Iniciamos una transacción. Si cualquiera de las dos operaciones (INSERT o UPDATE) falla,
 	// se hará un rollback automático de ambas, manteniendo la base de datos consistente.
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("no se pudo iniciar la transacción: %w", err)
+		return "", nil, fmt.Errorf("no se pudo iniciar la transacción: %w", err)
 	}
 	defer tx.Rollback(ctx) // Defer Rollback es un patrón seguro. Si Commit() tiene éxito, no hace nada.
 
-	// 1. Insertamos la nueva lectura en la tabla 'readings'.
+	// 1. Insertamos la nueva lectura en la tabla 'readings', ignorando duplicados exactos.
 	insertReadingSQL := `
         INSERT INTO readings (container_id, fill_level, recorded_at)
-        VALUES ($1, $2, $3)`
-	_, err = tx.Exec(ctx, insertReadingSQL, reading.ContainerID, reading.FillLevel, reading.Timestamp)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (container_id, recorded_at) DO NOTHING`
+	tag, err := tx.Exec(ctx, insertReadingSQL, reading.ContainerID, reading.FillLevel, reading.Timestamp)
 	if err != nil {
-		return fmt.Errorf("error al insertar la lectura: %w", err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return "", nil, ErrContainerNotFound
+		}
+		return "", nil, fmt.Errorf("error al insertar la lectura: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.OutcomeDiscarded, nil, nil
 	}
 
-	// 2. Actualizamos el estado denormalizado en la tabla 'containers'.
+	// Calculamos el nuevo estado basado en la lógica de dominio y en los umbrales del contenedor.
+	thresholds, err := thresholdsFor(ctx, tx, []string{reading.ContainerID})
+	if err != nil {
+		return "", nil, err
+	}
+	newStatus := effectiveThresholds(thresholds, reading.ContainerID).StatusFor(reading.FillLevel)
+
+	// 2. Actualizamos el estado denormalizado en la tabla 'containers', solo si la lectura es
+	// más reciente que la última aplicada. La condición se evalúa con la fila bloqueada,
+	// así que dos lecturas concurrentes nunca retroceden el estado.
+	// Devolvemos el estado anterior para detectar si el contenedor se ha vaciado y para notificar el cambio.
 	updateContainerSQL := `
-        UPDATE containers
+        UPDATE containers AS c
         SET current_status = $1, last_fill_level = $2, last_updated_at = $3, updated_at = NOW()
-        WHERE id = $4`
-	_, err = tx.Exec(ctx, updateContainerSQL, newStatus, reading.FillLevel, reading.Timestamp, reading.ContainerID)
-	if err != nil {
-		return fmt.Errorf("error al actualizar el contenedor: %w", err)
+        FROM (SELECT id, last_fill_level, current_status, capacity_liters FROM containers WHERE id = $4 FOR UPDATE) AS prev
+        WHERE c.id = prev.id AND (c.last_updated_at IS NULL OR c.last_updated_at < $3)
+        RETURNING prev.last_fill_level, prev.current_status, prev.capacity_liters,
+                  ST_Y(c.location::geometry), ST_X(c.location::geometry), c.fraction`
+	var previousLevel, capacityLiters int
+	update := domain.ContainerUpdate{ContainerID: reading.ContainerID, FillLevel: reading.FillLevel, Status: newStatus, RecordedAt: reading.Timestamp}
+	err = tx.QueryRow(ctx, updateContainerSQL, newStatus, reading.FillLevel, reading.Timestamp, reading.ContainerID).Scan(
+		&previousLevel, &update.PreviousStatus, &capacityLiters, &update.Location.Latitude, &update.Location.Longitude, &update.Fraction)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", nil, fmt.Errorf("error al actualizar el contenedor: %w", err)
+	}
+
+	outcome := domain.OutcomeApplied
+	if errors.Is(err, pgx.ErrNoRows) {
+		outcome = domain.OutcomeHistory
+	}
+
+	// 3. Una caída brusca del nivel significa que un camión ha vaciado el contenedor sin registrarlo.
+	if outcome == domain.OutcomeApplied && domain.IsEmptyingDrop(previousLevel, reading.FillLevel) {
+		inferred := domain.Collection{
+			ContainerID:           reading.ContainerID,
+			CollectedAt:           reading.Timestamp,
+			EstimatedVolumeLiters: domain.EstimateVolumeLiters(previousLevel, capacityLiters),
+			FillLevelBefore:       previousLevel,
+			Inferred:              true,
+		}
+		if _, err := insertCollection(ctx, tx, inferred); err != nil {
+			return "", nil, err
+		}
 	}
 
 	// Si ambas operaciones fueron exitosas, hacemos commit de la transacción.
-	return tx.Commit(ctx)
+	if err := tx.Commit(ctx); err != nil {
+		return "", nil, fmt.Errorf("error al confirmar la transacción: %w", err)
+	}
+	if outcome != domain.OutcomeApplied {
+		return outcome, nil, nil
+	}
+	update.PreviousFillLevel = previousLevel
+	return outcome, &update, nil
+}
+
+// readingKey identifica una lectura por contenedor y marca de tiempo con la precisión de PostgreSQL (microsegundos).
+type readingKey struct {
+	containerID string
+	recordedAt  int64
+}
+
+func keyOf(containerID string, recordedAt time.Time) readingKey {
+	return readingKey{containerID: containerID, recordedAt: recordedAt.UnixMicro()}
+}
+
+// SaveReadings implementa la ingesta por lotes.
+// En lugar de abrir una transacción por lectura, inserta todas las lecturas en una única sentencia
+// multi-fila (vía unnest) y después actualiza el estado denormalizado de cada contenedor una sola vez,
+// usando la lectura más reciente del lote para ese contenedor. Se aplican las mismas reglas de
+// deduplicación, de orden y de detección de vaciados que en SaveReading.
+func (r *postgresRepository) SaveReadings(ctx context.Context, readings []domain.Reading) ([]domain.ReadingOutcome, []domain.ContainerUpdate, error) {
+	outcomes := make([]domain.ReadingOutcome, len(readings))
+	if len(readings) == 0 {
+		return outcomes, nil, nil
+	}
+
+	containerIDs := make([]string, len(readings))
+	fillLevels := make([]int, len(readings))
+	recordedAts := make([]time.Time, len(readings))
+	for i, reading := range readings {
+		containerIDs[i] = reading.ContainerID
+		fillLevels[i] = reading.FillLevel
+		recordedAts[i] = reading.Timestamp
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("no se pudo iniciar la transacción: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Insertamos todas las lecturas en una sola ida y vuelta a la base de datos.
+	// RETURNING nos indica cuáles se insertaron realmente (las demás eran duplicadas).
+	insertReadingsSQL := `
+        INSERT INTO readings (container_id, fill_level, recorded_at)
+        SELECT v.container_id::uuid, v.fill_level, v.recorded_at
+        FROM unnest($1::text[], $2::int[], $3::timestamptz[]) AS v(container_id, fill_level, recorded_at)
+        ON CONFLICT (container_id, recorded_at) DO NOTHING
+        RETURNING container_id::text, recorded_at`
+	rows, err := tx.Query(ctx, insertReadingsSQL, containerIDs, fillLevels, recordedAts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error al insertar el lote de lecturas: %w", err)
+	}
+	inserted := make(map[readingKey]bool)
+	for rows.Next() {
+		var id string
+		var recordedAt time.Time
+		if err := rows.Scan(&id, &recordedAt); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("error al escanear la lectura insertada: %w", err)
+		}
+		inserted[keyOf(id, recordedAt)] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error al insertar el lote de lecturas: %w", err)
+	}
+
+	// 2. Clasificamos cada lectura y buscamos la más reciente insertada de cada contenedor.
+	// Si el lote contiene la misma lectura varias veces, solo la primera cuenta como insertada.
+	latest := make(map[string]int)             // ID de contenedor -> índice de su lectura más reciente
+	batch := make(map[string][]domain.Reading) // ID de contenedor -> sus lecturas insertadas
+	for i, reading := range readings {
+		key := keyOf(reading.ContainerID, reading.Timestamp)
+		if !inserted[key] {
+			outcomes[i] = domain.OutcomeDiscarded
+			continue
+		}
+		delete(inserted, key)
+		outcomes[i] = domain.OutcomeHistory
+		batch[reading.ContainerID] = append(batch[reading.ContainerID], reading)
+
+		if j, ok := latest[reading.ContainerID]; !ok || reading.Timestamp.After(readings[j].Timestamp) {
+			latest[reading.ContainerID] = i
+		}
+	}
+	if len(latest) == 0 {
+		return outcomes, nil, tx.Commit(ctx)
+	}
+
+	// 3. Actualizamos cada contenedor una única vez con su lectura más reciente del lote,
+	// siempre que sea más reciente que su estado actual. El estado se calcula con los umbrales de cada contenedor.
+	ids := make([]string, 0, len(latest))
+	for id := range latest {
+		ids = append(ids, id)
+	}
+	thresholds, err := thresholdsFor(ctx, tx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	levels := make([]int, 0, len(latest))
+	timestamps := make([]time.Time, 0, len(latest))
+	statuses := make([]string, 0, len(latest))
+	for _, id := range ids {
+		reading := readings[latest[id]]
+		levels = append(levels, reading.FillLevel)
+		timestamps = append(timestamps, reading.Timestamp)
+		statuses = append(statuses, string(effectiveThresholds(thresholds, id).StatusFor(reading.FillLevel)))
+	}
+
+	updateContainersSQL := `
+        UPDATE containers AS c
+        SET current_status = v.status::container_status, last_fill_level = v.fill_level,
+            last_updated_at = v.recorded_at, updated_at = NOW()
+        FROM unnest($1::text[], $2::int[], $3::timestamptz[], $4::text[]) AS v(id, fill_level, recorded_at, status),
+             (SELECT id, last_fill_level, current_status, capacity_liters, last_updated_at FROM containers
+              WHERE id IN (SELECT unnest($1::text[])::uuid) FOR UPDATE) AS prev
+        WHERE c.id = v.id::uuid AND prev.id = c.id AND (c.last_updated_at IS NULL OR c.last_updated_at < v.recorded_at)
+        RETURNING c.id::text, prev.last_fill_level, prev.current_status, prev.capacity_liters, prev.last_updated_at,
+                  ST_Y(c.location::geometry), ST_X(c.location::geometry), c.fraction`
+	rows, err = tx.Query(ctx, updateContainersSQL, ids, levels, timestamps, statuses)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error al actualizar los contenedores del lote: %w", err)
+	}
+	var updates []domain.ContainerUpdate
+	var inferred []domain.Collection
+	for rows.Next() {
+		var u domain.ContainerUpdate
+		var capacityLiters int
+		var previousUpdatedAt *time.Time
+		err := rows.Scan(&u.ContainerID, &u.PreviousFillLevel, &u.PreviousStatus, &capacityLiters, &previousUpdatedAt,
+			&u.Location.Latitude, &u.Location.Longitude, &u.Fraction)
+		if err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("error al escanear el contenedor actualizado: %w", err)
+		}
+		i := latest[u.ContainerID]
+		outcomes[i] = domain.OutcomeApplied
+		u.FillLevel, u.RecordedAt = readings[i].FillLevel, readings[i].Timestamp
+		u.Status = effectiveThresholds(thresholds, u.ContainerID).StatusFor(u.FillLevel)
+		updates = append(updates, u)
+		inferred = append(inferred, inferCollections(u.PreviousFillLevel, previousUpdatedAt, capacityLiters, batch[u.ContainerID])...)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error al actualizar los contenedores del lote: %w", err)
+	}
+
+	// 4. Igual que en SaveReading, una caída brusca del nivel significa que un camión ha vaciado el
+	// contenedor sin registrarlo, tanto respecto al estado anterior como dentro del propio lote.
+	for _, collection := range inferred {
+		if _, err := insertCollection(ctx, tx, collection); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("error al confirmar la transacción: %w", err)
+	}
+	return outcomes, updates, nil
+}
+
+// inferCollections devuelve las recogidas que se deducen de las lecturas de un contenedor en un
+// lote: cada caída brusca del nivel entre una lectura y la anterior, empezando por el estado que
+// tenía el contenedor. Solo cuentan las lecturas posteriores a ese estado; las que llegan fuera de
+// orden solo van al historial.
+func inferCollections(previousLevel int, previousUpdatedAt *time.Time, capacityLiters int, batch []domain.Reading) []domain.Collection {
+	sorted := make([]domain.Reading, 0, len(batch))
+	for _, reading := range batch {
+		if previousUpdatedAt == nil || reading.Timestamp.After(*previousUpdatedAt) {
+			sorted = append(sorted, reading)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Timestamp.Before(sorted[j].Timestamp) })
+
+	var collections []domain.Collection
+	level := previousLevel
+	for _, reading := range sorted {
+		if domain.IsEmptyingDrop(level, reading.FillLevel) {
+			collections = append(collections, domain.Collection{
+				ContainerID:           reading.ContainerID,
+				CollectedAt:           reading.Timestamp,
+				EstimatedVolumeLiters: domain.EstimateVolumeLiters(level, capacityLiters),
+				FillLevelBefore:       level,
+				Inferred:              true,
+			})
+		}
+		level = reading.FillLevel
+	}
+	return collections
+}
+
+// FindExistingContainerIDs comprueba en una sola consulta qué IDs existen en la tabla 'containers'.
+// Los IDs deben tener formato UUID válido; de lo contrario la conversión en la consulta fallaría.
+// Las claves del mapa devuelto están en la forma canónica (minúsculas) de PostgreSQL.
+func (r *postgresRepository) FindExistingContainerIDs(ctx context.Context, ids []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(ids))
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	query := `SELECT id::text FROM containers WHERE id IN (SELECT unnest($1::text[])::uuid)`
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error al comprobar la existencia de contenedores: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error al escanear el ID del contenedor: %w", err)
+		}
+		existing[id] = true
+	}
+	return existing, rows.Err()
+}
+
+// FindAllContainers recupera todos los contenedores que cumplen el filtro, en el orden por defecto.
+func (r *postgresRepository) FindAllContainers(ctx context.Context, filter ContainerFilter) ([]domain.Container, error) {
+	return r.FindContainerPage(ctx, filter, defaultPage(filter))
+}
+
+// containerConditions traduce el filtro a condiciones SQL, añadiendo sus valores con 'arg'. Devuelve
+// también la expresión de la distancia al punto de la búsqueda por radio, o NULL si no la hay. Los
+// filtros geográficos usan el índice GIST de 'location'.
+func containerConditions(filter ContainerFilter, arg func(v any) string) ([]string, string) {
+	var conditions []string
+	if filter.Fraction != "" {
+		conditions = append(conditions, "fraction::text = "+arg(string(filter.Fraction)))
+	}
+	if b := filter.BBox; b != nil {
+		conditions = append(conditions, fmt.Sprintf("ST_Intersects(location, ST_MakeEnvelope(%s, %s, %s, %s, 4326)::geography)",
+			arg(b.MinLongitude), arg(b.MinLatitude), arg(b.MaxLongitude), arg(b.MaxLatitude)))
+	}
+	distance := "NULL::double precision"
+	if filter.Near != nil {
+		near := fmt.Sprintf("ST_SetSRID(ST_MakePoint(%s, %s), 4326)::geography", arg(filter.Near.Longitude), arg(filter.Near.Latitude))
+		conditions = append(conditions, fmt.Sprintf("ST_DWithin(location, %s, %s)", near, arg(filter.RadiusMeters)))
+		distance = "ROUND(ST_Distance(location, " + near + ")::numeric, 1)::double precision"
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, s := range filter.Statuses {
+			statuses[i] = string(s)
+		}
+		conditions = append(conditions, "current_status::text = ANY("+arg(statuses)+"::text[])")
+	}
+	for _, bound := range []struct {
+		condition string
+		value     *int
+	}{
+		{"last_fill_level >= ", filter.MinFillLevel},
+		{"last_fill_level <= ", filter.MaxFillLevel},
+		{"capacity_liters >= ", filter.MinCapacity},
+		{"capacity_liters <= ", filter.MaxCapacity},
+	} {
+		if bound.value != nil {
+			conditions = append(conditions, bound.condition+arg(*bound.value))
+		}
+	}
+	if filter.UpdatedBefore != nil {
+		// Un sensor que nunca ha enviado una lectura también ha dejado de informar.
+		conditions = append(conditions, "(last_updated_at IS NULL OR last_updated_at < "+arg(*filter.UpdatedBefore)+")")
+	}
+	return conditions, distance
 }
 
-// FindAllContainers recupera todos los contenedores de la base de datos.
-func (r *postgresRepository) FindAllContainers(ctx context.Context) ([]domain.Container, error) {
+// FindContainerPage recupera una página de los contenedores que cumplen el filtro. La paginación es
+// por clave (keyset): la página empieza tras el par (campo de ordenación, id) del cursor, de modo
+// que no se salta ni repite contenedores aunque se den de alta otros entre página y página.
+func (r *postgresRepository) FindContainerPage(ctx context.Context, filter ContainerFilter, page PageQuery) ([]domain.Container, error) {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conditions, distance := containerConditions(filter, arg)
+
+	key := sortKeys[page.Sort]
+	column := key.column
+	if page.Sort == SortDistance {
+		column = distance
+	}
+	direction, comparison := "ASC", ">"
+	if page.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if after := page.After; after != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s::text::%s, %s::uuid)",
+			column, comparison, arg(after.Value), key.sqlType, arg(after.ID)))
+	}
+
 	query := `
         SELECT id, ST_Y(location::geometry) as latitude, ST_X(location::geometry) as longitude,
                capacity_liters, current_status, last_fill_level, last_updated_at,
-               created_at, updated_at
-        FROM containers
-        ORDER BY created_at DESC`
+               fraction, container_type_id, threshold_profile_id, time_window_profile_id, created_at, updated_at,
+               ` + distance + ` AS distance_meters
+        FROM containers`
+	if len(conditions) > 0 {
+		query += "\n        WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf("\n        ORDER BY %s %s, id %s", column, direction, direction)
+	if page.Limit > 0 {
+		query += "\n        LIMIT " + arg(page.Limit)
+	}
 
-	rows, err := r.db.Query(ctx, query)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error al consultar los contenedores: %w", err)
 	}
@@ -97,17 +522,20 @@ func (r *postgresRepository) FindAllContainers(ctx context.Context) ([]domain.Co
 	var containers []domain.Container
 	for rows.Next() {
 		var c domain.Container
-		var lastUpdatedAt time.Time
+		var lastUpdatedAt *time.Time // Es NULL hasta la primera lectura.
 
 		err := rows.Scan(
 			&c.ID, &c.Location.Latitude, &c.Location.Longitude,
 			&c.CapacityLiters, &c.CurrentStatus, &c.LastFillLevel,
-			&lastUpdatedAt, &c.CreatedAt, &c.UpdatedAt, // Añadimos los nuevos campos al Scan
+			&lastUpdatedAt, &c.Fraction, &c.ContainerTypeID, &c.ThresholdProfileID, &c.TimeWindowProfileID, &c.CreatedAt, &c.UpdatedAt, // Añadimos los nuevos campos al Scan
+			&c.DistanceMeters,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error al escanear la fila del contenedor: %w", err)
 		}
-		c.LastUpdatedAt = lastUpdatedAt
+		if lastUpdatedAt != nil {
+			c.LastUpdatedAt = *lastUpdatedAt
+		}
 		containers = append(containers, c)
 	}
 
@@ -118,28 +546,44 @@ func (r *postgresRepository) FindAllContainers(ctx context.Context) ([]domain.Co
 	return containers, nil
 }
 
-func (r *postgresRepository) FindContainersByStatus(ctx context.Context, statuses []domain.Status) ([]domain.Container, error) {
-	// --- INICIO DE LA MODIFICACIÓN ---
-	// Creamos un slice de strings vacío para la conversión explícita.
+// CountContainers cuenta los contenedores que cumplen el filtro.
+func (r *postgresRepository) CountContainers(ctx context.Context, filter ContainerFilter) (int, error) {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conditions, _ := containerConditions(filter, arg)
+
+	query := "SELECT count(*) FROM containers"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	var total int
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("error al contar los contenedores: %w", err)
+	}
+	return total, nil
+}
+
+func (r *postgresRepository) FindContainersByStatus(ctx context.Context, statuses []domain.Status, fraction domain.Fraction) ([]domain.Container, error) {
+	// pgx no sabe codificar []domain.Status, así que lo convertimos a []string.
 	stringStatuses := make([]string, len(statuses))
-	// Iteramos sobre nuestro slice de domain.Status y lo convertimos a un slice de string.
 	for i, s := range statuses {
 		stringStatuses[i] = string(s)
 	}
-	// --- FIN DE LA MODIFICACIÓN ---
-
-	// AÑADE ESTA LÍNEA PARA DEPURAR
-	fmt.Println(">>> DEBUG: Ejecutando consulta con statuses convertidos:", stringStatuses)
 
 	query := `
-        SELECT id, ST_Y(location::geometry) as latitude, ST_X(location::geometry) as longitude
+        SELECT id, ST_Y(location::geometry) as latitude, ST_X(location::geometry) as longitude, fraction,
+               capacity_liters, current_status, last_fill_level, last_updated_at
         FROM containers
         WHERE current_status = ANY($1)
+          AND ($2 = '' OR fraction::text = $2) -- Sin fracción, se incluyen todas
         ORDER BY id; -- Ordenar para tener un resultado consistente
     `
 
 	// Le pasamos el nuevo slice de strings a la consulta.
-	rows, err := r.db.Query(ctx, query, stringStatuses)
+	rows, err := r.db.Query(ctx, query, stringStatuses, string(fraction))
 	if err != nil {
 		return nil, fmt.Errorf("error al consultar contenedores por estado: %w", err)
 	}
@@ -148,10 +592,15 @@ func (r *postgresRepository) FindContainersByStatus(ctx context.Context, statuse
 	var containers []domain.Container
 	for rows.Next() {
 		var c domain.Container
-		err := rows.Scan(&c.ID, &c.Location.Latitude, &c.Location.Longitude)
+		var lastUpdatedAt *time.Time // Es NULL hasta la primera lectura.
+		err := rows.Scan(&c.ID, &c.Location.Latitude, &c.Location.Longitude, &c.Fraction,
+			&c.CapacityLiters, &c.CurrentStatus, &c.LastFillLevel, &lastUpdatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("error al escanear contenedor por estado: %w", err)
 		}
+		if lastUpdatedAt != nil {
+			c.LastUpdatedAt = *lastUpdatedAt
+		}
 		containers = append(containers, c)
 	}
 
@@ -162,13 +611,41 @@ func (r *postgresRepository) FindContainersByStatus(ctx context.Context, statuse
 	return containers, nil
 }
 
+func (r *postgresRepository) FindTimeWindows(ctx context.Context, ids []string) (map[string]domain.TimeWindows, error) {
+	query := `
+        SELECT c.id::text, twp.windows
+        FROM containers c
+        JOIN time_window_profiles twp ON twp.id = c.time_window_profile_id
+        WHERE c.id IN (SELECT unnest($1::text[])::uuid)`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar las franjas horarias de los contenedores: %w", err)
+	}
+	defer rows.Close()
+
+	windows := make(map[string]domain.TimeWindows)
+	for rows.Next() {
+		var id string
+		var w domain.TimeWindows
+		if err := rows.Scan(&id, &w); err != nil {
+			return nil, fmt.Errorf("error al escanear las franjas horarias: %w", err)
+		}
+		windows[id] = w
+	}
+	return windows, rows.Err()
+}
+
 func (r *postgresRepository) CreateContainer(ctx context.Context, container domain.Container) (domain.Container, error) {
 	query := `
-        INSERT INTO containers (location, capacity_liters)
-        VALUES (ST_SetSRID(ST_MakePoint($1, $2), 4326), $3)
+        INSERT INTO containers (location, capacity_liters, fraction, container_type_id, threshold_profile_id, time_window_profile_id)
+        VALUES (ST_SetSRID(ST_MakePoint($1, $2), 4326), $3, $4, $5, $6, $7)
         RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRow(ctx, query, container.Location.Longitude, container.Location.Latitude, container.CapacityLiters).Scan(
+	err := r.db.QueryRow(ctx, query,
+		container.Location.Longitude, container.Location.Latitude, container.CapacityLiters,
+		container.Fraction, container.ContainerTypeID, container.ThresholdProfileID, container.TimeWindowProfileID,
+	).Scan(
 		&container.ID,
 		&container.CreatedAt, // Asumiendo que has añadido CreatedAt y UpdatedAt a tu struct de dominio
 		&container.UpdatedAt,
@@ -184,35 +661,51 @@ func (r *postgresRepository) FindContainerByID(ctx context.Context, id string) (
 	query := `
         SELECT id, ST_Y(location::geometry) as latitude, ST_X(location::geometry) as longitude,
                capacity_liters, current_status, last_fill_level, last_updated_at,
-               created_at, updated_at
+               fraction, container_type_id, threshold_profile_id, time_window_profile_id, created_at, updated_at
         FROM containers
         WHERE id = $1`
 
 	var c domain.Container
-	var lastUpdatedAt time.Time
+	var lastUpdatedAt *time.Time // Es NULL hasta la primera lectura.
 
 	err := r.db.QueryRow(ctx, query, id).Scan(
 		&c.ID, &c.Location.Latitude, &c.Location.Longitude,
 		&c.CapacityLiters, &c.CurrentStatus, &c.LastFillLevel,
-		&lastUpdatedAt, &c.CreatedAt, &c.UpdatedAt, // Añadimos los nuevos campos al Scan
+		&lastUpdatedAt, &c.Fraction, &c.ContainerTypeID, &c.ThresholdProfileID, &c.TimeWindowProfileID, &c.CreatedAt, &c.UpdatedAt, // Añadimos los nuevos campos al Scan
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return domain.Container{}, fmt.Errorf("contenedor no encontrado") // Error específico para "no encontrado"
+			return domain.Container{}, ErrContainerNotFound // Error específico para "no encontrado"
 		}
 		return domain.Container{}, fmt.Errorf("error al buscar contenedor por ID: %w", err)
 	}
-	c.LastUpdatedAt = lastUpdatedAt
+	if lastUpdatedAt != nil {
+		c.LastUpdatedAt = *lastUpdatedAt
+	}
 	return c, nil
 }
 
 func (r *postgresRepository) UpdateContainer(ctx context.Context, container domain.Container) error {
+	// Al cambiar el perfil de umbrales, el estado actual se re-deriva en la misma sentencia
+	// a partir del último nivel de llenado y de los umbrales del nuevo perfil (o los por defecto).
 	query := `
-        UPDATE containers
-        SET location = ST_SetSRID(ST_MakePoint($1, $2), 4326), capacity_liters = $3, updated_at = NOW()
-        WHERE id = $4`
-
-	_, err := r.db.Exec(ctx, query, container.Location.Longitude, container.Location.Latitude, container.CapacityLiters, container.ID)
+        UPDATE containers AS c
+        SET location = ST_SetSRID(ST_MakePoint($1, $2), 4326), capacity_liters = $3,
+            threshold_profile_id = $4, fraction = $8, container_type_id = $9, time_window_profile_id = $10,
+            current_status = (CASE WHEN c.last_fill_level >= COALESCE(tp.high_at, $6) THEN 'high'
+                                   WHEN c.last_fill_level >= COALESCE(tp.medium_at, $7) THEN 'medium'
+                                   ELSE 'low' END)::container_status,
+            updated_at = NOW()
+        FROM (SELECT $4::uuid AS profile_id) AS p
+        LEFT JOIN threshold_profiles tp ON tp.id = p.profile_id
+        WHERE c.id = $5`
+
+	_, err := r.db.Exec(ctx, query,
+		container.Location.Longitude, container.Location.Latitude, container.CapacityLiters,
+		container.ThresholdProfileID, container.ID,
+		domain.DefaultThresholds.HighAt, domain.DefaultThresholds.MediumAt,
+		container.Fraction, container.ContainerTypeID, container.TimeWindowProfileID,
+	)
 	return err
 }
 
@@ -222,15 +715,34 @@ func (r *postgresRepository) DeleteContainer(ctx context.Context, id string) err
 	return err
 }
 
-func (r *postgresRepository) FindReadingsByContainerID(ctx context.Context, id string, limit int) ([]domain.Reading, error) {
+// readingConditions traduce el contenedor y el intervalo de lecturas a condiciones SQL, añadiendo sus
+// valores con 'arg'. Usan el índice único (container_id, recorded_at).
+func readingConditions(id string, r ReadingRange, arg func(v any) string) []string {
+	conditions := []string{"container_id = " + arg(id) + "::uuid"}
+	if !r.From.IsZero() {
+		conditions = append(conditions, "recorded_at >= "+arg(r.From))
+	}
+	if !r.To.IsZero() {
+		conditions = append(conditions, "recorded_at < "+arg(r.To))
+	}
+	return conditions
+}
+
+// FindReadings devuelve las lecturas del contenedor en el intervalo, de la más reciente a la más antigua.
+func (r *postgresRepository) FindReadings(ctx context.Context, id string, rng ReadingRange) ([]domain.Reading, error) {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
 	query := `
         SELECT container_id, fill_level, recorded_at
         FROM readings
-        WHERE container_id = $1
+        WHERE ` + strings.Join(readingConditions(id, rng, arg), " AND ") + `
         ORDER BY recorded_at DESC
-        LIMIT $2`
+        LIMIT ` + arg(rng.Limit)
 
-	rows, err := r.db.Query(ctx, query, id, limit)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error al consultar lecturas: %w", err)
 	}
@@ -246,3 +758,165 @@ func (r *postgresRepository) FindReadingsByContainerID(ctx context.Context, id s
 	}
 	return readings, rows.Err()
 }
+
+// FindReadingBuckets agrega en SQL las lecturas del contenedor en intervalos de 'size', alineados
+// con la medianoche UTC, y los devuelve del más reciente al más antiguo. Solo devuelve los
+// intervalos con alguna lectura.
+func (r *postgresRepository) FindReadingBuckets(ctx context.Context, id string, size time.Duration, rng ReadingRange) ([]domain.ReadingBucket, error) {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	bucket := "date_bin(make_interval(secs => " + arg(size.Seconds()) + "), recorded_at, TIMESTAMPTZ '2000-01-01 00:00:00+00')"
+	query := `
+        SELECT ` + bucket + ` AS bucket,
+               MIN(fill_level), MAX(fill_level), ROUND(AVG(fill_level), 1)::double precision,
+               (array_agg(fill_level ORDER BY recorded_at DESC))[1], COUNT(*)
+        FROM readings
+        WHERE ` + strings.Join(readingConditions(id, rng, arg), " AND ") + `
+        GROUP BY bucket
+        ORDER BY bucket DESC
+        LIMIT ` + arg(rng.Limit)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al agregar las lecturas: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []domain.ReadingBucket
+	for rows.Next() {
+		var b domain.ReadingBucket
+		if err := rows.Scan(&b.Start, &b.MinFillLevel, &b.MaxFillLevel, &b.AvgFillLevel, &b.LastFillLevel, &b.Readings); err != nil {
+			return nil, fmt.Errorf("error al escanear el intervalo de lecturas: %w", err)
+		}
+		b.End = b.Start.Add(size)
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+func (r *postgresRepository) FindReadingsSince(ctx context.Context, ids []string, since time.Time) (map[string][]domain.Reading, error) {
+	query := `
+        SELECT container_id::text, fill_level, recorded_at
+        FROM readings
+        WHERE container_id IN (SELECT unnest($1::text[])::uuid) AND recorded_at >= $2
+        ORDER BY container_id, recorded_at`
+
+	rows, err := r.db.Query(ctx, query, ids, since)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar el historial de lecturas: %w", err)
+	}
+	defer rows.Close()
+
+	history := make(map[string][]domain.Reading, len(ids))
+	for rows.Next() {
+		var r domain.Reading
+		if err := rows.Scan(&r.ContainerID, &r.FillLevel, &r.Timestamp); err != nil {
+			return nil, fmt.Errorf("error al escanear lectura: %w", err)
+		}
+		history[r.ContainerID] = append(history[r.ContainerID], r)
+	}
+	return history, rows.Err()
+}
+
+// insertCollection inserta una recogida dentro de la transacción indicada.
+func insertCollection(ctx context.Context, tx pgx.Tx, c domain.Collection) (domain.Collection, error) {
+	query := `
+        INSERT INTO collections (container_id, collected_at, collected_by, estimated_volume_liters, fill_level_before, inferred)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at`
+
+	err := tx.QueryRow(ctx, query,
+		c.ContainerID, c.CollectedAt, c.CollectedBy, c.EstimatedVolumeLiters, c.FillLevelBefore, c.Inferred,
+	).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return domain.Collection{}, fmt.Errorf("error al registrar la recogida: %w", err)
+	}
+	return c, nil
+}
+
+func (r *postgresRepository) RecordCollection(ctx context.Context, collection domain.Collection) (domain.Collection, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.Collection{}, fmt.Errorf("no se pudo iniciar la transacción: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	created, err := RecordCollectionTx(ctx, tx, collection)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Collection{}, fmt.Errorf("error al confirmar la transacción: %w", err)
+	}
+	return created, nil
+}
+
+// RecordCollectionTx registra una recogida y, si es posterior al estado actual, deja el contenedor
+// vacío, dentro de la transacción indicada. Las rutas la usan para guardar la recogida de una
+// parada junto con su resultado.
+func RecordCollectionTx(ctx context.Context, tx pgx.Tx, collection domain.Collection) (domain.Collection, error) {
+	// 1. Bloqueamos el contenedor para que ninguna lectura concurrente se intercale con la recogida.
+	var capacityLiters int
+	err := tx.QueryRow(ctx, `SELECT capacity_liters FROM containers WHERE id = $1 FOR UPDATE`, collection.ContainerID).Scan(&capacityLiters)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Collection{}, ErrContainerNotFound
+		}
+		return domain.Collection{}, fmt.Errorf("error al buscar el contenedor: %w", err)
+	}
+
+	// 2. El nivel previo es el de la última lectura anterior a la recogida, que puede registrarse con retraso.
+	levelBeforeSQL := `
+        SELECT COALESCE((SELECT fill_level FROM readings
+                         WHERE container_id = $1 AND recorded_at <= $2
+                         ORDER BY recorded_at DESC LIMIT 1), 0)`
+	if err := tx.QueryRow(ctx, levelBeforeSQL, collection.ContainerID, collection.CollectedAt).Scan(&collection.FillLevelBefore); err != nil {
+		return domain.Collection{}, fmt.Errorf("error al obtener el nivel previo a la recogida: %w", err)
+	}
+	if collection.EstimatedVolumeLiters == 0 {
+		collection.EstimatedVolumeLiters = domain.EstimateVolumeLiters(collection.FillLevelBefore, capacityLiters)
+	}
+
+	created, err := insertCollection(ctx, tx, collection)
+	if err != nil {
+		return domain.Collection{}, err
+	}
+
+	// 3. Reiniciamos el estado del contenedor, salvo que ya tenga una lectura posterior a la recogida.
+	resetSQL := `
+        UPDATE containers
+        SET current_status = $1, last_fill_level = 0, last_updated_at = $2, updated_at = NOW()
+        WHERE id = $3 AND (last_updated_at IS NULL OR last_updated_at <= $2)`
+	if _, err := tx.Exec(ctx, resetSQL, domain.StatusLow, collection.CollectedAt, collection.ContainerID); err != nil {
+		return domain.Collection{}, fmt.Errorf("error al reiniciar el estado del contenedor: %w", err)
+	}
+	return created, nil
+}
+
+func (r *postgresRepository) FindCollectionsByContainerID(ctx context.Context, id string, limit int) ([]domain.Collection, error) {
+	query := `
+        SELECT id, container_id, collected_at, collected_by, estimated_volume_liters, fill_level_before, inferred, created_at
+        FROM collections
+        WHERE container_id = $1
+        ORDER BY collected_at DESC
+        LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar las recogidas: %w", err)
+	}
+	defer rows.Close()
+
+	var collections []domain.Collection
+	for rows.Next() {
+		var c domain.Collection
+		if err := rows.Scan(&c.ID, &c.ContainerID, &c.CollectedAt, &c.CollectedBy, &c.EstimatedVolumeLiters, &c.FillLevelBefore, &c.Inferred, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear la recogida: %w", err)
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
@@ -0,0 +1,269 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/facility"
+	"smart-waste-management/internal/optimizer"
+	"time"
+)
+
+// DefaultFillThreshold es el nivel de llenado previsto (en %) a partir del cual se recoge un contenedor
+// en las rutas generadas a partir de predicciones.
+const DefaultFillThreshold = 90
+
+// DefaultOptimizationBudget es el tiempo máximo de mejora local de una ruta si no se indica otro.
+const DefaultOptimizationBudget = 2 * time.Second
+
+// DefaultAlgorithms son las fases de mejora local que se aplican si no se indican otras.
+var DefaultAlgorithms = []optimizer.Algorithm{optimizer.TwoOpt, optimizer.OrOpt}
+
+// ErrInvalidRouteOptions se devuelve cuando los parámetros de generación de la ruta no son coherentes.
+var ErrInvalidRouteOptions = errors.New("parámetros de ruta no válidos")
+
+// RouteOptions son los parámetros de generación de una ruta de recogida.
+type RouteOptions struct {
+	StartPoint domain.Point
+	// Statuses selecciona los contenedores por su estado actual.
+	Statuses []domain.Status
+	// Fraction limita la ruta a una fracción de residuo. Vacía incluye todas.
+	Fraction domain.Fraction
+	// Forecast, si se indica, selecciona además los contenedores por su llenado previsto.
+	Forecast *ForecastSelection
+	// Algorithms son las fases de mejora local aplicadas sobre la ruta del vecino más cercano.
+	// Si es nil se usan DefaultAlgorithms; vacío deja la ruta del vecino más cercano.
+	Algorithms []optimizer.Algorithm
+	// TimeBudget limita la mejora local (de todas las rutas). Si es 0, se usa DefaultOptimizationBudget.
+	TimeBudget time.Duration
+	// DepotID es el depósito del que salen y al que vuelven los camiones. Si se indica, sustituye a StartPoint.
+	DepotID string
+	// Fleet, si se indica, reparte las paradas entre varios camiones con capacidad limitada.
+	// Sin flota, se genera una única ruta desde el depósito o desde StartPoint.
+	Fleet *Fleet
+	// Shift es el turno de los camiones. Sin turno, las rutas empiezan ahora y no tienen hora límite.
+	Shift *Shift
+	// ServiceTime es el tiempo de recogida de cada contenedor. Si es 0, se usa DefaultServiceTime.
+	ServiceTime time.Duration
+	// AverageSpeedKmh es la velocidad media de los camiones. Si es 0, se usa DefaultAverageSpeedKmh.
+	AverageSpeedKmh float64
+	// Location es la zona horaria de las franjas horarias de los contenedores. Si es nil, se usa DefaultTimeZone.
+	Location *time.Location
+	// DryRun calcula las rutas sin guardarlas.
+	DryRun bool
+}
+
+// DefaultTimeZone es la zona horaria en la que se interpretan las franjas horarias si no se indica otra.
+const DefaultTimeZone = "Europe/Madrid"
+
+// Shift es el turno de trabajo de los camiones: todos salen a Start y deben terminar antes de End.
+type Shift struct {
+	Start time.Time
+	End   time.Time
+}
+
+// validateSchedule comprueba los parámetros de horario de la ruta.
+func (o RouteOptions) validateSchedule() error {
+	if o.Shift != nil && !o.Shift.End.After(o.Shift.Start) {
+		return fmt.Errorf("%w: el fin de turno debe ser posterior a su inicio", ErrInvalidRouteOptions)
+	}
+	if o.ServiceTime < 0 || o.AverageSpeedKmh < 0 {
+		return fmt.Errorf("%w: el tiempo de servicio y la velocidad media no pueden ser negativos", ErrInvalidRouteOptions)
+	}
+	return nil
+}
+
+// clock devuelve los parámetros de horario efectivos de la planificación.
+func (o RouteOptions) clock() clock {
+	c := clock{
+		start:    time.Now(),
+		speedKmh: o.AverageSpeedKmh,
+		service:  o.ServiceTime,
+		loc:      o.Location,
+	}
+	if o.Shift != nil {
+		c.start, c.end = o.Shift.Start, o.Shift.End
+	}
+	if c.speedKmh == 0 {
+		c.speedKmh = DefaultAverageSpeedKmh
+	}
+	if c.service == 0 {
+		c.service = DefaultServiceTime
+	}
+	if c.loc == nil {
+		c.loc = time.UTC
+		if loc, err := time.LoadLocation(DefaultTimeZone); err == nil {
+			c.loc = loc
+		}
+	}
+	return c
+}
+
+// Fleet describe los camiones disponibles para la recogida. Todos tienen la misma capacidad.
+type Fleet struct {
+	Vehicles int
+	// Capacidad de carga de cada camión en litros y en kilos. Cero significa sin límite en esa
+	// dimensión, pero al menos una de las dos es obligatoria.
+	CapacityLiters float64
+	CapacityKg     float64
+}
+
+// validate comprueba que la flota tiene camiones y una capacidad con la que repartir las paradas.
+func (f Fleet) validate() error {
+	if f.Vehicles <= 0 {
+		return fmt.Errorf("%w: la flota debe tener al menos un camión", ErrInvalidRouteOptions)
+	}
+	if f.CapacityLiters < 0 || f.CapacityKg < 0 {
+		return fmt.Errorf("%w: la capacidad de los camiones no puede ser negativa", ErrInvalidRouteOptions)
+	}
+	if f.CapacityLiters == 0 && f.CapacityKg == 0 {
+		return fmt.Errorf("%w: hay que indicar la capacidad de los camiones en litros o en kilos", ErrInvalidRouteOptions)
+	}
+	return nil
+}
+
+// capacity devuelve la capacidad en [litros, kilos]; las dimensiones sin límite son infinitas.
+func (f Fleet) capacity() []float64 {
+	capacity := []float64{f.CapacityLiters, f.CapacityKg}
+	for d, c := range capacity {
+		if c == 0 {
+			capacity[d] = math.Inf(1)
+		}
+	}
+	return capacity
+}
+
+// ForecastSelection selecciona los contenedores que se prevé que superen FillThreshold
+// a la hora de la visita o antes de la siguiente ruta (p. ej. "todo lo que pasará del 90%
+// antes de la ruta del próximo martes").
+type ForecastSelection struct {
+	// VisitAt es la hora prevista de la visita. Si es cero, se usa el inicio del turno o, sin turno, la hora actual.
+	VisitAt time.Time
+	// NextRunAt es la hora de la siguiente ruta, es decir, el horizonte de planificación.
+	NextRunAt time.Time
+	// FillThreshold es el nivel previsto (en %) a partir del cual se recoge. Si es 0, se usa DefaultFillThreshold.
+	FillThreshold int
+}
+
+// normalize completa los valores por defecto y valida la selección.
+func (f *ForecastSelection) normalize(now time.Time) error {
+	if f.VisitAt.IsZero() {
+		f.VisitAt = now
+	}
+	if f.FillThreshold == 0 {
+		f.FillThreshold = DefaultFillThreshold
+	}
+	if f.FillThreshold < 0 || f.FillThreshold > 100 {
+		return fmt.Errorf("%w: el umbral de llenado debe estar entre 1 y 100", ErrInvalidRouteOptions)
+	}
+	if !f.NextRunAt.After(f.VisitAt) {
+		return fmt.Errorf("%w: la siguiente ruta debe ser posterior a la visita", ErrInvalidRouteOptions)
+	}
+	return nil
+}
+
+// selectStops elige los contenedores que debe visitar la ruta y el motivo de cada uno.
+func (s *service) selectStops(ctx context.Context, opts RouteOptions) ([]domain.RouteStop, error) {
+	// Sin predicción, la selección es solo por estado actual.
+	if opts.Forecast == nil {
+		containers, err := s.repo.FindContainersByStatus(ctx, opts.Statuses, opts.Fraction)
+		if err != nil {
+			return nil, err
+		}
+		stops := make([]domain.RouteStop, len(containers))
+		for i, c := range containers {
+			stops[i] = domain.RouteStop{Kind: domain.StopContainer, Container: &c, Reason: domain.ReasonStatus, ReasonDetail: "seleccionado por estado actual"}
+		}
+		return stops, nil
+	}
+
+	// Con predicción, evaluamos todos los contenedores de la fracción con su modelo de llenado.
+	sel := *opts.Forecast
+	now := time.Now()
+	if opts.Shift != nil {
+		now = opts.Shift.Start
+	}
+	if err := sel.normalize(now); err != nil {
+		return nil, err
+	}
+
+	containers, err := s.repo.FindAllContainers(ctx, ContainerFilter{Fraction: opts.Fraction})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(containers))
+	for i, c := range containers {
+		ids[i] = c.ID
+	}
+	models, err := s.forecaster.Models(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudieron obtener los modelos de llenado: %w", err)
+	}
+
+	threshold := float64(sel.FillThreshold)
+	var stops []domain.RouteStop
+	for _, c := range containers {
+		stop := domain.RouteStop{Kind: domain.StopContainer, Container: &c}
+
+		m, hasModel := models[c.ID]
+		if hasModel {
+			from := c.LastUpdatedAt
+			if from.IsZero() {
+				from = sel.VisitAt
+			}
+			atVisit, _, _ := m.LevelAt(c.LastFillLevel, from, sel.VisitAt)
+			atNextRun, _, _ := m.LevelAt(c.LastFillLevel, from, sel.NextRunAt)
+			visitLevel, nextRunLevel := int(math.Round(atVisit)), int(math.Round(atNextRun))
+			stop.PredictedFillAtVisit = &visitLevel
+			stop.PredictedFillAtNextRun = &nextRunLevel
+		}
+
+		switch {
+		case slices.Contains(opts.Statuses, c.CurrentStatus):
+			stop.Reason = domain.ReasonStatus
+			stop.ReasonDetail = fmt.Sprintf("estado actual '%s'", c.CurrentStatus)
+		case hasModel && float64(*stop.PredictedFillAtVisit) >= threshold:
+			stop.Reason = domain.ReasonPredictedAtVisit
+			stop.ReasonDetail = fmt.Sprintf("llenado previsto del %d%% a la hora de la visita (umbral %d%%)", *stop.PredictedFillAtVisit, sel.FillThreshold)
+		case hasModel && float64(*stop.PredictedFillAtNextRun) >= threshold:
+			stop.Reason = domain.ReasonPredictedBeforeNextRun
+			stop.ReasonDetail = fmt.Sprintf("llenado previsto del %d%% antes de la siguiente ruta del %s (umbral %d%%)",
+				*stop.PredictedFillAtNextRun, sel.NextRunAt.UTC().Format(time.RFC3339), sel.FillThreshold)
+		case !hasModel && float64(c.LastFillLevel) >= threshold:
+			stop.Reason = domain.ReasonCurrentLevel
+			stop.ReasonDetail = fmt.Sprintf("sin historial suficiente para predecir; nivel actual del %d%% (umbral %d%%)", c.LastFillLevel, sel.FillThreshold)
+		default:
+			continue
+		}
+		stops = append(stops, stop)
+	}
+	return stops, nil
+}
+
+// routeSites resuelve el depósito de la ruta y los puntos de descarga que admiten su fracción.
+func (s *service) routeSites(ctx context.Context, opts RouteOptions) (routeSites, error) {
+	var sites routeSites
+	if opts.DepotID != "" {
+		depot, err := s.facilities.GetFacilityByID(ctx, opts.DepotID)
+		if err != nil {
+			if errors.Is(err, facility.ErrFacilityNotFound) {
+				return routeSites{}, fmt.Errorf("%w: el depósito %s no existe", ErrInvalidRouteOptions, opts.DepotID)
+			}
+			return routeSites{}, fmt.Errorf("no se pudo obtener el depósito: %w", err)
+		}
+		if depot.Kind != domain.FacilityDepot {
+			return routeSites{}, fmt.Errorf("%w: la instalación %s no es un depósito", ErrInvalidRouteOptions, opts.DepotID)
+		}
+		sites.depot = &depot
+	}
+
+	disposals, err := s.facilities.GetDisposalFacilities(ctx, opts.Fraction)
+	if err != nil {
+		return routeSites{}, fmt.Errorf("no se pudieron obtener los puntos de descarga: %w", err)
+	}
+	sites.disposals = disposals
+	return sites, nil
+}
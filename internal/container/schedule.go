@@ -0,0 +1,229 @@
+package container
+
+import (
+	"math"
+	"time"
+)
+
+// maxWaitAtStop es la espera máxima de un camión ante un contenedor hasta que se abre su franja
+// horaria. Si hay que esperar más, la parada se retira y se intenta colocar en otro punto de las
+// rutas; al recolocarla se admite cualquier espera que quepa en el turno.
+const maxWaitAtStop = 15 * time.Minute
+
+// Motivos por los que una parada queda fuera de las rutas al ajustar el horario.
+const (
+	reasonOutsideWindow = "no se puede recoger dentro de sus franjas horarias en ningún punto de las rutas"
+	reasonShiftEnd      = "no cabe en el turno: la ruta terminaría después del fin de turno"
+)
+
+// clock son los parámetros de horario de la planificación.
+type clock struct {
+	start time.Time
+	// end es el fin de turno; cero significa sin límite.
+	end      time.Time
+	speedKmh float64
+	service  time.Duration
+	loc      *time.Location
+}
+
+// travel devuelve el tiempo de conducción de 'km' kilómetros.
+func (c clock) travel(km float64) time.Duration {
+	return time.Duration(km / c.speedKmh * float64(time.Hour))
+}
+
+// timing es el horario de una secuencia de nodos: la llegada, el inicio del servicio (tras esperar
+// a que se abra la franja) y la salida de cada posición, y la primera posición que incumple el
+// horario (-1 si ninguna).
+type timing struct {
+	arrival      []time.Time
+	serviceStart []time.Time
+	departure    []time.Time
+	end          time.Time
+	violation    int
+	reason       string
+}
+
+// simulate recorre la secuencia desde el inicio del turno y calcula su horario. Una espera ante
+// una franja horaria mayor que 'maxWait' cuenta como incumplimiento; cero significa sin límite.
+func (n *network) simulate(seq []int, maxWait time.Duration) timing {
+	t := timing{
+		arrival:      make([]time.Time, len(seq)),
+		serviceStart: make([]time.Time, len(seq)),
+		departure:    make([]time.Time, len(seq)),
+		violation:    -1,
+	}
+	fail := func(k int, reason string) {
+		if t.violation < 0 {
+			t.violation, t.reason = k, reason
+		}
+	}
+
+	now := n.clock.start
+	t.arrival[0], t.serviceStart[0], t.departure[0] = now, now, now
+	for k := 1; k < len(seq); k++ {
+		now = now.Add(n.clock.travel(n.matrix[seq[k-1]][seq[k]]))
+		t.arrival[k] = now
+		t.serviceStart[k] = now
+
+		switch node := seq[k]; {
+		case node == 0:
+			// Regreso al depósito.
+		case n.isDisposal(node):
+			now = now.Add(UnloadTime)
+		default:
+			start, ok := n.stopOf(node).TimeWindows.EarliestStart(now, n.clock.service, n.clock.loc)
+			if !ok || (maxWait > 0 && start.Sub(now) > maxWait) {
+				fail(k, reasonOutsideWindow)
+				start = now
+			}
+			t.serviceStart[k] = start
+			now = start.Add(n.clock.service)
+		}
+
+		t.departure[k] = now
+		if !n.clock.end.IsZero() && now.After(n.clock.end) {
+			fail(k, reasonShiftEnd)
+		}
+	}
+	t.end = now
+	return t
+}
+
+// schedule ajusta las rutas al horario. Mientras una ruta incumpla una franja horaria o el fin de
+// turno, retira la parada afectada (o, si falla una descarga o el regreso, la última parada
+// anterior). Después intenta colocar cada parada retirada en la posición más barata de cualquier
+// ruta en la que se cumplan el horario y la capacidad. Devuelve las que no caben en ninguna.
+func (n *network) schedule(routes []*vehicleRoute) []unassignedStop {
+	var removed []unassignedStop
+	for _, vr := range routes {
+		for {
+			t := n.simulate(vr.seq, maxWaitAtStop)
+			if t.violation < 0 {
+				break
+			}
+			k := t.violation
+			for k > 0 && !n.isStop(vr.seq[k]) {
+				k--
+			}
+			if k == 0 {
+				break // No quedan paradas que retirar: la ruta se descarta al construir el plan.
+			}
+			removed = append(removed, unassignedStop{node: vr.seq[k], reason: t.reason})
+			vr.seq = n.removeAt(vr.seq, k)
+		}
+	}
+
+	var unassigned []unassignedStop
+	for _, u := range removed {
+		if !n.reinsert(routes, u.node) {
+			unassigned = append(unassigned, u)
+		}
+	}
+	return unassigned
+}
+
+// reinsert coloca la parada 'node' en la posición que menos retrasa el fin de su ruta (y, a
+// igualdad, que menos la alarga) de entre las que respetan el horario y la capacidad. Así las
+// paradas cuya franja abre más tarde se dejan para el final en lugar de esperar a mitad de ruta.
+// Devuelve false si no cabe en ninguna.
+func (n *network) reinsert(routes []*vehicleRoute, node int) bool {
+	var best *vehicleRoute
+	bestK, bestDelay, bestDelta := -1, time.Duration(math.MaxInt64), math.Inf(1)
+	for _, vr := range routes {
+		if !n.hasStops(vr.seq) {
+			continue
+		}
+		end := n.simulate(vr.seq, 0).end
+		for k := 1; k <= len(vr.seq); k++ {
+			if !n.canInsertAt(vr.seq, k, node) {
+				continue
+			}
+			t := n.simulate(insertAt(vr.seq, k, node), 0)
+			if t.violation >= 0 {
+				continue
+			}
+			delta := n.matrix[vr.seq[k-1]][node]
+			if k < len(vr.seq) {
+				delta += n.matrix[node][vr.seq[k]] - n.matrix[vr.seq[k-1]][vr.seq[k]]
+			}
+			delay := t.end.Sub(end)
+			if delay > bestDelay || (delay == bestDelay && delta >= bestDelta) {
+				continue
+			}
+			best, bestK, bestDelay, bestDelta = vr, k, delay, delta
+		}
+	}
+	if best == nil {
+		return false
+	}
+	best.seq = insertAt(best.seq, bestK, node)
+	return true
+}
+
+// canInsertAt indica si la parada 'node' se puede insertar antes de la posición k de la secuencia
+// sin dejar un viaje sin descarga (si hay puntos de descarga) ni superar la capacidad del viaje.
+func (n *network) canInsertAt(seq []int, k, node int) bool {
+	hasDisposals := len(n.sites.disposals) > 0
+
+	// Solo se puede añadir al final de una ruta abierta sin puntos de descarga.
+	if k == len(seq) && (n.closed || hasDisposals) {
+		return false
+	}
+
+	// El viaje en el que cae la parada va desde la frontera anterior (origen o descarga) hasta la
+	// siguiente. Si hay puntos de descarga, el viaje tiene que terminar en uno.
+	right := k
+	for right < len(seq) && n.isStop(seq[right]) {
+		right++
+	}
+	if hasDisposals && (right == len(seq) || seq[right] == 0) {
+		return false
+	}
+	left := k - 1
+	for left > 0 && n.isStop(seq[left]) {
+		left--
+	}
+
+	stop := n.stopOf(node)
+	liters, kg := stop.EstimatedLoadLiters, stop.EstimatedLoadKg
+	for _, other := range seq[left+1 : right] {
+		s := n.stopOf(other)
+		liters += s.EstimatedLoadLiters
+		kg += s.EstimatedLoadKg
+	}
+	return liters <= n.capacity[0] && kg <= n.capacity[1]
+}
+
+// removeAt quita la posición k de la secuencia y elimina las descargas que se quedan sin paradas.
+func (n *network) removeAt(seq []int, k int) []int {
+	seq = append(append([]int(nil), seq[:k]...), seq[k+1:]...)
+	cleaned := seq[:1]
+	for _, node := range seq[1:] {
+		prev := cleaned[len(cleaned)-1]
+		if n.isDisposal(node) && !n.isStop(prev) {
+			continue
+		}
+		cleaned = append(cleaned, node)
+	}
+	return cleaned
+}
+
+// insertAt devuelve una copia de la secuencia con 'node' insertado antes de la posición k.
+func insertAt(seq []int, k, node int) []int {
+	result := make([]int, 0, len(seq)+1)
+	result = append(result, seq[:k]...)
+	result = append(result, node)
+	return append(result, seq[k:]...)
+}
+
+func (n *network) isStop(node int) bool { return node > len(n.sites.disposals) }
+
+// hasStops indica si la secuencia recoge algún contenedor.
+func (n *network) hasStops(seq []int) bool {
+	for _, node := range seq {
+		if n.isStop(node) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package container
+
+import (
+	"fmt"
+	"math"
+	"smart-waste-management/internal/domain"
+	"testing"
+	"time"
+)
+
+// window devuelve la franja horaria [start, end) con horas en punto.
+func window(start, end int) domain.TimeWindows {
+	return domain.TimeWindows{{Start: domain.TimeOfDay(start * 60), End: domain.TimeOfDay(end * 60)}}
+}
+
+func TestSchedule(t *testing.T) {
+	tests := []struct {
+		name           string
+		windows        map[int]domain.TimeWindows // Franjas de cada parada, por índice en 'stops'.
+		shiftEnd       time.Duration
+		wantSeq        []int
+		wantUnassigned []unassignedStop
+	}{
+		{
+			name:    "paradas sin franjas",
+			wantSeq: []int{0, 1, 2, 0},
+		},
+		{
+			name:    "franja que abre tarde: la parada se recoloca al final de la ruta",
+			windows: map[int]domain.TimeWindows{0: window(7, 8)},
+			wantSeq: []int{0, 2, 1, 0},
+		},
+		{
+			name:           "franja imposible dentro del turno: la parada queda sin asignar",
+			windows:        map[int]domain.TimeWindows{1: window(0, 1)},
+			shiftEnd:       8 * time.Hour,
+			wantSeq:        []int{0, 1, 0},
+			wantUnassigned: []unassignedStop{{node: 2, reason: reasonOutsideWindow}},
+		},
+		{
+			name:           "la ruta terminaría después del fin de turno",
+			shiftEnd:       10 * time.Minute,
+			wantSeq:        []int{0, 1, 0},
+			wantUnassigned: []unassignedStop{{node: 2, reason: reasonShiftEnd}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := lineNetwork(nil, []float64{1, 2}, 0, []float64{math.Inf(1), math.Inf(1)})
+			for i, ws := range tt.windows {
+				n.stops[i].TimeWindows = ws
+			}
+			if tt.shiftEnd > 0 {
+				n.clock.end = shiftStart.Add(tt.shiftEnd)
+			}
+			vr := &vehicleRoute{seq: []int{0, 1, 2, 0}}
+
+			unassigned := n.schedule([]*vehicleRoute{vr})
+
+			if fmt.Sprint(vr.seq) != fmt.Sprint(tt.wantSeq) {
+				t.Errorf("secuencia = %v, se esperaba %v", vr.seq, tt.wantSeq)
+			}
+			if fmt.Sprint(unassigned) != fmt.Sprint(tt.wantUnassigned) {
+				t.Errorf("sin asignar = %v, se esperaba %v", unassigned, tt.wantUnassigned)
+			}
+		})
+	}
+}
+
+func TestCanInsertAt(t *testing.T) {
+	// Descarga en el nodo 1 y paradas de 400 litros en los nodos 2 a 5, con camiones de 1000 litros.
+	withDisposal := lineNetwork([]float64{5}, []float64{1, 2, 3, 4}, 400, []float64{1000, 1000})
+	// Ruta abierta sin descargas, con paradas en los nodos 1 a 3.
+	open := lineNetwork(nil, []float64{1, 2, 3}, 400, []float64{1000, 1000})
+	open.closed = false
+
+	tests := []struct {
+		name string
+		n    *network
+		seq  []int
+		k    int
+		want bool
+	}{
+		{name: "viaje lleno", n: withDisposal, seq: []int{0, 2, 3, 1, 4, 1, 0}, k: 1, want: false},
+		{name: "antes de una parada de un viaje con hueco", n: withDisposal, seq: []int{0, 2, 3, 1, 4, 1, 0}, k: 4, want: true},
+		{name: "antes de la descarga de un viaje con hueco", n: withDisposal, seq: []int{0, 2, 3, 1, 4, 1, 0}, k: 5, want: true},
+		{name: "tras la última descarga, sin descargar después", n: withDisposal, seq: []int{0, 2, 3, 1, 4, 1, 0}, k: 6, want: false},
+		{name: "al final de una ruta cerrada", n: withDisposal, seq: []int{0, 2, 3, 1, 4, 1, 0}, k: 7, want: false},
+		{name: "al final de una ruta abierta sin descargas", n: open, seq: []int{0, 1}, k: 2, want: true},
+		{name: "ruta abierta que supera la capacidad", n: open, seq: []int{0, 1, 2}, k: 3, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := len(tt.n.matrix) - 1
+			if got := tt.n.canInsertAt(tt.seq, tt.k, node); got != tt.want {
+				t.Errorf("canInsertAt(%v, %d, %d) = %v, se esperaba %v", tt.seq, tt.k, node, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveAt(t *testing.T) {
+	// Descarga en el nodo 1 y paradas en los nodos 2 a 4.
+	n := lineNetwork([]float64{5}, []float64{1, 2, 3}, 0, nil)
+
+	tests := []struct {
+		name string
+		seq  []int
+		k    int
+		want []int
+	}{
+		{name: "parada de un viaje con más paradas", seq: []int{0, 2, 3, 1, 0}, k: 1, want: []int{0, 3, 1, 0}},
+		{name: "única parada del primer viaje", seq: []int{0, 2, 1, 3, 1, 0}, k: 1, want: []int{0, 3, 1, 0}},
+		{name: "única parada del último viaje", seq: []int{0, 2, 1, 3, 1, 0}, k: 3, want: []int{0, 2, 1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.removeAt(tt.seq, tt.k); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("removeAt(%v, %d) = %v, se esperaba %v", tt.seq, tt.k, got, tt.want)
+			}
+		})
+	}
+}
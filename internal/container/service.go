@@ -2,138 +2,459 @@ package container
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math"
+	"regexp"
 	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/forecast"
+	"smart-waste-management/internal/optimizer"
+	"strings"
+	"time"
 )
 
+// MaxBatchSize es el número máximo de lecturas aceptadas en una única petición de ingesta por lotes.
+const MaxBatchSize = 10000
+
+// uuidPattern valida el formato de los IDs de contenedor antes de consultarlos en la BBDD.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// BatchItemResult describe el resultado del procesamiento de una lectura dentro de un lote.
+type BatchItemResult struct {
+	Index       int    `json:"index"`
+	ContainerID string `json:"container_id"`
+	Accepted    bool   `json:"accepted"`
+	// Outcome indica, para las lecturas aceptadas, si se aplicaron, se guardaron solo como historial o se descartaron por duplicadas.
+	Outcome domain.ReadingOutcome `json:"outcome,omitempty"`
+	Error   string                `json:"error,omitempty"`
+}
+
+// ContainerFilter restringe los contenedores devueltos por los listados. Los campos vacíos no filtran.
+type ContainerFilter struct {
+	Fraction domain.Fraction
+	// BBox limita los contenedores a los que están dentro del rectángulo.
+	BBox *domain.BoundingBox
+	// Near y RadiusMeters limitan los contenedores a los que están a menos de RadiusMeters metros
+	// de Near. Con Near, por defecto se ordenan del más cercano al más lejano.
+	Near         *domain.Point
+	RadiusMeters float64
+	// Statuses limita los contenedores a los que están en alguno de los estados.
+	Statuses []domain.Status
+	// Rangos de nivel de llenado (0-100) y de capacidad en litros, ambos extremos incluidos.
+	MinFillLevel *int
+	MaxFillLevel *int
+	MinCapacity  *int
+	MaxCapacity  *int
+	// UpdatedBefore limita los contenedores a los que no informan desde esa fecha (sensores sin
+	// actividad).
+	UpdatedBefore *time.Time
+}
+
+// Forecaster estima cuándo se llenarán los contenedores a partir de su historial. Lo implementa forecast.Service.
+type Forecaster interface {
+	ForecastContainer(ctx context.Context, c domain.Container) (domain.Forecast, error)
+	ForecastContainers(ctx context.Context, containers []domain.Container) (map[string]domain.Forecast, error)
+	// Models devuelve los modelos de llenado de los contenedores que tienen historial suficiente.
+	Models(ctx context.Context, ids []string) (map[string]*forecast.Model, error)
+}
+
+// FacilitySource da acceso a los depósitos y puntos de descarga que usan las rutas. Lo implementa facility.Service.
+type FacilitySource interface {
+	GetFacilityByID(ctx context.Context, id string) (domain.Facility, error)
+	// GetDisposalFacilities devuelve los puntos de descarga que admiten la fracción.
+	GetDisposalFacilities(ctx context.Context, fraction domain.Fraction) ([]domain.Facility, error)
+}
+
+// RouteStore guarda las rutas generadas para su ejecución. Lo implementa dispatch.Repository.
+type RouteStore interface {
+	// SaveRoutes guarda las rutas y devuelve sus IDs, en el mismo orden.
+	SaveRoutes(ctx context.Context, fraction domain.Fraction, routes []domain.Route) ([]string, error)
+}
+
+// EventPublisher notifica los cambios de estado de los contenedores a quien esté suscrito. Lo
+// implementa stream.Hub. Publish no debe bloquear la ingesta.
+type EventPublisher interface {
+	Publish(update domain.ContainerUpdate)
+}
+
 // Service define la interfaz para la lógica de negocio relacionada con los contenedores.
 // Esta abstracción permite que los handlers dependan de la interfaz, no de la implementación concreta.
 type Service interface {
-	// ProcessNewReading valida y procesa una nueva lectura de un sensor.
-	ProcessNewReading(ctx context.Context, reading domain.Reading) error
-	// GetAllContainers obtiene todos los contenedores para su visualización.
-	GetAllContainers(ctx context.Context) ([]domain.Container, error)
-	// GenerateRoute crea una ruta de recogida optimizada.
-	GenerateRoute(ctx context.Context, startPoint domain.Point, statuses []domain.Status) ([]domain.Container, error)
+	// ProcessNewReading valida y procesa una nueva lectura de un sensor de forma síncrona,
+	// indicando si se aplicó, se guardó solo como historial o se descartó por duplicada.
+	ProcessNewReading(ctx context.Context, reading domain.Reading) (domain.ReadingOutcome, error)
+	// EnqueueReading valida una lectura, comprueba que el contenedor existe y la deja en la cola de
+	// ingesta para procesarla en segundo plano.
+	// Devuelve ErrQueueFull si el buffer está lleno.
+	EnqueueReading(ctx context.Context, reading domain.Reading) error
+	// IngestStats devuelve las métricas de la cola de ingesta asíncrona.
+	IngestStats() IngestStats
+	// Shutdown deja de aceptar lecturas y espera a que se procesen las ya aceptadas.
+	Shutdown(ctx context.Context) error
+	// ProcessReadingsBatch valida y persiste un lote de lecturas, informando del resultado de cada una.
+	ProcessReadingsBatch(ctx context.Context, readings []domain.Reading) ([]BatchItemResult, error)
+	// ListContainers obtiene una página de los contenedores que cumplen el filtro para su
+	// visualización. Devuelve ErrInvalidPage si el orden, el tamaño o el cursor no son válidos.
+	ListContainers(ctx context.Context, filter ContainerFilter, page PageRequest) (ContainerPage, error)
+	// GenerateRoute crea una ruta de recogida optimizada. Si se indica una fracción,
+	// solo incluye contenedores de esa fracción, ya que cada camión recoge una única fracción.
+	// Cada parada indica por qué se ha incluido (estado actual o llenado previsto).
+	// La respuesta incluye la longitud de la ruta antes y después de la mejora local. Las distancias
+	// son por carretera si hay una red viaria cargada y, si no, en línea recta.
+	// Con una flota, las paradas se reparten entre los camiones según su carga estimada y se
+	// devuelve una ruta por camión. Las rutas salen del depósito indicado y vuelven a él, y
+	// descargan en el punto de descarga más cercano que admite la fracción.
+	// Cada parada lleva su hora de llegada prevista según el turno, la velocidad media y el tiempo
+	// de servicio; las que no caben en sus franjas horarias o en el turno se devuelven sin asignar.
+	// Salvo en modo de prueba (DryRun), las rutas se guardan para su ejecución y llevan su ID.
+	GenerateRoute(ctx context.Context, opts RouteOptions) (domain.RoutePlan, error)
 
 	CreateContainer(ctx context.Context, container domain.Container) (domain.Container, error)
 	GetContainerByID(ctx context.Context, id string) (domain.Container, error)
 	UpdateContainer(ctx context.Context, container domain.Container) error
 	DeleteContainer(ctx context.Context, id string) error
-	GetReadingsForContainer(ctx context.Context, id string, limit int) ([]domain.Reading, error)
+	// GetReadings devuelve una página del historial de lecturas del contenedor, de la más reciente
+	// a la más antigua. Devuelve ErrInvalidHistoryQuery o ErrInvalidPage si la consulta no es válida.
+	GetReadings(ctx context.Context, id string, q HistoryQuery) (ReadingPage, error)
+	// GetReadingBuckets devuelve el historial de lecturas agregado en intervalos de q.Bucket, con el
+	// nivel de llenado mínimo, máximo, medio y último de cada intervalo, del más reciente al más antiguo.
+	GetReadingBuckets(ctx context.Context, id string, q HistoryQuery) (ReadingBucketPage, error)
+	// GetForecast predice cuándo se llenará el contenedor.
+	GetForecast(ctx context.Context, id string) (domain.Forecast, error)
+
+	// RecordCollection registra que un camión ha vaciado el contenedor y reinicia su estado.
+	RecordCollection(ctx context.Context, collection domain.Collection) (domain.Collection, error)
+	GetCollectionsForContainer(ctx context.Context, id string, limit int) ([]domain.Collection, error)
 }
 
+// ErrInvalidReading se devuelve cuando una lectura no supera la validación de negocio.
+var ErrInvalidReading = errors.New("la lectura proporcionada no es válida")
+
 // service es la implementación concreta de la interfaz Service.
 type service struct {
-	repo Repository // Depende de la interfaz del Repositorio, no de su implementación.
+	repo       Repository // Depende de la interfaz del Repositorio, no de su implementación.
+	forecaster Forecaster
+	facilities FacilitySource
+	// distances calcula la matriz de distancias de las rutas (por carretera o en línea recta).
+	distances optimizer.DistanceProvider
+	routes    RouteStore
+	// events recibe los cambios de estado de los contenedores; puede ser nil.
+	events EventPublisher
+	ingest *ingestQueue
 }
 
 // NewService crea una nueva instancia del servicio.
-// Recibe el repositorio como una dependencia (Inyección de Dependencias).
-func NewService(repo Repository) Service {
-	return &service{
-		repo: repo,
+// Recibe el repositorio como una dependencia (Inyección de Dependencias) y arranca
+// los workers de la cola de ingesta asíncrona según la configuración indicada.
+func NewService(repo Repository, forecaster Forecaster, facilities FacilitySource, distances optimizer.DistanceProvider, routes RouteStore, events EventPublisher, ingestCfg IngestConfig) Service {
+	s := &service{
+		repo:       repo,
+		forecaster: forecaster,
+		facilities: facilities,
+		distances:  distances,
+		routes:     routes,
+		events:     events,
 	}
+	s.ingest = newIngestQueue(ingestCfg, s.ProcessNewReading)
+	return s
 }
 
 // ProcessNewReading contiene la lógica de negocio para procesar una nueva lectura.
-func (s *service) ProcessNewReading(ctx context.Context, reading domain.Reading) error {
+func (s *service) ProcessNewReading(ctx context.Context, reading domain.Reading) (domain.ReadingOutcome, error) {
 	// 1. Validación de negocio.
 	// La capa de servicio es el lugar ideal para este tipo de reglas.
 	if !reading.IsValid() {
-		return fmt.Errorf("la lectura proporcionada no es válida: %+v", reading)
+		return "", fmt.Errorf("%w: %+v", ErrInvalidReading, reading)
+	}
+	if !uuidPattern.MatchString(reading.ContainerID) {
+		return "", fmt.Errorf("%w: ID de contenedor con formato inválido", ErrInvalidReading)
 	}
 
-	// 2. Aquí se podrían añadir más lógicas de negocio complejas.
-	// Por ejemplo:
-	// - Comprobar si el `container_id` existe antes de intentar guardar (aunque la FK de la BBDD ya lo hace).
-	// - Enviar una notificación (ej. un evento a Kafka o RabbitMQ) si el `fill_level` supera el 95%.
-	// - Comprobar si la lectura es más antigua que la última registrada y, en ese caso, ignorarla.
+	// 2. Aquí se podrían añadir más lógicas de negocio complejas. Si el contenedor no existe, la
+	// FK de la BBDD rechaza la lectura y el repositorio devuelve ErrContainerNotFound.
 
 	fmt.Printf("Procesando nueva lectura para el contenedor %s con nivel %d%%\n", reading.ContainerID, reading.FillLevel)
 
 	// 3. Delegar la persistencia al repositorio.
-	// El servicio no sabe cómo se guarda, solo que debe guardarse.
-	err := s.repo.SaveReading(ctx, reading)
+	// El servicio no sabe cómo se guarda, solo que debe guardarse. El repositorio se encarga de
+	// descartar duplicados, de no retroceder el estado con lecturas que llegan fuera de orden y de
+	// registrar una recogida inferida si el nivel cae bruscamente (ver domain.IsEmptyingDrop).
+	outcome, update, err := s.repo.SaveReading(ctx, reading)
 	if err != nil {
 		// Envolvemos el error del repositorio para dar más contexto.
-		return fmt.Errorf("error al guardar la lectura en el repositorio: %w", err)
+		return "", fmt.Errorf("error al guardar la lectura en el repositorio: %w", err)
+	}
+
+	// 4. Notificamos el cambio a los paneles conectados en tiempo real.
+	if update != nil {
+		s.publish(*update)
+	}
+
+	if outcome != domain.OutcomeApplied {
+		fmt.Printf("Lectura del contenedor %s (%s) no aplicada al estado actual: %s\n", reading.ContainerID, reading.Timestamp.Format(time.RFC3339), outcome)
+	}
+
+	return outcome, nil
+}
+
+// EnqueueReading valida la lectura y comprueba que el contenedor existe de forma síncrona (para
+// poder responder 400 o 404 al cliente) y delega su persistencia a los workers de la cola de ingesta.
+func (s *service) EnqueueReading(ctx context.Context, reading domain.Reading) error {
+	if !reading.IsValid() {
+		return fmt.Errorf("%w: %+v", ErrInvalidReading, reading)
+	}
+	if !uuidPattern.MatchString(reading.ContainerID) {
+		return fmt.Errorf("%w: ID de contenedor con formato inválido", ErrInvalidReading)
+	}
+	reading.ContainerID = strings.ToLower(reading.ContainerID)
+	existing, err := s.repo.FindExistingContainerIDs(ctx, []string{reading.ContainerID})
+	if err != nil {
+		return fmt.Errorf("error al comprobar el contenedor de la lectura: %w", err)
+	}
+	if !existing[reading.ContainerID] {
+		return ErrContainerNotFound
 	}
+	return s.ingest.enqueue(reading)
+}
+
+// IngestStats devuelve una instantánea de las métricas de la cola de ingesta.
+func (s *service) IngestStats() IngestStats {
+	return s.ingest.stats()
+}
 
-	return nil
+// Shutdown vacía la cola de ingesta. Debe llamarse después de detener el servidor HTTP.
+func (s *service) Shutdown(ctx context.Context) error {
+	return s.ingest.shutdown(ctx)
 }
 
-// GetAllContainers simplemente delega la llamada al repositorio.
-// En un caso más complejo, podría enriquecer los datos antes de devolverlos.
-func (s *service) GetAllContainers(ctx context.Context) ([]domain.Container, error) {
-	fmt.Println("Obteniendo todos los contenedores desde el servicio.")
+// ProcessReadingsBatch procesa un lote de lecturas enviado por un gateway.
+// Las lecturas inválidas o de contenedores inexistentes se rechazan individualmente;
+// las válidas se persisten juntas con una única operación del repositorio.
+func (s *service) ProcessReadingsBatch(ctx context.Context, readings []domain.Reading) ([]BatchItemResult, error) {
+	results := make([]BatchItemResult, len(readings))
+
+	// 1. Validación individual de cada lectura.
+	var candidateIDs []string
+	seen := make(map[string]bool)
+	for i := range readings {
+		reading := &readings[i]
+		reading.ContainerID = strings.ToLower(reading.ContainerID)
+		results[i] = BatchItemResult{Index: i, ContainerID: reading.ContainerID}
+
+		if !reading.IsValid() {
+			results[i].Error = "lectura inválida: nivel fuera de 0-100, sin fecha o con fecha futura"
+			continue
+		}
+		if !uuidPattern.MatchString(reading.ContainerID) {
+			results[i].Error = "ID de contenedor con formato inválido"
+			continue
+		}
+		results[i].Accepted = true
+		if !seen[reading.ContainerID] {
+			seen[reading.ContainerID] = true
+			candidateIDs = append(candidateIDs, reading.ContainerID)
+		}
+	}
 
-	containers, err := s.repo.FindAllContainers(ctx)
+	// 2. Comprobamos en una sola consulta qué contenedores existen, para que una lectura
+	// de un contenedor desconocido no haga fallar la inserción de todo el lote.
+	existing, err := s.repo.FindExistingContainerIDs(ctx, candidateIDs)
 	if err != nil {
-		// Envolvemos el error del repositorio.
-		return nil, fmt.Errorf("error al obtener los contenedores desde el repositorio: %w", err)
+		return nil, fmt.Errorf("error al comprobar los contenedores del lote: %w", err)
 	}
 
-	// Lógica de negocio adicional podría ir aquí.
-	// Por ejemplo, si el frontend necesita un campo extra que no está en la BBDD,
-	// se podría calcular y añadir aquí.
+	var toSave []domain.Reading
+	var toSaveIndex []int // Posición en 'results' de cada lectura de 'toSave'.
+	for i, reading := range readings {
+		if !results[i].Accepted {
+			continue
+		}
+		if !existing[reading.ContainerID] {
+			results[i].Accepted = false
+			results[i].Error = "contenedor no encontrado"
+			continue
+		}
+		toSave = append(toSave, reading)
+		toSaveIndex = append(toSaveIndex, i)
+	}
+
+	fmt.Printf("Procesando lote de %d lecturas (%d aceptadas)\n", len(readings), len(toSave))
 
-	return containers, nil
+	// 3. Persistencia en bloque.
+	outcomes, updates, err := s.repo.SaveReadings(ctx, toSave)
+	if err != nil {
+		return nil, fmt.Errorf("error al guardar el lote de lecturas en el repositorio: %w", err)
+	}
+	for j, outcome := range outcomes {
+		results[toSaveIndex[j]].Outcome = outcome
+	}
+	for _, update := range updates {
+		s.publish(update)
+	}
+
+	return results, nil
 }
 
-func (s *service) GenerateRoute(ctx context.Context, startPoint domain.Point, statuses []domain.Status) ([]domain.Container, error) {
-	// 1. Obtener todos los contenedores que cumplen con el criterio desde el repositorio.
-	containersToVisit, err := s.repo.FindContainersByStatus(ctx, statuses)
+// publish notifica el cambio de estado de un contenedor si la lectura ha cambiado su nivel de
+// llenado o su estado. No bloquea: el publicador descarta a los suscriptores que no dan abasto.
+func (s *service) publish(update domain.ContainerUpdate) {
+	if s.events != nil && update.Changed() {
+		s.events.Publish(update)
+	}
+}
+
+// ListContainers devuelve una página de los contenedores que cumplen el filtro, con el total de
+// contenedores del filtro y el cursor de la página siguiente.
+func (s *service) ListContainers(ctx context.Context, filter ContainerFilter, req PageRequest) (ContainerPage, error) {
+	page, err := newPageQuery(filter, req)
+	if err != nil {
+		return ContainerPage{}, err
+	}
+
+	// Pedimos un contenedor más de los que caben en la página para saber si hay página siguiente.
+	limit := page.Limit
+	page.Limit++
+	containers, err := s.repo.FindContainerPage(ctx, filter, page)
+	if err != nil {
+		// Envolvemos el error del repositorio.
+		return ContainerPage{}, fmt.Errorf("error al obtener los contenedores desde el repositorio: %w", err)
+	}
+	total, err := s.repo.CountContainers(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("no se pudieron obtener los contenedores para la ruta: %w", err)
+		return ContainerPage{}, fmt.Errorf("error al contar los contenedores: %w", err)
+	}
+
+	result := ContainerPage{Items: containers, Total: total}
+	if len(containers) > limit {
+		result.Items = containers[:limit]
+		next := nextCursor(filter, page, result.Items[limit-1])
+		result.NextCursor = &next
+	}
+	if result.Items == nil {
+		result.Items = []domain.Container{}
 	}
 
-	if len(containersToVisit) == 0 {
-		return []domain.Container{}, nil // No hay contenedores que visitar, devolvemos una ruta vacía.
+	// Añadimos la predicción de llenado, que no está en la BBDD sino que se calcula a partir del historial.
+	// Si falla, devolvemos igualmente los contenedores: la predicción es informativa.
+	forecasts, err := s.forecaster.ForecastContainers(ctx, result.Items)
+	if err != nil {
+		fmt.Printf("Error al calcular las predicciones de llenado: %v\n", err)
+		return result, nil
+	}
+	for i := range result.Items {
+		if f, ok := forecasts[result.Items[i].ID]; ok {
+			result.Items[i].Forecast = &f
+		}
 	}
 
-	// 2. Aplicar el algoritmo de optimización (Vecino más cercano).
-	var route []domain.Container
-	currentPoint := startPoint
+	return result, nil
+}
 
-	for len(containersToVisit) > 0 {
-		nearestIndex := -1
-		minDistance := math.MaxFloat64
+func (s *service) GenerateRoute(ctx context.Context, opts RouteOptions) (domain.RoutePlan, error) {
+	if opts.Fleet != nil {
+		if err := opts.Fleet.validate(); err != nil {
+			return domain.RoutePlan{}, err
+		}
+	}
+	if err := opts.validateSchedule(); err != nil {
+		return domain.RoutePlan{}, err
+	}
 
-		// Encontrar el contenedor más cercano al punto actual.
-		for i, container := range containersToVisit {
-			dist := haversineDistance(currentPoint, container.Location)
-			if dist < minDistance {
-				minDistance = dist
-				nearestIndex = i
-			}
+	// 1. Resolver el depósito y los puntos de descarga de la fracción.
+	sites, err := s.routeSites(ctx, opts)
+	if err != nil {
+		return domain.RoutePlan{}, err
+	}
+
+	// 2. Seleccionar los contenedores que hay que visitar, por estado actual o por llenado previsto.
+	stopsToVisit, err := s.selectStops(ctx, opts)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRouteOptions) {
+			return domain.RoutePlan{}, err
 		}
+		return domain.RoutePlan{}, fmt.Errorf("no se pudieron obtener los contenedores para la ruta: %w", err)
+	}
 
-		// Añadir el contenedor más cercano a la ruta.
-		nearestContainer := containersToVisit[nearestIndex]
-		route = append(route, nearestContainer)
+	if len(stopsToVisit) == 0 {
+		// No hay contenedores que visitar, devolvemos un plan vacío.
+		return domain.RoutePlan{Routes: []domain.Route{}, Unassigned: []domain.RouteStop{}}, nil
+	}
+	ids := make([]string, len(stopsToVisit))
+	for i := range stopsToVisit {
+		stopsToVisit[i].EstimateLoad()
+		ids[i] = stopsToVisit[i].ID
+	}
+	windows, err := s.repo.FindTimeWindows(ctx, ids)
+	if err != nil {
+		return domain.RoutePlan{}, fmt.Errorf("no se pudieron obtener las franjas horarias de los contenedores: %w", err)
+	}
+	for i := range stopsToVisit {
+		stopsToVisit[i].TimeWindows = windows[stopsToVisit[i].ID]
+	}
 
-		// Actualizar el punto actual para la siguiente iteración.
-		currentPoint = nearestContainer.Location
+	// 3. Calcular las distancias entre el origen, los puntos de descarga y las paradas.
+	n, err := newNetwork(ctx, s.distances, opts, sites, stopsToVisit)
+	if err != nil {
+		return domain.RoutePlan{}, fmt.Errorf("no se pudieron calcular las distancias de la ruta: %w", err)
+	}
 
-		// Eliminar el contenedor visitado de la lista de pendientes.
-		containersToVisit = append(containersToVisit[:nearestIndex], containersToVisit[nearestIndex+1:]...)
+	// 4. Construir las rutas y mejorarlas con búsqueda local (2-opt, Or-opt).
+	var plan domain.RoutePlan
+	if opts.Fleet != nil {
+		plan = planFleet(n, opts)
+	} else {
+		plan = planRoute(n, opts)
 	}
 
-	return route, nil
+	// 5. Guardar las rutas para su ejecución.
+	if opts.DryRun || len(plan.Routes) == 0 {
+		return plan, nil
+	}
+	routeIDs, err := s.routes.SaveRoutes(ctx, opts.Fraction, plan.Routes)
+	if err != nil {
+		return domain.RoutePlan{}, fmt.Errorf("no se pudieron guardar las rutas: %w", err)
+	}
+	for i := range plan.Routes {
+		plan.Routes[i].ID = routeIDs[i]
+	}
+	return plan, nil
 }
 
 func (s *service) CreateContainer(ctx context.Context, container domain.Container) (domain.Container, error) {
 	// Aquí podría ir la validación de negocio, por ejemplo, comprobar si la capacidad es válida.
+	if container.Fraction == "" {
+		container.Fraction = domain.DefaultFraction
+	}
 	return s.repo.CreateContainer(ctx, container)
 }
 
 func (s *service) GetContainerByID(ctx context.Context, id string) (domain.Container, error) {
-	return s.repo.FindContainerByID(ctx, id)
+	container, err := s.repo.FindContainerByID(ctx, id)
+	if err != nil {
+		return domain.Container{}, err
+	}
+
+	f, err := s.forecaster.ForecastContainer(ctx, container)
+	switch {
+	case err == nil:
+		container.Forecast = &f
+	case !errors.Is(err, forecast.ErrInsufficientHistory):
+		fmt.Printf("Error al calcular la predicción de llenado del contenedor %s: %v\n", id, err)
+	}
+	return container, nil
+}
+
+func (s *service) GetForecast(ctx context.Context, id string) (domain.Forecast, error) {
+	container, err := s.repo.FindContainerByID(ctx, id)
+	if err != nil {
+		return domain.Forecast{}, err
+	}
+	return s.forecaster.ForecastContainer(ctx, container)
 }
 
 func (s *service) UpdateContainer(ctx context.Context, container domain.Container) error {
+	if container.Fraction == "" {
+		container.Fraction = domain.DefaultFraction
+	}
 	return s.repo.UpdateContainer(ctx, container)
 }
 
@@ -141,27 +462,86 @@ func (s *service) DeleteContainer(ctx context.Context, id string) error {
 	return s.repo.DeleteContainer(ctx, id)
 }
 
-func (s *service) GetReadingsForContainer(ctx context.Context, id string, limit int) ([]domain.Reading, error) {
-	if limit <= 0 || limit > 100 { // Ponemos un límite por defecto y máximo
-		limit = 50
+// GetReadings devuelve una página del historial de lecturas del contenedor.
+func (s *service) GetReadings(ctx context.Context, id string, q HistoryQuery) (ReadingPage, error) {
+	if q.Bucket != "" {
+		return ReadingPage{}, fmt.Errorf("%w: las lecturas sin agregar no admiten 'bucket'", ErrInvalidHistoryQuery)
+	}
+	rng, err := newReadingRange(id, q, DefaultReadingsLimit)
+	if err != nil {
+		return ReadingPage{}, err
 	}
-	return s.repo.FindReadingsByContainerID(ctx, id, limit)
+
+	// Pedimos una lectura más de las que caben en la página para saber si hay página siguiente.
+	limit := rng.Limit
+	rng.Limit++
+	readings, err := s.repo.FindReadings(ctx, id, rng)
+	if err != nil {
+		return ReadingPage{}, fmt.Errorf("error al obtener las lecturas: %w", err)
+	}
+
+	page := ReadingPage{Items: readings}
+	if len(readings) > limit {
+		page.Items = readings[:limit]
+		page.NextCursor = encodeHistoryCursor("", page.Items[limit-1].Timestamp)
+	}
+	if page.Items == nil {
+		page.Items = []domain.Reading{}
+	}
+	return page, nil
 }
 
-// haversineDistance calcula la distancia en kilómetros entre dos puntos geográficos.
-// Es una función de utilidad que podemos añadir al final del fichero.
-func haversineDistance(p1, p2 domain.Point) float64 {
-	const R = 6371 // Radio de la Tierra en kilómetros
-	lat1Rad := p1.Latitude * math.Pi / 180
-	lon1Rad := p1.Longitude * math.Pi / 180
-	lat2Rad := p2.Latitude * math.Pi / 180
-	lon2Rad := p2.Longitude * math.Pi / 180
+// GetReadingBuckets devuelve una página del historial de lecturas del contenedor agregado por intervalos.
+func (s *service) GetReadingBuckets(ctx context.Context, id string, q HistoryQuery) (ReadingBucketPage, error) {
+	size, ok := BucketSizes[q.Bucket]
+	if !ok {
+		return ReadingBucketPage{}, fmt.Errorf("%w: intervalo de agregación desconocido '%s'", ErrInvalidHistoryQuery, q.Bucket)
+	}
+	rng, err := newReadingRange(id, q, DefaultBucketsLimit)
+	if err != nil {
+		return ReadingBucketPage{}, err
+	}
+
+	limit := rng.Limit
+	rng.Limit++
+	buckets, err := s.repo.FindReadingBuckets(ctx, id, size, rng)
+	if err != nil {
+		return ReadingBucketPage{}, fmt.Errorf("error al agregar las lecturas: %w", err)
+	}
+
+	// Los intervalos están alineados, así que la página siguiente son las lecturas anteriores al
+	// inicio del último intervalo devuelto.
+	page := ReadingBucketPage{Bucket: q.Bucket, Items: buckets}
+	if len(buckets) > limit {
+		page.Items = buckets[:limit]
+		page.NextCursor = encodeHistoryCursor(q.Bucket, page.Items[limit-1].Start)
+	}
+	if page.Items == nil {
+		page.Items = []domain.ReadingBucket{}
+	}
+	return page, nil
+}
 
-	dLon := lon2Rad - lon1Rad
-	dLat := lat2Rad - lat1Rad
+// ErrInvalidCollection se devuelve cuando una recogida no supera la validación de negocio.
+var ErrInvalidCollection = errors.New("la recogida proporcionada no es válida")
 
-	a := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1Rad)*math.Cos(lat2Rad)*math.Pow(math.Sin(dLon/2), 2)
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+func (s *service) RecordCollection(ctx context.Context, collection domain.Collection) (domain.Collection, error) {
+	if collection.CollectedAt.IsZero() {
+		collection.CollectedAt = time.Now().UTC()
+	}
+	if collection.CollectedAt.After(time.Now().Add(time.Minute)) {
+		return domain.Collection{}, fmt.Errorf("%w: la fecha de recogida está en el futuro", ErrInvalidCollection)
+	}
+	if collection.EstimatedVolumeLiters < 0 {
+		return domain.Collection{}, fmt.Errorf("%w: el volumen recogido no puede ser negativo", ErrInvalidCollection)
+	}
+	collection.Inferred = false
+	return s.repo.RecordCollection(ctx, collection)
+}
 
-	return R * c
+func (s *service) GetCollectionsForContainer(ctx context.Context, id string, limit int) ([]domain.Collection, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	return s.repo.FindCollectionsByContainerID(ctx, id, limit)
 }
@@ -0,0 +1,163 @@
+package containertype
+
+import (
+	"errors"
+	"net/http"
+	"smart-waste-management/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler maneja las peticiones HTTP para los tipos de contenedor.
+type Handler struct {
+	service Service
+}
+
+// UpsertTypeRequest define el cuerpo de la petición para crear o actualizar un tipo de contenedor.
+type UpsertTypeRequest struct {
+	Model         string               `json:"model" binding:"required"`
+	VolumeLiters  int                  `json:"volume_liters" binding:"required,gt=0"`
+	LiftMechanism domain.LiftMechanism `json:"lift_mechanism" binding:"required,oneof=rear_loader side_loader front_loader crane"`
+}
+
+// NewHandler crea una nueva instancia del handler.
+func NewHandler(s Service) *Handler {
+	return &Handler{
+		service: s,
+	}
+}
+
+// RegisterRoutes registra todas las rutas de este handler en el router de Gin.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/container-types", h.GetTypes)
+	router.POST("/container-types", h.CreateType)
+	router.GET("/container-types/:id", h.GetTypeByID)
+	router.PUT("/container-types/:id", h.UpdateType)
+	router.DELETE("/container-types/:id", h.DeleteType)
+}
+
+// @Summary      Obtiene los tipos de contenedor
+// @Description  Devuelve todos los modelos de contenedor registrados con su volumen y sistema de elevación.
+// @Tags         ContainerTypes
+// @Produce      json
+// @Success      200  {object}  []domain.ContainerType
+// @Failure      500  {object}  map[string]string "Error interno del servidor"
+// @Router       /container-types [get]
+func (h *Handler) GetTypes(c *gin.Context) {
+	types, err := h.service.GetAllTypes(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener la lista de tipos de contenedor"})
+		return
+	}
+	c.JSON(http.StatusOK, types)
+}
+
+// @Summary      Crea un tipo de contenedor
+// @Description  Registra un modelo de contenedor (volumen y sistema de elevación) que se puede asignar a los contenedores.
+// @Tags         ContainerTypes
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        type     body      UpsertTypeRequest     true  "Datos del tipo de contenedor"
+// @Success      201      {object}  domain.ContainerType  "Tipo de contenedor creado"
+// @Failure      400      {object}  map[string]string     "Petición inválida o datos incorrectos"
+// @Failure      422      {object}  map[string]string     "Idempotency-Key reutilizada con una petición distinta"
+// @Failure      500      {object}  map[string]string     "Error interno del servidor"
+// @Router       /container-types [post]
+func (h *Handler) CreateType(c *gin.Context) {
+	var req UpsertTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := h.service.CreateType(c.Request.Context(), domain.ContainerType{
+		Model:         req.Model,
+		VolumeLiters:  req.VolumeLiters,
+		LiftMechanism: req.LiftMechanism,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo crear el tipo de contenedor"})
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// @Summary      Obtiene un tipo de contenedor por su ID
+// @Tags         ContainerTypes
+// @Produce      json
+// @Param        id   path      string  true  "ID del tipo de contenedor (UUID)"
+// @Success      200  {object}  domain.ContainerType
+// @Failure      404  {object}  map[string]string  "Tipo de contenedor no encontrado"
+// @Failure      500  {object}  map[string]string  "Error interno del servidor"
+// @Router       /container-types/{id} [get]
+func (h *Handler) GetTypeByID(c *gin.Context) {
+	t, err := h.service.GetTypeByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ErrTypeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al buscar el tipo de contenedor"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// @Summary      Actualiza un tipo de contenedor
+// @Tags         ContainerTypes
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id       path      string             true  "ID del tipo de contenedor (UUID)"
+// @Param        type     body      UpsertTypeRequest  true  "Nuevos datos del tipo de contenedor"
+// @Success      200      {object}  map[string]string  "Tipo de contenedor actualizado"
+// @Failure      400      {object}  map[string]string  "Petición inválida o datos incorrectos"
+// @Failure      404      {object}  map[string]string  "Tipo de contenedor no encontrado"
+// @Failure      422      {object}  map[string]string  "Idempotency-Key reutilizada con una petición distinta"
+// @Failure      500      {object}  map[string]string  "Error interno del servidor"
+// @Router       /container-types/{id} [put]
+func (h *Handler) UpdateType(c *gin.Context) {
+	var req UpsertTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.service.UpdateType(c.Request.Context(), domain.ContainerType{
+		ID:            c.Param("id"),
+		Model:         req.Model,
+		VolumeLiters:  req.VolumeLiters,
+		LiftMechanism: req.LiftMechanism,
+	})
+	if err != nil {
+		if errors.Is(err, ErrTypeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar el tipo de contenedor"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tipo de contenedor actualizado exitosamente"})
+}
+
+// @Summary      Elimina un tipo de contenedor
+// @Description  Elimina el tipo. No se puede eliminar mientras haya contenedores que lo usen.
+// @Tags         ContainerTypes
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id   path      string  true  "ID del tipo de contenedor (UUID)"
+// @Success      204  "Sin contenido"
+// @Failure      409  {object}  map[string]string "El tipo está asignado a algún contenedor"
+// @Failure      500  {object}  map[string]string "Error interno del servidor"
+// @Router       /container-types/{id} [delete]
+func (h *Handler) DeleteType(c *gin.Context) {
+	if err := h.service.DeleteType(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, ErrTypeInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar el tipo de contenedor"})
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,125 @@
+package containertype
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/platform/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrTypeNotFound se devuelve cuando no existe un tipo de contenedor con el ID indicado.
+var ErrTypeNotFound = errors.New("tipo de contenedor no encontrado")
+
+// ErrTypeInUse se devuelve al intentar eliminar un tipo que todavía usan algunos contenedores.
+var ErrTypeInUse = errors.New("el tipo de contenedor está asignado a uno o más contenedores")
+
+// foreignKeyViolation es el código SQLSTATE de PostgreSQL para una violación de clave foránea.
+const foreignKeyViolation = "23503"
+
+// Repository define las operaciones de persistencia de los tipos de contenedor.
+type Repository interface {
+	CreateType(ctx context.Context, t domain.ContainerType) (domain.ContainerType, error)
+	FindAllTypes(ctx context.Context) ([]domain.ContainerType, error)
+	FindTypeByID(ctx context.Context, id string) (domain.ContainerType, error)
+	UpdateType(ctx context.Context, t domain.ContainerType) error
+	DeleteType(ctx context.Context, id string) error
+}
+
+// postgresRepository es la implementación concreta de Repository para PostgreSQL.
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresRepository crea una nueva instancia del repositorio.
+func NewPostgresRepository(db *database.DB) Repository {
+	return &postgresRepository{
+		db: db.Pool,
+	}
+}
+
+const typeColumns = `id, model, volume_liters, lift_mechanism, created_at, updated_at`
+
+func scanType(row pgx.Row) (domain.ContainerType, error) {
+	var t domain.ContainerType
+	err := row.Scan(&t.ID, &t.Model, &t.VolumeLiters, &t.LiftMechanism, &t.CreatedAt, &t.UpdatedAt)
+	return t, err
+}
+
+func (r *postgresRepository) CreateType(ctx context.Context, t domain.ContainerType) (domain.ContainerType, error) {
+	query := `
+        INSERT INTO container_types (model, volume_liters, lift_mechanism)
+        VALUES ($1, $2, $3)
+        RETURNING ` + typeColumns
+
+	created, err := scanType(r.db.QueryRow(ctx, query, t.Model, t.VolumeLiters, t.LiftMechanism))
+	if err != nil {
+		return domain.ContainerType{}, fmt.Errorf("error al crear el tipo de contenedor: %w", err)
+	}
+	return created, nil
+}
+
+func (r *postgresRepository) FindAllTypes(ctx context.Context) ([]domain.ContainerType, error) {
+	query := `SELECT ` + typeColumns + ` FROM container_types ORDER BY model`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar los tipos de contenedor: %w", err)
+	}
+	defer rows.Close()
+
+	var types []domain.ContainerType
+	for rows.Next() {
+		t, err := scanType(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al escanear el tipo de contenedor: %w", err)
+		}
+		types = append(types, t)
+	}
+	return types, rows.Err()
+}
+
+func (r *postgresRepository) FindTypeByID(ctx context.Context, id string) (domain.ContainerType, error) {
+	query := `SELECT ` + typeColumns + ` FROM container_types WHERE id = $1`
+
+	t, err := scanType(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ContainerType{}, ErrTypeNotFound
+		}
+		return domain.ContainerType{}, fmt.Errorf("error al buscar el tipo de contenedor: %w", err)
+	}
+	return t, nil
+}
+
+func (r *postgresRepository) UpdateType(ctx context.Context, t domain.ContainerType) error {
+	query := `
+        UPDATE container_types
+        SET model = $1, volume_liters = $2, lift_mechanism = $3, updated_at = NOW()
+        WHERE id = $4`
+
+	tag, err := r.db.Exec(ctx, query, t.Model, t.VolumeLiters, t.LiftMechanism, t.ID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el tipo de contenedor: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTypeNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepository) DeleteType(ctx context.Context, id string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM container_types WHERE id = $1`, id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return ErrTypeInUse
+		}
+		return fmt.Errorf("error al eliminar el tipo de contenedor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package containertype
+
+import (
+	"context"
+	"smart-waste-management/internal/domain"
+)
+
+// Service define la lógica de negocio de los tipos de contenedor.
+type Service interface {
+	CreateType(ctx context.Context, t domain.ContainerType) (domain.ContainerType, error)
+	GetAllTypes(ctx context.Context) ([]domain.ContainerType, error)
+	GetTypeByID(ctx context.Context, id string) (domain.ContainerType, error)
+	UpdateType(ctx context.Context, t domain.ContainerType) error
+	DeleteType(ctx context.Context, id string) error
+}
+
+type service struct {
+	repo Repository
+}
+
+// NewService crea una nueva instancia del servicio.
+func NewService(repo Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+func (s *service) CreateType(ctx context.Context, t domain.ContainerType) (domain.ContainerType, error) {
+	return s.repo.CreateType(ctx, t)
+}
+
+func (s *service) GetAllTypes(ctx context.Context) ([]domain.ContainerType, error) {
+	return s.repo.FindAllTypes(ctx)
+}
+
+func (s *service) GetTypeByID(ctx context.Context, id string) (domain.ContainerType, error) {
+	return s.repo.FindTypeByID(ctx, id)
+}
+
+func (s *service) UpdateType(ctx context.Context, t domain.ContainerType) error {
+	return s.repo.UpdateType(ctx, t)
+}
+
+func (s *service) DeleteType(ctx context.Context, id string) error {
+	return s.repo.DeleteType(ctx, id)
+}
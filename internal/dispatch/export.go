@@ -0,0 +1,412 @@
+package dispatch
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/platform/geojson"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format es el formato en el que se descarga una ruta.
+type Format string
+
+const (
+	// FormatJSON es la representación propia de la API (domain.DispatchedRoute).
+	FormatJSON Format = "json"
+	// FormatGeoJSON es una FeatureCollection con una Feature Point por parada y el trazado como LineString.
+	FormatGeoJSON Format = "geojson"
+	// FormatGPX es un GPX 1.1 con un waypoint por parada y el trazado como track, para los navegadores de los camiones.
+	FormatGPX Format = "gpx"
+	// FormatKML es un KML 2.2 con un Placemark por parada y otro con el trazado.
+	FormatKML Format = "kml"
+)
+
+// exportFormat describe cómo se codifica y se descarga una ruta en un formato.
+type exportFormat struct {
+	contentType string
+	extension   string
+	encode      func(route domain.DispatchedRoute, track []domain.Point) ([]byte, error)
+}
+
+var exportFormats = map[Format]exportFormat{
+	FormatGeoJSON: {contentType: geojson.ContentType, extension: "geojson", encode: encodeGeoJSON},
+	FormatGPX:     {contentType: "application/gpx+xml", extension: "gpx", encode: encodeGPX},
+	FormatKML:     {contentType: "application/vnd.google-earth.kml+xml", extension: "kml", encode: encodeKML},
+}
+
+// IsValid comprueba si el formato es uno de los conocidos.
+func (f Format) IsValid() bool {
+	_, ok := exportFormats[f]
+	return ok || f == FormatJSON
+}
+
+// NegotiateFormat elige el formato a partir de la cabecera Accept: el primero de los tipos aceptados
+// que corresponde a un formato de exportación. Si no hay ninguno (o se acepta cualquier tipo), se
+// usa la representación JSON propia.
+func NegotiateFormat(accept string) Format {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if mediaType == "application/json" {
+			return FormatJSON
+		}
+		for format, ef := range exportFormats {
+			if mediaType == ef.contentType {
+				return format
+			}
+		}
+	}
+	return FormatJSON
+}
+
+// Export es una ruta codificada en un formato de intercambio, lista para descargarse.
+type Export struct {
+	ContentType string
+	FileName    string
+	Body        []byte
+}
+
+// property es un dato de una parada o de la ruta que se incluye en las propiedades de la exportación.
+type property struct {
+	name  string
+	value any
+}
+
+// routeProperties son los datos de la ruta que acompañan al trazado.
+func routeProperties(route domain.DispatchedRoute) []property {
+	props := []property{
+		{"route_id", route.ID},
+		{"status", string(route.Status)},
+	}
+	if route.Vehicle != nil {
+		props = append(props, property{"vehicle", *route.Vehicle})
+	}
+	if route.Driver != nil {
+		props = append(props, property{"driver", *route.Driver})
+	}
+	if route.Fraction != nil {
+		props = append(props, property{"fraction", string(*route.Fraction)})
+	}
+	return append(props,
+		property{"planned_start_at", route.PlannedStartAt},
+		property{"planned_end_at", route.PlannedEndAt},
+		property{"planned_distance_km", route.PlannedDistanceKm},
+		property{"planned_duration_minutes", route.PlannedDurationMinutes},
+	)
+}
+
+// stopProperties son los datos de una parada: su posición en la ruta, lo planificado, su resultado
+// y los datos del contenedor o del punto de descarga.
+func stopProperties(stop domain.DispatchedStop) []property {
+	props := []property{
+		{"stop_id", stop.ID},
+		{"sequence", stop.Sequence},
+		{"kind", string(stop.Kind)},
+	}
+	if stop.ContainerID != nil {
+		props = append(props, property{"container_id", *stop.ContainerID})
+	}
+	if c := stop.Container; c != nil {
+		props = append(props,
+			property{"fraction", string(c.Fraction)},
+			property{"capacity_liters", c.CapacityLiters},
+			property{"container_status", string(c.Status)},
+			property{"last_fill_level", c.LastFillLevel},
+		)
+		if c.ContainerTypeID != nil {
+			props = append(props, property{"container_type_id", *c.ContainerTypeID})
+		}
+	}
+	if stop.FacilityID != nil {
+		props = append(props, property{"facility_id", *stop.FacilityID})
+	}
+	if stop.FacilityName != nil {
+		props = append(props, property{"facility_name", *stop.FacilityName})
+	}
+	if stop.PlannedETA != nil {
+		props = append(props, property{"planned_eta", *stop.PlannedETA})
+	}
+	props = append(props,
+		property{"estimated_load_liters", stop.EstimatedLoadLiters},
+		property{"estimated_load_kg", stop.EstimatedLoadKg},
+		property{"status", string(stop.Status)},
+	)
+	if stop.SkipReason != nil {
+		props = append(props, property{"skip_reason", string(*stop.SkipReason)})
+	}
+	if stop.VisitedAt != nil {
+		props = append(props, property{"visited_at", *stop.VisitedAt})
+	}
+	if stop.Notes != nil {
+		props = append(props, property{"notes", *stop.Notes})
+	}
+	return props
+}
+
+// formatValue escribe el valor de una propiedad como texto, para los formatos XML.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// stopName es el nombre corto de una parada en los navegadores y en los visores de mapas.
+func stopName(stop domain.DispatchedStop) string {
+	switch {
+	case stop.Kind == domain.StopUnload && stop.FacilityName != nil:
+		return fmt.Sprintf("%d. Descarga: %s", stop.Sequence, *stop.FacilityName)
+	case stop.Kind == domain.StopUnload:
+		return fmt.Sprintf("%d. Descarga", stop.Sequence)
+	case stop.Container != nil:
+		return fmt.Sprintf("%d. Contenedor %s (%s)", stop.Sequence, shortID(*stop.ContainerID), stop.Container.Fraction)
+	case stop.ContainerID != nil:
+		return fmt.Sprintf("%d. Contenedor %s", stop.Sequence, shortID(*stop.ContainerID))
+	}
+	return fmt.Sprintf("%d. Contenedor eliminado", stop.Sequence)
+}
+
+// shortID acorta un UUID a su primer bloque, suficiente para distinguir las paradas en pantalla.
+func shortID(id string) string {
+	if i := strings.IndexByte(id, '-'); i > 0 {
+		return id[:i]
+	}
+	return id
+}
+
+// --- GeoJSON (RFC 7946) ---
+
+func propertyMap(props []property) map[string]any {
+	m := make(map[string]any, len(props))
+	for _, p := range props {
+		m[p.name] = p.value
+	}
+	return m
+}
+
+func encodeGeoJSON(route domain.DispatchedRoute, track []domain.Point) ([]byte, error) {
+	lineProps := propertyMap(routeProperties(route))
+	lineProps["feature"] = "route"
+
+	startProps := map[string]any{"feature": "start", "route_id": route.ID, "sequence": 0}
+	if route.DepotID != nil {
+		startProps["depot_id"] = *route.DepotID
+	}
+
+	features := []geojson.Feature{
+		geojson.NewFeature(route.ID, geojson.LineString(track), lineProps),
+		geojson.NewFeature("", geojson.Point(route.StartPoint), startProps),
+	}
+	for _, stop := range route.Stops {
+		props := propertyMap(stopProperties(stop))
+		props["feature"] = "stop"
+		props["name"] = stopName(stop)
+		features = append(features, geojson.NewFeature(stop.ID, geojson.Point(stop.Location), props))
+	}
+	return json.Marshal(geojson.NewFeatureCollection(features...))
+}
+
+// --- GPX 1.1 ---
+
+// gpxExtensionsNamespace es el espacio de nombres de los datos propios de las paradas en el GPX.
+const gpxExtensionsNamespace = "urn:smart-waste-management:gpx:stop:1"
+
+type gpxDocument struct {
+	XMLName   xml.Name    `xml:"gpx"`
+	Version   string      `xml:"version,attr"`
+	Creator   string      `xml:"creator,attr"`
+	Namespace string      `xml:"xmlns,attr"`
+	Stop      string      `xml:"xmlns:stop,attr"`
+	Metadata  gpxMetadata `xml:"metadata"`
+	Waypoints []gpxPoint  `xml:"wpt"`
+	Track     gpxTrack    `xml:"trk"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name"`
+	Desc string `xml:"desc,omitempty"`
+	Time string `xml:"time,omitempty"`
+}
+
+type gpxPoint struct {
+	Lat        float64        `xml:"lat,attr"`
+	Lon        float64        `xml:"lon,attr"`
+	Time       string         `xml:"time,omitempty"`
+	Name       string         `xml:"name,omitempty"`
+	Desc       string         `xml:"desc,omitempty"`
+	Type       string         `xml:"type,omitempty"`
+	Extensions *gpxExtensions `xml:"extensions,omitempty"`
+}
+
+type gpxExtensions struct {
+	Fields []gpxField
+}
+
+type gpxField struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+type gpxTrack struct {
+	Name    string     `xml:"name"`
+	Segment gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+func encodeGPX(route domain.DispatchedRoute, track []domain.Point) ([]byte, error) {
+	doc := gpxDocument{
+		Version:   "1.1",
+		Creator:   "smart-waste-management",
+		Namespace: "http://www.topografix.com/GPX/1/1",
+		Stop:      gpxExtensionsNamespace,
+		Metadata: gpxMetadata{
+			Name: "Ruta " + route.ID,
+			Desc: describe(routeProperties(route)),
+			Time: route.PlannedStartAt.UTC().Format(time.RFC3339),
+		},
+		Track: gpxTrack{Name: "Ruta " + route.ID},
+	}
+	for _, stop := range route.Stops {
+		wpt := gpxPoint{
+			Lat:        stop.Location.Latitude,
+			Lon:        stop.Location.Longitude,
+			Name:       stopName(stop),
+			Desc:       describe(stopProperties(stop)),
+			Type:       string(stop.Kind),
+			Extensions: &gpxExtensions{},
+		}
+		if stop.PlannedETA != nil {
+			wpt.Time = stop.PlannedETA.UTC().Format(time.RFC3339)
+		}
+		for _, p := range stopProperties(stop) {
+			wpt.Extensions.Fields = append(wpt.Extensions.Fields, gpxField{
+				XMLName: xml.Name{Local: "stop:" + p.name},
+				Value:   formatValue(p.value),
+			})
+		}
+		doc.Waypoints = append(doc.Waypoints, wpt)
+	}
+	for _, p := range track {
+		doc.Track.Segment.Points = append(doc.Track.Segment.Points, gpxPoint{Lat: p.Latitude, Lon: p.Longitude})
+	}
+	return marshalXML(doc)
+}
+
+// describe resume las propiedades en una línea de texto, para los campos de descripción que
+// muestran los navegadores y los visores.
+func describe(props []property) string {
+	parts := make([]string, len(props))
+	for i, p := range props {
+		parts[i] = p.name + ": " + formatValue(p.value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// --- KML 2.2 ---
+
+type kmlDocument struct {
+	XMLName   xml.Name `xml:"kml"`
+	Namespace string   `xml:"xmlns,attr"`
+	Document  kmlFolder
+}
+
+type kmlFolder struct {
+	XMLName    xml.Name       `xml:"Document"`
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name         string         `xml:"name"`
+	TimeStamp    *kmlTimeStamp  `xml:"TimeStamp,omitempty"`
+	ExtendedData kmlData        `xml:"ExtendedData"`
+	Point        *kmlPoint      `xml:"Point,omitempty"`
+	LineString   *kmlLineString `xml:"LineString,omitempty"`
+}
+
+type kmlTimeStamp struct {
+	When string `xml:"when"`
+}
+
+type kmlData struct {
+	Data []kmlDataField `xml:"Data"`
+}
+
+type kmlDataField struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlLineString struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"coordinates"`
+}
+
+// kmlCoordinates escribe los puntos como tuplas "longitud,latitud" separadas por espacios.
+func kmlCoordinates(points ...domain.Point) string {
+	parts := make([]string, len(points))
+	for i, p := range points {
+		parts[i] = strconv.FormatFloat(p.Longitude, 'f', -1, 64) + "," + strconv.FormatFloat(p.Latitude, 'f', -1, 64)
+	}
+	return strings.Join(parts, " ")
+}
+
+func kmlExtendedData(props []property) kmlData {
+	var data kmlData
+	for _, p := range props {
+		data.Data = append(data.Data, kmlDataField{Name: p.name, Value: formatValue(p.value)})
+	}
+	return data
+}
+
+func encodeKML(route domain.DispatchedRoute, track []domain.Point) ([]byte, error) {
+	doc := kmlDocument{
+		Namespace: "http://www.opengis.net/kml/2.2",
+		Document: kmlFolder{
+			Name: "Ruta " + route.ID,
+			Placemarks: []kmlPlacemark{{
+				Name:         "Trazado",
+				ExtendedData: kmlExtendedData(routeProperties(route)),
+				LineString:   &kmlLineString{Tessellate: 1, Coordinates: kmlCoordinates(track...)},
+			}},
+		},
+	}
+	for _, stop := range route.Stops {
+		pm := kmlPlacemark{
+			Name:         stopName(stop),
+			ExtendedData: kmlExtendedData(stopProperties(stop)),
+			Point:        &kmlPoint{Coordinates: kmlCoordinates(stop.Location)},
+		}
+		if stop.PlannedETA != nil {
+			pm.TimeStamp = &kmlTimeStamp{When: stop.PlannedETA.UTC().Format(time.RFC3339)}
+		}
+		doc.Document.Placemarks = append(doc.Document.Placemarks, pm)
+	}
+	return marshalXML(doc)
+}
+
+func marshalXML(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,345 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"smart-waste-management/internal/domain"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler maneja las peticiones HTTP de las rutas guardadas y su ejecución.
+type Handler struct {
+	service Service
+}
+
+// AssignRouteRequest define el camión y el conductor de una ruta. Un campo omitido queda sin asignar.
+type AssignRouteRequest struct {
+	Vehicle *string `json:"vehicle" binding:"omitempty,min=1,max=50" example:"1234-KLM"`
+	Driver  *string `json:"driver" binding:"omitempty,min=1,max=100" example:"conductor-17"`
+}
+
+// RouteTransitionRequest define la hora real de inicio o de fin de una ruta. El cuerpo es opcional.
+type RouteTransitionRequest struct {
+	// At es la hora del cambio; si se omite, se usa la hora actual.
+	At time.Time `json:"at"`
+}
+
+// StopUpdateRequest define el resultado de una parada.
+type StopUpdateRequest struct {
+	Status domain.StopStatus `json:"status" binding:"required,oneof=collected skipped overflowing"`
+	// SkipReason es obligatorio si la parada no se recogió.
+	SkipReason *domain.SkipReason `json:"skip_reason" binding:"omitempty,oneof=blocked inaccessible"`
+	Notes      *string            `json:"notes" binding:"omitempty,max=500"`
+	// VisitedAt es la hora de la visita; si se omite, se usa la hora actual.
+	VisitedAt time.Time `json:"visited_at"`
+	// VolumeLiters es el volumen recogido; si se omite, se estima a partir del nivel de llenado previo.
+	VolumeLiters int `json:"volume_liters" binding:"omitempty,gt=0"`
+}
+
+// RerouteRequest define la re-planificación de una ruta en curso.
+type RerouteRequest struct {
+	// Position es la posición actual del camión.
+	Position *domain.Point `json:"position" binding:"required"`
+	// Statuses son los estados de los contenedores que se consideran urgentes; por defecto, 'high'.
+	Statuses []domain.Status `json:"statuses" binding:"omitempty,dive,oneof=low medium high"`
+	// Capacidad del camión en litros y/o en kilos; si se omite, se usa la de la flota con la que se
+	// planificó la ruta (y, si se planificó sin flota, no se limita la carga).
+	CapacityLiters float64 `json:"capacity_liters" binding:"omitempty,gt=0"`
+	CapacityKg     float64 `json:"capacity_kg" binding:"omitempty,gt=0"`
+	// MaxDetourKm es lo máximo que puede alargar la ruta cada contenedor añadido; si se omite, no hay límite.
+	MaxDetourKm float64 `json:"max_detour_km" binding:"omitempty,gt=0"`
+	// DryRun calcula los cambios sin guardarlos.
+	DryRun bool `json:"dry_run"`
+}
+
+// NewHandler crea una nueva instancia del handler.
+func NewHandler(s Service) *Handler {
+	return &Handler{
+		service: s,
+	}
+}
+
+// RegisterRoutes registra todas las rutas de este handler en el router de Gin.
+// La generación de rutas (POST /routes) la registra el módulo de contenedores.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/routes", h.GetRoutes)
+	router.GET("/routes/:id", h.GetRouteByID)
+	router.PUT("/routes/:id/assignment", h.AssignRoute)
+	router.POST("/routes/:id/start", h.StartRoute)
+	router.POST("/routes/:id/complete", h.CompleteRoute)
+	router.PUT("/routes/:id/stops/:stop_id", h.UpdateStop)
+	router.POST("/routes/:id/reroute", h.Reroute)
+}
+
+// @Summary      Obtiene las rutas guardadas
+// @Description  Devuelve las rutas generadas con POST /routes, de la salida planificada más reciente a la más antigua, sin sus paradas.
+// @Tags         Routes
+// @Produce      json
+// @Param        status   query     []string  false  "Filtra por estado (se puede repetir)"  collectionFormat(multi) Enums(planned, in_progress, completed)
+// @Param        vehicle  query     string    false  "Filtra por camión"
+// @Param        driver   query     string    false  "Filtra por conductor"
+// @Param        from     query     string    false  "Salida planificada a partir de esta fecha (RFC 3339)"
+// @Param        to       query     string    false  "Salida planificada antes de esta fecha (RFC 3339)"
+// @Success      200      {object}  []domain.DispatchedRoute
+// @Failure      400      {object}  map[string]string  "Filtro inválido"
+// @Failure      500      {object}  map[string]string  "Error interno del servidor"
+// @Router       /routes [get]
+func (h *Handler) GetRoutes(c *gin.Context) {
+	filter := Filter{Vehicle: c.Query("vehicle"), Driver: c.Query("driver")}
+	for _, status := range c.QueryArray("status") {
+		s := domain.RouteStatus(status)
+		if !s.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Estado de ruta desconocido: " + status})
+			return
+		}
+		filter.Statuses = append(filter.Statuses, s)
+	}
+	for param, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("El parámetro '%s' debe tener formato RFC 3339", param)})
+				return
+			}
+			*dst = t
+		}
+	}
+
+	routes, err := h.service.GetRoutes(c.Request.Context(), filter)
+	if err != nil {
+		fmt.Printf("Error al obtener las rutas: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener la lista de rutas"})
+		return
+	}
+	c.JSON(http.StatusOK, routes)
+}
+
+// @Summary      Obtiene una ruta guardada
+// @Description  Devuelve la ruta con sus paradas en el orden planificado, el resultado de cada una y la comparación entre lo planificado y lo ejecutado.
+// @Description  Con 'format' (o la cabecera Accept) la ruta se descarga como GeoJSON (application/geo+json), GPX (application/gpx+xml) o KML (application/vnd.google-earth.kml+xml): una entidad por parada con su secuencia y los datos del contenedor, y el trazado desde el punto de salida, por carretera si hay una red viaria cargada.
+// @Tags         Routes
+// @Produce      json
+// @Produce      application/geo+json
+// @Produce      application/gpx+xml
+// @Produce      application/vnd.google-earth.kml+xml
+// @Param        id      path      string  true   "ID de la ruta (UUID)"
+// @Param        format  query     string  false  "Formato de descarga; tiene prioridad sobre la cabecera Accept"  Enums(json, geojson, gpx, kml)
+// @Success      200     {object}  domain.DispatchedRoute
+// @Failure      400     {object}  map[string]string  "Formato no soportado"
+// @Failure      404     {object}  map[string]string  "Ruta no encontrada"
+// @Failure      500     {object}  map[string]string  "Error interno del servidor"
+// @Router       /routes/{id} [get]
+func (h *Handler) GetRouteByID(c *gin.Context) {
+	format := NegotiateFormat(c.GetHeader("Accept"))
+	if value := c.Query("format"); value != "" {
+		format = Format(strings.ToLower(value))
+		if !format.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Formato no soportado: " + value + ". Usa 'json', 'geojson', 'gpx' o 'kml'"})
+			return
+		}
+	}
+
+	if format == FormatJSON {
+		route, err := h.service.GetRoute(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			h.writeError(c, err, "Error al buscar la ruta")
+			return
+		}
+		c.JSON(http.StatusOK, route)
+		return
+	}
+
+	export, err := h.service.ExportRoute(c.Request.Context(), c.Param("id"), format)
+	if err != nil {
+		h.writeError(c, err, "No se pudo exportar la ruta")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.FileName))
+	c.Data(http.StatusOK, export.ContentType, export.Body)
+}
+
+// @Summary      Asigna el camión y el conductor de una ruta
+// @Tags         Routes
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id          path      string              true  "ID de la ruta (UUID)"
+// @Param        assignment  body      AssignRouteRequest  true  "Camión y conductor"
+// @Success      200         {object}  domain.DispatchedRoute
+// @Failure      400         {object}  map[string]string  "Petición inválida o datos incorrectos"
+// @Failure      404         {object}  map[string]string  "Ruta no encontrada"
+// @Failure      409         {object}  map[string]string  "La ruta ya está terminada"
+// @Failure      422         {object}  map[string]string  "Idempotency-Key reutilizada con una petición distinta"
+// @Failure      500         {object}  map[string]string  "Error interno del servidor"
+// @Router       /routes/{id}/assignment [put]
+func (h *Handler) AssignRoute(c *gin.Context) {
+	var req AssignRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	route, err := h.service.AssignRoute(c.Request.Context(), c.Param("id"), req.Vehicle, req.Driver)
+	if err != nil {
+		h.writeError(c, err, "No se pudo asignar la ruta")
+		return
+	}
+	c.JSON(http.StatusOK, route)
+}
+
+// @Summary      Inicia una ruta
+// @Description  Pasa una ruta planificada a 'in_progress'. Marcar la primera parada también la inicia.
+// @Tags         Routes
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id          path      string                  true   "ID de la ruta (UUID)"
+// @Param        transition  body      RouteTransitionRequest  false  "Hora real de inicio"
+// @Success      200         {object}  domain.DispatchedRoute
+// @Failure      400         {object}  map[string]string  "Petición inválida o datos incorrectos"
+// @Failure      404         {object}  map[string]string  "Ruta no encontrada"
+// @Failure      409         {object}  map[string]string  "La ruta no está planificada"
+// @Failure      422         {object}  map[string]string  "Idempotency-Key reutilizada con una petición distinta"
+// @Failure      500         {object}  map[string]string  "Error interno del servidor"
+// @Router       /routes/{id}/start [post]
+func (h *Handler) StartRoute(c *gin.Context) {
+	var req RouteTransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	route, err := h.service.StartRoute(c.Request.Context(), c.Param("id"), req.At)
+	if err != nil {
+		h.writeError(c, err, "No se pudo iniciar la ruta")
+		return
+	}
+	c.JSON(http.StatusOK, route)
+}
+
+// @Summary      Termina una ruta
+// @Description  Pasa una ruta en curso a 'completed'. Las paradas sin resultado quedan como 'pending' y cuentan como no visitadas en la comparación.
+// @Tags         Routes
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id          path      string                  true   "ID de la ruta (UUID)"
+// @Param        transition  body      RouteTransitionRequest  false  "Hora real de fin"
+// @Success      200         {object}  domain.DispatchedRoute
+// @Failure      400         {object}  map[string]string  "Petición inválida o datos incorrectos"
+// @Failure      404         {object}  map[string]string  "Ruta no encontrada"
+// @Failure      409         {object}  map[string]string  "La ruta no está en curso"
+// @Failure      422         {object}  map[string]string  "Idempotency-Key reutilizada con una petición distinta"
+// @Failure      500         {object}  map[string]string  "Error interno del servidor"
+// @Router       /routes/{id}/complete [post]
+func (h *Handler) CompleteRoute(c *gin.Context) {
+	var req RouteTransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	route, err := h.service.CompleteRoute(c.Request.Context(), c.Param("id"), req.At)
+	if err != nil {
+		h.writeError(c, err, "No se pudo terminar la ruta")
+		return
+	}
+	c.JSON(http.StatusOK, route)
+}
+
+// @Summary      Marca el resultado de una parada
+// @Description  El conductor marca un contenedor como recogido ('collected'), no recogido ('skipped', con el motivo 'blocked' o 'inaccessible') o desbordado ('overflowing').
+// @Description  Si el contenedor se vació (recogido o desbordado) se registra la recogida y el contenedor vuelve a estado bajo. Cada parada se marca una sola vez.
+// @Tags         Routes
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id       path      string             true  "ID de la ruta (UUID)"
+// @Param        stop_id  path      string             true  "ID de la parada (UUID)"
+// @Param        result   body      StopUpdateRequest  true  "Resultado de la parada"
+// @Success      200      {object}  domain.DispatchedStop
+// @Failure      400      {object}  map[string]string  "Petición inválida o resultado incoherente"
+// @Failure      404      {object}  map[string]string  "Ruta o parada no encontrada"
+// @Failure      409      {object}  map[string]string  "La parada ya tiene resultado o la ruta está terminada"
+// @Failure      422      {object}  map[string]string  "Idempotency-Key reutilizada con una petición distinta"
+// @Failure      500      {object}  map[string]string  "Error interno del servidor"
+// @Router       /routes/{id}/stops/{stop_id} [put]
+func (h *Handler) UpdateStop(c *gin.Context) {
+	var req StopUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stop, err := h.service.UpdateStop(c.Request.Context(), c.Param("id"), c.Param("stop_id"), StopUpdate{
+		Status:       req.Status,
+		SkipReason:   req.SkipReason,
+		Notes:        req.Notes,
+		VisitedAt:    req.VisitedAt,
+		VolumeLiters: req.VolumeLiters,
+	})
+	if err != nil {
+		h.writeError(c, err, "No se pudo guardar el resultado de la parada")
+		return
+	}
+	c.JSON(http.StatusOK, stop)
+}
+
+// @Summary      Re-planifica una ruta en curso
+// @Description  Añade a una ruta en curso los contenedores que se han vuelto urgentes (por defecto, en estado 'high') y que no están en otra ruta pendiente. Cada contenedor se inserta en la posición de las paradas pendientes que menos alarga la ruta desde la posición del camión, sin superar su capacidad en ningún viaje (entre descargas). La capacidad es la de la flota con la que se planificó la ruta; 'capacity_liters' y 'capacity_kg' la sustituyen.
+// @Description  Devuelve las paradas añadidas, las que cambian de posición y los kilómetros de más. Con 'dry_run' los cambios no se guardan. Las paradas añadidas no tienen ETA planificada.
+// @Tags         Routes
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id       path      string          true  "ID de la ruta (UUID)"
+// @Param        reroute  body      RerouteRequest  true  "Posición del camión y capacidad"
+// @Success      200      {object}  domain.Reroute
+// @Failure      400      {object}  map[string]string  "Petición inválida o datos incorrectos"
+// @Failure      404      {object}  map[string]string  "Ruta no encontrada"
+// @Failure      409      {object}  map[string]string  "La ruta no está en curso o ha cambiado mientras se re-planificaba"
+// @Failure      422      {object}  map[string]string  "Idempotency-Key reutilizada con una petición distinta"
+// @Failure      500      {object}  map[string]string  "Error interno del servidor"
+// @Router       /routes/{id}/reroute [post]
+func (h *Handler) Reroute(c *gin.Context) {
+	var req RerouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.Reroute(c.Request.Context(), c.Param("id"), RerouteOptions{
+		Position:       *req.Position,
+		Statuses:       req.Statuses,
+		CapacityLiters: req.CapacityLiters,
+		CapacityKg:     req.CapacityKg,
+		MaxDetourKm:    req.MaxDetourKm,
+		DryRun:         req.DryRun,
+	})
+	if err != nil {
+		h.writeError(c, err, "No se pudo re-planificar la ruta")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// writeError traduce los errores del servicio a códigos HTTP.
+func (h *Handler) writeError(c *gin.Context, err error, internalMessage string) {
+	switch {
+	case errors.Is(err, ErrRouteNotFound), errors.Is(err, ErrStopNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidStopResult), errors.Is(err, ErrFutureTimestamp), errors.Is(err, ErrUnsupportedFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidTransition), errors.Is(err, ErrRouteCompleted), errors.Is(err, ErrStopAlreadyVisited),
+		errors.Is(err, ErrRouteNotInProgress), errors.Is(err, ErrRouteChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		fmt.Printf("%s: %v\n", internalMessage, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": internalMessage})
+	}
+}
@@ -0,0 +1,482 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smart-waste-management/internal/container"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/platform/database"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrRouteNotFound se devuelve cuando no existe una ruta guardada con el ID indicado.
+	ErrRouteNotFound = errors.New("ruta no encontrada")
+	// ErrStopNotFound se devuelve cuando la ruta no tiene una parada con el ID indicado.
+	ErrStopNotFound = errors.New("parada no encontrada en la ruta")
+	// ErrRouteChanged se devuelve al guardar una re-planificación si, entretanto, la ruta ha dejado
+	// de estar en curso o se ha modificado (p. ej. se ha marcado una parada o se le han añadido otras).
+	ErrRouteChanged = errors.New("la ruta ha cambiado mientras se re-planificaba")
+)
+
+// uniqueViolation es el código SQLSTATE de PostgreSQL para una violación de restricción única.
+const uniqueViolation = "23505"
+
+// Filter restringe el listado de rutas. Los campos vacíos no filtran.
+type Filter struct {
+	Statuses []domain.RouteStatus
+	Vehicle  string
+	Driver   string
+	// From y To limitan la hora de salida planificada.
+	From, To time.Time
+}
+
+// StopResult es el resultado de una parada tal como lo marca el conductor.
+type StopResult struct {
+	Status     domain.StopStatus
+	SkipReason *domain.SkipReason
+	Notes      *string
+	VisitedAt  time.Time
+	// Collection es la recogida que se registra con la parada si el contenedor se vació.
+	Collection *domain.Collection
+}
+
+// Repository define las operaciones de persistencia de las rutas guardadas.
+type Repository interface {
+	// SaveRoutes guarda las rutas de un plan en estado 'planned' y devuelve sus IDs, en el mismo orden.
+	SaveRoutes(ctx context.Context, fraction domain.Fraction, routes []domain.Route) ([]string, error)
+	FindRoutes(ctx context.Context, filter Filter) ([]domain.DispatchedRoute, error)
+	// FindRouteByID devuelve la ruta con sus paradas.
+	FindRouteByID(ctx context.Context, id string) (domain.DispatchedRoute, error)
+	AssignRoute(ctx context.Context, id string, vehicle, driver *string) error
+	// UpdateRouteStatus pasa la ruta de uno de los estados 'from' a 'to' y devuelve false si no
+	// estaba en ninguno de ellos.
+	UpdateRouteStatus(ctx context.Context, id string, from []domain.RouteStatus, to domain.RouteStatus, at time.Time) (bool, error)
+	// RecordStopResult guarda el resultado de una parada pendiente y su recogida, si la hay, y, si
+	// la ruta aún no había empezado, la pone en curso, todo en la misma transacción. Devuelve la
+	// recogida registrada, o false si la parada ya no estaba pendiente.
+	RecordStopResult(ctx context.Context, routeID, stopID string, result StopResult) (*domain.Collection, bool, error)
+	// FindUrgentContainers devuelve los contenedores en alguno de los estados indicados (y de la
+	// fracción, si se indica) que no están en la ruta ni pendientes en otra ruta sin terminar.
+	FindUrgentContainers(ctx context.Context, routeID string, fraction *domain.Fraction, statuses []domain.Status) ([]domain.Container, error)
+	// ApplyReroute cambia la posición de las paradas pendientes de una ruta en curso y le añade
+	// paradas nuevas. 'readAt' es el updated_at de la ruta cuando se leyó para re-planificarla.
+	// Devuelve los IDs de las paradas añadidas, en el mismo orden, o ErrRouteChanged si la ruta
+	// ya no está como se leyó.
+	ApplyReroute(ctx context.Context, routeID string, readAt time.Time, sequences map[string]int, added []domain.DispatchedStop, extraDistanceKm float64) ([]string, error)
+}
+
+// postgresRepository es la implementación concreta de Repository para PostgreSQL.
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresRepository crea una nueva instancia del repositorio.
+func NewPostgresRepository(db *database.DB) Repository {
+	return &postgresRepository{
+		db: db.Pool,
+	}
+}
+
+func (r *postgresRepository) SaveRoutes(ctx context.Context, fraction domain.Fraction, routes []domain.Route) ([]string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo iniciar la transacción: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var fractionArg *string
+	if fraction != "" {
+		s := string(fraction)
+		fractionArg = &s
+	}
+
+	const routeSQL = `
+        INSERT INTO routes (fraction, depot_id, start_point, planned_start_at, planned_end_at,
+                            planned_distance_km, planned_duration_minutes, planned_load_liters, planned_load_kg,
+                            capacity_liters, capacity_kg)
+        VALUES ($1::waste_fraction, $2, ST_SetSRID(ST_MakePoint($3, $4), 4326), $5, $6, $7, $8, $9, $10, $11, $12)
+        RETURNING id`
+	const stopSQL = `
+        INSERT INTO route_stops (route_id, sequence, kind, container_id, facility_id, location,
+                                 planned_eta, estimated_load_liters, estimated_load_kg)
+        VALUES ($1, $2, $3, $4, $5, ST_SetSRID(ST_MakePoint($6, $7), 4326), $8, $9, $10)`
+
+	ids := make([]string, len(routes))
+	for i, route := range routes {
+		var depotID *string
+		if route.Depot != nil {
+			depotID = &route.Depot.ID
+		}
+		err := tx.QueryRow(ctx, routeSQL, fractionArg, depotID, route.StartPoint.Longitude, route.StartPoint.Latitude,
+			route.StartAt, route.EndAt, route.DistanceKm, route.DurationMinutes, route.LoadLiters, route.LoadKg,
+			route.CapacityLiters, route.CapacityKg,
+		).Scan(&ids[i])
+		if err != nil {
+			return nil, fmt.Errorf("error al guardar la ruta: %w", err)
+		}
+
+		batch := &pgx.Batch{}
+		for k, stop := range route.Stops {
+			var containerID, facilityID *string
+			location := stop.Coordinates()
+			if stop.Container != nil {
+				containerID = &stop.Container.ID
+			}
+			if stop.Facility != nil {
+				facilityID = &stop.Facility.ID
+			}
+			batch.Queue(stopSQL, ids[i], k+1, string(stop.Kind), containerID, facilityID, location.Longitude, location.Latitude,
+				stop.ETA, stop.EstimatedLoadLiters, stop.EstimatedLoadKg)
+		}
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return nil, fmt.Errorf("error al guardar las paradas de la ruta: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error al confirmar la transacción: %w", err)
+	}
+	return ids, nil
+}
+
+// routeColumns son las columnas de una ruta en el orden en que las lee scanRoute.
+const routeColumns = `id, status, vehicle, driver, fraction::text, depot_id,
+        ST_Y(start_point::geometry), ST_X(start_point::geometry),
+        planned_start_at, planned_end_at, planned_distance_km, planned_duration_minutes, planned_load_liters, planned_load_kg,
+        capacity_liters, capacity_kg, started_at, completed_at, rerouted_at, added_distance_km, created_at, updated_at`
+
+func scanRoute(row pgx.Row) (domain.DispatchedRoute, error) {
+	var rt domain.DispatchedRoute
+	var fraction *string
+	err := row.Scan(&rt.ID, &rt.Status, &rt.Vehicle, &rt.Driver, &fraction, &rt.DepotID,
+		&rt.StartPoint.Latitude, &rt.StartPoint.Longitude,
+		&rt.PlannedStartAt, &rt.PlannedEndAt, &rt.PlannedDistanceKm, &rt.PlannedDurationMinutes, &rt.PlannedLoadLiters, &rt.PlannedLoadKg,
+		&rt.CapacityLiters, &rt.CapacityKg, &rt.StartedAt, &rt.CompletedAt, &rt.ReroutedAt, &rt.AddedDistanceKm, &rt.CreatedAt, &rt.UpdatedAt)
+	if fraction != nil {
+		f := domain.Fraction(*fraction)
+		rt.Fraction = &f
+	}
+	return rt, err
+}
+
+func (r *postgresRepository) FindRoutes(ctx context.Context, filter Filter) ([]domain.DispatchedRoute, error) {
+	var conditions []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, s := range filter.Statuses {
+			statuses[i] = string(s)
+		}
+		conditions = append(conditions, "status::text = ANY("+arg(statuses)+"::text[])")
+	}
+	if filter.Vehicle != "" {
+		conditions = append(conditions, "vehicle = "+arg(filter.Vehicle))
+	}
+	if filter.Driver != "" {
+		conditions = append(conditions, "driver = "+arg(filter.Driver))
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "planned_start_at >= "+arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "planned_start_at < "+arg(filter.To))
+	}
+
+	query := `SELECT ` + routeColumns + ` FROM routes`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY planned_start_at DESC, created_at DESC"
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar las rutas: %w", err)
+	}
+	defer rows.Close()
+
+	routes := []domain.DispatchedRoute{}
+	for rows.Next() {
+		rt, err := scanRoute(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al escanear la fila de la ruta: %w", err)
+		}
+		routes = append(routes, rt)
+	}
+	return routes, rows.Err()
+}
+
+func (r *postgresRepository) FindRouteByID(ctx context.Context, id string) (domain.DispatchedRoute, error) {
+	rt, err := scanRoute(r.db.QueryRow(ctx, `SELECT `+routeColumns+` FROM routes WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.DispatchedRoute{}, ErrRouteNotFound
+		}
+		return domain.DispatchedRoute{}, fmt.Errorf("error al buscar la ruta: %w", err)
+	}
+
+	const stopsSQL = `
+        SELECT s.id, s.sequence, s.kind, s.container_id, s.facility_id,
+               ST_Y(s.location::geometry), ST_X(s.location::geometry),
+               s.planned_eta, s.estimated_load_liters, s.estimated_load_kg,
+               s.status, s.skip_reason, s.notes, s.visited_at, s.collection_id, col.estimated_volume_liters, s.added_at,
+               c.fraction::text, c.capacity_liters, c.current_status::text, c.last_fill_level, c.container_type_id,
+               f.name
+        FROM route_stops s
+        LEFT JOIN collections col ON col.id = s.collection_id
+        LEFT JOIN containers c ON c.id = s.container_id
+        LEFT JOIN facilities f ON f.id = s.facility_id
+        WHERE s.route_id = $1
+        ORDER BY s.sequence`
+	rows, err := r.db.Query(ctx, stopsSQL, id)
+	if err != nil {
+		return domain.DispatchedRoute{}, fmt.Errorf("error al consultar las paradas de la ruta: %w", err)
+	}
+	defer rows.Close()
+
+	rt.Stops = []domain.DispatchedStop{}
+	for rows.Next() {
+		var s domain.DispatchedStop
+		var fraction, status *string
+		var capacity, fillLevel *int
+		var containerTypeID *string
+		err := rows.Scan(&s.ID, &s.Sequence, &s.Kind, &s.ContainerID, &s.FacilityID,
+			&s.Location.Latitude, &s.Location.Longitude,
+			&s.PlannedETA, &s.EstimatedLoadLiters, &s.EstimatedLoadKg,
+			&s.Status, &s.SkipReason, &s.Notes, &s.VisitedAt, &s.CollectionID, &s.CollectedVolumeLiters, &s.AddedAt,
+			&fraction, &capacity, &status, &fillLevel, &containerTypeID,
+			&s.FacilityName)
+		if err != nil {
+			return domain.DispatchedRoute{}, fmt.Errorf("error al escanear la parada: %w", err)
+		}
+		if fraction != nil {
+			s.Container = &domain.StopContainerDetails{
+				Fraction:        domain.Fraction(*fraction),
+				CapacityLiters:  *capacity,
+				ContainerTypeID: containerTypeID,
+			}
+			if status != nil {
+				s.Container.Status = domain.Status(*status)
+			}
+			if fillLevel != nil {
+				s.Container.LastFillLevel = *fillLevel
+			}
+		}
+		rt.Stops = append(rt.Stops, s)
+	}
+	return rt, rows.Err()
+}
+
+func (r *postgresRepository) AssignRoute(ctx context.Context, id string, vehicle, driver *string) error {
+	const query = `UPDATE routes SET vehicle = $1, driver = $2, updated_at = NOW() WHERE id = $3`
+	cmdTag, err := r.db.Exec(ctx, query, vehicle, driver, id)
+	if err != nil {
+		return fmt.Errorf("error al asignar la ruta: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return ErrRouteNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepository) UpdateRouteStatus(ctx context.Context, id string, from []domain.RouteStatus, to domain.RouteStatus, at time.Time) (bool, error) {
+	column := "started_at"
+	if to == domain.RouteStatusCompleted {
+		column = "completed_at"
+	}
+	fromStatuses := make([]string, len(from))
+	for i, s := range from {
+		fromStatuses[i] = string(s)
+	}
+
+	query := `UPDATE routes SET status = $1, ` + column + ` = COALESCE(` + column + `, $2), updated_at = NOW()
+        WHERE id = $3 AND status::text = ANY($4::text[])`
+	cmdTag, err := r.db.Exec(ctx, query, to, at, id, fromStatuses)
+	if err != nil {
+		return false, fmt.Errorf("error al actualizar el estado de la ruta: %w", err)
+	}
+	return cmdTag.RowsAffected() > 0, nil
+}
+
+func (r *postgresRepository) RecordStopResult(ctx context.Context, routeID, stopID string, result StopResult) (*domain.Collection, bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("no se pudo iniciar la transacción: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Reclamamos la parada: si otra petición (un reintento o una doble pulsación) se ha
+	// adelantado, no se registra una segunda recogida.
+	const stopSQL = `
+        UPDATE route_stops
+        SET status = $1, skip_reason = $2, notes = $3, visited_at = $4
+        WHERE id = $5 AND route_id = $6 AND status = 'pending'`
+	var skipReason *string
+	if result.SkipReason != nil {
+		s := string(*result.SkipReason)
+		skipReason = &s
+	}
+	cmdTag, err := tx.Exec(ctx, stopSQL, result.Status, skipReason, result.Notes, result.VisitedAt, stopID, routeID)
+	if err != nil {
+		return nil, false, fmt.Errorf("error al guardar el resultado de la parada: %w", err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		return nil, false, nil
+	}
+
+	// 2. Registramos la recogida y la enlazamos con la parada.
+	var collection *domain.Collection
+	if result.Collection != nil {
+		created, err := container.RecordCollectionTx(ctx, tx, *result.Collection)
+		if err != nil {
+			if errors.Is(err, container.ErrContainerNotFound) {
+				return nil, false, fmt.Errorf("%w: el contenedor de la parada se ha eliminado", ErrInvalidStopResult)
+			}
+			return nil, false, fmt.Errorf("no se pudo registrar la recogida de la parada: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `UPDATE route_stops SET collection_id = $1 WHERE id = $2`, created.ID, stopID); err != nil {
+			return nil, false, fmt.Errorf("error al enlazar la recogida con la parada: %w", err)
+		}
+		collection = &created
+	}
+
+	// 3. La primera parada marcada pone la ruta en curso si el conductor no la había iniciado.
+	const routeSQL = `
+        UPDATE routes
+        SET status = CASE WHEN status = 'planned' THEN 'in_progress'::route_status ELSE status END,
+            started_at = COALESCE(started_at, $1), updated_at = NOW()
+        WHERE id = $2`
+	if _, err := tx.Exec(ctx, routeSQL, result.VisitedAt, routeID); err != nil {
+		return nil, false, fmt.Errorf("error al actualizar la ruta: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("error al confirmar la transacción: %w", err)
+	}
+	return collection, true, nil
+}
+
+func (r *postgresRepository) FindUrgentContainers(ctx context.Context, routeID string, fraction *domain.Fraction, statuses []domain.Status) ([]domain.Container, error) {
+	statusArgs := make([]string, len(statuses))
+	for i, st := range statuses {
+		statusArgs[i] = string(st)
+	}
+	var fractionArg *string
+	if fraction != nil {
+		f := string(*fraction)
+		fractionArg = &f
+	}
+
+	const query = `
+        SELECT c.id, ST_Y(c.location::geometry), ST_X(c.location::geometry), c.capacity_liters,
+               c.current_status, c.last_fill_level, c.last_updated_at, c.fraction
+        FROM containers c
+        WHERE c.current_status::text = ANY($1::text[])
+          AND ($2::text IS NULL OR c.fraction::text = $2)
+          AND NOT EXISTS (SELECT 1 FROM route_stops s WHERE s.route_id = $3 AND s.container_id = c.id)
+          AND NOT EXISTS (
+              SELECT 1 FROM route_stops s
+              JOIN routes r ON r.id = s.route_id
+              WHERE s.container_id = c.id AND s.status = 'pending' AND r.status <> 'completed')
+        ORDER BY c.last_fill_level DESC, c.id`
+	rows, err := r.db.Query(ctx, query, statusArgs, fractionArg, routeID)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar los contenedores urgentes: %w", err)
+	}
+	defer rows.Close()
+
+	var containers []domain.Container
+	for rows.Next() {
+		var c domain.Container
+		if err := rows.Scan(&c.ID, &c.Location.Latitude, &c.Location.Longitude, &c.CapacityLiters,
+			&c.CurrentStatus, &c.LastFillLevel, &c.LastUpdatedAt, &c.Fraction); err != nil {
+			return nil, fmt.Errorf("error al escanear el contenedor: %w", err)
+		}
+		containers = append(containers, c)
+	}
+	return containers, rows.Err()
+}
+
+func (r *postgresRepository) ApplyReroute(ctx context.Context, routeID string, readAt time.Time, sequences map[string]int, added []domain.DispatchedStop, extraDistanceKm float64) ([]string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo iniciar la transacción: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Bloqueamos la ruta: el resultado de una parada también la actualiza, así que no se puede
+	// marcar ninguna mientras tanto. Si se ha modificado desde que se leyó (una parada marcada u
+	// otra re-planificación que ha añadido paradas), el plan calculado ya no vale.
+	var status domain.RouteStatus
+	var updatedAt time.Time
+	err = tx.QueryRow(ctx, `SELECT status, updated_at FROM routes WHERE id = $1 FOR UPDATE`, routeID).Scan(&status, &updatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRouteNotFound
+		}
+		return nil, fmt.Errorf("error al bloquear la ruta: %w", err)
+	}
+	if status != domain.RouteStatusInProgress || !updatedAt.Equal(readAt) {
+		return nil, ErrRouteChanged
+	}
+
+	// Las nuevas posiciones pueden coincidir con las antiguas de otras paradas, así que primero
+	// apartamos las paradas que se mueven a posiciones libres y después las colocamos.
+	stopIDs := make([]string, 0, len(sequences))
+	for id := range sequences {
+		stopIDs = append(stopIDs, id)
+	}
+	const parkSQL = `
+        UPDATE route_stops SET sequence = sequence + 1000000
+        WHERE route_id = $1 AND id::text = ANY($2::text[]) AND status = 'pending'`
+	cmdTag, err := tx.Exec(ctx, parkSQL, routeID, stopIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error al reordenar las paradas: %w", err)
+	}
+	if cmdTag.RowsAffected() != int64(len(stopIDs)) {
+		return nil, ErrRouteChanged
+	}
+
+	batch := &pgx.Batch{}
+	for id, sequence := range sequences {
+		batch.Queue(`UPDATE route_stops SET sequence = $1 WHERE id = $2`, sequence, id)
+	}
+	const stopSQL = `
+        INSERT INTO route_stops (route_id, sequence, kind, container_id, location,
+                                 estimated_load_liters, estimated_load_kg, added_at)
+        VALUES ($1, $2, $3, $4, ST_SetSRID(ST_MakePoint($5, $6), 4326), $7, $8, NOW())
+        RETURNING id`
+	ids := make([]string, len(added))
+	for i, stop := range added {
+		batch.Queue(stopSQL, routeID, stop.Sequence, string(stop.Kind), stop.ContainerID,
+			stop.Location.Longitude, stop.Location.Latitude, stop.EstimatedLoadLiters, stop.EstimatedLoadKg,
+		).QueryRow(func(row pgx.Row) error {
+			return row.Scan(&ids[i])
+		})
+	}
+	batch.Queue(`UPDATE routes SET rerouted_at = NOW(), added_distance_km = added_distance_km + $1, updated_at = NOW() WHERE id = $2`,
+		extraDistanceKm, routeID)
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		// Dos paradas en la misma posición: la ruta ha cambiado entre la lectura y el bloqueo.
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, ErrRouteChanged
+		}
+		return nil, fmt.Errorf("error al guardar la re-planificación: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error al confirmar la transacción: %w", err)
+	}
+	return ids, nil
+}
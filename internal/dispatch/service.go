@@ -0,0 +1,532 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/optimizer"
+	"smart-waste-management/internal/routing"
+	"time"
+)
+
+// uuidPattern valida el formato de los IDs de ruta antes de consultarlos en la BBDD.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+var (
+	// ErrInvalidTransition se devuelve al iniciar o terminar una ruta que no está en el estado adecuado.
+	ErrInvalidTransition = errors.New("la ruta no está en un estado que permita este cambio")
+	// ErrRouteCompleted se devuelve al modificar una ruta que ya ha terminado.
+	ErrRouteCompleted = errors.New("la ruta ya está terminada")
+	// ErrStopAlreadyVisited se devuelve al marcar una parada que ya tiene resultado.
+	ErrStopAlreadyVisited = errors.New("la parada ya tiene un resultado")
+	// ErrInvalidStopResult se devuelve cuando el resultado de una parada no es coherente.
+	ErrInvalidStopResult = errors.New("resultado de parada no válido")
+	// ErrFutureTimestamp se devuelve cuando la hora de inicio, de fin o de una visita está en el futuro.
+	ErrFutureTimestamp = errors.New("la fecha no puede estar en el futuro")
+	// ErrUnsupportedFormat se devuelve al exportar una ruta en un formato desconocido.
+	ErrUnsupportedFormat = errors.New("formato de exportación no soportado")
+	// ErrRouteNotInProgress se devuelve al re-planificar una ruta que no está en curso.
+	ErrRouteNotInProgress = errors.New("solo se pueden re-planificar las rutas en curso")
+)
+
+// PathTracer traza el recorrido por carretera entre dos puntos. Lo implementa routing.Provider.
+type PathTracer interface {
+	ShortestPath(from, to domain.Point) (routing.Path, error)
+}
+
+// RerouteOptions define la re-planificación de una ruta en curso.
+type RerouteOptions struct {
+	// Position es la posición actual del camión.
+	Position domain.Point
+	// Statuses son los estados de los contenedores urgentes; por defecto, 'high'.
+	Statuses []domain.Status
+	// Capacidad del camión en litros y en kilos. Cero significa la capacidad con la que se planificó
+	// la ruta.
+	CapacityLiters float64
+	CapacityKg     float64
+	// MaxDetourKm limita lo que puede alargar la ruta cada contenedor añadido. Cero significa sin límite.
+	MaxDetourKm float64
+	// DryRun calcula los cambios sin guardarlos.
+	DryRun bool
+}
+
+// StopUpdate es el resultado de una parada que envía el conductor.
+type StopUpdate struct {
+	Status     domain.StopStatus
+	SkipReason *domain.SkipReason
+	Notes      *string
+	// VisitedAt es la hora de la visita; si es cero, se usa la hora actual.
+	VisitedAt time.Time
+	// VolumeLiters es el volumen recogido si el conductor lo conoce; si no, se estima a partir del
+	// nivel de llenado previo.
+	VolumeLiters int
+}
+
+// Service define la lógica de negocio de las rutas guardadas y su ejecución.
+type Service interface {
+	GetRoutes(ctx context.Context, filter Filter) ([]domain.DispatchedRoute, error)
+	// GetRoute devuelve la ruta con sus paradas y la comparación entre lo planificado y lo ejecutado.
+	GetRoute(ctx context.Context, id string) (domain.DispatchedRoute, error)
+	// AssignRoute asigna el camión y el conductor de una ruta que no ha terminado.
+	AssignRoute(ctx context.Context, id string, vehicle, driver *string) (domain.DispatchedRoute, error)
+	// StartRoute pone en curso una ruta planificada.
+	StartRoute(ctx context.Context, id string, at time.Time) (domain.DispatchedRoute, error)
+	// CompleteRoute termina una ruta en curso. Las paradas que no se marcaron quedan pendientes.
+	CompleteRoute(ctx context.Context, id string, at time.Time) (domain.DispatchedRoute, error)
+	// UpdateStop guarda el resultado de una parada. Si el contenedor se vació, registra la recogida.
+	UpdateStop(ctx context.Context, routeID, stopID string, update StopUpdate) (domain.DispatchedStop, error)
+	// ExportRoute codifica la ruta en un formato de intercambio (GeoJSON, GPX o KML) con sus paradas
+	// y su trazado.
+	ExportRoute(ctx context.Context, id string, format Format) (Export, error)
+	// Reroute añade a una ruta en curso los contenedores que se han vuelto urgentes, cada uno en la
+	// posición de las paradas pendientes que menos alarga la ruta desde la posición del camión y sin
+	// superar su capacidad. Devuelve las diferencias con la ruta anterior.
+	Reroute(ctx context.Context, id string, opts RerouteOptions) (domain.Reroute, error)
+}
+
+type service struct {
+	repo      Repository
+	distances optimizer.DistanceProvider
+	paths     PathTracer
+}
+
+// NewService crea una nueva instancia del servicio. Si 'paths' es nil, el trazado de las rutas
+// exportadas une las paradas en línea recta.
+func NewService(repo Repository, distances optimizer.DistanceProvider, paths PathTracer) Service {
+	return &service{
+		repo:      repo,
+		distances: distances,
+		paths:     paths,
+	}
+}
+
+func (s *service) GetRoutes(ctx context.Context, filter Filter) ([]domain.DispatchedRoute, error) {
+	return s.repo.FindRoutes(ctx, filter)
+}
+
+// findRoute busca la ruta; un ID con formato inválido no existe.
+func (s *service) findRoute(ctx context.Context, id string) (domain.DispatchedRoute, error) {
+	if !uuidPattern.MatchString(id) {
+		return domain.DispatchedRoute{}, ErrRouteNotFound
+	}
+	return s.repo.FindRouteByID(ctx, id)
+}
+
+func (s *service) GetRoute(ctx context.Context, id string) (domain.DispatchedRoute, error) {
+	route, err := s.findRoute(ctx, id)
+	if err != nil {
+		return domain.DispatchedRoute{}, err
+	}
+	for i, stop := range route.Stops {
+		if stop.VisitedAt != nil && stop.PlannedETA != nil {
+			delay := math.Round(stop.VisitedAt.Sub(*stop.PlannedETA).Minutes()*10) / 10
+			route.Stops[i].DelayMinutes = &delay
+		}
+	}
+	cmp := route.Compare()
+	route.Comparison = &cmp
+	return route, nil
+}
+
+func (s *service) AssignRoute(ctx context.Context, id string, vehicle, driver *string) (domain.DispatchedRoute, error) {
+	route, err := s.findRoute(ctx, id)
+	if err != nil {
+		return domain.DispatchedRoute{}, err
+	}
+	if route.Status == domain.RouteStatusCompleted {
+		return domain.DispatchedRoute{}, ErrRouteCompleted
+	}
+	if err := s.repo.AssignRoute(ctx, id, vehicle, driver); err != nil {
+		return domain.DispatchedRoute{}, err
+	}
+	return s.GetRoute(ctx, id)
+}
+
+func (s *service) StartRoute(ctx context.Context, id string, at time.Time) (domain.DispatchedRoute, error) {
+	return s.transition(ctx, id, []domain.RouteStatus{domain.RouteStatusPlanned}, domain.RouteStatusInProgress, at)
+}
+
+func (s *service) CompleteRoute(ctx context.Context, id string, at time.Time) (domain.DispatchedRoute, error) {
+	return s.transition(ctx, id, []domain.RouteStatus{domain.RouteStatusInProgress}, domain.RouteStatusCompleted, at)
+}
+
+// transition cambia el estado de la ruta si está en uno de los estados 'from'.
+func (s *service) transition(ctx context.Context, id string, from []domain.RouteStatus, to domain.RouteStatus, at time.Time) (domain.DispatchedRoute, error) {
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	if at.After(time.Now().Add(time.Minute)) {
+		return domain.DispatchedRoute{}, ErrFutureTimestamp
+	}
+	if !uuidPattern.MatchString(id) {
+		return domain.DispatchedRoute{}, ErrRouteNotFound
+	}
+
+	ok, err := s.repo.UpdateRouteStatus(ctx, id, from, to, at)
+	if err != nil {
+		return domain.DispatchedRoute{}, err
+	}
+	route, err := s.GetRoute(ctx, id)
+	if err != nil {
+		return domain.DispatchedRoute{}, err
+	}
+	if !ok {
+		return domain.DispatchedRoute{}, fmt.Errorf("%w: la ruta está en estado '%s'", ErrInvalidTransition, route.Status)
+	}
+	return route, nil
+}
+
+func (s *service) UpdateStop(ctx context.Context, routeID, stopID string, update StopUpdate) (domain.DispatchedStop, error) {
+	// 1. Validar el resultado.
+	if update.VisitedAt.IsZero() {
+		update.VisitedAt = time.Now().UTC()
+	}
+	if err := validateStopUpdate(update); err != nil {
+		return domain.DispatchedStop{}, err
+	}
+
+	// 2. Comprobar que la parada se puede marcar.
+	route, err := s.findRoute(ctx, routeID)
+	if err != nil {
+		return domain.DispatchedStop{}, err
+	}
+	if route.Status == domain.RouteStatusCompleted {
+		return domain.DispatchedStop{}, ErrRouteCompleted
+	}
+	idx := -1
+	for i, stop := range route.Stops {
+		if stop.ID == stopID {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return domain.DispatchedStop{}, ErrStopNotFound
+	}
+	stop := route.Stops[idx]
+	switch {
+	case stop.Kind != domain.StopContainer:
+		return domain.DispatchedStop{}, fmt.Errorf("%w: solo se marcan las paradas de recogida de contenedores", ErrInvalidStopResult)
+	case stop.ContainerID == nil:
+		return domain.DispatchedStop{}, fmt.Errorf("%w: el contenedor de la parada se ha eliminado", ErrInvalidStopResult)
+	case stop.Status.IsVisited():
+		return domain.DispatchedStop{}, ErrStopAlreadyVisited
+	}
+
+	// 3. Guardar el resultado y, si el contenedor se vació, la recogida a nombre del conductor (o, si
+	// no hay, del camión), salvo que otra petición se haya adelantado.
+	result := StopResult{Status: update.Status, SkipReason: update.SkipReason, Notes: update.Notes, VisitedAt: update.VisitedAt}
+	if update.Status.IsCollected() {
+		collectedBy := route.Driver
+		if collectedBy == nil {
+			collectedBy = route.Vehicle
+		}
+		result.Collection = &domain.Collection{
+			ContainerID:           *stop.ContainerID,
+			CollectedAt:           update.VisitedAt,
+			CollectedBy:           collectedBy,
+			EstimatedVolumeLiters: update.VolumeLiters,
+		}
+	}
+	collection, ok, err := s.repo.RecordStopResult(ctx, routeID, stopID, result)
+	if err != nil {
+		return domain.DispatchedStop{}, err
+	}
+	if !ok {
+		return domain.DispatchedStop{}, ErrStopAlreadyVisited
+	}
+
+	stop.Status, stop.SkipReason, stop.Notes = result.Status, result.SkipReason, result.Notes
+	stop.VisitedAt = &result.VisitedAt
+	if collection != nil {
+		stop.CollectionID, stop.CollectedVolumeLiters = &collection.ID, &collection.EstimatedVolumeLiters
+	}
+	if stop.PlannedETA != nil {
+		delay := math.Round(result.VisitedAt.Sub(*stop.PlannedETA).Minutes()*10) / 10
+		stop.DelayMinutes = &delay
+	}
+	return stop, nil
+}
+
+func (s *service) ExportRoute(ctx context.Context, id string, format Format) (Export, error) {
+	ef, ok := exportFormats[format]
+	if !ok {
+		return Export{}, fmt.Errorf("%w: '%s'", ErrUnsupportedFormat, format)
+	}
+	route, err := s.GetRoute(ctx, id)
+	if err != nil {
+		return Export{}, err
+	}
+	body, err := ef.encode(route, s.track(route))
+	if err != nil {
+		return Export{}, fmt.Errorf("no se pudo codificar la ruta en %s: %w", format, err)
+	}
+	return Export{
+		ContentType: ef.contentType,
+		FileName:    fmt.Sprintf("ruta-%s.%s", route.ID, ef.extension),
+		Body:        body,
+	}, nil
+}
+
+// track es el trazado de la ruta: desde el punto de salida, por todas las paradas en orden y, si
+// sale de un depósito, de vuelta a él. Cada tramo sigue la red viaria si hay una cargada y el
+// trayecto existe; si no, es una línea recta.
+func (s *service) track(route domain.DispatchedRoute) []domain.Point {
+	waypoints := make([]domain.Point, 0, len(route.Stops)+2)
+	waypoints = append(waypoints, route.StartPoint)
+	for _, stop := range route.Stops {
+		waypoints = append(waypoints, stop.Location)
+	}
+	if route.DepotID != nil {
+		waypoints = append(waypoints, route.StartPoint)
+	}
+
+	track := []domain.Point{waypoints[0]}
+	for i := 1; i < len(waypoints); i++ {
+		from, to := waypoints[i-1], waypoints[i]
+		if from == to {
+			continue
+		}
+		if s.paths != nil {
+			if path, err := s.paths.ShortestPath(from, to); err == nil {
+				// El primer punto del trayecto es el último del tramo anterior.
+				track = append(track, path.Points[1:]...)
+				continue
+			}
+		}
+		track = append(track, to)
+	}
+	return track
+}
+
+func (s *service) Reroute(ctx context.Context, id string, opts RerouteOptions) (domain.Reroute, error) {
+	// 1. Separar lo ya hecho de lo que queda: las paradas de contenedor sin marcar y las descargas
+	// posteriores a la última parada visitada.
+	route, err := s.findRoute(ctx, id)
+	if err != nil {
+		return domain.Reroute{}, err
+	}
+	if route.Status != domain.RouteStatusInProgress {
+		return domain.Reroute{}, fmt.Errorf("%w: la ruta está en estado '%s'", ErrRouteNotInProgress, route.Status)
+	}
+	if len(opts.Statuses) == 0 {
+		opts.Statuses = []domain.Status{domain.StatusHigh}
+	}
+	var lastVisited int
+	for _, stop := range route.Stops {
+		if stop.Status.IsVisited() {
+			lastVisited = max(lastVisited, stop.Sequence)
+		}
+	}
+	var remaining []domain.DispatchedStop
+	for _, stop := range route.Stops {
+		if stop.Status == domain.StopPending && (stop.Kind == domain.StopContainer || stop.Sequence > lastVisited) {
+			remaining = append(remaining, stop)
+		}
+	}
+
+	// 2. Buscar los contenedores urgentes que no están ya en una ruta.
+	candidates, err := s.repo.FindUrgentContainers(ctx, id, route.Fraction, opts.Statuses)
+	if err != nil {
+		return domain.Reroute{}, err
+	}
+
+	// 3. Matriz de distancias: [posición, paradas pendientes..., regreso al depósito?, candidatos...].
+	points := []domain.Point{opts.Position}
+	for _, stop := range remaining {
+		points = append(points, stop.Location)
+	}
+	tour := make([]int, len(points))
+	for i := range tour {
+		tour[i] = i
+	}
+	closed := route.DepotID != nil
+	if closed {
+		tour = append(tour, len(points))
+		points = append(points, route.StartPoint)
+	}
+	firstCandidate := len(points)
+	nodes := make([]int, len(candidates))
+	for i, c := range candidates {
+		nodes[i] = len(points)
+		points = append(points, c.Location)
+	}
+	m, err := s.distances.DistanceMatrix(ctx, points)
+	if err != nil {
+		return domain.Reroute{}, fmt.Errorf("no se pudieron calcular las distancias: %w", err)
+	}
+
+	// 4. Insertar los candidatos donde menos alarguen la ruta, sin superar la capacidad de cada viaje.
+	// La capacidad es la del camión con la que se planificó la ruta, salvo que se indique otra.
+	capacityLiters, capacityKg := route.CapacityLiters, route.CapacityKg
+	if opts.CapacityLiters > 0 {
+		capacityLiters = opts.CapacityLiters
+	}
+	if opts.CapacityKg > 0 {
+		capacityKg = opts.CapacityKg
+	}
+	constraints := optimizer.InsertionConstraints{
+		Demands:     make([][]float64, len(points)),
+		Capacity:    []float64{capacityOrInf(capacityLiters), capacityOrInf(capacityKg)},
+		InitialLoad: currentLoad(route.Stops, lastVisited),
+		Unloads:     make(map[int]bool),
+		MaxCost:     opts.MaxDetourKm,
+		FixedEnd:    closed,
+	}
+	for i := range constraints.Demands {
+		constraints.Demands[i] = make([]float64, 2)
+	}
+	for k, stop := range remaining {
+		if stop.Kind == domain.StopUnload {
+			constraints.Unloads[k+1] = true
+			continue
+		}
+		constraints.Demands[k+1] = []float64{stop.EstimatedLoadLiters, stop.EstimatedLoadKg}
+	}
+	urgent := make([]domain.RouteStop, len(candidates))
+	for i := range candidates {
+		urgent[i] = domain.RouteStop{Kind: domain.StopContainer, Container: &candidates[i]}
+		urgent[i].EstimateLoad()
+		constraints.Demands[nodes[i]] = []float64{urgent[i].EstimatedLoadLiters, urgent[i].EstimatedLoadKg}
+	}
+	newTour, unassigned := optimizer.CheapestInsertion(m, tour, nodes, constraints)
+
+	// 5. Calcular las diferencias: nuevas posiciones, paradas añadidas y kilómetros de más.
+	result := domain.Reroute{
+		RouteID:                   id,
+		Position:                  opts.Position,
+		AddedStops:                []domain.DispatchedStop{},
+		SequenceChanges:           []domain.SequenceChange{},
+		RemainingStops:            []domain.DispatchedStop{},
+		Unassigned:                []domain.RerouteCandidate{},
+		RemainingDistanceBeforeKm: math.Round(optimizer.TourLength(m, tour)*100) / 100,
+		RemainingDistanceAfterKm:  math.Round(optimizer.TourLength(m, newTour)*100) / 100,
+	}
+	result.ExtraDistanceKm = math.Round((result.RemainingDistanceAfterKm-result.RemainingDistanceBeforeKm)*100) / 100
+
+	sequences := make(map[string]int)
+	var addedAt []int // posiciones de las paradas añadidas en RemainingStops
+	sequence := lastVisited
+	for _, node := range newTour[1:] {
+		if closed && node == firstCandidate-1 {
+			continue
+		}
+		sequence++
+		if node < firstCandidate {
+			stop := remaining[node-1]
+			if stop.Sequence != sequence {
+				result.SequenceChanges = append(result.SequenceChanges, domain.SequenceChange{
+					StopID: stop.ID, ContainerID: stop.ContainerID, From: stop.Sequence, To: sequence,
+				})
+				sequences[stop.ID] = sequence
+			}
+			stop.Sequence = sequence
+			result.RemainingStops = append(result.RemainingStops, stop)
+			continue
+		}
+		c := urgent[node-firstCandidate]
+		stop := domain.DispatchedStop{
+			Sequence:            sequence,
+			Kind:                domain.StopContainer,
+			ContainerID:         &c.Container.ID,
+			Location:            c.Container.Location,
+			EstimatedLoadLiters: c.EstimatedLoadLiters,
+			EstimatedLoadKg:     c.EstimatedLoadKg,
+			Status:              domain.StopPending,
+			Container: &domain.StopContainerDetails{
+				Fraction:        c.Container.Fraction,
+				CapacityLiters:  c.Container.CapacityLiters,
+				Status:          c.Container.CurrentStatus,
+				LastFillLevel:   c.Container.LastFillLevel,
+				ContainerTypeID: c.Container.ContainerTypeID,
+			},
+		}
+		result.AddedStops = append(result.AddedStops, stop)
+		addedAt = append(addedAt, len(result.RemainingStops))
+		result.RemainingStops = append(result.RemainingStops, stop)
+	}
+
+	reason := "no cabe en la capacidad que le queda al camión"
+	if opts.MaxDetourKm > 0 {
+		reason = fmt.Sprintf("no cabe en la capacidad que le queda al camión o alarga la ruta más de %g km", opts.MaxDetourKm)
+	}
+	for _, node := range unassigned {
+		c := urgent[node-firstCandidate]
+		result.Unassigned = append(result.Unassigned, domain.RerouteCandidate{
+			ContainerID:         c.Container.ID,
+			Location:            c.Container.Location,
+			Status:              c.Container.CurrentStatus,
+			LastFillLevel:       c.Container.LastFillLevel,
+			EstimatedLoadLiters: c.EstimatedLoadLiters,
+			EstimatedLoadKg:     c.EstimatedLoadKg,
+			Reason:              reason,
+		})
+	}
+
+	// 6. Guardar los cambios, salvo en modo de prueba o si no hay nada que añadir.
+	if opts.DryRun || len(result.AddedStops) == 0 {
+		return result, nil
+	}
+	ids, err := s.repo.ApplyReroute(ctx, id, route.UpdatedAt, sequences, result.AddedStops, result.ExtraDistanceKm)
+	if err != nil {
+		return domain.Reroute{}, err
+	}
+	now := time.Now().UTC()
+	for i := range result.AddedStops {
+		result.AddedStops[i].ID, result.AddedStops[i].AddedAt = ids[i], &now
+		result.RemainingStops[addedAt[i]] = result.AddedStops[i]
+	}
+	result.Applied = true
+	return result, nil
+}
+
+// capacityOrInf convierte una capacidad no indicada (cero) en ilimitada.
+func capacityOrInf(capacity float64) float64 {
+	if capacity <= 0 {
+		return math.Inf(1)
+	}
+	return capacity
+}
+
+// currentLoad estima la carga del camión: lo recogido desde la última descarga ya pasada. Se usa el
+// volumen registrado en la recogida si se conoce y, si no, la carga estimada al planificar. Las
+// paradas están en orden de visita.
+func currentLoad(stops []domain.DispatchedStop, lastVisited int) []float64 {
+	load := make([]float64, 2)
+	for _, stop := range stops {
+		if stop.Sequence > lastVisited {
+			break
+		}
+		switch {
+		case stop.Kind == domain.StopUnload:
+			load[0], load[1] = 0, 0
+		case stop.Status.IsCollected() && stop.CollectedVolumeLiters != nil && stop.Container != nil:
+			liters := float64(*stop.CollectedVolumeLiters)
+			load[0] += liters
+			load[1] += liters * stop.Container.Fraction.DensityKgPerLiter()
+		case stop.Status.IsCollected():
+			load[0] += stop.EstimatedLoadLiters
+			load[1] += stop.EstimatedLoadKg
+		}
+	}
+	return load
+}
+
+// validateStopUpdate comprueba las reglas que no cubre la validación de la petición.
+func validateStopUpdate(u StopUpdate) error {
+	switch {
+	case !u.Status.IsVisited():
+		return fmt.Errorf("%w: el resultado debe ser 'collected', 'skipped' u 'overflowing'", ErrInvalidStopResult)
+	case u.Status == domain.StopSkipped && u.SkipReason == nil:
+		return fmt.Errorf("%w: indica el motivo ('blocked' o 'inaccessible') de la parada no recogida", ErrInvalidStopResult)
+	case u.Status != domain.StopSkipped && u.SkipReason != nil:
+		return fmt.Errorf("%w: solo las paradas no recogidas tienen motivo", ErrInvalidStopResult)
+	case u.Status == domain.StopSkipped && u.VolumeLiters > 0:
+		return fmt.Errorf("%w: una parada no recogida no tiene volumen", ErrInvalidStopResult)
+	case u.VisitedAt.After(time.Now().Add(time.Minute)):
+		return ErrFutureTimestamp
+	}
+	return nil
+}
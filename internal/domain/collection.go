@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// Umbrales de la detección automática de vaciados: una lectura se interpreta como un vaciado
+// si el nivel baja al menos EmptyingMinDrop puntos y queda en EmptyingMaxResidual% o menos.
+const (
+	EmptyingMinDrop     = 40
+	EmptyingMaxResidual = 15
+)
+
+// Collection es el registro de que un camión vació un contenedor.
+type Collection struct {
+	ID          string    `json:"id"`
+	ContainerID string    `json:"container_id"`
+	CollectedAt time.Time `json:"collected_at"`
+	// CollectedBy identifica al operario o camión que hizo la recogida. Es nil en las recogidas inferidas.
+	CollectedBy *string `json:"collected_by,omitempty"`
+	// EstimatedVolumeLiters es el volumen recogido, informado por el operario o estimado
+	// a partir del nivel de llenado previo y de la capacidad del contenedor.
+	EstimatedVolumeLiters int `json:"estimated_volume_liters"`
+	// FillLevelBefore es el nivel de llenado (%) que tenía el contenedor antes de vaciarse.
+	FillLevelBefore int `json:"fill_level_before"`
+	// Inferred indica que la recogida no se registró explícitamente, sino que se dedujo
+	// de una caída brusca del nivel de llenado.
+	Inferred  bool      `json:"inferred"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsEmptyingDrop determina si el paso de un nivel de llenado a otro indica que el contenedor se ha vaciado.
+func IsEmptyingDrop(previousLevel, currentLevel int) bool {
+	return previousLevel-currentLevel >= EmptyingMinDrop && currentLevel <= EmptyingMaxResidual
+}
+
+// EstimateVolumeLiters estima el volumen de residuo de un contenedor a partir de su nivel de llenado.
+func EstimateVolumeLiters(fillLevel, capacityLiters int) int {
+	return fillLevel * capacityLiters / 100
+}
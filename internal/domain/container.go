@@ -1,6 +1,11 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,12 +21,85 @@ const (
 	StatusHigh   Status = "high"
 )
 
+// IsValid comprueba si el estado es uno de los conocidos.
+func (s Status) IsValid() bool {
+	switch s {
+	case StatusLow, StatusMedium, StatusHigh:
+		return true
+	}
+	return false
+}
+
 // Point representa una coordenada geográfica.
 type Point struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 }
 
+// BoundingBox es un rectángulo en longitud y latitud (p. ej. la parte del mapa que se está viendo).
+type BoundingBox struct {
+	MinLongitude float64 `json:"min_longitude"`
+	MinLatitude  float64 `json:"min_latitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+}
+
+// Contains indica si el punto está dentro del rectángulo (bordes incluidos).
+func (b BoundingBox) Contains(p Point) bool {
+	return p.Longitude >= b.MinLongitude && p.Longitude <= b.MaxLongitude &&
+		p.Latitude >= b.MinLatitude && p.Latitude <= b.MaxLatitude
+}
+
+// ParseBoundingBox lee un rectángulo con el formato "minLon,minLat,maxLon,maxLat". No admite
+// rectángulos que crucen el antimeridiano.
+func ParseBoundingBox(value string) (BoundingBox, error) {
+	coords, err := parseCoordinates(value, 4)
+	if err != nil {
+		return BoundingBox{}, fmt.Errorf("debe tener el formato minLon,minLat,maxLon,maxLat: %w", err)
+	}
+	b := BoundingBox{MinLongitude: coords[0], MinLatitude: coords[1], MaxLongitude: coords[2], MaxLatitude: coords[3]}
+	switch {
+	case !validLongitude(b.MinLongitude) || !validLongitude(b.MaxLongitude) ||
+		!validLatitude(b.MinLatitude) || !validLatitude(b.MaxLatitude):
+		return BoundingBox{}, errors.New("tiene coordenadas fuera de rango")
+	case b.MinLongitude >= b.MaxLongitude || b.MinLatitude >= b.MaxLatitude:
+		return BoundingBox{}, errors.New("debe tener el mínimo menor que el máximo (no se admiten zonas que crucen el antimeridiano)")
+	}
+	return b, nil
+}
+
+// ParsePoint lee un punto con el formato "lat,lon".
+func ParsePoint(value string) (Point, error) {
+	coords, err := parseCoordinates(value, 2)
+	if err != nil {
+		return Point{}, fmt.Errorf("debe tener el formato lat,lon: %w", err)
+	}
+	if !validLatitude(coords[0]) || !validLongitude(coords[1]) {
+		return Point{}, errors.New("tiene coordenadas fuera de rango")
+	}
+	return Point{Latitude: coords[0], Longitude: coords[1]}, nil
+}
+
+// parseCoordinates lee una lista de n números separados por comas.
+func parseCoordinates(value string, n int) ([]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("se esperaban %d valores y hay %d", n, len(parts))
+	}
+	coords := make([]float64, n)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("'%s' no es un número", part)
+		}
+		coords[i] = v
+	}
+	return coords, nil
+}
+
+func validLatitude(v float64) bool  { return v >= -90 && v <= 90 }
+func validLongitude(v float64) bool { return v >= -180 && v <= 180 }
+
 // Container representa la entidad principal de nuestro dominio.
 // Contiene la información estática y el estado actual de un contenedor de basura.
 type Container struct {
@@ -31,6 +109,18 @@ type Container struct {
 	CurrentStatus  Status    `json:"status,omitempty"` // omitempty porque no se establece al crear
 	LastFillLevel  int       `json:"last_fill_level,omitempty"`
 	LastUpdatedAt  time.Time `json:"last_updated,omitempty"`
+	// Fraction es la fracción de residuo que recoge el contenedor.
+	Fraction Fraction `json:"fraction"`
+	// ContainerTypeID referencia el modelo de contenedor (volumen y sistema de elevación), si se conoce.
+	ContainerTypeID *string `json:"container_type_id,omitempty"`
+	// ThresholdProfileID referencia el perfil de umbrales del contenedor. Si es nil se usan los umbrales por defecto.
+	ThresholdProfileID *string `json:"threshold_profile_id,omitempty"`
+	// TimeWindowProfileID referencia las franjas horarias en las que se puede recoger. Si es nil, a cualquier hora.
+	TimeWindowProfileID *string `json:"time_window_profile_id,omitempty"`
+	// Forecast es la predicción de llenado calculada a partir del historial. No se persiste.
+	Forecast *Forecast `json:"forecast,omitempty"`
+	// DistanceMeters es la distancia al punto de una búsqueda por radio. No se persiste.
+	DistanceMeters *float64 `json:"distance_meters,omitempty"`
 
 	// --- CAMPOS ACTUALIZADOS ---
 	// Estos campos son gestionados por la base de datos y son cruciales para el tracking.
@@ -46,20 +136,102 @@ type Reading struct {
 	Timestamp   time.Time `json:"timestamp"`
 }
 
+// ContainerUpdate es el cambio del estado actual de un contenedor al aplicar una lectura.
+type ContainerUpdate struct {
+	ContainerID       string   `json:"container_id"`
+	Location          Point    `json:"location"`
+	Fraction          Fraction `json:"fraction"`
+	PreviousFillLevel int      `json:"previous_fill_level"`
+	FillLevel         int      `json:"fill_level"`
+	PreviousStatus    Status   `json:"previous_status"`
+	Status            Status   `json:"status"`
+	// RecordedAt es la marca de tiempo de la lectura aplicada.
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// Changed indica si la lectura ha cambiado el nivel de llenado o el estado del contenedor.
+func (u ContainerUpdate) Changed() bool {
+	return u.FillLevel != u.PreviousFillLevel || u.StatusChanged()
+}
+
+// StatusChanged indica si el contenedor ha pasado a otro estado.
+func (u ContainerUpdate) StatusChanged() bool {
+	return u.Status != u.PreviousStatus
+}
+
+// ReadingBucket resume las lecturas de un contenedor en un intervalo de tiempo [Start, End).
+type ReadingBucket struct {
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	MinFillLevel  int       `json:"min_fill_level"`
+	MaxFillLevel  int       `json:"max_fill_level"`
+	AvgFillLevel  float64   `json:"avg_fill_level"`
+	LastFillLevel int       `json:"last_fill_level"`
+	// Readings es el número de lecturas del intervalo.
+	Readings int `json:"readings"`
+}
+
+// ReadingOutcome indica qué efecto tuvo una lectura al persistirse.
+type ReadingOutcome string
+
+const (
+	// OutcomeApplied: la lectura es la más reciente y ha actualizado el estado actual del contenedor.
+	OutcomeApplied ReadingOutcome = "applied"
+	// OutcomeHistory: la lectura llegó fuera de orden (es más antigua que el estado actual)
+	// y solo se ha guardado en el historial.
+	OutcomeHistory ReadingOutcome = "history"
+	// OutcomeDiscarded: ya existía una lectura del mismo contenedor con la misma marca de tiempo.
+	OutcomeDiscarded ReadingOutcome = "discarded"
+)
+
+// Thresholds define a partir de qué nivel de llenado (en %) un contenedor pasa a 'medium' y a 'high'.
+type Thresholds struct {
+	MediumAt int `json:"medium_at"`
+	HighAt   int `json:"high_at"`
+}
+
+// DefaultThresholds son los umbrales que se aplican a los contenedores sin perfil propio.
+var DefaultThresholds = Thresholds{MediumAt: 40, HighAt: 80}
+
+// ThresholdProfile es un conjunto de umbrales con nombre que se puede asignar a varios contenedores
+// (p. ej. "orgánico verano" o "iglú de vidrio").
+type ThresholdProfile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Thresholds
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 // === Lógica de Negocio Pura ===
 
 // CalculateStatus determina el estado del contenedor ('low', 'medium', 'high')
-// basándose en su nivel de llenado.
+// basándose en su nivel de llenado y en los umbrales por defecto.
 func CalculateStatus(fillLevel int) Status {
-	if fillLevel >= 80 {
+	return DefaultThresholds.StatusFor(fillLevel)
+}
+
+// StatusFor determina el estado del contenedor para un nivel de llenado según estos umbrales.
+func (t Thresholds) StatusFor(fillLevel int) Status {
+	if fillLevel >= t.HighAt {
 		return StatusHigh
 	}
-	if fillLevel >= 40 {
+	if fillLevel >= t.MediumAt {
 		return StatusMedium
 	}
 	return StatusLow
 }
 
+// IsValid comprueba que los umbrales son coherentes: 0 < medium < high <= 100.
+func (t Thresholds) IsValid() bool {
+	return t.MediumAt > 0 && t.MediumAt < t.HighAt && t.HighAt <= 100
+}
+
+// MaxClockSkew es cuánto puede adelantarse el reloj de un sensor o de un gateway respecto al del
+// servidor. Una lectura con una fecha posterior se rechaza: pasaría a ser la última del contenedor
+// y las lecturas reales, más antiguas, ya no actualizarían su estado.
+const MaxClockSkew = 5 * time.Minute
+
 // IsValid comprueba si los datos de una nueva lectura son válidos.
 func (r *Reading) IsValid() bool {
 	if r.ContainerID == "" {
@@ -71,5 +243,8 @@ func (r *Reading) IsValid() bool {
 	if r.Timestamp.IsZero() {
 		return false
 	}
+	if r.Timestamp.After(time.Now().Add(MaxClockSkew)) {
+		return false
+	}
 	return true
 }
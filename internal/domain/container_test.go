@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadingIsValid(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		reading Reading
+		want    bool
+	}{
+		{"lectura válida", Reading{ContainerID: "c", FillLevel: 50, Timestamp: now}, true},
+		{"nivel 0 y 100", Reading{ContainerID: "c", FillLevel: 100, Timestamp: now.Add(-time.Hour)}, true},
+		{"sin contenedor", Reading{FillLevel: 50, Timestamp: now}, false},
+		{"nivel negativo", Reading{ContainerID: "c", FillLevel: -1, Timestamp: now}, false},
+		{"nivel mayor que 100", Reading{ContainerID: "c", FillLevel: 101, Timestamp: now}, false},
+		{"sin fecha", Reading{ContainerID: "c", FillLevel: 50}, false},
+		{"reloj algo adelantado", Reading{ContainerID: "c", FillLevel: 50, Timestamp: now.Add(MaxClockSkew - time.Minute)}, true},
+		{"fecha futura", Reading{ContainerID: "c", FillLevel: 50, Timestamp: now.Add(MaxClockSkew + time.Minute)}, false},
+		{"año 2099", Reading{ContainerID: "c", FillLevel: 50, Timestamp: time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.reading.IsValid(); got != tt.want {
+				t.Errorf("IsValid() = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package domain
+
+import "time"
+
+// Fraction es la fracción de residuo que recoge un contenedor.
+// Cada camión recoge una única fracción, así que las rutas se generan por fracción.
+type Fraction string
+
+// Constantes que definen las fracciones de residuo.
+// Corresponden al tipo ENUM 'waste_fraction' en la base de datos.
+const (
+	FractionOrganic   Fraction = "organic"
+	FractionPaper     Fraction = "paper"
+	FractionPackaging Fraction = "packaging" // Plásticos, latas y briks.
+	FractionGlass     Fraction = "glass"
+	FractionResidual  Fraction = "residual"
+	FractionTextile   Fraction = "textile"
+)
+
+// DefaultFraction es la fracción que se asigna a los contenedores que no indican ninguna.
+const DefaultFraction = FractionResidual
+
+// Fractions devuelve todas las fracciones conocidas.
+func Fractions() []Fraction {
+	return []Fraction{FractionOrganic, FractionPaper, FractionPackaging, FractionGlass, FractionResidual, FractionTextile}
+}
+
+// IsValid comprueba si la fracción es una de las conocidas.
+func (f Fraction) IsValid() bool {
+	for _, known := range Fractions() {
+		if f == known {
+			return true
+		}
+	}
+	return false
+}
+
+// fractionDensityKgPerLiter es la densidad aparente típica de cada fracción dentro del contenedor (sin compactar).
+var fractionDensityKgPerLiter = map[Fraction]float64{
+	FractionOrganic:   0.40,
+	FractionPaper:     0.07,
+	FractionPackaging: 0.03,
+	FractionGlass:     0.30,
+	FractionResidual:  0.12,
+	FractionTextile:   0.15,
+}
+
+// DensityKgPerLiter devuelve la densidad aparente típica de la fracción, para estimar
+// el peso de los residuos a partir de su volumen. Las fracciones desconocidas usan la del resto.
+func (f Fraction) DensityKgPerLiter() float64 {
+	if d, ok := fractionDensityKgPerLiter[f]; ok {
+		return d
+	}
+	return fractionDensityKgPerLiter[FractionResidual]
+}
+
+// LiftMechanism es el sistema de elevación con el que el camión vacía el contenedor.
+// Determina qué camiones pueden recoger un contenedor.
+type LiftMechanism string
+
+// Constantes que definen los sistemas de elevación.
+// Corresponden al tipo ENUM 'lift_mechanism' en la base de datos.
+const (
+	LiftRearLoader  LiftMechanism = "rear_loader"  // Carga trasera (contenedores de 2 o 4 ruedas).
+	LiftSideLoader  LiftMechanism = "side_loader"  // Carga lateral automatizada.
+	LiftFrontLoader LiftMechanism = "front_loader" // Carga frontal.
+	LiftCrane       LiftMechanism = "crane"        // Grúa con gancho (iglús y soterrados).
+)
+
+// ContainerType describe un modelo de contenedor: su volumen y cómo se vacía.
+type ContainerType struct {
+	ID            string        `json:"id"`
+	Model         string        `json:"model"`
+	VolumeLiters  int           `json:"volume_liters"`
+	LiftMechanism LiftMechanism `json:"lift_mechanism"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// SensorDevice representa un sensor LoRaWAN instalado en un contenedor.
+// El DevEUI identifica al dispositivo en el servidor de red; el resto de campos
+// indican cómo decodificar sus uplinks y convertir la distancia medida en porcentaje de llenado.
+type SensorDevice struct {
+	DevEUI      string `json:"dev_eui"`
+	ContainerID string `json:"container_id"`
+	Decoder     string `json:"decoder"`
+	// Distancia (en mm) que mide el sensor ultrasónico con el contenedor vacío y lleno.
+	EmptyDistanceMM int `json:"empty_distance_mm"`
+	FullDistanceMM  int `json:"full_distance_mm"`
+
+	// Telemetría del propio dispositivo, actualizada con cada uplink.
+	// La batería se guarda en mV o en porcentaje, según de cuál informe el modelo de sensor.
+	LastBatteryMV      *int       `json:"last_battery_mv,omitempty"`
+	LastBatteryPercent *int       `json:"last_battery_percent,omitempty"`
+	LastTemperatureC   *float64   `json:"last_temperature_c,omitempty"`
+	LastSeenAt         *time.Time `json:"last_seen_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FillLevelFromDistance convierte la distancia medida por un sensor ultrasónico en porcentaje de llenado,
+// interpolando entre la distancia con el contenedor vacío y con el contenedor lleno.
+func (d *SensorDevice) FillLevelFromDistance(distanceMM int) int {
+	span := d.EmptyDistanceMM - d.FullDistanceMM
+	if span <= 0 {
+		return 0
+	}
+	level := (d.EmptyDistanceMM - distanceMM) * 100 / span
+	if level < 0 {
+		return 0
+	}
+	if level > 100 {
+		return 100
+	}
+	return level
+}
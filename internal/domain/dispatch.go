@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// RouteStatus es el estado de ejecución de una ruta guardada.
+type RouteStatus string
+
+const (
+	RouteStatusPlanned    RouteStatus = "planned"
+	RouteStatusInProgress RouteStatus = "in_progress"
+	RouteStatusCompleted  RouteStatus = "completed"
+)
+
+// IsValid comprueba si el estado es uno de los conocidos.
+func (s RouteStatus) IsValid() bool {
+	switch s {
+	case RouteStatusPlanned, RouteStatusInProgress, RouteStatusCompleted:
+		return true
+	}
+	return false
+}
+
+// StopStatus es el resultado de una parada, tal como lo marca el conductor.
+type StopStatus string
+
+const (
+	// StopPending: la parada aún no se ha visitado.
+	StopPending StopStatus = "pending"
+	// StopCollected: el contenedor se ha vaciado.
+	StopCollected StopStatus = "collected"
+	// StopSkipped: no se ha podido recoger el contenedor (ver SkipReason).
+	StopSkipped StopStatus = "skipped"
+	// StopOverflowing: el contenedor se ha vaciado, pero estaba desbordado.
+	StopOverflowing StopStatus = "overflowing"
+)
+
+// IsVisited indica si el conductor ya ha marcado la parada.
+func (s StopStatus) IsVisited() bool { return s != StopPending }
+
+// IsCollected indica si el contenedor se vació en la parada (y, por tanto, hay una recogida registrada).
+func (s StopStatus) IsCollected() bool { return s == StopCollected || s == StopOverflowing }
+
+// SkipReason es el motivo por el que no se ha podido recoger un contenedor.
+type SkipReason string
+
+const (
+	// SkipBlocked: el acceso al contenedor está bloqueado (p. ej. un vehículo aparcado delante).
+	SkipBlocked SkipReason = "blocked"
+	// SkipInaccessible: el contenedor no es accesible (p. ej. calle cortada, obras o contenedor dañado).
+	SkipInaccessible SkipReason = "inaccessible"
+)
+
+// DispatchedRoute es una ruta guardada para su ejecución: lo planificado, el camión y el conductor
+// asignados y el resultado de cada parada.
+type DispatchedRoute struct {
+	ID       string      `json:"id"`
+	Status   RouteStatus `json:"status"`
+	Vehicle  *string     `json:"vehicle,omitempty"`
+	Driver   *string     `json:"driver,omitempty"`
+	Fraction *Fraction   `json:"fraction,omitempty"`
+	DepotID  *string     `json:"depot_id,omitempty"`
+	// StartPoint es el punto de salida de la ruta (el depósito, si sale de uno).
+	StartPoint Point `json:"start_point"`
+	// Datos planificados al generar la ruta.
+	PlannedStartAt         time.Time `json:"planned_start_at"`
+	PlannedEndAt           time.Time `json:"planned_end_at"`
+	PlannedDistanceKm      float64   `json:"planned_distance_km"`
+	PlannedDurationMinutes float64   `json:"planned_duration_minutes"`
+	PlannedLoadLiters      float64   `json:"planned_load_liters"`
+	PlannedLoadKg          float64   `json:"planned_load_kg"`
+	// Capacidad del camión en litros y en kilos con la que se planificó la ruta. Cero significa sin límite.
+	CapacityLiters float64 `json:"capacity_liters"`
+	CapacityKg     float64 `json:"capacity_kg"`
+	// StartedAt y CompletedAt son el inicio y el fin reales de la ruta.
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// ReroutedAt es la última vez que se añadieron paradas a la ruta en curso y AddedDistanceKm, lo
+	// que se ha alargado la ruta en total por ello.
+	ReroutedAt      *time.Time `json:"rerouted_at,omitempty"`
+	AddedDistanceKm float64    `json:"added_distance_km"`
+	// Stops son las paradas en el orden planificado. No se incluyen en los listados.
+	Stops []DispatchedStop `json:"stops,omitempty"`
+	// Comparison compara lo planificado con lo ejecutado. No se incluye en los listados.
+	Comparison *RouteComparison `json:"comparison,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// DispatchedStop es una parada de una ruta guardada, con lo planificado y su resultado.
+type DispatchedStop struct {
+	ID string `json:"id"`
+	// Sequence es la posición planificada de la parada en la ruta, empezando por 1.
+	Sequence    int      `json:"sequence"`
+	Kind        StopKind `json:"kind"`
+	ContainerID *string  `json:"container_id,omitempty"`
+	FacilityID  *string  `json:"facility_id,omitempty"`
+	Location    Point    `json:"location"`
+	// Container y FacilityName describen el contenedor o el punto de descarga tal como están ahora.
+	// Faltan si se han eliminado.
+	Container    *StopContainerDetails `json:"container,omitempty"`
+	FacilityName *string               `json:"facility_name,omitempty"`
+	// Datos planificados de la parada.
+	PlannedETA          *time.Time `json:"planned_eta,omitempty"`
+	EstimatedLoadLiters float64    `json:"estimated_load_liters"`
+	EstimatedLoadKg     float64    `json:"estimated_load_kg"`
+	// Resultado de la parada. Las descargas se quedan siempre en 'pending'.
+	Status     StopStatus  `json:"status"`
+	SkipReason *SkipReason `json:"skip_reason,omitempty"`
+	Notes      *string     `json:"notes,omitempty"`
+	// VisitedAt es la hora real de la visita y DelayMinutes, el retraso respecto a la ETA planificada
+	// (negativo si se llegó antes).
+	VisitedAt    *time.Time `json:"visited_at,omitempty"`
+	DelayMinutes *float64   `json:"delay_minutes,omitempty"`
+	// CollectionID es la recogida registrada al vaciar el contenedor y CollectedVolumeLiters, su volumen.
+	CollectionID          *string `json:"collection_id,omitempty"`
+	CollectedVolumeLiters *int    `json:"collected_volume_liters,omitempty"`
+	// AddedAt es la hora a la que se añadió la parada al re-planificar la ruta en curso; falta en las
+	// paradas planificadas al generarla. Las paradas añadidas no tienen ETA planificada.
+	AddedAt *time.Time `json:"added_at,omitempty"`
+}
+
+// StopContainerDetails son los datos del contenedor de una parada que se incluyen al consultar y
+// exportar la ruta.
+type StopContainerDetails struct {
+	Fraction        Fraction `json:"fraction"`
+	CapacityLiters  int      `json:"capacity_liters"`
+	Status          Status   `json:"status"`
+	LastFillLevel   int      `json:"last_fill_level"`
+	ContainerTypeID *string  `json:"container_type_id,omitempty"`
+}
+
+// RouteComparison compara lo planificado en una ruta con lo ejecutado.
+type RouteComparison struct {
+	// Número de paradas de contenedor planificadas (incluidas las añadidas en ruta) y resultado de cada una.
+	PlannedStops     int `json:"planned_stops"`
+	AddedStops       int `json:"added_stops"`
+	CollectedStops   int `json:"collected_stops"`
+	OverflowingStops int `json:"overflowing_stops"`
+	SkippedStops     int `json:"skipped_stops"`
+	PendingStops     int `json:"pending_stops"`
+	// CompletionRate es el porcentaje de paradas planificadas en las que se vació el contenedor.
+	CompletionRate float64 `json:"completion_rate"`
+	// Carga estimada al planificar frente al volumen de las recogidas registradas.
+	PlannedLoadLiters     float64 `json:"planned_load_liters"`
+	CollectedVolumeLiters int     `json:"collected_volume_liters"`
+	// Duración planificada frente a la real (solo si la ruta ha terminado).
+	PlannedDurationMinutes float64  `json:"planned_duration_minutes"`
+	ActualDurationMinutes  *float64 `json:"actual_duration_minutes,omitempty"`
+	// AverageDelayMinutes es el retraso medio de las paradas visitadas respecto a su ETA.
+	AverageDelayMinutes *float64 `json:"average_delay_minutes,omitempty"`
+}
+
+// Compare calcula la comparación entre lo planificado y lo ejecutado a partir de las paradas.
+func (r DispatchedRoute) Compare() RouteComparison {
+	cmp := RouteComparison{PlannedDurationMinutes: r.PlannedDurationMinutes}
+	var delay float64
+	var delays int
+	for _, s := range r.Stops {
+		if s.Kind != StopContainer {
+			continue
+		}
+		cmp.PlannedStops++
+		if s.AddedAt != nil {
+			cmp.AddedStops++
+		}
+		cmp.PlannedLoadLiters += s.EstimatedLoadLiters
+		switch s.Status {
+		case StopCollected:
+			cmp.CollectedStops++
+		case StopOverflowing:
+			cmp.OverflowingStops++
+		case StopSkipped:
+			cmp.SkippedStops++
+		default:
+			cmp.PendingStops++
+		}
+		if s.CollectedVolumeLiters != nil {
+			cmp.CollectedVolumeLiters += *s.CollectedVolumeLiters
+		}
+		if s.DelayMinutes != nil {
+			delay += *s.DelayMinutes
+			delays++
+		}
+	}
+
+	if cmp.PlannedStops > 0 {
+		cmp.CompletionRate = math.Round(float64(cmp.CollectedStops+cmp.OverflowingStops)*1000/float64(cmp.PlannedStops)) / 10
+	}
+	if delays > 0 {
+		avg := math.Round(delay/float64(delays)*10) / 10
+		cmp.AverageDelayMinutes = &avg
+	}
+	if r.StartedAt != nil && r.CompletedAt != nil {
+		actual := math.Round(r.CompletedAt.Sub(*r.StartedAt).Minutes()*10) / 10
+		cmp.ActualDurationMinutes = &actual
+	}
+	return cmp
+}
+
+// Reroute es el resultado de añadir contenedores urgentes a las paradas pendientes de una ruta en curso.
+type Reroute struct {
+	RouteID string `json:"route_id"`
+	// Applied indica si los cambios se han guardado en la ruta; es falso en modo de prueba o si no
+	// se ha añadido ninguna parada.
+	Applied bool `json:"applied"`
+	// Position es la posición del camión desde la que se re-planifica.
+	Position Point `json:"position"`
+	// AddedStops son las paradas añadidas, con su posición en la ruta.
+	AddedStops []DispatchedStop `json:"added_stops"`
+	// SequenceChanges son las paradas pendientes que cambian de posición en la ruta.
+	SequenceChanges []SequenceChange `json:"sequence_changes"`
+	// RemainingStops son las paradas pendientes en el nuevo orden, incluidas las añadidas.
+	RemainingStops []DispatchedStop `json:"remaining_stops"`
+	// Unassigned son los contenedores urgentes que no se han podido añadir, con el motivo.
+	Unassigned []RerouteCandidate `json:"unassigned"`
+	// Distancia que queda desde la posición del camión (regreso al depósito incluido) antes y después
+	// de añadir las paradas, y la diferencia.
+	RemainingDistanceBeforeKm float64 `json:"remaining_distance_before_km"`
+	RemainingDistanceAfterKm  float64 `json:"remaining_distance_after_km"`
+	ExtraDistanceKm           float64 `json:"extra_distance_km"`
+}
+
+// SequenceChange es el cambio de posición de una parada al re-planificar una ruta.
+type SequenceChange struct {
+	StopID      string  `json:"stop_id"`
+	ContainerID *string `json:"container_id,omitempty"`
+	From        int     `json:"from"`
+	To          int     `json:"to"`
+}
+
+// RerouteCandidate es un contenedor urgente que no se ha podido añadir a la ruta.
+type RerouteCandidate struct {
+	ContainerID         string  `json:"container_id"`
+	Location            Point   `json:"location"`
+	Status              Status  `json:"status"`
+	LastFillLevel       int     `json:"last_fill_level"`
+	EstimatedLoadLiters float64 `json:"estimated_load_liters"`
+	EstimatedLoadKg     float64 `json:"estimated_load_kg"`
+	Reason              string  `json:"reason"`
+}
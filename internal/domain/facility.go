@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// FacilityKind es el tipo de instalación fija que participa en las rutas de recogida.
+type FacilityKind string
+
+// Constantes que definen los tipos de instalación.
+// Corresponden al tipo ENUM 'facility_kind' en la base de datos.
+const (
+	// FacilityDepot es la base de la que salen y a la que vuelven los camiones.
+	FacilityDepot FacilityKind = "depot"
+	// FacilityTransferStation es una estación de transferencia donde los camiones descargan.
+	FacilityTransferStation FacilityKind = "transfer_station"
+	// FacilityLandfill es un vertedero o planta de tratamiento donde los camiones descargan.
+	FacilityLandfill FacilityKind = "landfill"
+)
+
+// IsDisposal indica si los camiones pueden descargar en este tipo de instalación.
+func (k FacilityKind) IsDisposal() bool {
+	return k == FacilityTransferStation || k == FacilityLandfill
+}
+
+// Facility es una instalación fija: un depósito de camiones o un punto de descarga.
+type Facility struct {
+	ID       string       `json:"id"`
+	Name     string       `json:"name"`
+	Kind     FacilityKind `json:"kind"`
+	Location Point        `json:"location"`
+	// Fractions son las fracciones que admite un punto de descarga. Vacío significa que admite todas.
+	Fractions []Fraction `json:"fractions"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Accepts indica si la instalación admite la fracción. Una fracción vacía (rutas con varias
+// fracciones) solo la admiten las instalaciones que aceptan todas.
+func (f Facility) Accepts(fraction Fraction) bool {
+	if len(f.Fractions) == 0 {
+		return true
+	}
+	return fraction != "" && slices.Contains(f.Fractions, fraction)
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// Forecast es la predicción de llenado de un contenedor a partir de su historial de lecturas.
+// Los campos de predicción son nil cuando el contenedor no se llenará dentro del horizonte de predicción.
+type Forecast struct {
+	ContainerID string `json:"container_id"`
+	// FillRatePerHour es la tasa media de llenado (puntos porcentuales por hora), sin estacionalidad.
+	FillRatePerHour float64 `json:"fill_rate_per_hour"`
+	// PredictedFillLevel es el nivel de llenado estimado en el momento de generar la predicción.
+	PredictedFillLevel int `json:"predicted_fill_level"`
+	// PredictedFullAt es el momento estimado en que el contenedor alcanzará el 100%.
+	PredictedFullAt *time.Time `json:"predicted_full_at,omitempty"`
+	HoursToFull     *float64   `json:"hours_to_full,omitempty"`
+	// FullAtEarliest y FullAtLatest delimitan el intervalo de confianza de PredictedFullAt.
+	FullAtEarliest  *time.Time `json:"full_at_earliest,omitempty"`
+	FullAtLatest    *time.Time `json:"full_at_latest,omitempty"`
+	ConfidenceLevel float64    `json:"confidence_level"`
+	// SampleSize es el número de intervalos entre lecturas usados para ajustar el modelo.
+	SampleSize  int       `json:"sample_size"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
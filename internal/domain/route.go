@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// SelectionReason indica por qué se ha incluido un contenedor en una ruta.
+type SelectionReason string
+
+const (
+	// ReasonStatus: el estado actual del contenedor es uno de los solicitados.
+	ReasonStatus SelectionReason = "status"
+	// ReasonPredictedAtVisit: se prevé que supere el umbral de llenado a la hora de la visita.
+	ReasonPredictedAtVisit SelectionReason = "predicted_fill_at_visit"
+	// ReasonPredictedBeforeNextRun: se prevé que supere el umbral antes de la siguiente ruta,
+	// así que no puede esperar a ella.
+	ReasonPredictedBeforeNextRun SelectionReason = "predicted_fill_before_next_run"
+	// ReasonCurrentLevel: no hay historial para predecir, pero su nivel actual ya supera el umbral.
+	ReasonCurrentLevel SelectionReason = "current_fill_level"
+)
+
+// StopKind es el tipo de parada de una ruta.
+type StopKind string
+
+const (
+	// StopContainer es la recogida de un contenedor.
+	StopContainer StopKind = "container"
+	// StopUnload es una descarga en una estación de transferencia o vertedero.
+	StopUnload StopKind = "unload"
+)
+
+// RouteStop es una parada de una ruta de recogida: un contenedor y el motivo por el que se visita,
+// o una descarga en un punto de descarga.
+type RouteStop struct {
+	Kind StopKind `json:"kind"`
+	// Container es el contenedor que se recoge; nil en las descargas.
+	*Container
+	// Facility es el punto de descarga; solo en las descargas.
+	Facility     *Facility       `json:"facility,omitempty"`
+	Reason       SelectionReason `json:"reason,omitempty"`
+	ReasonDetail string          `json:"reason_detail,omitempty"`
+	// Nivel de llenado previsto a la hora de la visita y a la hora de la siguiente ruta,
+	// solo en las rutas generadas a partir de predicciones.
+	PredictedFillAtVisit   *int `json:"predicted_fill_at_visit,omitempty"`
+	PredictedFillAtNextRun *int `json:"predicted_fill_at_next_run,omitempty"`
+	// Carga estimada que aporta la parada al camión, a partir de la capacidad y del nivel de llenado.
+	// En las descargas, es la carga que se descarga.
+	EstimatedLoadLiters float64 `json:"estimated_load_liters"`
+	EstimatedLoadKg     float64 `json:"estimated_load_kg"`
+	// TimeWindows son las franjas horarias en las que se puede recoger el contenedor, si las tiene.
+	TimeWindows TimeWindows `json:"time_windows,omitempty"`
+	// ETA es la hora estimada de llegada a la parada y WaitMinutes, la espera hasta que se abre su franja.
+	ETA         *time.Time `json:"eta,omitempty"`
+	WaitMinutes float64    `json:"wait_minutes,omitempty"`
+	// UnassignedReason explica por qué la parada no se ha podido asignar a ninguna ruta.
+	UnassignedReason string `json:"unassigned_reason,omitempty"`
+}
+
+// Coordinates devuelve la ubicación de la parada: la del contenedor o la del punto de descarga.
+func (s RouteStop) Coordinates() Point {
+	if s.Container != nil {
+		return s.Container.Location
+	}
+	if s.Facility != nil {
+		return s.Facility.Location
+	}
+	return Point{}
+}
+
+// EstimateLoad estima el volumen y el peso de residuo que se recogerá en la parada: a partir del
+// llenado previsto a la hora de la visita si lo hay o, si no, del último nivel conocido.
+func (s *RouteStop) EstimateLoad() {
+	if s.Container == nil {
+		return
+	}
+	level := s.LastFillLevel
+	if s.PredictedFillAtVisit != nil {
+		level = *s.PredictedFillAtVisit
+	}
+	s.EstimatedLoadLiters = float64(level*s.CapacityLiters) / 100
+	s.EstimatedLoadKg = math.Round(s.EstimatedLoadLiters*s.Fraction.DensityKgPerLiter()*10) / 10
+}
+
+// Route es la ruta de recogida de un camión: las paradas en orden de visita (incluidas las
+// descargas), su carga, su longitud y su duración.
+type Route struct {
+	// ID es el identificador de la ruta guardada; vacío si la ruta no se ha guardado.
+	ID string `json:"id,omitempty"`
+	// Vehicle numera los camiones de la flota, empezando por 1.
+	Vehicle int `json:"vehicle"`
+	// Depot es el depósito del que sale y al que vuelve el camión, si la ruta sale de uno.
+	Depot *Facility `json:"depot,omitempty"`
+	// StartPoint es el punto de salida de la ruta: el depósito o el punto de salida indicado.
+	StartPoint Point       `json:"start_point"`
+	Stops      []RouteStop `json:"stops"`
+	// Carga total estimada que recoge el camión a lo largo de la ruta.
+	LoadLiters float64 `json:"load_liters"`
+	LoadKg     float64 `json:"load_kg"`
+	// Capacidad del camión en litros y en kilos, si la ruta se ha planificado con flota. Cero
+	// significa sin límite.
+	CapacityLiters float64 `json:"capacity_liters,omitempty"`
+	CapacityKg     float64 `json:"capacity_kg,omitempty"`
+	// InitialDistanceKm es la longitud de la ruta construida con el vecino más cercano (o, con flota,
+	// con el algoritmo de ahorros), antes de la mejora local.
+	InitialDistanceKm float64 `json:"initial_distance_km"`
+	// DistanceKm es la longitud final de la ruta, desde el punto de salida hasta la última parada
+	// y, si la ruta es cerrada, de vuelta al depósito.
+	DistanceKm float64 `json:"distance_km"`
+	// ReturnDistanceKm es la parte de DistanceKm que corresponde al regreso al depósito.
+	ReturnDistanceKm float64 `json:"return_distance_km"`
+	// StartAt y EndAt son la salida del camión y el final estimado de la ruta (regreso incluido).
+	StartAt time.Time `json:"start_at"`
+	EndAt   time.Time `json:"end_at"`
+	// DurationMinutes es la duración estimada: conducción, esperas, recogida de cada contenedor, descargas y regreso.
+	DurationMinutes float64           `json:"duration_minutes"`
+	Optimization    RouteOptimization `json:"optimization"`
+}
+
+// RouteOptimization resume la fase de mejora local aplicada a una ruta.
+type RouteOptimization struct {
+	Algorithms []string `json:"algorithms"`
+	// Iterations es el número de movimientos de mejora aplicados.
+	Iterations int `json:"iterations"`
+	// TimeBudgetExhausted indica que la mejora se detuvo por el límite de tiempo y no en un óptimo local.
+	TimeBudgetExhausted bool  `json:"time_budget_exhausted"`
+	ElapsedMs           int64 `json:"elapsed_ms"`
+}
+
+// RoutePlan es el resultado de planificar la recogida: una ruta por camión y las paradas que no
+// caben en la flota.
+type RoutePlan struct {
+	Routes []Route `json:"routes"`
+	// Unassigned son las paradas seleccionadas que no caben en ninguna ruta (por capacidad, por sus
+	// franjas horarias o por el fin de turno), cada una con su motivo.
+	Unassigned           []RouteStop `json:"unassigned"`
+	TotalDistanceKm      float64     `json:"total_distance_km"`
+	TotalDurationMinutes float64     `json:"total_duration_minutes"`
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TimeOfDay es una hora local del día en minutos desde la medianoche. Se serializa como "HH:MM";
+// "24:00" representa el final del día.
+type TimeOfDay int
+
+// minutesPerDay es el valor de "24:00".
+const minutesPerDay = 24 * 60
+
+// ParseTimeOfDay interpreta una hora con formato "HH:MM".
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || len(s) != 5 {
+		return 0, fmt.Errorf("hora no válida %q: el formato es HH:MM", s)
+	}
+	t := TimeOfDay(h*60 + m)
+	if h < 0 || m < 0 || m >= 60 || t > minutesPerDay {
+		return 0, fmt.Errorf("hora no válida %q: debe estar entre 00:00 y 24:00", s)
+	}
+	return t, nil
+}
+
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", int(t)/60, int(t)%60)
+}
+
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *TimeOfDay) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseTimeOfDay(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// on devuelve el instante en que se alcanza la hora en el día de 'day' (en la zona 'loc').
+func (t TimeOfDay) on(day time.Time, loc *time.Location) time.Time {
+	y, m, d := day.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc).Add(time.Duration(t) * time.Minute)
+}
+
+// TimeWindow es una franja horaria diaria [Start, End) en la que se permite recoger un contenedor.
+type TimeWindow struct {
+	Start TimeOfDay `json:"start" swaggertype:"string" example:"06:00"`
+	End   TimeOfDay `json:"end" swaggertype:"string" example:"08:00"`
+}
+
+// IsValid comprueba que la franja no está vacía. Las franjas que cruzan la medianoche se
+// expresan como dos franjas (p. ej. 22:00-24:00 y 00:00-06:00).
+func (w TimeWindow) IsValid() bool {
+	return w.Start >= 0 && w.Start < w.End && w.End <= minutesPerDay
+}
+
+// TimeWindows son las franjas en las que se permite recoger un contenedor. Vacío significa a cualquier hora.
+type TimeWindows []TimeWindow
+
+// EarliestStart devuelve el primer instante, a partir de 'at', en que puede empezar un servicio
+// de duración 'service' que termine dentro de alguna franja (en la hora local de 'loc').
+// Busca en el día de 'at' y en el siguiente; devuelve false si no hay hueco.
+func (ws TimeWindows) EarliestStart(at time.Time, service time.Duration, loc *time.Location) (time.Time, bool) {
+	if len(ws) == 0 {
+		return at, true
+	}
+
+	var best time.Time
+	found := false
+	for day := 0; day < 2; day++ {
+		date := at.In(loc).AddDate(0, 0, day)
+		for _, w := range ws {
+			start, end := w.Start.on(date, loc), w.End.on(date, loc)
+			if start.Before(at) {
+				start = at
+			}
+			if start.Add(service).After(end) {
+				continue
+			}
+			if !found || start.Before(best) {
+				best, found = start, true
+			}
+		}
+		if found {
+			return best, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// TimeWindowProfile es un conjunto de franjas horarias con nombre que se asigna a los contenedores
+// de una zona (p. ej. "entorno escolar" o "zona comercial, antes de las 8:00").
+type TimeWindowProfile struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Windows   TimeWindows `json:"windows"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEarliestStart(t *testing.T) {
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	at := func(h, m int) time.Time { return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+	cet := time.FixedZone("CET", 3600)
+
+	tests := []struct {
+		name    string
+		windows TimeWindows
+		at      time.Time
+		service time.Duration
+		loc     *time.Location
+		want    time.Time
+		wantOK  bool
+	}{
+		{name: "sin franjas", at: at(3, 0), service: 2 * time.Minute, want: at(3, 0), wantOK: true},
+		{
+			name:    "dentro de la franja",
+			windows: TimeWindows{{Start: 6 * 60, End: 8 * 60}},
+			at:      at(7, 0), service: 2 * time.Minute, want: at(7, 0), wantOK: true,
+		},
+		{
+			name:    "antes de la franja: espera a que abra",
+			windows: TimeWindows{{Start: 6 * 60, End: 8 * 60}},
+			at:      at(5, 30), service: 2 * time.Minute, want: at(6, 0), wantOK: true,
+		},
+		{
+			name:    "el servicio no termina dentro de la franja: pasa a la siguiente",
+			windows: TimeWindows{{Start: 6 * 60, End: 8 * 60}, {Start: 20 * 60, End: 22 * 60}},
+			at:      at(7, 59), service: 2 * time.Minute, want: at(20, 0), wantOK: true,
+		},
+		{
+			name:    "franjas del día ya cerradas: pasa al día siguiente",
+			windows: TimeWindows{{Start: 6 * 60, End: 8 * 60}},
+			at:      at(9, 0), service: 2 * time.Minute, want: at(30, 0), wantOK: true,
+		},
+		{
+			name:    "franja que cruza la medianoche",
+			windows: TimeWindows{{Start: 22 * 60, End: minutesPerDay}, {Start: 0, End: 6 * 60}},
+			at:      at(23, 59), service: 2 * time.Minute, want: at(24, 0), wantOK: true,
+		},
+		{
+			name:    "hora local de la zona",
+			windows: TimeWindows{{Start: 7 * 60, End: 8 * 60}},
+			at:      at(5, 0), service: 2 * time.Minute, loc: cet, want: at(6, 0), wantOK: true,
+		},
+		{
+			name:    "servicio más largo que la franja",
+			windows: TimeWindows{{Start: 6 * 60, End: 6*60 + 1}},
+			at:      at(5, 0), service: 2 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := tt.loc
+			if loc == nil {
+				loc = time.UTC
+			}
+			got, ok := tt.windows.EarliestStart(tt.at, tt.service, loc)
+			if ok != tt.wantOK {
+				t.Fatalf("EarliestStart() ok = %v, se esperaba %v", ok, tt.wantOK)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("EarliestStart() = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package facility
+
+import (
+	"errors"
+	"net/http"
+	"smart-waste-management/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler maneja las peticiones HTTP para los depósitos y puntos de descarga.
+type Handler struct {
+	service Service
+}
+
+// UpsertFacilityRequest define el cuerpo de la petición para crear o actualizar una instalación.
+type UpsertFacilityRequest struct {
+	Name      string              `json:"name" binding:"required"`
+	Kind      domain.FacilityKind `json:"kind" binding:"required,oneof=depot transfer_station landfill"`
+	Latitude  float64             `json:"latitude" binding:"required,latitude"`
+	Longitude float64             `json:"longitude" binding:"required,longitude"`
+	// Fractions son las fracciones que admite un punto de descarga; si se omite, admite todas.
+	Fractions []domain.Fraction `json:"fractions" binding:"omitempty,dive,oneof=organic paper packaging glass residual textile"`
+}
+
+func (req UpsertFacilityRequest) toFacility(id string) domain.Facility {
+	fractions := req.Fractions
+	if fractions == nil {
+		fractions = []domain.Fraction{}
+	}
+	return domain.Facility{
+		ID:        id,
+		Name:      req.Name,
+		Kind:      req.Kind,
+		Location:  domain.Point{Latitude: req.Latitude, Longitude: req.Longitude},
+		Fractions: fractions,
+	}
+}
+
+// NewHandler crea una nueva instancia del handler.
+func NewHandler(s Service) *Handler {
+	return &Handler{
+		service: s,
+	}
+}
+
+// RegisterRoutes registra todas las rutas de este handler en el router de Gin.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/facilities", h.GetFacilities)
+	router.POST("/facilities", h.CreateFacility)
+	router.GET("/facilities/:id", h.GetFacilityByID)
+	router.PUT("/facilities/:id", h.UpdateFacility)
+	router.DELETE("/facilities/:id", h.DeleteFacility)
+}
+
+// @Summary      Obtiene los depósitos y puntos de descarga
+// @Description  Devuelve las instalaciones fijas que usan las rutas: depósitos de camiones, estaciones de transferencia y vertederos.
+// @Tags         Facilities
+// @Produce      json
+// @Param        kind  query     string  false  "Filtra por tipo de instalación"  Enums(depot, transfer_station, landfill)
+// @Success      200   {object}  []domain.Facility
+// @Failure      400   {object}  map[string]string "Tipo de instalación desconocido"
+// @Failure      500   {object}  map[string]string "Error interno del servidor"
+// @Router       /facilities [get]
+func (h *Handler) GetFacilities(c *gin.Context) {
+	var filter Filter
+	if kind := domain.FacilityKind(c.Query("kind")); kind != "" {
+		if kind != domain.FacilityDepot && !kind.IsDisposal() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tipo de instalación desconocido: " + string(kind)})
+			return
+		}
+		filter.Kinds = []domain.FacilityKind{kind}
+	}
+
+	facilities, err := h.service.GetAllFacilities(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener la lista de instalaciones"})
+		return
+	}
+	c.JSON(http.StatusOK, facilities)
+}
+
+// @Summary      Crea un depósito o punto de descarga
+// @Description  Registra una instalación fija. Las rutas salen de un depósito y vuelven a él, y descargan en las estaciones de transferencia o vertederos que admiten su fracción.
+// @Tags         Facilities
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        facility  body      UpsertFacilityRequest  true  "Datos de la instalación"
+// @Success      201       {object}  domain.Facility        "Instalación creada"
+// @Failure      400       {object}  map[string]string      "Petición inválida o datos incorrectos"
+// @Failure      422       {object}  map[string]string      "Idempotency-Key reutilizada con una petición distinta"
+// @Failure      500       {object}  map[string]string      "Error interno del servidor"
+// @Router       /facilities [post]
+func (h *Handler) CreateFacility(c *gin.Context) {
+	var req UpsertFacilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := h.service.CreateFacility(c.Request.Context(), req.toFacility(""))
+	if err != nil {
+		if errors.Is(err, ErrInvalidFacility) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo crear la instalación"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// @Summary      Obtiene una instalación por su ID
+// @Tags         Facilities
+// @Produce      json
+// @Param        id   path      string  true  "ID de la instalación (UUID)"
+// @Success      200  {object}  domain.Facility
+// @Failure      404  {object}  map[string]string  "Instalación no encontrada"
+// @Failure      500  {object}  map[string]string  "Error interno del servidor"
+// @Router       /facilities/{id} [get]
+func (h *Handler) GetFacilityByID(c *gin.Context) {
+	f, err := h.service.GetFacilityByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ErrFacilityNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al buscar la instalación"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// @Summary      Actualiza una instalación
+// @Tags         Facilities
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id        path      string                 true  "ID de la instalación (UUID)"
+// @Param        facility  body      UpsertFacilityRequest  true  "Nuevos datos de la instalación"
+// @Success      200       {object}  map[string]string      "Instalación actualizada"
+// @Failure      400       {object}  map[string]string      "Petición inválida o datos incorrectos"
+// @Failure      404       {object}  map[string]string      "Instalación no encontrada"
+// @Failure      422       {object}  map[string]string      "Idempotency-Key reutilizada con una petición distinta"
+// @Failure      500       {object}  map[string]string      "Error interno del servidor"
+// @Router       /facilities/{id} [put]
+func (h *Handler) UpdateFacility(c *gin.Context) {
+	var req UpsertFacilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.UpdateFacility(c.Request.Context(), req.toFacility(c.Param("id"))); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidFacility):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrFacilityNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar la instalación"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Instalación actualizada exitosamente"})
+}
+
+// @Summary      Elimina una instalación
+// @Tags         Facilities
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id   path      string  true  "ID de la instalación (UUID)"
+// @Success      204  "Sin contenido"
+// @Failure      500  {object}  map[string]string "Error interno del servidor"
+// @Router       /facilities/{id} [delete]
+func (h *Handler) DeleteFacility(c *gin.Context) {
+	if err := h.service.DeleteFacility(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar la instalación"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,143 @@
+package facility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/platform/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrFacilityNotFound se devuelve cuando no existe una instalación con el ID indicado.
+var ErrFacilityNotFound = errors.New("instalación no encontrada")
+
+// Filter restringe el listado de instalaciones. Los campos vacíos no filtran.
+type Filter struct {
+	Kinds []domain.FacilityKind
+}
+
+// Repository define las operaciones de persistencia de las instalaciones.
+type Repository interface {
+	CreateFacility(ctx context.Context, f domain.Facility) (domain.Facility, error)
+	FindAllFacilities(ctx context.Context, filter Filter) ([]domain.Facility, error)
+	FindFacilityByID(ctx context.Context, id string) (domain.Facility, error)
+	UpdateFacility(ctx context.Context, f domain.Facility) error
+	DeleteFacility(ctx context.Context, id string) error
+}
+
+// postgresRepository es la implementación concreta de Repository para PostgreSQL.
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresRepository crea una nueva instancia del repositorio.
+func NewPostgresRepository(db *database.DB) Repository {
+	return &postgresRepository{
+		db: db.Pool,
+	}
+}
+
+// Las fracciones se leen y escriben como text[] para no depender del registro del array del ENUM.
+const facilityColumns = `id, name, kind, ST_Y(location::geometry), ST_X(location::geometry), fractions::text[], created_at, updated_at`
+
+func scanFacility(row pgx.Row) (domain.Facility, error) {
+	var f domain.Facility
+	var fractions []string
+	err := row.Scan(&f.ID, &f.Name, &f.Kind, &f.Location.Latitude, &f.Location.Longitude, &fractions, &f.CreatedAt, &f.UpdatedAt)
+	f.Fractions = make([]domain.Fraction, len(fractions))
+	for i, fr := range fractions {
+		f.Fractions[i] = domain.Fraction(fr)
+	}
+	return f, err
+}
+
+func fractionStrings(fractions []domain.Fraction) []string {
+	s := make([]string, len(fractions))
+	for i, f := range fractions {
+		s[i] = string(f)
+	}
+	return s
+}
+
+func (r *postgresRepository) CreateFacility(ctx context.Context, f domain.Facility) (domain.Facility, error) {
+	query := `
+        INSERT INTO facilities (name, kind, location, fractions)
+        VALUES ($1, $2, ST_SetSRID(ST_MakePoint($3, $4), 4326), $5::text[]::waste_fraction[])
+        RETURNING ` + facilityColumns
+
+	created, err := scanFacility(r.db.QueryRow(ctx, query,
+		f.Name, f.Kind, f.Location.Longitude, f.Location.Latitude, fractionStrings(f.Fractions)))
+	if err != nil {
+		return domain.Facility{}, fmt.Errorf("error al crear la instalación: %w", err)
+	}
+	return created, nil
+}
+
+func (r *postgresRepository) FindAllFacilities(ctx context.Context, filter Filter) ([]domain.Facility, error) {
+	kinds := make([]string, len(filter.Kinds))
+	for i, k := range filter.Kinds {
+		kinds[i] = string(k)
+	}
+	query := `
+        SELECT ` + facilityColumns + `
+        FROM facilities
+        WHERE (cardinality($1::text[]) = 0 OR kind::text = ANY($1))
+        ORDER BY kind, name`
+
+	rows, err := r.db.Query(ctx, query, kinds)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar las instalaciones: %w", err)
+	}
+	defer rows.Close()
+
+	var facilities []domain.Facility
+	for rows.Next() {
+		f, err := scanFacility(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al escanear la instalación: %w", err)
+		}
+		facilities = append(facilities, f)
+	}
+	return facilities, rows.Err()
+}
+
+func (r *postgresRepository) FindFacilityByID(ctx context.Context, id string) (domain.Facility, error) {
+	query := `SELECT ` + facilityColumns + ` FROM facilities WHERE id = $1`
+
+	f, err := scanFacility(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Facility{}, ErrFacilityNotFound
+		}
+		return domain.Facility{}, fmt.Errorf("error al buscar la instalación: %w", err)
+	}
+	return f, nil
+}
+
+func (r *postgresRepository) UpdateFacility(ctx context.Context, f domain.Facility) error {
+	query := `
+        UPDATE facilities
+        SET name = $1, kind = $2, location = ST_SetSRID(ST_MakePoint($3, $4), 4326),
+            fractions = $5::text[]::waste_fraction[], updated_at = NOW()
+        WHERE id = $6`
+
+	tag, err := r.db.Exec(ctx, query,
+		f.Name, f.Kind, f.Location.Longitude, f.Location.Latitude, fractionStrings(f.Fractions), f.ID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar la instalación: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFacilityNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepository) DeleteFacility(ctx context.Context, id string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM facilities WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("error al eliminar la instalación: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package facility
+
+import (
+	"context"
+	"errors"
+	"smart-waste-management/internal/domain"
+)
+
+// ErrInvalidFacility se devuelve cuando los datos de la instalación no son coherentes.
+var ErrInvalidFacility = errors.New("un depósito no admite fracciones de residuo; solo los puntos de descarga")
+
+// Service define la lógica de negocio de los depósitos y puntos de descarga.
+type Service interface {
+	CreateFacility(ctx context.Context, f domain.Facility) (domain.Facility, error)
+	GetAllFacilities(ctx context.Context, filter Filter) ([]domain.Facility, error)
+	GetFacilityByID(ctx context.Context, id string) (domain.Facility, error)
+	UpdateFacility(ctx context.Context, f domain.Facility) error
+	DeleteFacility(ctx context.Context, id string) error
+	// GetDisposalFacilities devuelve los puntos de descarga que admiten la fracción.
+	GetDisposalFacilities(ctx context.Context, fraction domain.Fraction) ([]domain.Facility, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+// NewService crea una nueva instancia del servicio.
+func NewService(repo Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+func (s *service) CreateFacility(ctx context.Context, f domain.Facility) (domain.Facility, error) {
+	if err := validate(f); err != nil {
+		return domain.Facility{}, err
+	}
+	return s.repo.CreateFacility(ctx, f)
+}
+
+func (s *service) GetAllFacilities(ctx context.Context, filter Filter) ([]domain.Facility, error) {
+	return s.repo.FindAllFacilities(ctx, filter)
+}
+
+func (s *service) GetFacilityByID(ctx context.Context, id string) (domain.Facility, error) {
+	return s.repo.FindFacilityByID(ctx, id)
+}
+
+func (s *service) UpdateFacility(ctx context.Context, f domain.Facility) error {
+	if err := validate(f); err != nil {
+		return err
+	}
+	return s.repo.UpdateFacility(ctx, f)
+}
+
+func (s *service) DeleteFacility(ctx context.Context, id string) error {
+	return s.repo.DeleteFacility(ctx, id)
+}
+
+func (s *service) GetDisposalFacilities(ctx context.Context, fraction domain.Fraction) ([]domain.Facility, error) {
+	facilities, err := s.repo.FindAllFacilities(ctx, Filter{
+		Kinds: []domain.FacilityKind{domain.FacilityTransferStation, domain.FacilityLandfill},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var accepted []domain.Facility
+	for _, f := range facilities {
+		if f.Accepts(fraction) {
+			accepted = append(accepted, f)
+		}
+	}
+	return accepted, nil
+}
+
+// validate comprueba las reglas que no cubre la validación de la petición.
+func validate(f domain.Facility) error {
+	if f.Kind == domain.FacilityDepot && len(f.Fractions) > 0 {
+		return ErrInvalidFacility
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package forecast
+
+import (
+	"errors"
+	"math"
+	"smart-waste-management/internal/domain"
+	"sort"
+	"time"
+)
+
+// ErrInsufficientHistory se devuelve cuando no hay suficientes lecturas para estimar la tasa de llenado.
+var ErrInsufficientHistory = errors.New("historial de lecturas insuficiente para estimar el llenado")
+
+const (
+	// ConfidenceLevel es la probabilidad que cubre el intervalo de confianza de las predicciones.
+	ConfidenceLevel = 0.8
+	// confidenceZ es el cuantil de la normal correspondiente a un intervalo bilateral del 80%.
+	confidenceZ = 1.2816
+
+	// MaxHorizon es el tiempo máximo que se proyecta hacia el futuro. Más allá no se predice.
+	MaxHorizon = 60 * 24 * time.Hour
+
+	// maxNoiseDrop es la bajada máxima (en puntos) que se atribuye al ruido del sensor.
+	// Una bajada mayor es un vaciado (total o parcial) y no cuenta como intervalo de llenado.
+	maxNoiseDrop = 10
+	// minHistoryHours es el tiempo mínimo cubierto por los intervalos de llenado para ajustar un modelo.
+	minHistoryHours = 6.0
+
+	// Horas "virtuales" a la tasa media que se añaden a cada franja horaria y a cada día de la semana.
+	// Suavizan la estacionalidad hacia 1 cuando una franja tiene pocos datos.
+	hourPriorHours    = 6.0
+	weekdayPriorHours = 24.0
+
+	// minMultiplier evita que el extremo inferior del intervalo de la tasa llegue a cero.
+	minMultiplier = 0.05
+)
+
+// Model es un modelo de llenado ajustado al historial de un contenedor: una tasa media
+// modulada por la hora del día y el día de la semana (en UTC).
+type Model struct {
+	baseRate      float64 // Puntos de llenado por hora.
+	hourFactor    [24]float64
+	weekdayFactor [7]float64
+
+	// Dispersión de la tasa observada respecto a la esperada, para el intervalo de confianza.
+	ratioVariance     float64
+	samples           int
+	meanIntervalHours float64
+}
+
+// interval es el tramo entre dos lecturas consecutivas de un mismo ciclo de llenado.
+type interval struct {
+	from, to time.Time
+	rise     float64
+}
+
+func (iv interval) hours() float64 {
+	return iv.to.Sub(iv.from).Hours()
+}
+
+// Fit ajusta un modelo a las lecturas de un contenedor (en cualquier orden).
+func Fit(readings []domain.Reading) (*Model, error) {
+	sorted := make([]domain.Reading, len(readings))
+	copy(sorted, readings)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Timestamp.Before(sorted[j].Timestamp) })
+
+	// 1. Partimos el historial en intervalos de llenado, descartando los vaciados.
+	var intervals []interval
+	var totalRise, totalHours float64
+	for i := 1; i < len(sorted); i++ {
+		prev, cur := sorted[i-1], sorted[i]
+		if !cur.Timestamp.After(prev.Timestamp) || prev.FillLevel-cur.FillLevel > maxNoiseDrop {
+			continue
+		}
+		iv := interval{from: prev.Timestamp, to: cur.Timestamp, rise: float64(cur.FillLevel - prev.FillLevel)}
+		intervals = append(intervals, iv)
+		totalRise += iv.rise
+		totalHours += iv.hours()
+	}
+	if totalHours < minHistoryHours || totalRise <= 0 {
+		return nil, ErrInsufficientHistory
+	}
+
+	m := &Model{
+		baseRate:          totalRise / totalHours,
+		samples:           len(intervals),
+		meanIntervalHours: totalHours / float64(len(intervals)),
+	}
+
+	// 2. Estacionalidad: repartimos la subida de cada intervalo entre las horas que cubre.
+	var hourRise, hourHours [24]float64
+	var weekdayRise, weekdayHours [7]float64
+	for _, iv := range intervals {
+		ivHours := iv.hours()
+		spread(iv.from, iv.to, func(slot time.Time, hours float64) {
+			share := iv.rise * hours / ivHours
+			hourRise[slot.Hour()] += share
+			hourHours[slot.Hour()] += hours
+			weekdayRise[slot.Weekday()] += share
+			weekdayHours[slot.Weekday()] += hours
+		})
+	}
+	for h := range m.hourFactor {
+		m.hourFactor[h] = m.shrunkFactor(hourRise[h], hourHours[h], hourPriorHours)
+	}
+	for d := range m.weekdayFactor {
+		m.weekdayFactor[d] = m.shrunkFactor(weekdayRise[d], weekdayHours[d], weekdayPriorHours)
+	}
+
+	// Normalizamos para que el factor medio de la semana sea 1 y la tasa media se conserve.
+	var sum float64
+	for d := range m.weekdayFactor {
+		for h := range m.hourFactor {
+			sum += m.weekdayFactor[d] * m.hourFactor[h]
+		}
+	}
+	if mean := sum / (7 * 24); mean > 0 {
+		for h := range m.hourFactor {
+			m.hourFactor[h] /= mean
+		}
+	}
+
+	// 3. Dispersión: cuánto se desvía la tasa de cada intervalo de la que predice el modelo.
+	var weightSum, ratioSum, ratioSqSum float64
+	for _, iv := range intervals {
+		expected := m.baseRate * m.meanFactor(iv.from, iv.to)
+		if expected <= 0 {
+			continue
+		}
+		ratio := iv.rise / iv.hours() / expected
+		w := iv.hours()
+		weightSum += w
+		ratioSum += w * ratio
+		ratioSqSum += w * ratio * ratio
+	}
+	if weightSum > 0 {
+		mean := ratioSum / weightSum
+		m.ratioVariance = math.Max(0, ratioSqSum/weightSum-mean*mean)
+	}
+
+	return m, nil
+}
+
+// FillRate devuelve la tasa media de llenado en puntos por hora.
+func (m *Model) FillRate() float64 {
+	return m.baseRate
+}
+
+// Predict proyecta el nivel de un contenedor, medido como 'level' en 'from', y estima cuándo se llenará.
+func (m *Model) Predict(level int, from, now time.Time) domain.Forecast {
+	f := domain.Forecast{
+		FillRatePerHour:    math.Round(m.baseRate*100) / 100,
+		PredictedFillLevel: int(math.Round(m.levelAt(float64(level), from, now, 1))),
+		ConfidenceLevel:    ConfidenceLevel,
+		SampleSize:         m.samples,
+		GeneratedAt:        now,
+	}
+
+	limit := now.Add(MaxHorizon)
+	fullAt, ok := m.timeToLevel(float64(level), from, 100, 1, limit)
+	if !ok {
+		return f
+	}
+	hoursToFull := math.Max(0, math.Round(fullAt.Sub(now).Hours()*10)/10)
+	f.PredictedFullAt = &fullAt
+	f.HoursToFull = &hoursToFull
+
+	low, high := m.multiplierBounds(fullAt.Sub(from).Hours())
+	if earliest, ok := m.timeToLevel(float64(level), from, 100, high, limit); ok {
+		f.FullAtEarliest = &earliest
+	}
+	if latest, ok := m.timeToLevel(float64(level), from, 100, low, limit); ok {
+		f.FullAtLatest = &latest
+	}
+	return f
+}
+
+// LevelAt estima el nivel en 'at' de un contenedor medido como 'level' en 'from',
+// junto con los extremos de su intervalo de confianza. Los valores no superan 100.
+func (m *Model) LevelAt(level int, from, at time.Time) (expected, low, high float64) {
+	lowMult, highMult := m.multiplierBounds(at.Sub(from).Hours())
+	return m.levelAt(float64(level), from, at, 1),
+		m.levelAt(float64(level), from, at, lowMult),
+		m.levelAt(float64(level), from, at, highMult)
+}
+
+// multiplierBounds devuelve los extremos del intervalo de confianza de la tasa media (como
+// multiplicadores de la tasa del modelo) para una proyección de 'hours' horas. La incertidumbre
+// combina el ruido de la tasa, que se promedia a lo largo de la proyección, y la del propio ajuste.
+func (m *Model) multiplierBounds(hours float64) (low, high float64) {
+	k := math.Max(1, hours/m.meanIntervalHours)
+	sd := math.Sqrt(m.ratioVariance/k + m.ratioVariance/float64(m.samples))
+	return math.Max(minMultiplier, 1-confidenceZ*sd), 1 + confidenceZ*sd
+}
+
+// factor devuelve el factor estacional de la hora que contiene a 't'.
+func (m *Model) factor(t time.Time) float64 {
+	t = t.UTC()
+	return m.weekdayFactor[t.Weekday()] * m.hourFactor[t.Hour()]
+}
+
+// meanFactor devuelve el factor estacional medio entre 'from' y 'to'.
+func (m *Model) meanFactor(from, to time.Time) float64 {
+	var weighted, total float64
+	spread(from, to, func(slot time.Time, hours float64) {
+		weighted += m.factor(slot) * hours
+		total += hours
+	})
+	if total == 0 {
+		return 1
+	}
+	return weighted / total
+}
+
+// shrunkFactor calcula el factor de una franja a partir de su subida y sus horas observadas,
+// suavizado con 'priorHours' horas a la tasa media.
+func (m *Model) shrunkFactor(rise, hours, priorHours float64) float64 {
+	rate := (rise + priorHours*m.baseRate) / (hours + priorHours)
+	return math.Max(0, rate) / m.baseRate
+}
+
+// levelAt avanza el nivel desde 'from' hasta 'at' con la tasa del modelo multiplicada por 'multiplier'.
+func (m *Model) levelAt(level float64, from, at time.Time, multiplier float64) float64 {
+	spread(from, at, func(slot time.Time, hours float64) {
+		level += m.baseRate * multiplier * m.factor(slot) * hours
+	})
+	return math.Min(100, level)
+}
+
+// timeToLevel devuelve cuándo el nivel, medido como 'level' en 'from', alcanzará 'target'.
+// Devuelve false si no lo alcanza antes de 'limit'.
+func (m *Model) timeToLevel(level float64, from time.Time, target, multiplier float64, limit time.Time) (time.Time, bool) {
+	if level >= target {
+		return from, true
+	}
+	for t := from; t.Before(limit); {
+		next := t.Truncate(time.Hour).Add(time.Hour)
+		hours := next.Sub(t).Hours()
+		rate := m.baseRate * multiplier * m.factor(t)
+		if rate > 0 && level+rate*hours >= target {
+			return t.Add(time.Duration((target - level) / rate * float64(time.Hour))), true
+		}
+		level += rate * hours
+		t = next
+	}
+	return time.Time{}, false
+}
+
+// spread recorre el intervalo [from, to) en tramos que no cruzan un cambio de hora,
+// llamando a fn con el inicio de cada tramo y su duración en horas.
+func spread(from, to time.Time, fn func(slot time.Time, hours float64)) {
+	for t := from; t.Before(to); {
+		next := t.Truncate(time.Hour).Add(time.Hour)
+		if next.After(to) {
+			next = to
+		}
+		fn(t, next.Sub(t).Hours())
+		t = next
+	}
+}
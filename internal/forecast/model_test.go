@@ -0,0 +1,150 @@
+package forecast
+
+import (
+	"errors"
+	"math"
+	"smart-waste-management/internal/domain"
+	"testing"
+	"time"
+)
+
+// start es un lunes a medianoche (UTC), el inicio de los historiales de prueba.
+var start = time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+// history genera lecturas horarias durante 'hours' horas. En cada hora el nivel sube 'rise(i, t)'
+// puntos y, al pasar de 90, el contenedor se vacía.
+func history(hours int, rise func(i int, t time.Time) int) []domain.Reading {
+	level := 0
+	readings := []domain.Reading{{FillLevel: level, Timestamp: start}}
+	for i := 1; i <= hours; i++ {
+		t := start.Add(time.Duration(i) * time.Hour)
+		level += rise(i, t.Add(-time.Hour))
+		if level > 90 {
+			level = 0
+		}
+		readings = append(readings, domain.Reading{FillLevel: level, Timestamp: t})
+	}
+	return readings
+}
+
+func TestFitInsufficientHistory(t *testing.T) {
+	tests := []struct {
+		name     string
+		readings []domain.Reading
+	}{
+		{name: "sin lecturas"},
+		{name: "una lectura", readings: []domain.Reading{{FillLevel: 10, Timestamp: start}}},
+		{name: "menos horas de las necesarias", readings: history(5, func(int, time.Time) int { return 1 })},
+		{name: "nivel constante", readings: history(48, func(int, time.Time) int { return 0 })},
+		{
+			name: "solo vaciados",
+			readings: []domain.Reading{
+				{FillLevel: 90, Timestamp: start},
+				{FillLevel: 0, Timestamp: start.Add(12 * time.Hour)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Fit(tt.readings); !errors.Is(err, ErrInsufficientHistory) {
+				t.Errorf("Fit() error = %v, se esperaba ErrInsufficientHistory", err)
+			}
+		})
+	}
+}
+
+func TestFitConstantRate(t *testing.T) {
+	readings := history(7*24, func(int, time.Time) int { return 1 })
+	// El orden de las lecturas no importa.
+	readings[0], readings[len(readings)-1] = readings[len(readings)-1], readings[0]
+
+	m, err := Fit(readings)
+	if err != nil {
+		t.Fatalf("Fit() error inesperado: %v", err)
+	}
+	if math.Abs(m.FillRate()-1) > 1e-9 {
+		t.Errorf("FillRate() = %v, se esperaba 1", m.FillRate())
+	}
+
+	from := start.Add(8 * 24 * time.Hour)
+	tests := []struct {
+		name  string
+		level int
+		hours float64
+		want  float64
+	}{
+		{name: "dentro de la misma hora", level: 10, hours: 0.5, want: 10.5},
+		{name: "un día", level: 10, hours: 24, want: 34},
+		{name: "no pasa de 100", level: 90, hours: 24, want: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := from.Add(time.Duration(tt.hours * float64(time.Hour)))
+			expected, low, high := m.LevelAt(tt.level, from, at)
+			if math.Abs(expected-tt.want) > 1e-6 {
+				t.Errorf("LevelAt() = %v, se esperaba %v", expected, tt.want)
+			}
+			// Sin dispersión, el intervalo de confianza se reduce a la predicción.
+			if math.Abs(low-expected) > 1e-6 || math.Abs(high-expected) > 1e-6 {
+				t.Errorf("intervalo = [%v, %v], se esperaba %v", low, high, expected)
+			}
+		})
+	}
+
+	f := m.Predict(50, from, from)
+	if f.HoursToFull == nil || math.Abs(*f.HoursToFull-50) > 0.1 {
+		t.Fatalf("HoursToFull = %v, se esperaban 50 horas", f.HoursToFull)
+	}
+	if f.FullAtEarliest == nil || f.FullAtLatest == nil || !f.FullAtEarliest.Equal(*f.PredictedFullAt) || !f.FullAtLatest.Equal(*f.PredictedFullAt) {
+		t.Errorf("intervalo de llenado = [%v, %v], se esperaba %v", f.FullAtEarliest, f.FullAtLatest, f.PredictedFullAt)
+	}
+}
+
+func TestLevelAtSeasonality(t *testing.T) {
+	// El contenedor solo se llena de día (de 8:00 a 20:00), a 2 puntos por hora.
+	m, err := Fit(history(28*24, func(_ int, t time.Time) int {
+		if t.Hour() >= 8 && t.Hour() < 20 {
+			return 2
+		}
+		return 0
+	}))
+	if err != nil {
+		t.Fatalf("Fit() error inesperado: %v", err)
+	}
+
+	morning := start.Add(29*24*time.Hour + 8*time.Hour)
+	day, _, _ := m.LevelAt(0, morning, morning.Add(12*time.Hour))
+	night, _, _ := m.LevelAt(0, morning.Add(12*time.Hour), morning.Add(24*time.Hour))
+	if day < 20 || night > 4 {
+		t.Errorf("subida de día = %.1f y de noche = %.1f, se esperaba cerca de 24 de día y casi nada de noche", day, night)
+	}
+
+	// La tasa media se conserva: en un día completo sube lo mismo que en los datos.
+	full, _, _ := m.LevelAt(0, morning, morning.Add(24*time.Hour))
+	if math.Abs(full-24) > 1 {
+		t.Errorf("subida en un día = %.1f, se esperaban unos 24 puntos", full)
+	}
+}
+
+func TestLevelAtConfidenceInterval(t *testing.T) {
+	// Subidas irregulares (de 0 a 2 puntos por hora, 1 de media) que no siguen la hora del día.
+	pattern := []int{0, 2, 1, 2, 0}
+	m, err := Fit(history(14*24, func(i int, _ time.Time) int { return pattern[i%len(pattern)] }))
+	if err != nil {
+		t.Fatalf("Fit() error inesperado: %v", err)
+	}
+
+	from := start.Add(15 * 24 * time.Hour)
+	width := func(hours int) float64 {
+		expected, low, high := m.LevelAt(0, from, from.Add(time.Duration(hours)*time.Hour))
+		if !(low < expected && expected < high) {
+			t.Fatalf("a %d horas: intervalo [%v, %v] no contiene la predicción %v", hours, low, high, expected)
+		}
+		return (high - low) / expected
+	}
+	// El ruido se promedia: el intervalo relativo se estrecha con el horizonte.
+	if short, long := width(1), width(48); long >= short {
+		t.Errorf("anchura relativa a 48 h = %.3f, se esperaba menor que a 1 h (%.3f)", long, short)
+	}
+}
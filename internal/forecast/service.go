@@ -0,0 +1,177 @@
+package forecast
+
+import (
+	"context"
+	"smart-waste-management/internal/domain"
+	"sync"
+	"time"
+)
+
+const (
+	// HistoryWindow es el periodo de lecturas que se usa para ajustar los modelos.
+	HistoryWindow = 28 * 24 * time.Hour
+	// modelTTL es el tiempo durante el que se reutiliza un modelo ajustado antes de volver a ajustarlo.
+	// El nivel actual del contenedor no forma parte del modelo, así que las predicciones siguen al día.
+	modelTTL = 30 * time.Minute
+)
+
+// ReadingSource es la fuente del historial de lecturas. La implementa el repositorio de contenedores.
+type ReadingSource interface {
+	// FindReadingsSince devuelve, agrupadas por contenedor, las lecturas posteriores a 'since'.
+	FindReadingsSince(ctx context.Context, ids []string, since time.Time) (map[string][]domain.Reading, error)
+}
+
+// Service estima cuándo se llenarán los contenedores.
+type Service interface {
+	// ForecastContainer predice el llenado de un contenedor a partir de su estado actual.
+	// Devuelve ErrInsufficientHistory si no hay lecturas suficientes.
+	ForecastContainer(ctx context.Context, c domain.Container) (domain.Forecast, error)
+	// ForecastContainers predice el llenado de varios contenedores con una sola consulta de historial.
+	// Los contenedores sin historial suficiente no aparecen en el resultado.
+	ForecastContainers(ctx context.Context, containers []domain.Container) (map[string]domain.Forecast, error)
+	// Model devuelve el modelo de llenado ajustado de un contenedor.
+	Model(ctx context.Context, id string) (*Model, error)
+	// Models devuelve los modelos de varios contenedores. Los que no tienen historial suficiente no aparecen.
+	Models(ctx context.Context, ids []string) (map[string]*Model, error)
+}
+
+// cachedModel es un modelo ajustado (o el error de ajuste) junto con el momento en que se calculó.
+type cachedModel struct {
+	model    *Model
+	err      error
+	fittedAt time.Time
+}
+
+type service struct {
+	source ReadingSource
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedModel
+	// prunedAt es la última vez que se quitaron de la caché los modelos caducados.
+	prunedAt time.Time
+}
+
+// NewService crea una nueva instancia del servicio de predicción.
+func NewService(source ReadingSource) Service {
+	return &service{
+		source: source,
+		now:    time.Now,
+		cache:  make(map[string]cachedModel),
+	}
+}
+
+func (s *service) ForecastContainer(ctx context.Context, c domain.Container) (domain.Forecast, error) {
+	m, err := s.Model(ctx, c.ID)
+	if err != nil {
+		return domain.Forecast{}, err
+	}
+	return s.predict(m, c), nil
+}
+
+func (s *service) ForecastContainers(ctx context.Context, containers []domain.Container) (map[string]domain.Forecast, error) {
+	ids := make([]string, len(containers))
+	for i, c := range containers {
+		ids[i] = c.ID
+	}
+	models, err := s.Models(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	forecasts := make(map[string]domain.Forecast, len(models))
+	for _, c := range containers {
+		if m, ok := models[c.ID]; ok {
+			forecasts[c.ID] = s.predict(m, c)
+		}
+	}
+	return forecasts, nil
+}
+
+func (s *service) Model(ctx context.Context, id string) (*Model, error) {
+	if cached, ok := s.cached(id); ok {
+		return cached.model, cached.err
+	}
+
+	// Se ajusta con la misma ventana que Models: ambos comparten la caché.
+	history, err := s.source.FindReadingsSince(ctx, []string{id}, s.now().Add(-HistoryWindow))
+	if err != nil {
+		return nil, err
+	}
+	m, err := Fit(history[id])
+	s.store(id, m, err)
+	return m, err
+}
+
+func (s *service) Models(ctx context.Context, ids []string) (map[string]*Model, error) {
+	models := make(map[string]*Model, len(ids))
+	var missing []string
+	for _, id := range ids {
+		cached, ok := s.cached(id)
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		if cached.err == nil {
+			models[id] = cached.model
+		}
+	}
+	if len(missing) == 0 {
+		return models, nil
+	}
+
+	// Solo consultamos el historial de los contenedores sin un modelo vigente.
+	history, err := s.source.FindReadingsSince(ctx, missing, s.now().Add(-HistoryWindow))
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range missing {
+		m, err := Fit(history[id])
+		s.store(id, m, err)
+		if err == nil {
+			models[id] = m
+		}
+	}
+	return models, nil
+}
+
+// predict proyecta el modelo desde el último estado conocido del contenedor.
+func (s *service) predict(m *Model, c domain.Container) domain.Forecast {
+	now := s.now()
+	from := c.LastUpdatedAt
+	if from.IsZero() {
+		from = now
+	}
+	f := m.Predict(c.LastFillLevel, from, now)
+	f.ContainerID = c.ID
+	return f
+}
+
+func (s *service) cached(id string) (cachedModel, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, ok := s.cache[id]
+	if !ok || s.now().Sub(cached.fittedAt) > modelTTL {
+		return cachedModel{}, false
+	}
+	return cached, true
+}
+
+func (s *service) store(id string, m *Model, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Los modelos caducados de contenedores que ya no se consultan (p. ej. eliminados) se quitan
+	// como mucho una vez por modelTTL, para no recorrer la caché en cada ajuste.
+	now := s.now()
+	if now.Sub(s.prunedAt) > modelTTL {
+		for cachedID, cached := range s.cache {
+			if now.Sub(cached.fittedAt) > modelTTL {
+				delete(s.cache, cachedID)
+			}
+		}
+		s.prunedAt = now
+	}
+	s.cache[id] = cachedModel{model: m, err: err, fittedAt: now}
+}
@@ -0,0 +1,24 @@
+package forecast
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStorePrunesExpiredModels(t *testing.T) {
+	now := start
+	s := NewService(nil).(*service)
+	s.now = func() time.Time { return now }
+
+	s.store("antiguo", nil, ErrInsufficientHistory)
+	now = now.Add(modelTTL / 2)
+	s.store("reciente", nil, ErrInsufficientHistory)
+	now = now.Add(modelTTL/2 + time.Minute)
+	s.store("nuevo", nil, ErrInsufficientHistory)
+
+	for id, want := range map[string]bool{"antiguo": false, "reciente": true, "nuevo": true} {
+		if _, ok := s.cache[id]; ok != want {
+			t.Errorf("modelo %q en caché = %v, se esperaba %v", id, ok, want)
+		}
+	}
+}
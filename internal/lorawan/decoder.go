@@ -0,0 +1,155 @@
+package lorawan
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrDecode se devuelve cuando un payload no tiene el formato esperado por el decodificador.
+var ErrDecode = errors.New("no se pudo decodificar el payload")
+
+// Measurement es el resultado de decodificar un uplink.
+// Los campos son punteros porque no todos los modelos de sensor informan de todas las magnitudes.
+type Measurement struct {
+	DistanceMM *int `json:"distance_mm,omitempty"`
+	FillLevel  *int `json:"fill_level,omitempty"` // Para sensores que ya calculan el porcentaje.
+	// Los sensores informan de la batería en mV o en porcentaje, según el modelo.
+	BatteryMV      *int     `json:"battery_mv,omitempty"`
+	BatteryPercent *int     `json:"battery_percent,omitempty"`
+	TemperatureC   *float64 `json:"temperature_c,omitempty"`
+}
+
+// Decoder convierte el payload binario (frm_payload) de un modelo de sensor concreto en una medida.
+// Para soportar un nuevo modelo basta con implementar esta interfaz y registrarla en el Registry.
+type Decoder interface {
+	// Name es el identificador con el que se asocia el decodificador a cada dispositivo.
+	Name() string
+	// Decode interpreta el payload recibido en el puerto fPort.
+	Decode(fPort int, payload []byte) (Measurement, error)
+}
+
+// Registry mantiene los decodificadores disponibles indexados por nombre.
+type Registry struct {
+	mu       sync.RWMutex
+	decoders map[string]Decoder
+}
+
+// NewRegistry crea un registro con los decodificadores indicados.
+func NewRegistry(decoders ...Decoder) *Registry {
+	r := &Registry{decoders: make(map[string]Decoder)}
+	for _, d := range decoders {
+		r.Register(d)
+	}
+	return r
+}
+
+// DefaultRegistry devuelve un registro con los modelos de sensor soportados de serie.
+func DefaultRegistry() *Registry {
+	return NewRegistry(
+		DraginoLDDS75Decoder{},
+		MilesightEM310Decoder{},
+	)
+}
+
+// Register añade (o reemplaza) un decodificador.
+func (r *Registry) Register(d Decoder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.decoders[d.Name()] = d
+}
+
+// Get busca un decodificador por nombre.
+func (r *Registry) Get(name string) (Decoder, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.decoders[name]
+	return d, ok
+}
+
+// Names devuelve los nombres de los decodificadores registrados, ordenados alfabéticamente.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.decoders))
+	for name := range r.decoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DraginoLDDS75Decoder decodifica el sensor ultrasónico Dragino LDDS75.
+// Formato (8 bytes, big endian): batería en mV (2, los 14 bits bajos), distancia en mm (2),
+// flag de interrupción (1), temperatura DS18B20 en décimas de ºC (2, con signo), flag del sensor (1).
+type DraginoLDDS75Decoder struct{}
+
+func (DraginoLDDS75Decoder) Name() string { return "dragino-ldds75" }
+
+func (DraginoLDDS75Decoder) Decode(_ int, payload []byte) (Measurement, error) {
+	if len(payload) < 4 {
+		return Measurement{}, fmt.Errorf("%w: se esperaban al menos 4 bytes y se recibieron %d", ErrDecode, len(payload))
+	}
+
+	battery := int(binary.BigEndian.Uint16(payload[0:2]) & 0x3FFF)
+	distance := int(binary.BigEndian.Uint16(payload[2:4]))
+	m := Measurement{BatteryMV: &battery}
+
+	// El sensor informa 0 cuando no detecta eco (sin lectura válida).
+	if distance > 0 {
+		m.DistanceMM = &distance
+	}
+
+	if len(payload) >= 7 {
+		raw := int16(binary.BigEndian.Uint16(payload[5:7]))
+		// 0x7FFF indica que no hay sonda de temperatura conectada.
+		if raw != 0x7FFF {
+			temperature := float64(raw) / 10
+			m.TemperatureC = &temperature
+		}
+	}
+	return m, nil
+}
+
+// MilesightEM310Decoder decodifica el sensor ultrasónico Milesight EM310-UDL.
+// El payload es una secuencia de canales (canal, tipo, valor) en little endian:
+// 0x01 0x75 batería en % (1 byte), 0x03 0x82 distancia en mm (2 bytes), 0x04 0x00 posición (1 byte).
+type MilesightEM310Decoder struct{}
+
+func (MilesightEM310Decoder) Name() string { return "milesight-em310-udl" }
+
+func (MilesightEM310Decoder) Decode(_ int, payload []byte) (Measurement, error) {
+	var m Measurement
+	for i := 0; i+1 < len(payload); {
+		channel, kind := payload[i], payload[i+1]
+		i += 2
+
+		switch {
+		case channel == 0x01 && kind == 0x75: // Batería (%).
+			if i+1 > len(payload) {
+				return Measurement{}, fmt.Errorf("%w: canal de batería incompleto", ErrDecode)
+			}
+			battery := int(payload[i])
+			m.BatteryPercent = &battery
+			i++
+		case channel == 0x03 && kind == 0x82: // Distancia (mm).
+			if i+2 > len(payload) {
+				return Measurement{}, fmt.Errorf("%w: canal de distancia incompleto", ErrDecode)
+			}
+			distance := int(binary.LittleEndian.Uint16(payload[i : i+2]))
+			m.DistanceMM = &distance
+			i += 2
+		case channel == 0x04 && kind == 0x00: // Posición (inclinación); no se usa.
+			i++
+		default:
+			return Measurement{}, fmt.Errorf("%w: canal desconocido 0x%02x 0x%02x", ErrDecode, channel, kind)
+		}
+	}
+
+	if m.DistanceMM == nil && m.BatteryPercent == nil {
+		return Measurement{}, fmt.Errorf("%w: el payload no contiene ninguna medida", ErrDecode)
+	}
+	return m, nil
+}
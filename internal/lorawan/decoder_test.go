@@ -0,0 +1,154 @@
+package lorawan
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func intPtr(v int) *int { return &v }
+
+func floatPtr(v float64) *float64 { return &v }
+
+// format muestra una medida con los valores de sus punteros para comparar y para los mensajes de error.
+func format(m Measurement) string {
+	show := func(p any) string {
+		switch v := p.(type) {
+		case *int:
+			if v != nil {
+				return fmt.Sprint(*v)
+			}
+		case *float64:
+			if v != nil {
+				return fmt.Sprint(*v)
+			}
+		}
+		return "-"
+	}
+	return fmt.Sprintf("distancia=%s llenado=%s batería=%smV/%s%% temperatura=%s",
+		show(m.DistanceMM), show(m.FillLevel), show(m.BatteryMV), show(m.BatteryPercent), show(m.TemperatureC))
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("payload de prueba mal escrito %q: %v", s, err)
+	}
+	return b
+}
+
+func TestDraginoLDDS75Decode(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    Measurement
+		wantErr bool
+	}{
+		{
+			// Trama de ejemplo del manual: 2885 mV, 2821 mm, sin interrupción, 26,1 ºC.
+			name:    "trama de ejemplo del fabricante",
+			payload: "0B450B0500010501",
+			want:    Measurement{BatteryMV: intPtr(2885), DistanceMM: intPtr(2821), TemperatureC: floatPtr(26.1)},
+		},
+		{
+			name:    "temperatura bajo cero",
+			payload: "0B450B0500FF3F01",
+			want:    Measurement{BatteryMV: intPtr(2885), DistanceMM: intPtr(2821), TemperatureC: floatPtr(-19.3)},
+		},
+		{
+			name:    "sin sonda de temperatura",
+			payload: "0B450B05007FFF00",
+			want:    Measurement{BatteryMV: intPtr(2885), DistanceMM: intPtr(2821)},
+		},
+		{
+			name:    "los dos bits altos de la batería no forman parte del voltaje",
+			payload: "CB450B05",
+			want:    Measurement{BatteryMV: intPtr(2885), DistanceMM: intPtr(2821)},
+		},
+		{
+			name:    "sin eco",
+			payload: "0B4500000000FA01",
+			want:    Measurement{BatteryMV: intPtr(2885), TemperatureC: floatPtr(25)},
+		},
+		{name: "payload demasiado corto", payload: "0B450B", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DraginoLDDS75Decoder{}.Decode(2, decodeHex(t, tt.payload))
+			if tt.wantErr {
+				if !errors.Is(err, ErrDecode) {
+					t.Fatalf("Decode() error = %v, se esperaba ErrDecode", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error inesperado: %v", err)
+			}
+			if format(got) != format(tt.want) {
+				t.Errorf("Decode() = %s, se esperaba %s", format(got), format(tt.want))
+			}
+		})
+	}
+}
+
+func TestMilesightEM310Decode(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    Measurement
+		wantErr bool
+	}{
+		{
+			// Trama de ejemplo de la guía de usuario: batería al 92 %, 180 mm, posición normal.
+			name:    "trama de ejemplo del fabricante",
+			payload: "01755C0382B4000400",
+			want:    Measurement{BatteryPercent: intPtr(92), DistanceMM: intPtr(180)},
+		},
+		{
+			name:    "solo distancia",
+			payload: "03821E0F",
+			want:    Measurement{DistanceMM: intPtr(3870)},
+		},
+		{
+			name:    "solo batería",
+			payload: "017564",
+			want:    Measurement{BatteryPercent: intPtr(100)},
+		},
+		{name: "canal de distancia incompleto", payload: "01755C0382B4", wantErr: true},
+		{name: "canal de batería incompleto", payload: "0175", wantErr: true},
+		{name: "canal desconocido", payload: "05740A00", wantErr: true},
+		{name: "sin medidas", payload: "040001", wantErr: true},
+		{name: "vacío", payload: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MilesightEM310Decoder{}.Decode(85, decodeHex(t, tt.payload))
+			if tt.wantErr {
+				if !errors.Is(err, ErrDecode) {
+					t.Fatalf("Decode() error = %v, se esperaba ErrDecode", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error inesperado: %v", err)
+			}
+			if format(got) != format(tt.want) {
+				t.Errorf("Decode() = %s, se esperaba %s", format(got), format(tt.want))
+			}
+		})
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	r := DefaultRegistry()
+	if got, want := fmt.Sprint(r.Names()), "[dragino-ldds75 milesight-em310-udl]"; got != want {
+		t.Errorf("Names() = %s, se esperaba %s", got, want)
+	}
+	if _, ok := r.Get("desconocido"); ok {
+		t.Error("Get() ha encontrado un decodificador que no está registrado")
+	}
+}
@@ -0,0 +1,227 @@
+package lorawan
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"smart-waste-management/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler maneja los webhooks de los servidores de red LoRaWAN y la gestión de dispositivos.
+type Handler struct {
+	service      Service
+	webhookToken string
+}
+
+// RegisterDeviceRequest define el cuerpo de la petición para asociar un sensor a un contenedor.
+type RegisterDeviceRequest struct {
+	DevEUI          string `json:"dev_eui" binding:"required,hexadecimal,len=16"`
+	ContainerID     string `json:"container_id" binding:"required,uuid"`
+	Decoder         string `json:"decoder" binding:"required"`
+	EmptyDistanceMM int    `json:"empty_distance_mm" binding:"required,gt=0,gtfield=FullDistanceMM"`
+	FullDistanceMM  int    `json:"full_distance_mm" binding:"gte=0"`
+}
+
+// NewHandler crea una nueva instancia del handler.
+// Si webhookToken no está vacío, los webhooks deben incluirlo en la cabecera X-Webhook-Token.
+func NewHandler(s Service, webhookToken string) *Handler {
+	return &Handler{
+		service:      s,
+		webhookToken: webhookToken,
+	}
+}
+
+// RegisterRoutes registra todas las rutas de este handler en el router de Gin.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	uplinks := router.Group("/lorawan/uplinks", h.requireWebhookToken)
+	uplinks.POST("/ttn", h.ReceiveTTNUplink)
+	uplinks.POST("/chirpstack", h.ReceiveChirpStackUplink)
+
+	router.GET("/lorawan/devices", h.GetDevices)
+	router.POST("/lorawan/devices", h.RegisterDevice)
+	router.DELETE("/lorawan/devices/:dev_eui", h.DeleteDevice)
+	router.GET("/lorawan/decoders", h.GetDecoders)
+}
+
+// requireWebhookToken comprueba el secreto compartido configurado en el servidor de red.
+func (h *Handler) requireWebhookToken(c *gin.Context) {
+	if h.webhookToken == "" {
+		c.Next()
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Webhook-Token")), []byte(h.webhookToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token de webhook inválido"})
+		return
+	}
+	c.Next()
+}
+
+// ReceiveTTNUplink procesa un webhook de uplink de The Things Stack.
+// @Summary      Recibe un uplink de The Things Stack
+// @Description  Webhook para The Things Stack (v3). Resuelve el contenedor a partir del DevEUI, decodifica 'frm_payload' con el decodificador del modelo de sensor y registra la lectura de llenado.
+// @Tags         LoRaWAN
+// @Accept       json
+// @Produce      json
+// @Param        X-Webhook-Token  header    string        false  "Secreto compartido del webhook (si está configurado)"
+// @Param        uplink           body      TTNUplink     true   "Mensaje de uplink de The Things Stack"
+// @Success      200              {object}  UplinkResult  "Uplink procesado"
+// @Success      204              "Evento sin uplink, ignorado"
+// @Failure      400              {object}  map[string]string "Petición inválida"
+// @Failure      401              {object}  map[string]string "Token de webhook inválido"
+// @Failure      404              {object}  map[string]string "DevEUI no registrado"
+// @Failure      422              {object}  map[string]string "Payload no decodificable"
+// @Failure      500              {object}  map[string]string "Error interno del servidor"
+// @Router       /lorawan/uplinks/ttn [post]
+func (h *Handler) ReceiveTTNUplink(c *gin.Context) {
+	var msg TTNUplink
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cuerpo de la petición inválido: " + err.Error()})
+		return
+	}
+
+	uplink, ok := msg.ToUplink()
+	if !ok {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	h.handleUplink(c, uplink)
+}
+
+// ReceiveChirpStackUplink procesa un evento de la integración HTTP de ChirpStack.
+// @Summary      Recibe un uplink de ChirpStack
+// @Description  Webhook para la integración HTTP de ChirpStack (v4, codificación JSON). Los eventos distintos de 'up' se ignoran.
+// @Tags         LoRaWAN
+// @Accept       json
+// @Produce      json
+// @Param        X-Webhook-Token  header    string            false  "Secreto compartido del webhook (si está configurado)"
+// @Param        event            query     string            false  "Tipo de evento enviado por ChirpStack (up, join, status...)"
+// @Param        uplink           body      ChirpStackUplink  true   "Evento de uplink de ChirpStack"
+// @Success      200              {object}  UplinkResult      "Uplink procesado"
+// @Success      204              "Evento distinto de 'up', ignorado"
+// @Failure      400              {object}  map[string]string "Petición inválida"
+// @Failure      401              {object}  map[string]string "Token de webhook inválido"
+// @Failure      404              {object}  map[string]string "DevEUI no registrado"
+// @Failure      422              {object}  map[string]string "Payload no decodificable"
+// @Failure      500              {object}  map[string]string "Error interno del servidor"
+// @Router       /lorawan/uplinks/chirpstack [post]
+func (h *Handler) ReceiveChirpStackUplink(c *gin.Context) {
+	if event := c.Query("event"); event != "" && event != "up" {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	var msg ChirpStackUplink
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cuerpo de la petición inválido: " + err.Error()})
+		return
+	}
+	h.handleUplink(c, msg.ToUplink())
+}
+
+// handleUplink es común a todos los servidores de red: delega en el servicio y traduce los errores.
+func (h *Handler) handleUplink(c *gin.Context, uplink Uplink) {
+	if uplink.DevEUI == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El uplink no incluye el DevEUI"})
+		return
+	}
+
+	result, err := h.service.HandleUplink(c.Request.Context(), uplink)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrDeviceNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("DevEUI %s no registrado", uplink.DevEUI)})
+		case errors.Is(err, ErrDecode), errors.Is(err, ErrUnknownDecoder):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrNoFillMeasurement):
+			// La telemetría se ha guardado; simplemente no hay lectura que registrar.
+			c.JSON(http.StatusOK, result)
+		default:
+			fmt.Printf("Error al procesar el uplink de %s: %v\n", uplink.DevEUI, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo procesar el uplink"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary      Obtiene los dispositivos LoRaWAN
+// @Description  Devuelve todos los sensores LoRaWAN registrados, con el contenedor asociado y su última telemetría.
+// @Tags         LoRaWAN
+// @Produce      json
+// @Success      200  {object}  []domain.SensorDevice
+// @Failure      500  {object}  map[string]string "Error interno del servidor"
+// @Router       /lorawan/devices [get]
+func (h *Handler) GetDevices(c *gin.Context) {
+	devices, err := h.service.GetAllDevices(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener la lista de dispositivos"})
+		return
+	}
+	c.JSON(http.StatusOK, devices)
+}
+
+// @Summary      Registra un dispositivo LoRaWAN
+// @Description  Asocia un DevEUI a un contenedor y a un decodificador. Si el DevEUI ya existe, se actualiza.
+// @Tags         LoRaWAN
+// @Accept       json
+// @Produce      json
+// @Param        device  body      RegisterDeviceRequest  true  "Datos del dispositivo"
+// @Success      201     {object}  domain.SensorDevice
+// @Failure      400     {object}  map[string]string "Petición inválida o decodificador desconocido"
+// @Failure      500     {object}  map[string]string "Error interno del servidor"
+// @Router       /lorawan/devices [post]
+func (h *Handler) RegisterDevice(c *gin.Context) {
+	var req RegisterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	device := domain.SensorDevice{
+		DevEUI:          req.DevEUI,
+		ContainerID:     req.ContainerID,
+		Decoder:         req.Decoder,
+		EmptyDistanceMM: req.EmptyDistanceMM,
+		FullDistanceMM:  req.FullDistanceMM,
+	}
+
+	saved, err := h.service.RegisterDevice(c.Request.Context(), device)
+	if err != nil {
+		if errors.Is(err, ErrUnknownDecoder) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo registrar el dispositivo"})
+		return
+	}
+	c.JSON(http.StatusCreated, saved)
+}
+
+// @Summary      Elimina un dispositivo LoRaWAN
+// @Description  Elimina la asociación de un DevEUI con su contenedor.
+// @Tags         LoRaWAN
+// @Param        dev_eui  path  string  true  "DevEUI del dispositivo"
+// @Success      204      "Sin contenido"
+// @Failure      500      {object}  map[string]string "Error interno del servidor"
+// @Router       /lorawan/devices/{dev_eui} [delete]
+func (h *Handler) DeleteDevice(c *gin.Context) {
+	if err := h.service.DeleteDevice(c.Request.Context(), c.Param("dev_eui")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar el dispositivo"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary      Obtiene los decodificadores disponibles
+// @Description  Devuelve los modelos de sensor para los que existe un decodificador de payload.
+// @Tags         LoRaWAN
+// @Produce      json
+// @Success      200  {object}  []string
+// @Router       /lorawan/decoders [get]
+func (h *Handler) GetDecoders(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.DecoderNames())
+}
@@ -0,0 +1,127 @@
+package lorawan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/platform/database"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrDeviceNotFound se devuelve cuando no hay ningún dispositivo registrado con el DevEUI indicado.
+var ErrDeviceNotFound = errors.New("dispositivo no encontrado")
+
+// Repository define las operaciones de persistencia de los dispositivos LoRaWAN.
+type Repository interface {
+	// FindDeviceByEUI busca el dispositivo (y por tanto el contenedor) asociado a un DevEUI.
+	FindDeviceByEUI(ctx context.Context, devEUI string) (domain.SensorDevice, error)
+	FindAllDevices(ctx context.Context) ([]domain.SensorDevice, error)
+	// SaveDevice crea o reemplaza la asociación de un DevEUI con un contenedor.
+	SaveDevice(ctx context.Context, device domain.SensorDevice) (domain.SensorDevice, error)
+	DeleteDevice(ctx context.Context, devEUI string) error
+	// UpdateTelemetry guarda la batería y temperatura informadas en el último uplink.
+	UpdateTelemetry(ctx context.Context, devEUI string, m Measurement, seenAt time.Time) error
+}
+
+// postgresRepository es la implementación concreta de Repository para PostgreSQL.
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresRepository crea una nueva instancia del repositorio.
+func NewPostgresRepository(db *database.DB) Repository {
+	return &postgresRepository{
+		db: db.Pool,
+	}
+}
+
+const deviceColumns = `dev_eui, container_id, decoder, empty_distance_mm, full_distance_mm,
+               last_battery_mv, last_battery_percent, last_temperature_c, last_seen_at, created_at, updated_at`
+
+func scanDevice(row pgx.Row) (domain.SensorDevice, error) {
+	var d domain.SensorDevice
+	err := row.Scan(
+		&d.DevEUI, &d.ContainerID, &d.Decoder, &d.EmptyDistanceMM, &d.FullDistanceMM,
+		&d.LastBatteryMV, &d.LastBatteryPercent, &d.LastTemperatureC, &d.LastSeenAt, &d.CreatedAt, &d.UpdatedAt,
+	)
+	return d, err
+}
+
+func (r *postgresRepository) FindDeviceByEUI(ctx context.Context, devEUI string) (domain.SensorDevice, error) {
+	query := `SELECT ` + deviceColumns + ` FROM lorawan_devices WHERE dev_eui = $1`
+
+	d, err := scanDevice(r.db.QueryRow(ctx, query, devEUI))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.SensorDevice{}, ErrDeviceNotFound
+		}
+		return domain.SensorDevice{}, fmt.Errorf("error al buscar el dispositivo %s: %w", devEUI, err)
+	}
+	return d, nil
+}
+
+func (r *postgresRepository) FindAllDevices(ctx context.Context) ([]domain.SensorDevice, error) {
+	query := `SELECT ` + deviceColumns + ` FROM lorawan_devices ORDER BY dev_eui`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar los dispositivos: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []domain.SensorDevice
+	for rows.Next() {
+		d, err := scanDevice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al escanear el dispositivo: %w", err)
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+func (r *postgresRepository) SaveDevice(ctx context.Context, device domain.SensorDevice) (domain.SensorDevice, error) {
+	query := `
+        INSERT INTO lorawan_devices (dev_eui, container_id, decoder, empty_distance_mm, full_distance_mm)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (dev_eui) DO UPDATE
+        SET container_id = EXCLUDED.container_id, decoder = EXCLUDED.decoder,
+            empty_distance_mm = EXCLUDED.empty_distance_mm, full_distance_mm = EXCLUDED.full_distance_mm,
+            updated_at = NOW()
+        RETURNING ` + deviceColumns
+
+	saved, err := scanDevice(r.db.QueryRow(ctx, query,
+		device.DevEUI, device.ContainerID, device.Decoder, device.EmptyDistanceMM, device.FullDistanceMM,
+	))
+	if err != nil {
+		return domain.SensorDevice{}, fmt.Errorf("error al guardar el dispositivo: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *postgresRepository) DeleteDevice(ctx context.Context, devEUI string) error {
+	query := `DELETE FROM lorawan_devices WHERE dev_eui = $1`
+	_, err := r.db.Exec(ctx, query, devEUI)
+	return err
+}
+
+func (r *postgresRepository) UpdateTelemetry(ctx context.Context, devEUI string, m Measurement, seenAt time.Time) error {
+	// COALESCE conserva el último valor conocido si el uplink no informa de esa magnitud.
+	query := `
+        UPDATE lorawan_devices
+        SET last_battery_mv = COALESCE($1, last_battery_mv),
+            last_battery_percent = COALESCE($2, last_battery_percent),
+            last_temperature_c = COALESCE($3, last_temperature_c),
+            last_seen_at = $4, updated_at = NOW()
+        WHERE dev_eui = $5`
+
+	_, err := r.db.Exec(ctx, query, m.BatteryMV, m.BatteryPercent, m.TemperatureC, seenAt, devEUI)
+	if err != nil {
+		return fmt.Errorf("error al actualizar la telemetría del dispositivo %s: %w", devEUI, err)
+	}
+	return nil
+}
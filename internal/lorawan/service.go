@@ -0,0 +1,131 @@
+package lorawan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smart-waste-management/internal/domain"
+	"time"
+)
+
+// ErrUnknownDecoder se devuelve cuando un dispositivo referencia un decodificador no registrado.
+var ErrUnknownDecoder = errors.New("decodificador desconocido")
+
+// ErrNoFillMeasurement se devuelve cuando el uplink no contiene ninguna medida de llenado
+// (p. ej. un mensaje periódico de estado que solo informa de la batería).
+var ErrNoFillMeasurement = errors.New("el uplink no contiene una medida de llenado")
+
+// ReadingProcessor es la parte del servicio de contenedores que necesita este módulo.
+type ReadingProcessor interface {
+	ProcessNewReading(ctx context.Context, reading domain.Reading) (domain.ReadingOutcome, error)
+}
+
+// UplinkResult describe qué se ha obtenido de un uplink.
+type UplinkResult struct {
+	DevEUI      string                `json:"dev_eui"`
+	Measurement Measurement           `json:"measurement"`
+	Reading     *domain.Reading       `json:"reading,omitempty"`
+	Outcome     domain.ReadingOutcome `json:"outcome,omitempty"`
+}
+
+// Service define la lógica de negocio de la integración LoRaWAN.
+type Service interface {
+	// HandleUplink decodifica un uplink y, si contiene una medida de llenado, la procesa como lectura.
+	HandleUplink(ctx context.Context, uplink Uplink) (UplinkResult, error)
+	RegisterDevice(ctx context.Context, device domain.SensorDevice) (domain.SensorDevice, error)
+	GetAllDevices(ctx context.Context) ([]domain.SensorDevice, error)
+	DeleteDevice(ctx context.Context, devEUI string) error
+	// DecoderNames devuelve los modelos de sensor soportados.
+	DecoderNames() []string
+}
+
+type service struct {
+	repo      Repository
+	decoders  *Registry
+	processor ReadingProcessor
+}
+
+// NewService crea una nueva instancia del servicio.
+func NewService(repo Repository, decoders *Registry, processor ReadingProcessor) Service {
+	return &service{
+		repo:      repo,
+		decoders:  decoders,
+		processor: processor,
+	}
+}
+
+func (s *service) HandleUplink(ctx context.Context, uplink Uplink) (UplinkResult, error) {
+	result := UplinkResult{DevEUI: uplink.DevEUI}
+
+	// 1. Resolver el contenedor a partir del DevEUI.
+	device, err := s.repo.FindDeviceByEUI(ctx, uplink.DevEUI)
+	if err != nil {
+		return result, err
+	}
+
+	// 2. Decodificar el payload con el decodificador del modelo de sensor.
+	decoder, ok := s.decoders.Get(device.Decoder)
+	if !ok {
+		return result, fmt.Errorf("%w: %s", ErrUnknownDecoder, device.Decoder)
+	}
+	measurement, err := decoder.Decode(uplink.FPort, uplink.Payload)
+	if err != nil {
+		return result, err
+	}
+	result.Measurement = measurement
+
+	receivedAt := uplink.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now().UTC()
+	}
+
+	// 3. Guardar la telemetría del dispositivo (batería, temperatura) aunque no haya lectura de llenado.
+	if err := s.repo.UpdateTelemetry(ctx, device.DevEUI, measurement, receivedAt); err != nil {
+		return result, err
+	}
+
+	// 4. Convertir la medida en una lectura de llenado.
+	var fillLevel int
+	switch {
+	case measurement.FillLevel != nil:
+		fillLevel = *measurement.FillLevel
+	case measurement.DistanceMM != nil:
+		fillLevel = device.FillLevelFromDistance(*measurement.DistanceMM)
+	default:
+		return result, ErrNoFillMeasurement
+	}
+
+	reading := domain.Reading{
+		ContainerID: device.ContainerID,
+		FillLevel:   fillLevel,
+		Timestamp:   receivedAt,
+	}
+	outcome, err := s.processor.ProcessNewReading(ctx, reading)
+	if err != nil {
+		return result, fmt.Errorf("error al procesar la lectura del dispositivo %s: %w", device.DevEUI, err)
+	}
+	result.Reading = &reading
+	result.Outcome = outcome
+
+	return result, nil
+}
+
+func (s *service) RegisterDevice(ctx context.Context, device domain.SensorDevice) (domain.SensorDevice, error) {
+	device.DevEUI = normalizeDevEUI(device.DevEUI)
+	if _, ok := s.decoders.Get(device.Decoder); !ok {
+		return domain.SensorDevice{}, fmt.Errorf("%w: %s", ErrUnknownDecoder, device.Decoder)
+	}
+	return s.repo.SaveDevice(ctx, device)
+}
+
+func (s *service) GetAllDevices(ctx context.Context) ([]domain.SensorDevice, error) {
+	return s.repo.FindAllDevices(ctx)
+}
+
+func (s *service) DeleteDevice(ctx context.Context, devEUI string) error {
+	return s.repo.DeleteDevice(ctx, normalizeDevEUI(devEUI))
+}
+
+func (s *service) DecoderNames() []string {
+	return s.decoders.Names()
+}
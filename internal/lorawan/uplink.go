@@ -0,0 +1,74 @@
+package lorawan
+
+import (
+	"strings"
+	"time"
+)
+
+// Uplink es la representación común de un uplink, independiente del servidor de red que lo envía.
+type Uplink struct {
+	DevEUI     string
+	FPort      int
+	Payload    []byte
+	ReceivedAt time.Time
+}
+
+// TTNUplink es el subconjunto del mensaje de webhook de The Things Stack (v3) que necesitamos.
+// encoding/json decodifica automáticamente 'frm_payload' (base64) en []byte.
+type TTNUplink struct {
+	EndDeviceIDs struct {
+		DeviceID string `json:"device_id"`
+		DevEUI   string `json:"dev_eui"`
+	} `json:"end_device_ids"`
+	ReceivedAt    time.Time `json:"received_at"`
+	UplinkMessage *struct {
+		FPort      int       `json:"f_port"`
+		FRMPayload []byte    `json:"frm_payload"`
+		ReceivedAt time.Time `json:"received_at"`
+	} `json:"uplink_message"`
+}
+
+// ToUplink normaliza el mensaje. El segundo valor es false si el webhook no contiene un uplink
+// (p. ej. eventos de join o de downlink enviados al mismo endpoint).
+func (m TTNUplink) ToUplink() (Uplink, bool) {
+	if m.UplinkMessage == nil {
+		return Uplink{}, false
+	}
+	receivedAt := m.UplinkMessage.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = m.ReceivedAt
+	}
+	return Uplink{
+		DevEUI:     normalizeDevEUI(m.EndDeviceIDs.DevEUI),
+		FPort:      m.UplinkMessage.FPort,
+		Payload:    m.UplinkMessage.FRMPayload,
+		ReceivedAt: receivedAt,
+	}, true
+}
+
+// ChirpStackUplink es el subconjunto del evento 'up' de la integración HTTP de ChirpStack (v4, JSON).
+type ChirpStackUplink struct {
+	Time       time.Time `json:"time"`
+	DeviceInfo struct {
+		DevEUI     string `json:"devEui"`
+		DeviceName string `json:"deviceName"`
+	} `json:"deviceInfo"`
+	FPort int    `json:"fPort"`
+	Data  []byte `json:"data"`
+}
+
+// ToUplink normaliza el evento de ChirpStack.
+func (m ChirpStackUplink) ToUplink() Uplink {
+	return Uplink{
+		DevEUI:     normalizeDevEUI(m.DeviceInfo.DevEUI),
+		FPort:      m.FPort,
+		Payload:    m.Data,
+		ReceivedAt: m.Time,
+	}
+}
+
+// normalizeDevEUI unifica el formato del DevEUI (16 dígitos hexadecimales en mayúsculas, sin separadores).
+func normalizeDevEUI(devEUI string) string {
+	devEUI = strings.NewReplacer("-", "", ":", "").Replace(devEUI)
+	return strings.ToUpper(strings.TrimSpace(devEUI))
+}
@@ -0,0 +1,55 @@
+package optimizer
+
+import (
+	"context"
+	"math"
+	"smart-waste-management/internal/domain"
+)
+
+// DistanceProvider construye la matriz de distancias (km) que usan los algoritmos de rutas.
+// La matriz puede ser asimétrica (p. ej. por calles de sentido único).
+type DistanceProvider interface {
+	DistanceMatrix(ctx context.Context, points []domain.Point) (Matrix, error)
+}
+
+// Haversine es el DistanceProvider de distancias en línea recta. Es el proveedor por defecto cuando
+// no hay red viaria cargada y el respaldo para los puntos que quedan fuera de ella.
+type Haversine struct{}
+
+func (Haversine) DistanceMatrix(_ context.Context, points []domain.Point) (Matrix, error) {
+	return HaversineMatrix(points), nil
+}
+
+// earthRadiusKm es el radio medio de la Tierra en kilómetros.
+const earthRadiusKm = 6371
+
+// HaversineKm calcula la distancia en línea recta (ortodrómica), en kilómetros, entre dos puntos geográficos.
+func HaversineKm(p1, p2 domain.Point) float64 {
+	lat1Rad := p1.Latitude * math.Pi / 180
+	lon1Rad := p1.Longitude * math.Pi / 180
+	lat2Rad := p2.Latitude * math.Pi / 180
+	lon2Rad := p2.Longitude * math.Pi / 180
+
+	dLon := lon2Rad - lon1Rad
+	dLat := lat2Rad - lat1Rad
+
+	a := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1Rad)*math.Cos(lat2Rad)*math.Pow(math.Sin(dLon/2), 2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+
+	return earthRadiusKm * c
+}
+
+// HaversineMatrix construye la matriz de distancias en línea recta (km) entre todos los puntos.
+func HaversineMatrix(points []domain.Point) Matrix {
+	m := make(Matrix, len(points))
+	for i := range points {
+		m[i] = make([]float64, len(points))
+	}
+	for i := range points {
+		for j := i + 1; j < len(points); j++ {
+			d := HaversineKm(points[i], points[j])
+			m[i][j], m[j][i] = d, d
+		}
+	}
+	return m
+}
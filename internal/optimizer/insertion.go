@@ -0,0 +1,97 @@
+package optimizer
+
+import "math"
+
+// InsertionConstraints limita dónde se pueden insertar nodos en una ruta que ya está en marcha.
+type InsertionConstraints struct {
+	// Demands[n] es la demanda del nodo n en cada dimensión (p. ej. litros y kilos).
+	Demands [][]float64
+	// Capacity es el límite de cada dimensión y InitialLoad, la carga con la que sale el vehículo
+	// del primer nodo de la ruta.
+	Capacity    []float64
+	InitialLoad []float64
+	// Unloads son los nodos en los que el vehículo se vacía: la carga vuelve a cero tras ellos.
+	Unloads map[int]bool
+	// MaxCost limita lo que puede alargar la ruta cada inserción. Cero significa sin límite.
+	MaxCost float64
+	// FixedEnd mantiene el último nodo de la ruta al final (p. ej. el regreso al depósito).
+	FixedEnd bool
+}
+
+// CheapestInsertion inserta los nodos de 'nodes' en la ruta 'tour' sin cambiar el orden relativo
+// de los nodos que ya tiene. En cada paso inserta, de entre todos los nodos pendientes, el que menos
+// alarga la ruta, en la posición en la que menos la alarga y en la que la carga del viaje (el tramo
+// entre dos descargas) sigue cabiendo en la capacidad. El primer nodo de la ruta no se mueve.
+//
+// Devuelve la nueva ruta y los nodos que no se han podido insertar.
+func CheapestInsertion(m Matrix, tour []int, nodes []int, c InsertionConstraints) ([]int, []int) {
+	tour = append([]int(nil), tour...)
+	pending := append([]int(nil), nodes...)
+
+	for len(pending) > 0 {
+		loads, tripOf := tripLoads(tour, c)
+		best, bestPos, bestCost := -1, -1, math.Inf(1)
+		for k, n := range pending {
+			for p := 1; p <= len(tour); p++ {
+				if p == len(tour) && c.FixedEnd {
+					break
+				}
+				// El nodo se inserta tras tour[p-1] y pertenece a su viaje (o al siguiente, si es una descarga).
+				trip := tripOf[p-1]
+				if c.Unloads[tour[p-1]] {
+					trip++
+				}
+				if !fits(loads[trip], c.Demands[n], c.Capacity) {
+					continue
+				}
+				cost := m[tour[p-1]][n]
+				if p < len(tour) {
+					cost += m[n][tour[p]] - m[tour[p-1]][tour[p]]
+				}
+				if c.MaxCost > 0 && cost > c.MaxCost+improvementEpsilon {
+					continue
+				}
+				if cost < bestCost {
+					best, bestPos, bestCost = k, p, cost
+				}
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		tour = append(tour[:bestPos], append([]int{pending[best]}, tour[bestPos:]...)...)
+		pending = append(pending[:best], pending[best+1:]...)
+	}
+	return tour, pending
+}
+
+// tripLoads divide la ruta en viajes separados por las descargas y devuelve la carga de cada viaje
+// y el viaje al que pertenece cada posición. Una descarga cierra su viaje.
+func tripLoads(tour []int, c InsertionConstraints) (loads [][]float64, tripOf []int) {
+	newTrip := func() []float64 { return make([]float64, len(c.Capacity)) }
+	first := newTrip()
+	copy(first, c.InitialLoad)
+	loads = [][]float64{first}
+	tripOf = make([]int, len(tour))
+	for i, n := range tour {
+		if i > 0 {
+			tripOf[i] = tripOf[i-1]
+			if c.Unloads[tour[i-1]] {
+				tripOf[i]++
+				loads = append(loads, newTrip())
+			}
+		}
+		if i == 0 || c.Unloads[n] {
+			continue
+		}
+		for d := range c.Capacity {
+			loads[tripOf[i]][d] += c.Demands[n][d]
+		}
+	}
+	// Un nodo insertado tras la última descarga abre un viaje vacío.
+	if len(tour) > 0 && c.Unloads[tour[len(tour)-1]] {
+		loads = append(loads, newTrip())
+	}
+	return loads, tripOf
+}
@@ -0,0 +1,296 @@
+// Package optimizer construye y mejora el orden de visita de una ruta a partir de una matriz de distancias.
+//
+// Los algoritmos trabajan sobre índices de la matriz, así que sirven igual para distancias en línea
+// recta que para distancias por carretera (que pueden ser asimétricas). El primer nodo de la ruta
+// es siempre el punto de salida y no se mueve; opcionalmente, el último también es fijo (p. ej. el
+// regreso a la base).
+package optimizer
+
+import "time"
+
+// Matrix es una matriz de distancias: Matrix[i][j] es la distancia del nodo i al nodo j.
+type Matrix [][]float64
+
+// Algorithm identifica una fase de mejora local.
+type Algorithm string
+
+const (
+	// TwoOpt invierte tramos de la ruta para eliminar cruces.
+	TwoOpt Algorithm = "2opt"
+	// OrOpt mueve tramos cortos (1 a 3 paradas) a otra posición de la ruta.
+	OrOpt Algorithm = "oropt"
+)
+
+// orOptMaxSegment es la longitud máxima de los tramos que mueve Or-opt.
+const orOptMaxSegment = 3
+
+// improvementEpsilon evita bucles infinitos por errores de redondeo en las comparaciones.
+const improvementEpsilon = 1e-9
+
+// Options configura la fase de mejora local.
+type Options struct {
+	// Algorithms son las fases de mejora a aplicar, que se alternan hasta que ninguna mejora la ruta.
+	Algorithms []Algorithm
+	// TimeBudget limita el tiempo total de mejora. Cero significa sin límite.
+	TimeBudget time.Duration
+	// FixedEnd mantiene el último nodo de la ruta en su posición.
+	FixedEnd bool
+}
+
+// Result es el resultado de optimizar una ruta.
+type Result struct {
+	// Tour es el orden de visita como índices de la matriz, empezando por el nodo de salida.
+	Tour []int
+	// InitialDistance y Distance son la longitud de la ruta antes y después de la mejora local.
+	InitialDistance float64
+	Distance        float64
+	// Iterations es el número de movimientos de mejora aplicados.
+	Iterations int
+	// TimedOut indica que se agotó el presupuesto de tiempo antes de alcanzar un óptimo local.
+	TimedOut bool
+}
+
+// Optimize mejora la ruta 'tour' con las fases indicadas en 'opts'.
+func Optimize(m Matrix, tour []int, opts Options) Result {
+	var deadline time.Time
+	if opts.TimeBudget > 0 {
+		deadline = time.Now().Add(opts.TimeBudget)
+	}
+
+	tour = append([]int(nil), tour...)
+	res := Result{InitialDistance: TourLength(m, tour)}
+
+	// Alternamos las fases hasta que ninguna consiga mejorar la ruta.
+	for improved := true; improved; {
+		improved = false
+		for _, alg := range opts.Algorithms {
+			var n int
+			var timedOut bool
+			switch alg {
+			case TwoOpt:
+				n, timedOut = twoOpt(m, tour, opts.FixedEnd, deadline)
+			case OrOpt:
+				n, timedOut = orOpt(m, tour, opts.FixedEnd, deadline)
+			}
+			res.Iterations += n
+			improved = improved || n > 0
+			if timedOut {
+				res.TimedOut = true
+				improved = false
+				break
+			}
+		}
+	}
+
+	res.Tour = tour
+	res.Distance = TourLength(m, tour)
+	return res
+}
+
+// NearestNeighbour construye una ruta que sale del nodo 'start' y visita en cada paso el nodo
+// pendiente más cercano. Si 'end' es un índice válido, la ruta termina en él.
+func NearestNeighbour(m Matrix, start, end int) []int {
+	nodes := make([]int, 0, len(m))
+	for j := range m {
+		if j != start && j != end {
+			nodes = append(nodes, j)
+		}
+	}
+	return NearestNeighbourOver(m, start, nodes, end)
+}
+
+// NearestNeighbourOver es como NearestNeighbour, pero solo visita los nodos de 'nodes'.
+// Sirve para construir rutas sobre un subconjunto de la matriz (p. ej. un viaje entre descargas).
+func NearestNeighbourOver(m Matrix, start int, nodes []int, end int) []int {
+	pending := append([]int(nil), nodes...)
+
+	tour := make([]int, 0, len(nodes)+2)
+	tour = append(tour, start)
+	current := start
+	for len(pending) > 0 {
+		next := 0
+		for k := 1; k < len(pending); k++ {
+			if m[current][pending[k]] < m[current][pending[next]] {
+				next = k
+			}
+		}
+		current = pending[next]
+		tour = append(tour, current)
+		pending[next] = pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+	}
+	if end >= 0 {
+		tour = append(tour, end)
+	}
+	return tour
+}
+
+// TourLength devuelve la longitud total de una ruta.
+func TourLength(m Matrix, tour []int) float64 {
+	var total float64
+	for i := 1; i < len(tour); i++ {
+		total += m[tour[i-1]][tour[i]]
+	}
+	return total
+}
+
+// lastMovable devuelve el índice de la última posición que pueden mover los algoritmos.
+func lastMovable(tour []int, fixedEnd bool) int {
+	if fixedEnd {
+		return len(tour) - 2
+	}
+	return len(tour) - 1
+}
+
+// edge devuelve la distancia entre las posiciones i y j de la ruta, o 0 si j queda fuera (ruta abierta).
+func edge(m Matrix, tour []int, i, j int) float64 {
+	if j >= len(tour) {
+		return 0
+	}
+	return m[tour[i]][tour[j]]
+}
+
+// expired indica si se ha superado la fecha límite (si la hay).
+func expired(deadline time.Time) bool {
+	return !deadline.IsZero() && time.Now().After(deadline)
+}
+
+// twoOpt aplica movimientos 2-opt (invertir el tramo tour[i..j]) mientras alguno acorte la ruta.
+// Tiene en cuenta matrices asimétricas: invertir un tramo cambia también el sentido de sus aristas internas.
+// Devuelve cuántos movimientos aplicó y si se agotó el tiempo.
+func twoOpt(m Matrix, tour []int, fixedEnd bool, deadline time.Time) (int, bool) {
+	last := lastMovable(tour, fixedEnd)
+	moves := 0
+
+	// forward[k] y backward[k] acumulan la longitud de la ruta hasta la posición k en cada sentido.
+	forward := make([]float64, len(tour))
+	backward := make([]float64, len(tour))
+	prefix := func() {
+		for k := 1; k < len(tour); k++ {
+			forward[k] = forward[k-1] + m[tour[k-1]][tour[k]]
+			backward[k] = backward[k-1] + m[tour[k]][tour[k-1]]
+		}
+	}
+	prefix()
+
+	for improved := true; improved; {
+		improved = false
+		for i := 1; i < last; i++ {
+			if expired(deadline) {
+				return moves, true
+			}
+			for j := i + 1; j <= last; j++ {
+				delta := m[tour[i-1]][tour[j]] + edge(m, tour, i, j+1) -
+					m[tour[i-1]][tour[i]] - edge(m, tour, j, j+1) +
+					(backward[j] - backward[i]) - (forward[j] - forward[i])
+				if delta < -improvementEpsilon {
+					reverse(tour[i : j+1])
+					prefix()
+					moves++
+					improved = true
+				}
+			}
+		}
+	}
+	return moves, false
+}
+
+// orOpt aplica movimientos Or-opt (mover un tramo de 1 a 3 paradas a otra posición, en cualquier
+// sentido) mientras alguno acorte la ruta. Devuelve cuántos movimientos aplicó y si se agotó el tiempo.
+func orOpt(m Matrix, tour []int, fixedEnd bool, deadline time.Time) (int, bool) {
+	moves := 0
+	last := lastMovable(tour, fixedEnd)
+	for improved := true; improved; {
+		improved = false
+		for segLen := 1; segLen <= orOptMaxSegment; segLen++ {
+			for i := 1; i+segLen-1 <= last; i++ {
+				if expired(deadline) {
+					return moves, true
+				}
+				if orOptMove(m, tour, i, i+segLen-1, last) {
+					moves++
+					improved = true
+				}
+			}
+		}
+	}
+	return moves, false
+}
+
+// orOptMove busca la mejor posición para el tramo tour[i..j] y lo mueve si así se acorta la ruta.
+func orOptMove(m Matrix, tour []int, i, j, last int) bool {
+	segment := tour[i : j+1]
+	first, lastNode := segment[0], segment[len(segment)-1]
+
+	// Ahorro de sacar el tramo y unir sus vecinos.
+	removeGain := m[tour[i-1]][first] + edge(m, tour, j, j+1) - edge(m, tour, i-1, j+1)
+	reverseCost := segmentLength(m, segment, true) - segmentLength(m, segment, false)
+
+	bestDelta, bestK, bestReversed := -improvementEpsilon, -1, false
+	// Probamos a insertarlo entre tour[k] y tour[k+1], fuera del propio tramo.
+	for k := 0; k <= last; k++ {
+		if k >= i-1 && k <= j {
+			continue
+		}
+		insertCost := func(a, b int) float64 {
+			cost := m[tour[k]][a]
+			if k+1 < len(tour) {
+				cost += m[b][tour[k+1]] - m[tour[k]][tour[k+1]]
+			}
+			return cost
+		}
+
+		if delta := insertCost(first, lastNode) - removeGain; delta < bestDelta {
+			bestDelta, bestK, bestReversed = delta, k, false
+		}
+		if delta := insertCost(lastNode, first) + reverseCost - removeGain; delta < bestDelta {
+			bestDelta, bestK, bestReversed = delta, k, true
+		}
+	}
+
+	if bestK < 0 {
+		return false
+	}
+	moveSegment(tour, i, j, bestK, bestReversed)
+	return true
+}
+
+// segmentLength devuelve la longitud interna de un tramo, recorrido hacia delante o invertido.
+func segmentLength(m Matrix, segment []int, reversed bool) float64 {
+	var total float64
+	for k := 1; k < len(segment); k++ {
+		if reversed {
+			total += m[segment[k]][segment[k-1]]
+		} else {
+			total += m[segment[k-1]][segment[k]]
+		}
+	}
+	return total
+}
+
+// moveSegment mueve el tramo tour[i..j] para que quede justo después de la posición k (índice previo al movimiento).
+func moveSegment(tour []int, i, j, k int, reversed bool) {
+	segment := append([]int(nil), tour[i:j+1]...)
+	if reversed {
+		reverse(segment)
+	}
+	rest := append(append([]int(nil), tour[:i]...), tour[j+1:]...)
+
+	// Posición de inserción en 'rest': si k estaba después del tramo, se ha desplazado.
+	insertAt := k + 1
+	if k > j {
+		insertAt = k + 1 - len(segment)
+	}
+
+	result := make([]int, 0, len(tour))
+	result = append(result, rest[:insertAt]...)
+	result = append(result, segment...)
+	result = append(result, rest[insertAt:]...)
+	copy(tour, result)
+}
+
+func reverse(s []int) {
+	for a, b := 0, len(s)-1; a < b; a, b = a+1, b-1 {
+		s[a], s[b] = s[b], s[a]
+	}
+}
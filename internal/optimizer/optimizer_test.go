@@ -0,0 +1,82 @@
+package optimizer
+
+import (
+	"math/rand"
+	"smart-waste-management/internal/domain"
+	"testing"
+)
+
+// benchmarkSizes son los tamaños de ruta con los que se comparan los algoritmos.
+var benchmarkSizes = []struct {
+	name  string
+	stops int
+}{
+	{"50", 50},
+	{"200", 200},
+	{"500", 500},
+}
+
+// madridContainers genera un conjunto reproducible de contenedores repartidos por el centro de Madrid.
+// La salida es el nodo 0.
+func madridContainers(n int) Matrix {
+	rng := rand.New(rand.NewSource(42))
+	points := make([]domain.Point, n+1)
+	points[0] = domain.Point{Latitude: 40.416775, Longitude: -3.703790}
+	for i := 1; i <= n; i++ {
+		points[i] = domain.Point{
+			Latitude:  40.38 + rng.Float64()*0.08,
+			Longitude: -3.74 + rng.Float64()*0.08,
+		}
+	}
+	return HaversineMatrix(points)
+}
+
+// benchmarkOptimize mide una fase de mejora sobre la ruta inicial del vecino más cercano e informa
+// de la distancia final (km) y de la mejora (%) para poder comparar algoritmos.
+func benchmarkOptimize(b *testing.B, algorithms []Algorithm) {
+	for _, size := range benchmarkSizes {
+		b.Run(size.name, func(b *testing.B) {
+			m := madridContainers(size.stops)
+			initial := NearestNeighbour(m, 0, -1)
+
+			var res Result
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				res = Optimize(m, initial, Options{Algorithms: algorithms})
+			}
+			b.StopTimer()
+
+			if res.Distance > res.InitialDistance+improvementEpsilon {
+				b.Fatalf("la optimización empeoró la ruta: %.3f km -> %.3f km", res.InitialDistance, res.Distance)
+			}
+			b.ReportMetric(res.Distance, "km")
+			b.ReportMetric(100*(res.InitialDistance-res.Distance)/res.InitialDistance, "%saved")
+		})
+	}
+}
+
+func BenchmarkNearestNeighbour(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(size.name, func(b *testing.B) {
+			m := madridContainers(size.stops)
+
+			var tour []int
+			for i := 0; i < b.N; i++ {
+				tour = NearestNeighbour(m, 0, -1)
+			}
+			b.ReportMetric(TourLength(m, tour), "km")
+		})
+	}
+}
+
+func BenchmarkTwoOpt(b *testing.B) {
+	benchmarkOptimize(b, []Algorithm{TwoOpt})
+}
+
+func BenchmarkOrOpt(b *testing.B) {
+	benchmarkOptimize(b, []Algorithm{OrOpt})
+}
+
+func BenchmarkTwoOptOrOpt(b *testing.B) {
+	benchmarkOptimize(b, []Algorithm{TwoOpt, OrOpt})
+}
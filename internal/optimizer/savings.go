@@ -0,0 +1,88 @@
+package optimizer
+
+import "sort"
+
+// Savings reparte los nodos entre vehículos con el algoritmo de ahorros de Clarke-Wright.
+//
+// Cada nodo empieza en su propia ruta depósito -> nodo -> depósito y se van uniendo rutas, de mayor
+// a menor ahorro, mientras la carga conjunta no supere la capacidad del vehículo. demands[n] es la
+// demanda del nodo n en cada dimensión (p. ej. litros y kilos) y capacity el límite de cada dimensión.
+// Los nodos cuya demanda no cabe en un vehículo vacío se devuelven en 'unassigned'.
+//
+// Las rutas devueltas no incluyen el depósito y conservan el orden en que se unieron, que sirve
+// como ruta inicial para la mejora local.
+func Savings(m Matrix, depot int, nodes []int, demands [][]float64, capacity []float64) (routes [][]int, unassigned []int) {
+	type route struct {
+		nodes []int
+		load  []float64
+	}
+	routeOf := make(map[int]*route, len(nodes))
+	for _, n := range nodes {
+		if !fits(demands[n], make([]float64, len(capacity)), capacity) {
+			unassigned = append(unassigned, n)
+			continue
+		}
+		routeOf[n] = &route{nodes: []int{n}, load: append([]float64(nil), demands[n]...)}
+	}
+
+	// Ahorro de ir de i a j directamente en lugar de volver al depósito entre medias.
+	type saving struct {
+		i, j  int
+		value float64
+	}
+	var savings []saving
+	for _, i := range nodes {
+		if routeOf[i] == nil {
+			continue
+		}
+		for _, j := range nodes {
+			if i == j || routeOf[j] == nil {
+				continue
+			}
+			if v := m[i][depot] + m[depot][j] - m[i][j]; v > 0 {
+				savings = append(savings, saving{i: i, j: j, value: v})
+			}
+		}
+	}
+	sort.SliceStable(savings, func(a, b int) bool { return savings[a].value > savings[b].value })
+
+	// Unimos la ruta que termina en i con la que empieza en j, si caben en un vehículo.
+	for _, s := range savings {
+		ri, rj := routeOf[s.i], routeOf[s.j]
+		if ri == rj || ri.nodes[len(ri.nodes)-1] != s.i || rj.nodes[0] != s.j {
+			continue
+		}
+		if !fits(ri.load, rj.load, capacity) {
+			continue
+		}
+		ri.nodes = append(ri.nodes, rj.nodes...)
+		for d := range ri.load {
+			ri.load[d] += rj.load[d]
+		}
+		for _, n := range rj.nodes {
+			routeOf[n] = ri
+		}
+	}
+
+	// Recogemos cada ruta una sola vez, en el orden de los nodos de entrada para que el resultado sea estable.
+	seen := make(map[*route]bool)
+	for _, n := range nodes {
+		r := routeOf[n]
+		if r == nil || seen[r] {
+			continue
+		}
+		seen[r] = true
+		routes = append(routes, r.nodes)
+	}
+	return routes, unassigned
+}
+
+// fits indica si la suma de dos cargas cabe en la capacidad en todas las dimensiones.
+func fits(a, b, capacity []float64) bool {
+	for d := range capacity {
+		if a[d]+b[d] > capacity[d] {
+			return false
+		}
+	}
+	return true
+}
@@ -12,6 +12,9 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// customTypes son los tipos ENUM propios que cada conexión debe conocer.
+var customTypes = []string{"container_status", "waste_fraction", "lift_mechanism", "facility_kind", "route_status", "route_stop_status"}
+
 type DB struct {
 	Pool *pgxpool.Pool
 }
@@ -35,13 +38,15 @@ func NewDBConnection() (*DB, error) {
 	// La función AfterConnect sigue siendo la forma más idiomática y segura de asegurar
 	// que CADA conexión del pool conozca nuestros tipos personalizados.
 	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
-		// 1. Cargamos la definición del tipo 'container_status' desde la base de datos.
-		dataType, err := conn.LoadType(ctx, "container_status")
-		if err != nil {
-			return fmt.Errorf("no se pudo cargar el tipo 'container_status' desde la BBDD: %w", err)
+		for _, typeName := range customTypes {
+			// 1. Cargamos la definición del tipo desde la base de datos.
+			dataType, err := conn.LoadType(ctx, typeName)
+			if err != nil {
+				return fmt.Errorf("no se pudo cargar el tipo '%s' desde la BBDD: %w", typeName, err)
+			}
+			// 2. Registramos este tipo en el mapa de tipos de la conexión actual.
+			conn.TypeMap().RegisterType(dataType)
 		}
-		// 2. Registramos este tipo en el mapa de tipos de la conexión actual.
-		conn.TypeMap().RegisterType(dataType)
 		return nil
 	}
 	// --- FIN DE LA MODIFICACIÓN ---
@@ -0,0 +1,66 @@
+// Package geojson escribe entidades geográficas en GeoJSON (RFC 7946) para los visores de mapas
+// (Leaflet, MapLibre) y las herramientas GIS (QGIS).
+package geojson
+
+import "smart-waste-management/internal/domain"
+
+// ContentType es el tipo MIME de GeoJSON.
+const ContentType = "application/geo+json"
+
+// FeatureCollection es una colección de entidades.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature es una entidad: una geometría con sus propiedades.
+type Feature struct {
+	Type string `json:"type"`
+	// ID identifica la entidad en la colección; se omite si está vacío.
+	ID         string         `json:"id,omitempty"`
+	Geometry   Geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// Geometry es la geometría de una entidad. Coordinates depende del tipo: una posición para
+// "Point" y una lista de posiciones para "LineString".
+type Geometry struct {
+	Type        string `json:"type"`
+	Coordinates any    `json:"coordinates"`
+}
+
+// NewFeatureCollection crea una colección con las entidades indicadas. Una colección vacía se
+// escribe con "features": [], no null.
+func NewFeatureCollection(features ...Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// NewFeature crea una entidad. Si 'properties' es nil se escribe como un objeto vacío.
+func NewFeature(id string, geometry Geometry, properties map[string]any) Feature {
+	if properties == nil {
+		properties = map[string]any{}
+	}
+	return Feature{Type: "Feature", ID: id, Geometry: geometry, Properties: properties}
+}
+
+// Position es una posición GeoJSON: longitud y latitud, en ese orden.
+func Position(p domain.Point) [2]float64 {
+	return [2]float64{p.Longitude, p.Latitude}
+}
+
+// Point crea la geometría de un punto.
+func Point(p domain.Point) Geometry {
+	return Geometry{Type: "Point", Coordinates: Position(p)}
+}
+
+// LineString crea la geometría de una línea que une los puntos en orden.
+func LineString(points []domain.Point) Geometry {
+	line := make([][2]float64, len(points))
+	for i, p := range points {
+		line[i] = Position(p)
+	}
+	return Geometry{Type: "LineString", Coordinates: line}
+}
@@ -0,0 +1,180 @@
+// internal/platform/idempotency/middleware.go
+
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderKey es la cabecera con la que el cliente identifica una petición reintentable.
+const HeaderKey = "Idempotency-Key"
+
+// HeaderReplayed se añade a las respuestas que se sirven desde el almacén en lugar de procesarse de nuevo.
+const HeaderReplayed = "Idempotent-Replayed"
+
+// maxKeyLength evita claves arbitrariamente grandes en la base de datos.
+const maxKeyLength = 255
+
+// DefaultTTL es el tiempo que se conserva una respuesta si no se configura otro valor.
+const DefaultTTL = 24 * time.Hour
+
+// Middleware hace idempotentes las peticiones de escritura (POST, PUT, PATCH, DELETE)
+// que incluyen la cabecera Idempotency-Key:
+//   - La primera petición se procesa normalmente y su respuesta se guarda durante 'ttl'.
+//   - Un reintento con la misma clave y el mismo cuerpo recibe la respuesta original.
+//   - Un reintento con la misma clave pero distinto cuerpo (o ruta) recibe 422.
+//   - Un reintento mientras la petición original sigue en curso recibe 409.
+//
+// Las respuestas 5xx no se guardan, para que el cliente pueda reintentar.
+func Middleware(store Store, ttl time.Duration) gin.HandlerFunc {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderKey)
+		if key == "" || !isMutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("La cabecera %s no puede superar los %d caracteres", HeaderKey, maxKeyLength)})
+			return
+		}
+
+		// 1. Calculamos la huella de la petición (método, ruta y cuerpo) y restauramos el cuerpo para el handler.
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "No se pudo leer el cuerpo de la petición"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		hash := fingerprint(c.Request.Method, c.Request.URL.Path, body)
+
+		// 2. Reservamos la clave o recuperamos la petición original.
+		existing, reserved, err := store.Reserve(c.Request.Context(), key, hash, ttl)
+		if err != nil {
+			log.Printf("Error en el almacén de idempotencia: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "No se pudo comprobar la clave de idempotencia"})
+			return
+		}
+		if !reserved {
+			switch {
+			case existing.RequestHash != hash:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("La clave %s ya se usó con una petición distinta", HeaderKey)})
+			case existing.StatusCode == 0:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "La petición original con esta clave todavía se está procesando"})
+			default:
+				c.Header(HeaderReplayed, "true")
+				c.Data(existing.StatusCode, existing.ContentType, existing.ResponseBody)
+				c.Abort()
+			}
+			return
+		}
+
+		// 3. Procesamos la petición capturando la respuesta.
+		// Si el handler entra en pánico, el defer libera la clave para que el reintento no quede bloqueado.
+		completed := false
+		defer func() {
+			if !completed {
+				releaseKey(store, key)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// 4. Guardamos la respuesta, o liberamos la clave si hubo un error del servidor.
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+
+		// Usamos un contexto propio por si el cliente ya se desconectó.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := store.Complete(ctx, key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			log.Printf("Error al guardar la respuesta de la clave de idempotencia %s: %v", key, err)
+			return
+		}
+		completed = true
+	}
+}
+
+// releaseKey libera una reserva cuya petición no terminó correctamente.
+func releaseKey(store Store, key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := store.Release(ctx, key); err != nil {
+		log.Printf("Error al liberar la clave de idempotencia %s: %v", key, err)
+	}
+}
+
+// RunJanitor purga periódicamente las claves caducadas hasta que se cancela el contexto.
+func RunJanitor(ctx context.Context, store Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := store.PurgeExpired(ctx)
+			if err != nil {
+				log.Printf("Error al purgar las claves de idempotencia: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Purgadas %d claves de idempotencia caducadas", purged)
+			}
+		}
+	}
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder escribe la respuesta al cliente y a la vez guarda una copia del cuerpo.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,112 @@
+// internal/platform/idempotency/store.go
+
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smart-waste-management/internal/platform/database"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Record es una clave de idempotencia junto con la respuesta de la petición original.
+// StatusCode es 0 mientras la petición original sigue en curso.
+type Record struct {
+	Key          string
+	RequestHash  string
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+}
+
+// Store define la persistencia de las claves de idempotencia.
+type Store interface {
+	// Reserve registra la clave como "en curso". Si la clave ya existía (y no ha caducado),
+	// no la modifica y devuelve el registro existente con reserved = false.
+	Reserve(ctx context.Context, key, requestHash string, ttl time.Duration) (existing Record, reserved bool, err error)
+	// Complete guarda la respuesta de la petición original.
+	Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error
+	// Release elimina una reserva cuya petición falló, para que pueda reintentarse.
+	Release(ctx context.Context, key string) error
+	// PurgeExpired elimina las claves caducadas y devuelve cuántas se borraron.
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+// postgresStore es la implementación de Store sobre PostgreSQL.
+type postgresStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresStore crea una nueva instancia del almacén de claves.
+func NewPostgresStore(db *database.DB) Store {
+	return &postgresStore{
+		db: db.Pool,
+	}
+}
+
+func (s *postgresStore) Reserve(ctx context.Context, key, requestHash string, ttl time.Duration) (Record, bool, error) {
+	// Insertamos la reserva; si la clave existe pero ha caducado, la reutilizamos.
+	// La condición del DO UPDATE hace que una clave vigente no se toque y no devuelva filas.
+	reserveSQL := `
+        INSERT INTO idempotency_keys (key, request_hash, expires_at)
+        VALUES ($1, $2, NOW() + $3::interval)
+        ON CONFLICT (key) DO UPDATE
+        SET request_hash = EXCLUDED.request_hash, expires_at = EXCLUDED.expires_at,
+            status_code = NULL, content_type = NULL, response_body = NULL, created_at = NOW()
+        WHERE idempotency_keys.expires_at <= NOW()
+        RETURNING key`
+
+	var reservedKey string
+	err := s.db.QueryRow(ctx, reserveSQL, key, requestHash, ttl).Scan(&reservedKey)
+	if err == nil {
+		return Record{}, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return Record{}, false, fmt.Errorf("error al reservar la clave de idempotencia: %w", err)
+	}
+
+	// La clave ya existe y está vigente: devolvemos el registro para que el llamante decida.
+	query := `
+        SELECT key, request_hash, COALESCE(status_code, 0), COALESCE(content_type, ''), response_body
+        FROM idempotency_keys
+        WHERE key = $1`
+
+	var rec Record
+	err = s.db.QueryRow(ctx, query, key).Scan(&rec.Key, &rec.RequestHash, &rec.StatusCode, &rec.ContentType, &rec.ResponseBody)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("error al leer la clave de idempotencia: %w", err)
+	}
+	return rec, false, nil
+}
+
+func (s *postgresStore) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	query := `
+        UPDATE idempotency_keys
+        SET status_code = $1, content_type = $2, response_body = $3
+        WHERE key = $4`
+
+	_, err := s.db.Exec(ctx, query, statusCode, contentType, body, key)
+	if err != nil {
+		return fmt.Errorf("error al guardar la respuesta idempotente: %w", err)
+	}
+	return nil
+}
+
+func (s *postgresStore) Release(ctx context.Context, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE key = $1 AND status_code IS NULL`
+	_, err := s.db.Exec(ctx, query, key)
+	return err
+}
+
+func (s *postgresStore) PurgeExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`
+	tag, err := s.db.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("error al purgar las claves de idempotencia caducadas: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,292 @@
+// internal/platform/mqtt/subscriber.go
+
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"smart-waste-management/internal/container"
+	"smart-waste-management/internal/domain"
+	"strconv"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// processTimeout limita el tiempo dedicado a cada intento de procesar un mensaje.
+const processTimeout = 10 * time.Second
+
+// Reintentos de las lecturas que fallan al procesarse (p. ej. por una caída de la base de datos):
+// número de intentos y espera antes del primer reintento, que se duplica en cada uno.
+const (
+	processAttempts = 4
+	processBackoff  = time.Second
+)
+
+// uuidPattern valida el formato del ID del contenedor que viaja en el topic.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ReadingProcessor es la parte del servicio de contenedores que necesita el suscriptor.
+// container.Service la satisface, pero definirla aquí evita acoplar el adaptador al módulo completo.
+type ReadingProcessor interface {
+	ProcessNewReading(ctx context.Context, reading domain.Reading) (domain.ReadingOutcome, error)
+}
+
+// Config agrupa los parámetros de conexión al broker MQTT.
+type Config struct {
+	BrokerURL       string
+	ClientID        string
+	Username        string
+	Password        string
+	Topic           string // Patrón de suscripción; el primer '+' corresponde al ID del contenedor.
+	DeadLetterTopic string
+	QoS             byte
+	MaxReconnect    time.Duration
+}
+
+// ConfigFromEnv construye la configuración a partir de variables de entorno.
+// El segundo valor es false si MQTT_BROKER_URL no está definida, es decir, si el adaptador está deshabilitado.
+func ConfigFromEnv() (Config, bool) {
+	cfg := Config{
+		BrokerURL:       os.Getenv("MQTT_BROKER_URL"),
+		ClientID:        envOrDefault("MQTT_CLIENT_ID", "smart-waste-api"),
+		Username:        os.Getenv("MQTT_USERNAME"),
+		Password:        os.Getenv("MQTT_PASSWORD"),
+		Topic:           envOrDefault("MQTT_TOPIC", "bins/+/fill"),
+		DeadLetterTopic: envOrDefault("MQTT_DEAD_LETTER_TOPIC", "bins/deadletter"),
+		QoS:             1,
+		MaxReconnect:    time.Minute,
+	}
+	if qos, err := strconv.Atoi(os.Getenv("MQTT_QOS")); err == nil && qos >= 0 && qos <= 2 {
+		cfg.QoS = byte(qos)
+	}
+	return cfg, cfg.BrokerURL != ""
+}
+
+// payload es el formato JSON que publican los sensores.
+// El ID del contenedor se toma del topic; si el payload también lo incluye, deben coincidir.
+type payload struct {
+	ContainerID string     `json:"container_id,omitempty"`
+	FillLevel   *int       `json:"fill_level"`
+	Timestamp   *time.Time `json:"timestamp,omitempty"`
+}
+
+// deadLetter es el mensaje que se publica en el topic de mensajes muertos.
+type deadLetter struct {
+	Topic      string    `json:"topic"`
+	Payload    string    `json:"payload"`
+	Error      string    `json:"error"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// errInvalidPayload marca los mensajes que no se pueden convertir en una lectura.
+var errInvalidPayload = errors.New("payload inválido")
+
+// isPermanent indica si el error no se resuelve reintentando: el mensaje no es una lectura válida o
+// el contenedor no existe. El resto (conexión con la base de datos, tiempos de espera) puede ser
+// pasajero.
+func isPermanent(err error) bool {
+	return errors.Is(err, errInvalidPayload) ||
+		errors.Is(err, container.ErrInvalidReading) ||
+		errors.Is(err, container.ErrContainerNotFound)
+}
+
+// Subscriber consume lecturas de sensores desde un broker MQTT y las entrega al servicio.
+type Subscriber struct {
+	cfg       Config
+	processor ReadingProcessor
+	client    paho.Client
+	segment   int // Posición del ID del contenedor dentro del topic.
+	// backoff es la espera antes del primer reintento de una lectura que falla al procesarse.
+	backoff time.Duration
+	// stopped se cierra en Stop para cortar las esperas entre reintentos.
+	stopped chan struct{}
+}
+
+// NewSubscriber crea el suscriptor. La conexión no se establece hasta llamar a Start.
+func NewSubscriber(cfg Config, processor ReadingProcessor) (*Subscriber, error) {
+	segment := strings.Index(cfg.Topic, "+")
+	if segment < 0 {
+		return nil, fmt.Errorf("el topic MQTT %q debe contener un comodín '+' para el ID del contenedor", cfg.Topic)
+	}
+	s := &Subscriber{
+		cfg:       cfg,
+		processor: processor,
+		segment:   strings.Count(cfg.Topic[:segment], "/"),
+		backoff:   processBackoff,
+		stopped:   make(chan struct{}),
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.BrokerURL).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		// Sesión persistente: con QoS 1 el broker guarda los mensajes mientras estamos desconectados.
+		SetCleanSession(false).
+		// Reconexión automática con backoff exponencial hasta MaxReconnect.
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(cfg.MaxReconnect).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(5 * time.Second).
+		// Cada mensaje se procesa en su propia goroutine para no bloquear la conexión.
+		SetOrderMatters(false).
+		// Confirmamos (PUBACK) solo cuando la lectura se ha procesado o se ha enviado a mensajes muertos.
+		// Las que no se confirman las reenvía el broker en la siguiente conexión de la sesión.
+		SetAutoAckDisabled(true).
+		SetOnConnectHandler(s.onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.Printf("MQTT: conexión perdida con el broker: %v", err)
+		}).
+		SetReconnectingHandler(func(_ paho.Client, _ *paho.ClientOptions) {
+			log.Println("MQTT: intentando reconectar con el broker...")
+		})
+
+	s.client = paho.NewClient(opts)
+	return s, nil
+}
+
+// Start conecta con el broker. Si el broker no está disponible, el cliente sigue
+// reintentando en segundo plano, por lo que Start no bloquea el arranque de la API.
+func (s *Subscriber) Start() error {
+	token := s.client.Connect()
+	if token.WaitTimeout(5*time.Second) && token.Error() != nil {
+		return fmt.Errorf("no se pudo conectar con el broker MQTT: %w", token.Error())
+	}
+	log.Printf("MQTT: suscriptor iniciado contra %s (topic %s)", s.cfg.BrokerURL, s.cfg.Topic)
+	return nil
+}
+
+// Stop desconecta del broker dando un margen para terminar los mensajes en curso.
+func (s *Subscriber) Stop() {
+	close(s.stopped)
+	s.client.Disconnect(1000)
+	log.Println("MQTT: suscriptor detenido")
+}
+
+// onConnect se ejecuta en cada conexión (incluidas las reconexiones) y renueva la suscripción.
+func (s *Subscriber) onConnect(client paho.Client) {
+	token := client.Subscribe(s.cfg.Topic, s.cfg.QoS, s.handleMessage)
+	if token.Wait() && token.Error() != nil {
+		log.Printf("MQTT: error al suscribirse a %s: %v", s.cfg.Topic, token.Error())
+		return
+	}
+	log.Printf("MQTT: conectado y suscrito a %s con QoS %d", s.cfg.Topic, s.cfg.QoS)
+}
+
+// handleMessage convierte el mensaje en una lectura y la procesa.
+// Los mensajes que nunca se podrán procesar (no son una lectura válida o el contenedor no existe)
+// se reenvían al topic de mensajes muertos y se confirman, para que no ocupen la ventana de
+// mensajes en vuelo del broker. Si la lectura falla por un error pasajero (p. ej. la base de datos
+// no responde), se reintenta con espera creciente; si sigue fallando, el mensaje no se confirma
+// para que el broker lo vuelva a entregar y la lectura no se pierda.
+func (s *Subscriber) handleMessage(_ paho.Client, msg paho.Message) {
+	reading, err := s.decode(msg.Topic(), msg.Payload())
+	if err == nil {
+		err = s.process(reading)
+	}
+	switch {
+	case err == nil:
+		msg.Ack()
+	case isPermanent(err):
+		log.Printf("MQTT: mensaje descartado del topic %s: %v", msg.Topic(), err)
+		s.publishDeadLetter(msg, err)
+		msg.Ack()
+	default:
+		log.Printf("MQTT: no se pudo procesar el mensaje del topic %s tras %d intentos; queda sin confirmar para que el broker lo reenvíe: %v",
+			msg.Topic(), processAttempts, err)
+	}
+}
+
+// process entrega la lectura al servicio, reintentando con espera exponencial si falla por un
+// error pasajero. Deja de reintentar si el suscriptor se detiene.
+func (s *Subscriber) process(reading domain.Reading) error {
+	backoff := s.backoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), processTimeout)
+		_, err := s.processor.ProcessNewReading(ctx, reading)
+		cancel()
+		if err == nil || isPermanent(err) || attempt == processAttempts {
+			return err
+		}
+		log.Printf("MQTT: error al procesar la lectura del contenedor %s (intento %d de %d), se reintenta en %s: %v",
+			reading.ContainerID, attempt, processAttempts, backoff, err)
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-s.stopped:
+			return err
+		}
+	}
+}
+
+// decode extrae el ID del contenedor del topic y el resto de la lectura del payload.
+func (s *Subscriber) decode(topic string, raw []byte) (domain.Reading, error) {
+	segments := strings.Split(topic, "/")
+	if s.segment >= len(segments) || segments[s.segment] == "" {
+		return domain.Reading{}, fmt.Errorf("%w: no se pudo extraer el ID del contenedor del topic %s", errInvalidPayload, topic)
+	}
+	containerID := segments[s.segment]
+	if !uuidPattern.MatchString(containerID) {
+		return domain.Reading{}, fmt.Errorf("%w: el ID de contenedor del topic %s no es un UUID", errInvalidPayload, topic)
+	}
+
+	var p payload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return domain.Reading{}, fmt.Errorf("%w: %v", errInvalidPayload, err)
+	}
+	if p.FillLevel == nil {
+		return domain.Reading{}, fmt.Errorf("%w: falta el campo fill_level", errInvalidPayload)
+	}
+	if p.ContainerID != "" && p.ContainerID != containerID {
+		return domain.Reading{}, fmt.Errorf("%w: el container_id del payload no coincide con el del topic", errInvalidPayload)
+	}
+
+	reading := domain.Reading{
+		ContainerID: containerID,
+		FillLevel:   *p.FillLevel,
+		Timestamp:   time.Now().UTC(),
+	}
+	// Si el sensor no envía marca de tiempo, usamos la de recepción.
+	if p.Timestamp != nil {
+		reading.Timestamp = *p.Timestamp
+	}
+	if !reading.IsValid() {
+		return domain.Reading{}, fmt.Errorf("%w: lectura fuera de rango o con fecha futura %+v", errInvalidPayload, reading)
+	}
+	return reading, nil
+}
+
+// publishDeadLetter publica el mensaje original junto con el motivo del fallo.
+func (s *Subscriber) publishDeadLetter(msg paho.Message, cause error) {
+	if s.cfg.DeadLetterTopic == "" {
+		return
+	}
+	body, err := json.Marshal(deadLetter{
+		Topic:      msg.Topic(),
+		Payload:    string(msg.Payload()),
+		Error:      cause.Error(),
+		ReceivedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("MQTT: no se pudo serializar el mensaje muerto: %v", err)
+		return
+	}
+	token := s.client.Publish(s.cfg.DeadLetterTopic, s.cfg.QoS, false, body)
+	if token.WaitTimeout(5*time.Second) && token.Error() != nil {
+		log.Printf("MQTT: no se pudo publicar en %s: %v", s.cfg.DeadLetterTopic, token.Error())
+	}
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,244 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"smart-waste-management/internal/container"
+	"smart-waste-management/internal/domain"
+	"sync"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+const testContainerID = "3f1c2a9e-8b7d-4c6e-9a5f-1d2e3c4b5a69"
+
+// fakeProcessor devuelve, en cada llamada, el siguiente error de 'errs' (nil cuando se acaban).
+type fakeProcessor struct {
+	mu       sync.Mutex
+	errs     []error
+	readings []domain.Reading
+}
+
+func (p *fakeProcessor) ProcessNewReading(_ context.Context, reading domain.Reading) (domain.ReadingOutcome, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readings = append(p.readings, reading)
+	if len(p.errs) == 0 {
+		return domain.OutcomeApplied, nil
+	}
+	err := p.errs[0]
+	p.errs = p.errs[1:]
+	return "", err
+}
+
+// fakeMessage es un mensaje MQTT recibido que recuerda si se ha confirmado.
+type fakeMessage struct {
+	topic   string
+	payload []byte
+	acked   bool
+}
+
+func (m *fakeMessage) Duplicate() bool   { return false }
+func (m *fakeMessage) Qos() byte         { return 1 }
+func (m *fakeMessage) Retained() bool    { return false }
+func (m *fakeMessage) Topic() string     { return m.topic }
+func (m *fakeMessage) MessageID() uint16 { return 1 }
+func (m *fakeMessage) Payload() []byte   { return m.payload }
+func (m *fakeMessage) Ack()              { m.acked = true }
+
+// doneToken es un token ya completado sin error.
+type doneToken struct{}
+
+func (doneToken) Wait() bool                     { return true }
+func (doneToken) WaitTimeout(time.Duration) bool { return true }
+func (doneToken) Error() error                   { return nil }
+func (doneToken) Done() <-chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
+
+// fakeClient guarda los mensajes publicados. El resto de métodos de paho.Client no se usan.
+type fakeClient struct {
+	paho.Client
+	published []deadLetter
+}
+
+func (c *fakeClient) Publish(_ string, _ byte, _ bool, payload interface{}) paho.Token {
+	var dl deadLetter
+	if err := json.Unmarshal(payload.([]byte), &dl); err == nil {
+		c.published = append(c.published, dl)
+	}
+	return doneToken{}
+}
+
+func newTestSubscriber(t *testing.T, processor ReadingProcessor) (*Subscriber, *fakeClient) {
+	t.Helper()
+	s, err := NewSubscriber(Config{Topic: "bins/+/fill", DeadLetterTopic: "bins/deadletter", QoS: 1}, processor)
+	if err != nil {
+		t.Fatalf("NewSubscriber: %v", err)
+	}
+	client := &fakeClient{}
+	s.client = client
+	s.backoff = time.Millisecond
+	return s, client
+}
+
+func TestDecode(t *testing.T) {
+	recordedAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		topic   string
+		payload string
+		want    domain.Reading
+		wantErr bool
+	}{
+		{
+			name:    "lectura con marca de tiempo",
+			topic:   "bins/" + testContainerID + "/fill",
+			payload: `{"fill_level": 73, "timestamp": "2025-01-01T10:00:00Z"}`,
+			want:    domain.Reading{ContainerID: testContainerID, FillLevel: 73, Timestamp: recordedAt},
+		},
+		{
+			name:    "container_id del payload igual al del topic",
+			topic:   "bins/" + testContainerID + "/fill",
+			payload: `{"container_id": "` + testContainerID + `", "fill_level": 0, "timestamp": "2025-01-01T10:00:00Z"}`,
+			want:    domain.Reading{ContainerID: testContainerID, FillLevel: 0, Timestamp: recordedAt},
+		},
+		{name: "topic sin ID", topic: "bins", payload: `{"fill_level": 10}`, wantErr: true},
+		{name: "ID vacío", topic: "bins//fill", payload: `{"fill_level": 10}`, wantErr: true},
+		{name: "ID que no es un UUID", topic: "bins/contenedor-1/fill", payload: `{"fill_level": 10}`, wantErr: true},
+		{name: "JSON mal formado", topic: "bins/" + testContainerID + "/fill", payload: `{"fill_level": `, wantErr: true},
+		{name: "sin fill_level", topic: "bins/" + testContainerID + "/fill", payload: `{"timestamp": "2025-01-01T10:00:00Z"}`, wantErr: true},
+		{name: "fill_level fuera de rango", topic: "bins/" + testContainerID + "/fill", payload: `{"fill_level": 101}`, wantErr: true},
+		{name: "fecha futura", topic: "bins/" + testContainerID + "/fill", payload: `{"fill_level": 10, "timestamp": "2099-01-01T00:00:00Z"}`, wantErr: true},
+		{
+			name:    "container_id distinto del topic",
+			topic:   "bins/" + testContainerID + "/fill",
+			payload: `{"container_id": "00000000-0000-0000-0000-000000000000", "fill_level": 10}`,
+			wantErr: true,
+		},
+	}
+
+	s, _ := newTestSubscriber(t, &fakeProcessor{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.decode(tt.topic, []byte(tt.payload))
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidPayload) {
+					t.Fatalf("decode() error = %v, se esperaba errInvalidPayload", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode() error inesperado: %v", err)
+			}
+			if got.ContainerID != tt.want.ContainerID || got.FillLevel != tt.want.FillLevel || !got.Timestamp.Equal(tt.want.Timestamp) {
+				t.Errorf("decode() = %+v, se esperaba %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeWithoutTimestampUsesReceptionTime(t *testing.T) {
+	s, _ := newTestSubscriber(t, &fakeProcessor{})
+	before := time.Now().UTC()
+	got, err := s.decode("bins/"+testContainerID+"/fill", []byte(`{"fill_level": 5}`))
+	if err != nil {
+		t.Fatalf("decode() error inesperado: %v", err)
+	}
+	if got.Timestamp.Before(before) || got.Timestamp.After(time.Now().UTC()) {
+		t.Errorf("Timestamp = %v, se esperaba la hora de recepción", got.Timestamp)
+	}
+}
+
+func TestHandleMessage(t *testing.T) {
+	validTopic := "bins/" + testContainerID + "/fill"
+	validPayload := `{"fill_level": 42, "timestamp": "2025-01-01T10:00:00Z"}`
+	transient := errors.New("timeout al conectar con la base de datos")
+
+	tests := []struct {
+		name      string
+		topic     string
+		payload   string
+		errs      []error
+		wantCalls int
+		wantAck   bool
+		wantDead  bool
+	}{
+		{name: "lectura procesada", topic: validTopic, payload: validPayload, wantCalls: 1, wantAck: true},
+		{name: "topic inválido", topic: "bins/no-es-un-uuid/fill", payload: validPayload, wantAck: true, wantDead: true},
+		{name: "payload inválido", topic: validTopic, payload: `no es JSON`, wantAck: true, wantDead: true},
+		{
+			name:    "container_id distinto del topic",
+			topic:   validTopic,
+			payload: `{"container_id": "00000000-0000-0000-0000-000000000000", "fill_level": 42}`,
+			wantAck: true, wantDead: true,
+		},
+		{
+			name: "contenedor inexistente", topic: validTopic, payload: validPayload,
+			errs:      []error{fmt.Errorf("error al guardar la lectura en el repositorio: %w", container.ErrContainerNotFound)},
+			wantCalls: 1, wantAck: true, wantDead: true,
+		},
+		{
+			name: "lectura rechazada por el servicio", topic: validTopic, payload: validPayload,
+			errs:      []error{fmt.Errorf("%w: fuera de rango", container.ErrInvalidReading)},
+			wantCalls: 1, wantAck: true, wantDead: true,
+		},
+		{
+			name: "error pasajero que se resuelve al reintentar", topic: validTopic, payload: validPayload,
+			errs:      []error{transient, transient},
+			wantCalls: 3, wantAck: true,
+		},
+		{
+			name: "error pasajero persistente", topic: validTopic, payload: validPayload,
+			errs:      []error{transient, transient, transient, transient},
+			wantCalls: processAttempts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := &fakeProcessor{errs: tt.errs}
+			s, client := newTestSubscriber(t, processor)
+			msg := &fakeMessage{topic: tt.topic, payload: []byte(tt.payload)}
+
+			s.handleMessage(nil, msg)
+
+			if len(processor.readings) != tt.wantCalls {
+				t.Errorf("ProcessNewReading llamado %d veces, se esperaban %d", len(processor.readings), tt.wantCalls)
+			}
+			if msg.acked != tt.wantAck {
+				t.Errorf("acked = %v, se esperaba %v", msg.acked, tt.wantAck)
+			}
+			if got := len(client.published) > 0; got != tt.wantDead {
+				t.Fatalf("mensaje muerto publicado = %v, se esperaba %v", got, tt.wantDead)
+			}
+			if tt.wantDead {
+				dl := client.published[0]
+				if dl.Topic != tt.topic || dl.Payload != tt.payload || dl.Error == "" {
+					t.Errorf("mensaje muerto = %+v, no conserva el mensaje original y el motivo", dl)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessStopsRetryingOnStop(t *testing.T) {
+	transient := errors.New("la base de datos no responde")
+	processor := &fakeProcessor{errs: []error{transient, transient, transient, transient}}
+	s, _ := newTestSubscriber(t, processor)
+	s.backoff = time.Hour
+	close(s.stopped)
+
+	if err := s.process(domain.Reading{ContainerID: testContainerID, FillLevel: 1, Timestamp: time.Now()}); !errors.Is(err, transient) {
+		t.Fatalf("process() error = %v, se esperaba el error pasajero", err)
+	}
+	if len(processor.readings) != 1 {
+		t.Errorf("ProcessNewReading llamado %d veces, se esperaba 1", len(processor.readings))
+	}
+}
@@ -0,0 +1,345 @@
+// Package routing calcula distancias por carretera sobre un extracto local de OpenStreetMap.
+//
+// El extracto (.osm.pbf) se carga al arrancar en un grafo dirigido en memoria con las vías por las
+// que puede circular un camión, respetando los sentidos únicos. Los puntos (contenedores, depósitos,
+// puntos de descarga) se enganchan al nodo de la red más cercano y las distancias se calculan con
+// Dijkstra (matrices de distancias) o A* (trayecto entre dos puntos).
+package routing
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/optimizer"
+)
+
+// drivableHighways son los tipos de vía por los que puede circular un camión de recogida.
+var drivableHighways = map[string]bool{
+	"motorway": true, "motorway_link": true,
+	"trunk": true, "trunk_link": true,
+	"primary": true, "primary_link": true,
+	"secondary": true, "secondary_link": true,
+	"tertiary": true, "tertiary_link": true,
+	"unclassified": true, "residential": true, "living_street": true,
+	"service": true, "road": true,
+}
+
+// direction indica en qué sentidos se puede recorrer una vía respecto al orden de sus nodos.
+type direction uint8
+
+const (
+	forward direction = 1 << iota
+	backward
+	both = forward | backward
+)
+
+// wayDirection devuelve los sentidos de circulación de una vía, o 0 si no es transitable para un camión.
+func wayDirection(tags map[string]string) direction {
+	if !drivableHighways[tags["highway"]] || tags["area"] == "yes" {
+		return 0
+	}
+	for _, key := range []string{"access", "vehicle", "motor_vehicle"} {
+		if tags[key] == "no" {
+			return 0
+		}
+	}
+
+	switch tags["oneway"] {
+	case "yes", "true", "1":
+		return forward
+	case "-1", "reverse":
+		return backward
+	case "no", "false", "0":
+		return both
+	}
+	// Las autopistas y las rotondas son de sentido único aunque no lo indiquen.
+	if tags["highway"] == "motorway" || tags["junction"] == "roundabout" || tags["junction"] == "circular" {
+		return forward
+	}
+	return both
+}
+
+// Graph es la red viaria en memoria. Las aristas se guardan en formato CSR: las que salen del nodo
+// i son las posiciones first[i]..first[i+1] de 'target' y 'length'.
+type Graph struct {
+	lat, lon []float64
+	first    []int32
+	target   []int32
+	// length es la longitud de cada arista en metros.
+	length []float32
+	index  *grid
+}
+
+// Nodes devuelve el número de nodos de la red.
+func (g *Graph) Nodes() int { return len(g.lat) }
+
+// Edges devuelve el número de aristas (tramos dirigidos) de la red.
+func (g *Graph) Edges() int { return len(g.target) }
+
+func (g *Graph) point(node int32) domain.Point {
+	return domain.Point{Latitude: g.lat[node], Longitude: g.lon[node]}
+}
+
+// LoadPBF carga la red viaria de un extracto OSM PBF. El fichero se recorre dos veces: primero se
+// leen las vías transitables y después solo las coordenadas de los nodos que usan, para no tener
+// en memoria todos los nodos del extracto.
+func LoadPBF(path string) (*Graph, error) {
+	type way struct {
+		refs []int64
+		dir  direction
+	}
+	var ways []way
+	nodeIndex := make(map[int64]int32)
+	err := scanFile(path, pbfVisitor{
+		way: func(_ int64, refs []int64, tags map[string]string) {
+			dir := wayDirection(tags)
+			if dir == 0 || len(refs) < 2 {
+				return
+			}
+			ways = append(ways, way{refs: refs, dir: dir})
+			for _, ref := range refs {
+				if _, ok := nodeIndex[ref]; !ok {
+					nodeIndex[ref] = int32(len(nodeIndex))
+				}
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(ways) == 0 {
+		return nil, fmt.Errorf("el extracto %s no contiene vías transitables", path)
+	}
+
+	lat := make([]float64, len(nodeIndex))
+	lon := make([]float64, len(nodeIndex))
+	found := make([]bool, len(nodeIndex))
+	err = scanFile(path, pbfVisitor{
+		node: func(id int64, nodeLat, nodeLon float64) {
+			if i, ok := nodeIndex[id]; ok {
+				lat[i], lon[i], found[i] = nodeLat, nodeLon, true
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Las vías recortadas por el borde del extracto referencian nodos que no están: se omiten esos tramos.
+	var edges []edge
+	for _, w := range ways {
+		for k := 1; k < len(w.refs); k++ {
+			a, b := nodeIndex[w.refs[k-1]], nodeIndex[w.refs[k]]
+			if !found[a] || !found[b] || a == b {
+				continue
+			}
+			length := float32(1000 * optimizer.HaversineKm(
+				domain.Point{Latitude: lat[a], Longitude: lon[a]},
+				domain.Point{Latitude: lat[b], Longitude: lon[b]},
+			))
+			if w.dir&forward != 0 {
+				edges = append(edges, edge{from: a, to: b, length: length})
+			}
+			if w.dir&backward != 0 {
+				edges = append(edges, edge{from: b, to: a, length: length})
+			}
+		}
+	}
+	return newGraph(lat, lon, edges), nil
+}
+
+// scanFile abre el fichero y lo recorre con el visitante.
+func scanFile(path string, v pbfVisitor) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("no se pudo abrir el extracto OSM: %w", err)
+	}
+	defer f.Close()
+	if err := scanPBF(f, v); err != nil {
+		return fmt.Errorf("error al leer el extracto OSM %s: %w", path, err)
+	}
+	return nil
+}
+
+// edge es un tramo dirigido de la red.
+type edge struct {
+	from, to int32
+	length   float32
+}
+
+// newGraph construye el grafo con la mayor componente fuertemente conexa de la red. Así cualquier
+// punto enganchado a la red puede llegar a cualquier otro; los trozos aislados (p. ej. calles
+// recortadas por el borde del extracto o aparcamientos sin salida) se descartan.
+func newGraph(lat, lon []float64, edges []edge) *Graph {
+	keep := largestComponent(len(lat), edges)
+
+	remap := make([]int32, len(lat))
+	g := &Graph{}
+	for i := range lat {
+		remap[i] = -1
+		if keep[i] {
+			remap[i] = int32(len(g.lat))
+			g.lat = append(g.lat, lat[i])
+			g.lon = append(g.lon, lon[i])
+		}
+	}
+	kept := edges[:0]
+	for _, e := range edges {
+		if keep[e.from] && keep[e.to] {
+			kept = append(kept, edge{from: remap[e.from], to: remap[e.to], length: e.length})
+		}
+	}
+	g.first, g.target, g.length = csr(len(g.lat), kept, false)
+	g.index = newGrid(g.lat, g.lon)
+	return g
+}
+
+// csr ordena las aristas por nodo de origen (o de destino, si 'reverse') en formato CSR.
+func csr(nodes int, edges []edge, reverse bool) (first, target []int32, length []float32) {
+	ends := func(e edge) (int32, int32) {
+		if reverse {
+			return e.to, e.from
+		}
+		return e.from, e.to
+	}
+
+	first = make([]int32, nodes+1)
+	for _, e := range edges {
+		from, _ := ends(e)
+		first[from+1]++
+	}
+	for i := 1; i <= nodes; i++ {
+		first[i] += first[i-1]
+	}
+	target = make([]int32, len(edges))
+	length = make([]float32, len(edges))
+	next := append([]int32(nil), first[:nodes]...)
+	for _, e := range edges {
+		from, to := ends(e)
+		target[next[from]], length[next[from]] = to, e.length
+		next[from]++
+	}
+	return first, target, length
+}
+
+// largestComponent marca los nodos de la mayor componente fuertemente conexa (algoritmo de
+// Kosaraju con recorridos iterativos para no desbordar la pila en redes grandes).
+func largestComponent(nodes int, edges []edge) []bool {
+	fFirst, fTarget, _ := csr(nodes, edges, false)
+	rFirst, rTarget, _ := csr(nodes, edges, true)
+
+	// 1. Orden de finalización de un recorrido en profundidad del grafo.
+	order := make([]int32, 0, nodes)
+	visited := make([]bool, nodes)
+	type frame struct{ node, next int32 }
+	var stack []frame
+	for start := range nodes {
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+		stack = append(stack[:0], frame{node: int32(start), next: fFirst[start]})
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if top.next < fFirst[top.node+1] {
+				to := fTarget[top.next]
+				top.next++
+				if !visited[to] {
+					visited[to] = true
+					stack = append(stack, frame{node: to, next: fFirst[to]})
+				}
+				continue
+			}
+			order = append(order, top.node)
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	// 2. Componentes del grafo inverso en orden inverso de finalización.
+	component := make([]int32, nodes)
+	for i := range component {
+		component[i] = -1
+	}
+	var sizes []int
+	var queue []int32
+	for k := len(order) - 1; k >= 0; k-- {
+		start := order[k]
+		if component[start] >= 0 {
+			continue
+		}
+		c := int32(len(sizes))
+		size := 0
+		component[start] = c
+		queue = append(queue[:0], start)
+		for len(queue) > 0 {
+			node := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			size++
+			for e := rFirst[node]; e < rFirst[node+1]; e++ {
+				if from := rTarget[e]; component[from] < 0 {
+					component[from] = c
+					queue = append(queue, from)
+				}
+			}
+		}
+		sizes = append(sizes, size)
+	}
+
+	largest := int32(0)
+	for c, size := range sizes {
+		if size > sizes[largest] {
+			largest = int32(c)
+		}
+	}
+	keep := make([]bool, nodes)
+	for i, c := range component {
+		keep[i] = c == largest && len(sizes) > 0
+	}
+	return keep
+}
+
+// gridCellDegrees es el tamaño de las celdas del índice espacial (unos 500 m).
+const gridCellDegrees = 0.005
+
+// metersPerDegree es la longitud aproximada de un grado de latitud.
+const metersPerDegree = 111320.0
+
+// grid es un índice espacial de los nodos por celdas de tamaño fijo.
+type grid struct {
+	cells    map[[2]int32][]int32
+	lat, lon []float64
+}
+
+func cellOf(lat, lon float64) [2]int32 {
+	return [2]int32{int32(math.Floor(lat / gridCellDegrees)), int32(math.Floor(lon / gridCellDegrees))}
+}
+
+func newGrid(lat, lon []float64) *grid {
+	g := &grid{cells: make(map[[2]int32][]int32), lat: lat, lon: lon}
+	for i := range lat {
+		c := cellOf(lat[i], lon[i])
+		g.cells[c] = append(g.cells[c], int32(i))
+	}
+	return g
+}
+
+// nearest devuelve el nodo más cercano al punto y su distancia en metros, si hay alguno a menos de 'maxMeters'.
+func (g *grid) nearest(p domain.Point, maxMeters float64) (int32, float64, bool) {
+	dLat := maxMeters / metersPerDegree
+	dLon := maxMeters / (metersPerDegree * math.Max(math.Cos(p.Latitude*math.Pi/180), 0.01))
+	lo, hi := cellOf(p.Latitude-dLat, p.Longitude-dLon), cellOf(p.Latitude+dLat, p.Longitude+dLon)
+
+	best, bestDist := int32(-1), maxMeters
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			for _, node := range g.cells[[2]int32{x, y}] {
+				d := 1000 * optimizer.HaversineKm(p, domain.Point{Latitude: g.lat[node], Longitude: g.lon[node]})
+				if d <= bestDist {
+					best, bestDist = node, d
+				}
+			}
+		}
+	}
+	return best, bestDist, best >= 0
+}
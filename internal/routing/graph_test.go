@@ -0,0 +1,114 @@
+package routing
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWayDirection(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string]string
+		want direction
+	}{
+		{name: "calle de doble sentido", tags: map[string]string{"highway": "residential"}, want: both},
+		{name: "sentido único", tags: map[string]string{"highway": "residential", "oneway": "yes"}, want: forward},
+		{name: "sentido único con 1", tags: map[string]string{"highway": "tertiary", "oneway": "1"}, want: forward},
+		{name: "sentido único contrario al de los nodos", tags: map[string]string{"highway": "residential", "oneway": "-1"}, want: backward},
+		{name: "autopista", tags: map[string]string{"highway": "motorway"}, want: forward},
+		{name: "autopista de doble sentido explícito", tags: map[string]string{"highway": "motorway", "oneway": "no"}, want: both},
+		{name: "rotonda", tags: map[string]string{"highway": "primary", "junction": "roundabout"}, want: forward},
+		{name: "camino peatonal", tags: map[string]string{"highway": "footway"}, want: 0},
+		{name: "sin highway", tags: map[string]string{"building": "yes"}, want: 0},
+		{name: "área", tags: map[string]string{"highway": "service", "area": "yes"}, want: 0},
+		{name: "acceso prohibido", tags: map[string]string{"highway": "residential", "motor_vehicle": "no"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wayDirection(tt.tags); got != tt.want {
+				t.Errorf("wayDirection(%v) = %v, se esperaba %v", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPBFOneWayEdges(t *testing.T) {
+	// Una rotonda de sentido único 1 -> 2 -> 3 -> 4 -> 1, una calle de doble sentido 1 - 3, una calle
+	// de sentido único 3 -> 5 que sigue por otra de sentido contrario al de sus nodos (2 <- 5, de 5 a
+	// 2) y un camino peatonal 4 - 6 que no forma parte de la red.
+	nodes := []testNode{
+		{id: 1, lat: 40.4100, lon: -3.7000},
+		{id: 2, lat: 40.4110, lon: -3.7010},
+		{id: 3, lat: 40.4120, lon: -3.7000},
+		{id: 4, lat: 40.4110, lon: -3.6990},
+		{id: 5, lat: 40.4130, lon: -3.7020},
+		{id: 6, lat: 40.4140, lon: -3.6980},
+	}
+	ways := []testWay{
+		{id: 10, refs: []int64{1, 2, 3, 4, 1}, tags: map[string]string{"highway": "primary", "junction": "roundabout"}},
+		{id: 11, refs: []int64{1, 3}, tags: map[string]string{"highway": "residential"}},
+		{id: 12, refs: []int64{2, 5}, tags: map[string]string{"highway": "residential", "oneway": "-1"}},
+		{id: 13, refs: []int64{3, 5}, tags: map[string]string{"highway": "residential", "oneway": "yes"}},
+		{id: 14, refs: []int64{4, 6}, tags: map[string]string{"highway": "footway"}},
+	}
+	path := filepath.Join(t.TempDir(), "red.osm.pbf")
+	if err := os.WriteFile(path, encodePBF(t, nodes, ways, pbfOptions{compress: true}), 0o600); err != nil {
+		t.Fatalf("error al escribir el extracto: %v", err)
+	}
+
+	g, err := LoadPBF(path)
+	if err != nil {
+		t.Fatalf("LoadPBF() error inesperado: %v", err)
+	}
+	if g.Nodes() != 5 {
+		t.Errorf("la red tiene %d nodos, se esperaban 5", g.Nodes())
+	}
+
+	// Los nodos de la red se identifican por sus coordenadas, con la precisión del fichero.
+	nodeOf := make(map[int64]int32)
+	for _, n := range nodes {
+		for i := range g.lat {
+			if math.Abs(g.lat[i]-n.lat) < 1e-7 && math.Abs(g.lon[i]-n.lon) < 1e-7 {
+				nodeOf[n.id] = int32(i)
+			}
+		}
+	}
+	hasEdge := func(from, to int64) bool {
+		a, okA := nodeOf[from]
+		b, okB := nodeOf[to]
+		if !okA || !okB {
+			return false
+		}
+		for e := g.first[a]; e < g.first[a+1]; e++ {
+			if g.target[e] == b {
+				return true
+			}
+		}
+		return false
+	}
+
+	tests := []struct {
+		from, to int64
+		want     bool
+	}{
+		{1, 2, true}, {2, 1, false},
+		{2, 3, true}, {3, 2, false},
+		{3, 4, true}, {4, 3, false},
+		{4, 1, true}, {1, 4, false},
+		{1, 3, true}, {3, 1, true},
+		{5, 2, true}, {2, 5, false},
+		{3, 5, true}, {5, 3, false},
+		{4, 6, false}, {6, 4, false},
+	}
+	for _, tt := range tests {
+		if got := hasEdge(tt.from, tt.to); got != tt.want {
+			t.Errorf("tramo %d -> %d: %v, se esperaba %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+	if g.Edges() != 8 {
+		t.Errorf("la red tiene %d tramos, se esperaban 8", g.Edges())
+	}
+}
@@ -0,0 +1,172 @@
+package routing
+
+import (
+	"container/heap"
+	"math"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/optimizer"
+	"sync"
+)
+
+// search es el estado de una búsqueda de caminos mínimos. Se reutiliza entre búsquedas (los
+// arrays tienen el tamaño de la red) y solo se reinician los nodos que tocó la anterior.
+type search struct {
+	dist    []float32
+	prev    []int32
+	settled []bool
+	touched []int32
+	queue   nodeQueue
+}
+
+func (g *Graph) newSearch() *search {
+	s := &search{
+		dist:    make([]float32, g.Nodes()),
+		prev:    make([]int32, g.Nodes()),
+		settled: make([]bool, g.Nodes()),
+	}
+	for i := range s.dist {
+		s.dist[i] = float32(math.Inf(1))
+		s.prev[i] = -1
+	}
+	return s
+}
+
+func (s *search) reset() {
+	for _, node := range s.touched {
+		s.dist[node] = float32(math.Inf(1))
+		s.prev[node] = -1
+		s.settled[node] = false
+	}
+	s.touched = s.touched[:0]
+	s.queue = s.queue[:0]
+}
+
+// relax actualiza la distancia provisional de 'node' si se mejora llegando desde 'from'.
+// 'priority' es la distancia más la estimación restante (A*) o la propia distancia (Dijkstra).
+func (s *search) relax(node, from int32, dist, priority float32) {
+	if dist >= s.dist[node] {
+		return
+	}
+	if math.IsInf(float64(s.dist[node]), 1) {
+		s.touched = append(s.touched, node)
+	}
+	s.dist[node], s.prev[node] = dist, from
+	heap.Push(&s.queue, queueItem{node: node, priority: priority})
+}
+
+// searchPool reutiliza los estados de búsqueda de una red.
+type searchPool struct {
+	graph *Graph
+	pool  sync.Pool
+}
+
+func (p *searchPool) get() *search {
+	if s, ok := p.pool.Get().(*search); ok {
+		return s
+	}
+	return p.graph.newSearch()
+}
+
+func (p *searchPool) put(s *search) {
+	s.reset()
+	p.pool.Put(s)
+}
+
+// distancesFrom calcula con Dijkstra la distancia en metros desde 'source' hasta cada nodo de
+// 'targets'. La búsqueda se detiene en cuanto se han alcanzado todos.
+func (g *Graph) distancesFrom(s *search, source int32, targets []int32) []float64 {
+	pending := make(map[int32]bool, len(targets))
+	for _, t := range targets {
+		pending[t] = true
+	}
+
+	s.relax(source, -1, 0, 0)
+	for s.queue.Len() > 0 && len(pending) > 0 {
+		item := heap.Pop(&s.queue).(queueItem)
+		node := item.node
+		if s.settled[node] {
+			continue
+		}
+		s.settled[node] = true
+		delete(pending, node)
+		for e := g.first[node]; e < g.first[node+1]; e++ {
+			d := s.dist[node] + g.length[e]
+			s.relax(g.target[e], node, d, d)
+		}
+	}
+
+	dists := make([]float64, len(targets))
+	for i, t := range targets {
+		dists[i] = float64(s.dist[t])
+	}
+	return dists
+}
+
+// shortestPath calcula con A* el camino mínimo de 'from' a 'to'. La estimación restante es la
+// distancia en línea recta, que nunca supera la real porque las aristas miden lo mismo.
+// Devuelve la longitud en metros y los nodos del camino, o false si no hay camino.
+func (g *Graph) shortestPath(s *search, from, to int32) (float64, []int32, bool) {
+	goal := g.point(to)
+	estimate := func(node int32) float32 {
+		return float32(1000 * optimizer.HaversineKm(g.point(node), goal))
+	}
+
+	s.relax(from, -1, 0, estimate(from))
+	for s.queue.Len() > 0 {
+		node := heap.Pop(&s.queue).(queueItem).node
+		if s.settled[node] {
+			continue
+		}
+		s.settled[node] = true
+		if node == to {
+			break
+		}
+		for e := g.first[node]; e < g.first[node+1]; e++ {
+			next := g.target[e]
+			d := s.dist[node] + g.length[e]
+			s.relax(next, node, d, d+estimate(next))
+		}
+	}
+	if !s.settled[to] {
+		return 0, nil, false
+	}
+
+	var nodes []int32
+	for node := to; node >= 0; node = s.prev[node] {
+		nodes = append(nodes, node)
+	}
+	for a, b := 0, len(nodes)-1; a < b; a, b = a+1, b-1 {
+		nodes[a], nodes[b] = nodes[b], nodes[a]
+	}
+	return float64(s.dist[to]), nodes, true
+}
+
+// queueItem es una entrada de la cola de prioridad de las búsquedas.
+type queueItem struct {
+	node     int32
+	priority float32
+}
+
+// nodeQueue es una cola de prioridad (montículo binario) de nodos. Un nodo puede aparecer varias
+// veces; las entradas obsoletas se descartan al sacarlas si el nodo ya está asentado.
+type nodeQueue []queueItem
+
+func (q nodeQueue) Len() int           { return len(q) }
+func (q nodeQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+func (q nodeQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x any)        { *q = append(*q, x.(queueItem)) }
+func (q *nodeQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// pathPoints convierte los nodos de un camino en coordenadas.
+func (g *Graph) pathPoints(nodes []int32) []domain.Point {
+	points := make([]domain.Point, len(nodes))
+	for i, node := range nodes {
+		points[i] = g.point(node)
+	}
+	return points
+}
@@ -0,0 +1,398 @@
+package routing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Lectura del formato OSM PBF (https://wiki.openstreetmap.org/wiki/PBF_Format).
+//
+// El fichero es una secuencia de bloques: una cabecera (BlobHeader) precedida de su longitud y un
+// Blob con los datos, normalmente comprimidos con zlib. Solo se decodifican los campos que necesita
+// el grafo: las coordenadas de los nodos y las referencias y etiquetas de las vías.
+
+// Límites del formato para no reservar memoria sin control con un fichero corrupto.
+const (
+	maxBlobHeaderSize = 64 * 1024
+	maxBlobSize       = 32 * 1024 * 1024
+)
+
+// Funcionalidades de la cabecera que sabemos leer. Un fichero que exige otras no se puede cargar.
+var supportedFeatures = map[string]bool{
+	"OsmSchema-V0.6": true,
+	"DenseNodes":     true,
+}
+
+// ErrUnsupportedPBF indica que el fichero usa una compresión o una funcionalidad del formato que no se soporta.
+var ErrUnsupportedPBF = errors.New("fichero PBF no soportado")
+
+// pbfVisitor recibe los elementos del fichero. Cualquiera de las funciones puede ser nil para
+// saltarse ese tipo de elemento (y no decodificarlo).
+type pbfVisitor struct {
+	node func(id int64, lat, lon float64)
+	way  func(id int64, refs []int64, tags map[string]string)
+}
+
+// scanPBF recorre el fichero y llama al visitante con cada nodo y cada vía.
+func scanPBF(r io.Reader, v pbfVisitor) error {
+	var sizeBuf [4]byte
+	for {
+		if _, err := io.ReadFull(r, sizeBuf[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("error al leer el tamaño de la cabecera del bloque: %w", err)
+		}
+		size := binary.BigEndian.Uint32(sizeBuf[:])
+		if size > maxBlobHeaderSize {
+			return fmt.Errorf("cabecera de bloque demasiado grande (%d bytes)", size)
+		}
+		header := make([]byte, size)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return fmt.Errorf("error al leer la cabecera del bloque: %w", err)
+		}
+		blobType, dataSize, err := parseBlobHeader(header)
+		if err != nil {
+			return err
+		}
+		if dataSize > maxBlobSize {
+			return fmt.Errorf("bloque demasiado grande (%d bytes)", dataSize)
+		}
+		blob := make([]byte, dataSize)
+		if _, err := io.ReadFull(r, blob); err != nil {
+			return fmt.Errorf("error al leer el bloque: %w", err)
+		}
+
+		switch blobType {
+		case "OSMHeader":
+			data, err := blobData(blob)
+			if err != nil {
+				return err
+			}
+			if err := checkHeaderBlock(data); err != nil {
+				return err
+			}
+		case "OSMData":
+			if v.node == nil && v.way == nil {
+				continue
+			}
+			data, err := blobData(blob)
+			if err != nil {
+				return err
+			}
+			if err := parsePrimitiveBlock(data, v); err != nil {
+				return err
+			}
+		default:
+			// Los tipos de bloque desconocidos se ignoran, como indica la especificación.
+		}
+	}
+}
+
+// field es un campo de un mensaje protobuf: su número, su tipo y su valor (entero o bytes).
+type field struct {
+	num   protowire.Number
+	typ   protowire.Type
+	value uint64
+	bytes []byte
+}
+
+// eachField recorre los campos de un mensaje protobuf.
+func eachField(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("mensaje PBF corrupto: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		f := field{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.value, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.value = uint64(v)
+		case protowire.Fixed64Type:
+			f.value, n = protowire.ConsumeFixed64(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("mensaje PBF corrupto: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// varints devuelve los enteros de un campo repetido, empaquetado o no.
+func (f field) varints(dst []uint64) ([]uint64, error) {
+	if f.typ == protowire.VarintType {
+		return append(dst, f.value), nil
+	}
+	if f.typ != protowire.BytesType {
+		return dst, fmt.Errorf("mensaje PBF corrupto: tipo inesperado en el campo %d", f.num)
+	}
+	b := f.bytes
+	for len(b) > 0 {
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return dst, fmt.Errorf("mensaje PBF corrupto: %w", protowire.ParseError(n))
+		}
+		dst = append(dst, v)
+		b = b[n:]
+	}
+	return dst, nil
+}
+
+// parseBlobHeader devuelve el tipo y el tamaño del bloque que sigue a la cabecera.
+func parseBlobHeader(b []byte) (blobType string, dataSize int, err error) {
+	err = eachField(b, func(f field) error {
+		switch f.num {
+		case 1: // type
+			blobType = string(f.bytes)
+		case 3: // datasize
+			dataSize = int(int32(f.value))
+		}
+		return nil
+	})
+	if err == nil && (blobType == "" || dataSize < 0) {
+		err = errors.New("cabecera de bloque PBF incompleta")
+	}
+	return blobType, dataSize, err
+}
+
+// blobData devuelve el contenido descomprimido de un bloque.
+func blobData(b []byte) ([]byte, error) {
+	var raw, zlibData []byte
+	var rawSize int
+	compression := ""
+	err := eachField(b, func(f field) error {
+		switch f.num {
+		case 1: // raw
+			raw = f.bytes
+		case 2: // raw_size
+			rawSize = int(int32(f.value))
+		case 3: // zlib_data
+			zlibData = f.bytes
+		case 4:
+			compression = "lzma"
+		case 5:
+			compression = "bzip2"
+		case 6:
+			compression = "lz4"
+		case 7:
+			compression = "zstd"
+		}
+		return nil
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case raw != nil:
+		return raw, nil
+	case zlibData != nil:
+		if rawSize < 0 || rawSize > maxBlobSize {
+			return nil, fmt.Errorf("bloque PBF demasiado grande (%d bytes)", rawSize)
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(zlibData))
+		if err != nil {
+			return nil, fmt.Errorf("error al descomprimir un bloque PBF: %w", err)
+		}
+		defer zr.Close()
+		data := bytes.NewBuffer(make([]byte, 0, rawSize))
+		if _, err := io.Copy(data, io.LimitReader(zr, maxBlobSize+1)); err != nil {
+			return nil, fmt.Errorf("error al descomprimir un bloque PBF: %w", err)
+		}
+		if data.Len() > maxBlobSize {
+			return nil, fmt.Errorf("bloque PBF demasiado grande (más de %d bytes)", maxBlobSize)
+		}
+		return data.Bytes(), nil
+	case compression != "":
+		return nil, fmt.Errorf("%w: compresión %s", ErrUnsupportedPBF, compression)
+	default:
+		return nil, errors.New("bloque PBF vacío")
+	}
+}
+
+// checkHeaderBlock comprueba que el fichero no exige funcionalidades que no sabemos leer.
+func checkHeaderBlock(b []byte) error {
+	return eachField(b, func(f field) error {
+		if f.num == 4 && !supportedFeatures[string(f.bytes)] { // required_features
+			return fmt.Errorf("%w: requiere %q", ErrUnsupportedPBF, f.bytes)
+		}
+		return nil
+	})
+}
+
+// primitiveBlock es el contexto de un bloque de datos: su tabla de cadenas y la codificación de las coordenadas.
+type primitiveBlock struct {
+	strings     []string
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+}
+
+// coord convierte una coordenada codificada en grados.
+func (pb *primitiveBlock) coord(offset, value int64) float64 {
+	return 1e-9 * float64(offset+pb.granularity*value)
+}
+
+func (pb *primitiveBlock) str(i uint64) string {
+	if i < uint64(len(pb.strings)) {
+		return pb.strings[i]
+	}
+	return ""
+}
+
+// parsePrimitiveBlock decodifica un bloque de datos. La tabla de cadenas y la codificación de las
+// coordenadas pueden aparecer después de los grupos, así que se leen primero.
+func parsePrimitiveBlock(b []byte, v pbfVisitor) error {
+	pb := &primitiveBlock{granularity: 100}
+	var groups [][]byte
+	err := eachField(b, func(f field) error {
+		switch f.num {
+		case 1: // stringtable
+			return eachField(f.bytes, func(s field) error {
+				if s.num == 1 {
+					pb.strings = append(pb.strings, string(s.bytes))
+				}
+				return nil
+			})
+		case 2: // primitivegroup
+			groups = append(groups, f.bytes)
+		case 17:
+			pb.granularity = int64(int32(f.value))
+		case 19:
+			pb.latOffset = int64(f.value)
+		case 20:
+			pb.lonOffset = int64(f.value)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, g := range groups {
+		err := eachField(g, func(f field) error {
+			switch {
+			case f.num == 1 && v.node != nil:
+				return pb.parseNode(f.bytes, v.node)
+			case f.num == 2 && v.node != nil:
+				return pb.parseDenseNodes(f.bytes, v.node)
+			case f.num == 3 && v.way != nil:
+				return pb.parseWay(f.bytes, v.way)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pb *primitiveBlock) parseNode(b []byte, visit func(id int64, lat, lon float64)) error {
+	var id, lat, lon int64
+	err := eachField(b, func(f field) error {
+		switch f.num {
+		case 1:
+			id = protowire.DecodeZigZag(f.value)
+		case 8:
+			lat = protowire.DecodeZigZag(f.value)
+		case 9:
+			lon = protowire.DecodeZigZag(f.value)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	visit(id, pb.coord(pb.latOffset, lat), pb.coord(pb.lonOffset, lon))
+	return nil
+}
+
+// parseDenseNodes decodifica un grupo de nodos densos: los IDs y las coordenadas van codificados
+// como diferencias respecto al nodo anterior.
+func (pb *primitiveBlock) parseDenseNodes(b []byte, visit func(id int64, lat, lon float64)) error {
+	var ids, lats, lons []uint64
+	err := eachField(b, func(f field) error {
+		var err error
+		switch f.num {
+		case 1:
+			ids, err = f.varints(ids)
+		case 8:
+			lats, err = f.varints(lats)
+		case 9:
+			lons, err = f.varints(lons)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(lats) != len(ids) || len(lons) != len(ids) {
+		return errors.New("bloque de nodos densos corrupto: listas de distinta longitud")
+	}
+
+	var id, lat, lon int64
+	for i := range ids {
+		id += protowire.DecodeZigZag(ids[i])
+		lat += protowire.DecodeZigZag(lats[i])
+		lon += protowire.DecodeZigZag(lons[i])
+		visit(id, pb.coord(pb.latOffset, lat), pb.coord(pb.lonOffset, lon))
+	}
+	return nil
+}
+
+func (pb *primitiveBlock) parseWay(b []byte, visit func(id int64, refs []int64, tags map[string]string)) error {
+	var id int64
+	var keys, vals, deltas []uint64
+	err := eachField(b, func(f field) error {
+		var err error
+		switch f.num {
+		case 1:
+			id = int64(f.value)
+		case 2:
+			keys, err = f.varints(keys)
+		case 3:
+			vals, err = f.varints(vals)
+		case 8:
+			deltas, err = f.varints(deltas)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(keys) != len(vals) {
+		return fmt.Errorf("vía %d corrupta: etiquetas incompletas", id)
+	}
+
+	tags := make(map[string]string, len(keys))
+	for i := range keys {
+		tags[pb.str(keys[i])] = pb.str(vals[i])
+	}
+	refs := make([]int64, len(deltas))
+	var ref int64
+	for i, d := range deltas {
+		ref += protowire.DecodeZigZag(d)
+		refs[i] = ref
+	}
+	visit(id, refs, tags)
+	return nil
+}
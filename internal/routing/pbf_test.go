@@ -0,0 +1,287 @@
+package routing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type testNode struct {
+	id       int64
+	lat, lon float64
+}
+
+type testWay struct {
+	id   int64
+	refs []int64
+	tags map[string]string
+}
+
+// pbfOptions es la codificación del bloque de datos de un fichero de prueba.
+type pbfOptions struct {
+	compress    bool
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+}
+
+func appendVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendBytesField(b []byte, num protowire.Number, data []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, data)
+}
+
+func appendPacked(b []byte, num protowire.Number, values []uint64) []byte {
+	var packed []byte
+	for _, v := range values {
+		packed = protowire.AppendVarint(packed, v)
+	}
+	return appendBytesField(b, num, packed)
+}
+
+// frame codifica un bloque del fichero: el tamaño de la cabecera, la cabecera y el Blob.
+func frame(blobType string, blob []byte) []byte {
+	header := appendBytesField(nil, 1, []byte(blobType))
+	header = appendVarintField(header, 3, uint64(len(blob)))
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(header)))
+	return append(append(out, header...), blob...)
+}
+
+// rawBlob codifica un Blob sin comprimir.
+func rawBlob(data []byte) []byte {
+	return appendBytesField(nil, 1, data)
+}
+
+// zlibBlob codifica un Blob comprimido con zlib.
+func zlibBlob(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatalf("error al comprimir: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("error al comprimir: %v", err)
+	}
+	blob := appendVarintField(nil, 2, uint64(len(data)))
+	return appendBytesField(blob, 3, buf.Bytes())
+}
+
+// headerBlock codifica un OSMHeader que exige las funcionalidades 'features'.
+func headerBlock(features ...string) []byte {
+	var b []byte
+	for _, f := range features {
+		b = appendBytesField(b, 4, []byte(f))
+	}
+	return b
+}
+
+// encodePBF codifica un fichero PBF con una cabecera y un bloque de datos con los nodos (como
+// nodos densos) y las vías.
+func encodePBF(t *testing.T, nodes []testNode, ways []testWay, opts pbfOptions) []byte {
+	t.Helper()
+	if opts.granularity == 0 {
+		opts.granularity = 100
+	}
+
+	// Tabla de cadenas: la posición 0 queda vacía, como en los ficheros reales.
+	strings := []string{""}
+	index := map[string]uint64{}
+	intern := func(s string) uint64 {
+		if i, ok := index[s]; ok {
+			return i
+		}
+		index[s] = uint64(len(strings))
+		strings = append(strings, s)
+		return index[s]
+	}
+
+	var ids, lats, lons []uint64
+	var prevID, prevLat, prevLon int64
+	for _, n := range nodes {
+		lat := int64(math.Round((n.lat*1e9 - float64(opts.latOffset)) / float64(opts.granularity)))
+		lon := int64(math.Round((n.lon*1e9 - float64(opts.lonOffset)) / float64(opts.granularity)))
+		ids = append(ids, protowire.EncodeZigZag(n.id-prevID))
+		lats = append(lats, protowire.EncodeZigZag(lat-prevLat))
+		lons = append(lons, protowire.EncodeZigZag(lon-prevLon))
+		prevID, prevLat, prevLon = n.id, lat, lon
+	}
+	dense := appendPacked(nil, 1, ids)
+	dense = appendPacked(dense, 8, lats)
+	dense = appendPacked(dense, 9, lons)
+	nodeGroup := appendBytesField(nil, 2, dense)
+
+	var wayGroup []byte
+	for _, w := range ways {
+		keys := make([]string, 0, len(w.tags))
+		for k := range w.tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var ks, vs, refs []uint64
+		for _, k := range keys {
+			ks = append(ks, intern(k))
+			vs = append(vs, intern(w.tags[k]))
+		}
+		var prev int64
+		for _, ref := range w.refs {
+			refs = append(refs, protowire.EncodeZigZag(ref-prev))
+			prev = ref
+		}
+		way := appendVarintField(nil, 1, uint64(w.id))
+		way = appendPacked(way, 2, ks)
+		way = appendPacked(way, 3, vs)
+		way = appendPacked(way, 8, refs)
+		wayGroup = appendBytesField(wayGroup, 3, way)
+	}
+
+	var table []byte
+	for _, s := range strings {
+		table = appendBytesField(table, 1, []byte(s))
+	}
+	// La tabla de cadenas y la codificación de las coordenadas van al final para comprobar que se
+	// leen antes que los grupos.
+	block := appendBytesField(nil, 2, nodeGroup)
+	block = appendBytesField(block, 2, wayGroup)
+	block = appendBytesField(block, 1, table)
+	block = appendVarintField(block, 17, uint64(opts.granularity))
+	block = appendVarintField(block, 19, uint64(opts.latOffset))
+	block = appendVarintField(block, 20, uint64(opts.lonOffset))
+
+	data := rawBlob(block)
+	if opts.compress {
+		data = zlibBlob(t, block)
+	}
+	out := frame("OSMHeader", rawBlob(headerBlock("OsmSchema-V0.6", "DenseNodes")))
+	return append(out, frame("OSMData", data)...)
+}
+
+func TestScanPBF(t *testing.T) {
+	// IDs y coordenadas que suben y bajan para que las diferencias sean positivas y negativas.
+	nodes := []testNode{
+		{id: 100, lat: 40.4167754, lon: -3.7037902},
+		{id: 101, lat: 40.4170000, lon: -3.7040000},
+		{id: 99, lat: 40.4150000, lon: -3.7000000},
+		{id: 250, lat: 40.4200000, lon: -3.7100000},
+	}
+	ways := []testWay{
+		{id: 7, refs: []int64{100, 250, 99}, tags: map[string]string{"highway": "residential", "oneway": "yes"}},
+		{id: 8, refs: []int64{250, 101}, tags: map[string]string{"highway": "service"}},
+	}
+
+	tests := []struct {
+		name string
+		opts pbfOptions
+	}{
+		{name: "sin comprimir", opts: pbfOptions{}},
+		{name: "comprimido con zlib", opts: pbfOptions{compress: true}},
+		{name: "granularidad y desplazamiento", opts: pbfOptions{granularity: 1000, latOffset: 40_000_000_000, lonOffset: -3_000_000_000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotNodes []testNode
+			var gotWays []testWay
+			err := scanPBF(bytes.NewReader(encodePBF(t, nodes, ways, tt.opts)), pbfVisitor{
+				node: func(id int64, lat, lon float64) { gotNodes = append(gotNodes, testNode{id, lat, lon}) },
+				way: func(id int64, refs []int64, tags map[string]string) {
+					gotWays = append(gotWays, testWay{id, refs, tags})
+				},
+			})
+			if err != nil {
+				t.Fatalf("scanPBF() error inesperado: %v", err)
+			}
+
+			if len(gotNodes) != len(nodes) {
+				t.Fatalf("se han leído %d nodos, se esperaban %d", len(gotNodes), len(nodes))
+			}
+			// La precisión es la granularidad: 1e-7 grados por defecto y 1e-6 con granularidad 1000.
+			tolerance := 1e-9 * float64(max(tt.opts.granularity, 100))
+			for i, want := range nodes {
+				got := gotNodes[i]
+				if got.id != want.id || math.Abs(got.lat-want.lat) > tolerance || math.Abs(got.lon-want.lon) > tolerance {
+					t.Errorf("nodo %d = %+v, se esperaba %+v", i, got, want)
+				}
+			}
+			if fmt.Sprint(gotWays) != fmt.Sprint(ways) {
+				t.Errorf("vías = %v, se esperaba %v", gotWays, ways)
+			}
+		})
+	}
+}
+
+func TestScanPBFOnlyDecodesVisitedElements(t *testing.T) {
+	data := encodePBF(t, []testNode{{id: 1, lat: 40.4, lon: -3.7}}, []testWay{{id: 2, refs: []int64{1}, tags: map[string]string{"highway": "road"}}}, pbfOptions{})
+
+	ways := 0
+	err := scanPBF(bytes.NewReader(data), pbfVisitor{
+		way: func(int64, []int64, map[string]string) { ways++ },
+	})
+	if err != nil {
+		t.Fatalf("scanPBF() error inesperado: %v", err)
+	}
+	if ways != 1 {
+		t.Errorf("se han leído %d vías, se esperaba 1", ways)
+	}
+}
+
+func TestScanPBFUnsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "funcionalidad requerida desconocida",
+			data: frame("OSMHeader", rawBlob(headerBlock("OsmSchema-V0.6", "HistoricalInformation"))),
+		},
+		{
+			name: "compresión lz4",
+			data: frame("OSMData", appendBytesField(nil, 6, []byte{0x04, 0x22, 0x4d, 0x18})),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := scanPBF(bytes.NewReader(tt.data), pbfVisitor{node: func(int64, float64, float64) {}})
+			if !errors.Is(err, ErrUnsupportedPBF) {
+				t.Errorf("scanPBF() error = %v, se esperaba ErrUnsupportedPBF", err)
+			}
+		})
+	}
+}
+
+func TestScanPBFCorrupt(t *testing.T) {
+	valid := encodePBF(t, []testNode{{id: 1, lat: 40.4, lon: -3.7}}, nil, pbfOptions{})
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "fichero truncado", data: valid[:len(valid)-3]},
+		{name: "cabecera de bloque demasiado grande", data: binary.BigEndian.AppendUint32(nil, maxBlobHeaderSize+1)},
+		{
+			name: "nodos densos con listas de distinta longitud",
+			data: frame("OSMData", rawBlob(appendBytesField(nil, 2, appendBytesField(nil, 2,
+				appendPacked(appendPacked(appendPacked(nil, 1, []uint64{2, 2}), 8, []uint64{0}), 9, []uint64{0}))))),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := scanPBF(bytes.NewReader(tt.data), pbfVisitor{node: func(int64, float64, float64) {}}); err == nil {
+				t.Error("scanPBF() no ha devuelto error")
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"runtime"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/optimizer"
+	"sync"
+)
+
+// MaxSnapDistanceMeters es la distancia máxima entre un punto y el nodo de la red al que se
+// engancha. Los puntos más alejados (fuera del extracto o en mitad de una gran parcela) se tratan
+// con el proveedor de respaldo.
+const MaxSnapDistanceMeters = 500
+
+// ErrNoPath indica que no se puede ir por carretera entre dos puntos.
+var ErrNoPath = errors.New("no hay un camino por carretera entre los dos puntos")
+
+// Path es un trayecto por carretera entre dos puntos.
+type Path struct {
+	DistanceKm float64
+	// Points es el trazado del trayecto, del origen al destino.
+	Points []domain.Point
+}
+
+// Provider es el DistanceProvider de distancias por carretera. Cada punto se engancha al nodo más
+// cercano de la red y la distancia entre dos puntos es la del camino mínimo entre sus nodos más
+// los dos tramos de enganche. Las distancias que afectan a puntos que no se pueden enganchar se
+// toman del proveedor de respaldo (línea recta).
+type Provider struct {
+	graph    *Graph
+	searches *searchPool
+	fallback optimizer.DistanceProvider
+	workers  int
+}
+
+// NewProvider crea un proveedor de distancias sobre la red 'g' con la línea recta como respaldo.
+func NewProvider(g *Graph) *Provider {
+	return &Provider{
+		graph:    g,
+		searches: &searchPool{graph: g},
+		fallback: optimizer.Haversine{},
+		workers:  runtime.GOMAXPROCS(0),
+	}
+}
+
+// snap es el enganche de un punto a la red.
+type snap struct {
+	node   int32
+	meters float64
+	ok     bool
+}
+
+func (p *Provider) snap(point domain.Point) snap {
+	node, meters, ok := p.graph.index.nearest(point, MaxSnapDistanceMeters)
+	return snap{node: node, meters: meters, ok: ok}
+}
+
+// DistanceMatrix calcula la matriz de distancias por carretera (km). Lanza un Dijkstra desde cada
+// nodo de origen distinto, en paralelo, que se detiene al alcanzar todos los demás puntos.
+func (p *Provider) DistanceMatrix(ctx context.Context, points []domain.Point) (optimizer.Matrix, error) {
+	snaps := make([]snap, len(points))
+	var targets []int32
+	column := make(map[int32]int)
+	var unsnapped int
+	for i, point := range points {
+		snaps[i] = p.snap(point)
+		if !snaps[i].ok {
+			unsnapped++
+			continue
+		}
+		if _, ok := column[snaps[i].node]; !ok {
+			column[snaps[i].node] = len(targets)
+			targets = append(targets, snaps[i].node)
+		}
+	}
+
+	// Distancias en metros entre los nodos de la red: rows[k][c] va de targets[k] a targets[c].
+	rows := make([][]float64, len(targets))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(p.workers, len(targets)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range jobs {
+				s := p.searches.get()
+				rows[k] = p.graph.distancesFrom(s, targets[k], targets)
+				p.searches.put(s)
+			}
+		}()
+	}
+	var cancelled error
+	for k := range targets {
+		if cancelled = ctx.Err(); cancelled != nil {
+			break
+		}
+		jobs <- k
+	}
+	close(jobs)
+	wg.Wait()
+	if cancelled != nil {
+		return nil, cancelled
+	}
+
+	var fallback optimizer.Matrix
+	if unsnapped > 0 {
+		log.Printf("Rutas: %d de %d puntos están a más de %d m de la red viaria; se usa la distancia en línea recta para ellos", unsnapped, len(points), MaxSnapDistanceMeters)
+		var err error
+		if fallback, err = p.fallback.DistanceMatrix(ctx, points); err != nil {
+			return nil, err
+		}
+	}
+
+	m := make(optimizer.Matrix, len(points))
+	for i := range points {
+		m[i] = make([]float64, len(points))
+		for j := range points {
+			switch {
+			case i == j:
+			case !snaps[i].ok || !snaps[j].ok:
+				m[i][j] = fallback[i][j]
+			default:
+				meters := snaps[i].meters + rows[column[snaps[i].node]][column[snaps[j].node]] + snaps[j].meters
+				m[i][j] = meters / 1000
+			}
+		}
+	}
+	return m, nil
+}
+
+// ShortestPath calcula con A* el trayecto por carretera entre dos puntos, incluidos los tramos de
+// enganche a la red. Devuelve ErrNoPath si alguno de los puntos queda fuera de la red.
+func (p *Provider) ShortestPath(from, to domain.Point) (Path, error) {
+	a, b := p.snap(from), p.snap(to)
+	if !a.ok || !b.ok {
+		return Path{}, ErrNoPath
+	}
+
+	s := p.searches.get()
+	defer p.searches.put(s)
+	meters, nodes, ok := p.graph.shortestPath(s, a.node, b.node)
+	if !ok || math.IsInf(meters, 1) {
+		return Path{}, ErrNoPath
+	}
+
+	points := make([]domain.Point, 0, len(nodes)+2)
+	points = append(points, from)
+	points = append(points, p.graph.pathPoints(nodes)...)
+	points = append(points, to)
+	return Path{DistanceKm: (a.meters + meters + b.meters) / 1000, Points: points}, nil
+}
@@ -0,0 +1,245 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"smart-waste-management/internal/domain"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// heartbeatInterval es cada cuánto se envía un latido (comentario SSE o ping de WebSocket) para
+	// que los proxies no cierren la conexión y para detectar los clientes desconectados.
+	heartbeatInterval = 25 * time.Second
+	// writeTimeout limita cada escritura en el WebSocket.
+	writeTimeout = 10 * time.Second
+	// pongWait es el tiempo máximo sin recibir nada del cliente WebSocket (ni siquiera un pong).
+	pongWait = 2 * heartbeatInterval
+)
+
+// eventName es el tipo de los eventos de cambio de estado, en SSE y en WebSocket.
+const eventName = "container"
+
+// Message es un mensaje del WebSocket: un cambio de estado ("container") o el aviso de que la
+// suscripción se ha cerrado ("close").
+type Message struct {
+	Event string                  `json:"event"`
+	Data  *domain.ContainerUpdate `json:"data,omitempty"`
+	Error string                  `json:"error,omitempty"`
+}
+
+// Handler maneja las conexiones de los paneles en tiempo real.
+type Handler struct {
+	hub      *Hub
+	upgrader websocket.Upgrader
+}
+
+// NewHandler crea una nueva instancia del handler. 'allowedOrigins' son los orígenes (p. ej.
+// "https://panel.example.com") desde los que se aceptan conexiones WebSocket, además del propio;
+// "*" los acepta todos.
+func NewHandler(hub *Hub, allowedOrigins []string) *Handler {
+	h := &Handler{hub: hub}
+	h.upgrader.CheckOrigin = func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, allowed := range allowedOrigins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+	return h
+}
+
+// RegisterRoutes registra todas las rutas de este handler en el router de Gin.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/stream/containers", h.StreamContainers)
+}
+
+// @Summary      Recibe en tiempo real los cambios de estado de los contenedores
+// @Description  Abre un canal con un evento por cada lectura que cambia el nivel de llenado o el estado de un contenedor, con el nivel y el estado anteriores y nuevos, su ubicación y su fracción.
+// @Description  Por defecto responde con Server-Sent Events ('text/event-stream'): cada cambio es un evento 'container' con el cambio en JSON, y cada 25 s se envía un comentario de latido. Si la petición es un upgrade a WebSocket, cada cambio es un mensaje de texto {"event": "container", "data": {...}}.
+// @Description  Cada cliente tiene un buffer propio: si no consume los eventos a tiempo se cierra su canal (evento o mensaje 'close' con el motivo) para no frenar la ingesta; el cliente debe reconectar y volver a leer el estado con GET /containers.
+// @Tags         Stream
+// @Produce      text/event-stream
+// @Param        container_id      query  []string  false  "ID de contenedor (se puede repetir)"  collectionFormat(multi)
+// @Param        bbox              query  string    false  "Rectángulo minLon,minLat,maxLon,maxLat"  example(-3.72,40.40,-3.68,40.43)
+// @Param        fraction          query  string    false  "Fracción de residuo"  Enums(organic, paper, packaging, glass, residual, textile)
+// @Param        transitions_only  query  bool      false  "Solo los cambios de estado, sin los que solo cambian el nivel de llenado"
+// @Param        from_status       query  []string  false  "Solo los cambios de estado que salen de este estado (se puede repetir)"  collectionFormat(multi) Enums(low, medium, high)
+// @Param        to_status         query  []string  false  "Solo los cambios de estado que llegan a este estado (se puede repetir)"  collectionFormat(multi) Enums(low, medium, high)
+// @Success      200  {object}  domain.ContainerUpdate  "Flujo de eventos 'container'"
+// @Failure      400  {object}  map[string]string  "Filtro inválido"
+// @Failure      503  {object}  map[string]string  "El servidor se está deteniendo"
+// @Router       /stream/containers [get]
+func (h *Handler) StreamContainers(c *gin.Context) {
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sub, err := h.hub.Subscribe(filter)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	defer sub.Close()
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		h.serveWebSocket(c, sub)
+		return
+	}
+	serveSSE(c, sub)
+}
+
+// serveSSE envía los eventos como Server-Sent Events hasta que el cliente se desconecta o se
+// cierra la suscripción.
+func serveSSE(c *gin.Context, sub *Subscription) {
+	// El servidor limita el tiempo de escritura de cada respuesta; este flujo no termina.
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		fmt.Printf("Stream: no se pudo quitar el límite de escritura de la conexión SSE: %v\n", err)
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // Sin buffer en nginx.
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case update, ok := <-sub.Events():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					c.SSEvent("close", gin.H{"error": err.Error()})
+					c.Writer.Flush()
+				}
+				return
+			}
+			c.SSEvent(eventName, update)
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+		case <-c.Request.Context().Done():
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// serveWebSocket envía los eventos por WebSocket hasta que el cliente cierra la conexión o se
+// cierra la suscripción. Los mensajes del cliente se ignoran.
+func (h *Handler) serveWebSocket(c *gin.Context, sub *Subscription) {
+	// Si el upgrade falla, el upgrader ya ha respondido al cliente con el error.
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Leemos en segundo plano para procesar los pong y los cierres del cliente.
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case update, ok := <-sub.Events():
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if !ok {
+				closeWebSocket(conn, sub.Err())
+				return
+			}
+			if err := conn.WriteJSON(Message{Event: eventName, Data: &update}); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// closeWebSocket avisa al cliente de por qué se cierra la suscripción y cierra la conexión.
+func closeWebSocket(conn *websocket.Conn, err error) {
+	code := websocket.CloseNormalClosure
+	switch {
+	case errors.Is(err, ErrSlowSubscriber):
+		code = websocket.CloseTryAgainLater
+	case errors.Is(err, ErrHubClosed):
+		code = websocket.CloseGoingAway
+	}
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+		conn.WriteJSON(Message{Event: "close", Error: reason})
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+}
+
+// parseFilter lee el filtro de la suscripción de la consulta.
+func parseFilter(c *gin.Context) (Filter, error) {
+	var filter Filter
+	for _, value := range c.QueryArray("container_id") {
+		if filter.ContainerIDs == nil {
+			filter.ContainerIDs = make(map[string]bool)
+		}
+		filter.ContainerIDs[value] = true
+	}
+	if value := c.Query("bbox"); value != "" {
+		bbox, err := domain.ParseBoundingBox(value)
+		if err != nil {
+			return Filter{}, fmt.Errorf("'bbox' %w", err)
+		}
+		filter.BBox = &bbox
+	}
+	filter.Fraction = domain.Fraction(c.Query("fraction"))
+	if filter.Fraction != "" && !filter.Fraction.IsValid() {
+		return Filter{}, errors.New("fracción desconocida: " + string(filter.Fraction))
+	}
+	if value := c.Query("transitions_only"); value != "" {
+		only, err := strconv.ParseBool(value)
+		if err != nil {
+			return Filter{}, errors.New("'transitions_only' debe ser true o false")
+		}
+		filter.TransitionsOnly = only
+	}
+	for param, dst := range map[string]*[]domain.Status{"from_status": &filter.From, "to_status": &filter.To} {
+		for _, value := range c.QueryArray(param) {
+			status := domain.Status(value)
+			if !status.IsValid() {
+				return Filter{}, fmt.Errorf("'%s': estado desconocido: %s", param, value)
+			}
+			*dst = append(*dst, status)
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,173 @@
+// Package stream difunde en tiempo real los cambios de estado de los contenedores a los paneles
+// conectados por Server-Sent Events o WebSocket.
+package stream
+
+import (
+	"errors"
+	"log"
+	"smart-waste-management/internal/domain"
+	"sync"
+)
+
+var (
+	// ErrSlowSubscriber se devuelve al descartar a un suscriptor que no consume los eventos al
+	// ritmo al que llegan.
+	ErrSlowSubscriber = errors.New("suscripción cancelada: el cliente no consume los eventos a tiempo")
+	// ErrHubClosed se devuelve cuando el hub se ha cerrado (p. ej. al detener el servidor).
+	ErrHubClosed = errors.New("el canal de eventos se ha cerrado")
+)
+
+// DefaultBufferSize es el número de eventos que se guardan para cada suscriptor mientras los envía.
+const DefaultBufferSize = 64
+
+// Filter selecciona los eventos que recibe un suscriptor. Los campos vacíos no filtran.
+type Filter struct {
+	// ContainerIDs limita los eventos a los de esos contenedores.
+	ContainerIDs map[string]bool
+	// BBox limita los eventos a los de los contenedores que están dentro del rectángulo.
+	BBox *domain.BoundingBox
+	// Fraction limita los eventos a los de los contenedores de esa fracción.
+	Fraction domain.Fraction
+	// TransitionsOnly limita los eventos a los cambios de estado, sin los que solo cambian el nivel
+	// de llenado.
+	TransitionsOnly bool
+	// From y To limitan los cambios de estado a los que salen de alguno de los estados de From y
+	// llegan a alguno de los de To. Implican TransitionsOnly.
+	From []domain.Status
+	To   []domain.Status
+}
+
+// Matches indica si el suscriptor debe recibir el cambio.
+func (f Filter) Matches(u domain.ContainerUpdate) bool {
+	if len(f.ContainerIDs) > 0 && !f.ContainerIDs[u.ContainerID] {
+		return false
+	}
+	if f.BBox != nil && !f.BBox.Contains(u.Location) {
+		return false
+	}
+	if f.Fraction != "" && f.Fraction != u.Fraction {
+		return false
+	}
+	if f.TransitionsOnly || len(f.From) > 0 || len(f.To) > 0 {
+		if !u.StatusChanged() {
+			return false
+		}
+		if len(f.From) > 0 && !contains(f.From, u.PreviousStatus) {
+			return false
+		}
+		if len(f.To) > 0 && !contains(f.To, u.Status) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(statuses []domain.Status, s domain.Status) bool {
+	for _, status := range statuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription es la suscripción de un cliente a los cambios que cumplen su filtro.
+type Subscription struct {
+	hub    *Hub
+	filter Filter
+	events chan domain.ContainerUpdate
+	// err es el motivo por el que se cerró la suscripción; se escribe antes de cerrar 'events'.
+	err error
+}
+
+// Events devuelve el canal de eventos. Se cierra cuando la suscripción termina; Err indica entonces
+// el motivo.
+func (s *Subscription) Events() <-chan domain.ContainerUpdate {
+	return s.events
+}
+
+// Err devuelve por qué se cerró la suscripción: ErrSlowSubscriber, ErrHubClosed o nil si la cerró
+// el propio cliente. Solo es válido una vez cerrado el canal de Events.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// Close cancela la suscripción. Se puede llamar varias veces.
+func (s *Subscription) Close() {
+	s.hub.remove(s, nil)
+}
+
+// Hub reparte los cambios de estado de los contenedores entre los suscriptores. Cada suscriptor
+// tiene su propio buffer: si se llena, el suscriptor se descarta en lugar de bloquear la ingesta.
+type Hub struct {
+	mu          sync.Mutex
+	bufferSize  int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewHub crea un hub con un buffer de 'bufferSize' eventos por suscriptor.
+func NewHub(bufferSize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Hub{bufferSize: bufferSize, subscribers: make(map[*Subscription]struct{})}
+}
+
+// Subscribe da de alta un suscriptor con el filtro indicado. Devuelve ErrHubClosed si el hub ya
+// se ha cerrado.
+func (h *Hub) Subscribe(filter Filter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+	sub := &Subscription{hub: h, filter: filter, events: make(chan domain.ContainerUpdate, h.bufferSize)}
+	h.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// Publish entrega el cambio a los suscriptores cuyo filtro lo admite. Nunca bloquea: los
+// suscriptores con el buffer lleno se descartan.
+func (h *Hub) Publish(update domain.ContainerUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		if !sub.filter.Matches(update) {
+			continue
+		}
+		select {
+		case sub.events <- update:
+		default:
+			log.Printf("Stream: se descarta un suscriptor con %d eventos pendientes", len(sub.events))
+			h.removeLocked(sub, ErrSlowSubscriber)
+		}
+	}
+}
+
+// Close cierra todas las suscripciones y rechaza las nuevas. Debe llamarse al detener el servidor
+// para que terminen las conexiones abiertas.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		h.removeLocked(sub, ErrHubClosed)
+	}
+}
+
+func (h *Hub) remove(sub *Subscription, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(sub, err)
+}
+
+// removeLocked da de baja al suscriptor y cierra su canal, si no estaba ya dado de baja.
+func (h *Hub) removeLocked(sub *Subscription, err error) {
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+	delete(h.subscribers, sub)
+	sub.err = err
+	close(sub.events)
+}
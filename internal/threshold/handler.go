@@ -0,0 +1,189 @@
+package threshold
+
+import (
+	"errors"
+	"net/http"
+	"smart-waste-management/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler maneja las peticiones HTTP para los perfiles de umbrales.
+type Handler struct {
+	service Service
+}
+
+// UpsertProfileRequest define el cuerpo de la petición para crear o actualizar un perfil.
+type UpsertProfileRequest struct {
+	Name     string `json:"name" binding:"required"`
+	MediumAt int    `json:"medium_at" binding:"required,gt=0,lt=100"`
+	HighAt   int    `json:"high_at" binding:"required,gtfield=MediumAt,lte=100"`
+}
+
+// NewHandler crea una nueva instancia del handler.
+func NewHandler(s Service) *Handler {
+	return &Handler{
+		service: s,
+	}
+}
+
+// RegisterRoutes registra todas las rutas de este handler en el router de Gin.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/threshold-profiles", h.GetProfiles)
+	router.POST("/threshold-profiles", h.CreateProfile)
+	router.GET("/threshold-profiles/:id", h.GetProfileByID)
+	router.PUT("/threshold-profiles/:id", h.UpdateProfile)
+	router.DELETE("/threshold-profiles/:id", h.DeleteProfile)
+	router.POST("/threshold-profiles/:id/recompute", h.RecomputeStatuses)
+}
+
+// @Summary      Obtiene los perfiles de umbrales
+// @Description  Devuelve todos los perfiles de umbrales de estado definidos.
+// @Tags         Thresholds
+// @Produce      json
+// @Success      200  {object}  []domain.ThresholdProfile
+// @Failure      500  {object}  map[string]string "Error interno del servidor"
+// @Router       /threshold-profiles [get]
+func (h *Handler) GetProfiles(c *gin.Context) {
+	profiles, err := h.service.GetAllProfiles(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener la lista de perfiles"})
+		return
+	}
+	c.JSON(http.StatusOK, profiles)
+}
+
+// @Summary      Crea un perfil de umbrales
+// @Description  Define a partir de qué nivel de llenado un contenedor pasa a 'medium' y a 'high'.
+// @Tags         Thresholds
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        profile  body      UpsertProfileRequest     true  "Datos del perfil"
+// @Success      201      {object}  domain.ThresholdProfile  "Perfil creado"
+// @Failure      400      {object}  map[string]string        "Petición inválida o umbrales incoherentes"
+// @Failure      500      {object}  map[string]string        "Error interno del servidor"
+// @Router       /threshold-profiles [post]
+func (h *Handler) CreateProfile(c *gin.Context) {
+	var req UpsertProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile := domain.ThresholdProfile{
+		Name:       req.Name,
+		Thresholds: domain.Thresholds{MediumAt: req.MediumAt, HighAt: req.HighAt},
+	}
+
+	created, err := h.service.CreateProfile(c.Request.Context(), profile)
+	if err != nil {
+		if errors.Is(err, ErrInvalidThresholds) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo crear el perfil"})
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// @Summary      Obtiene un perfil de umbrales por su ID
+// @Tags         Thresholds
+// @Produce      json
+// @Param        id   path      string  true  "ID del perfil (UUID)"
+// @Success      200  {object}  domain.ThresholdProfile
+// @Failure      404  {object}  map[string]string  "Perfil no encontrado"
+// @Failure      500  {object}  map[string]string  "Error interno del servidor"
+// @Router       /threshold-profiles/{id} [get]
+func (h *Handler) GetProfileByID(c *gin.Context) {
+	profile, err := h.service.GetProfileByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ErrProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al buscar el perfil"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Summary      Actualiza un perfil de umbrales
+// @Description  Actualiza los umbrales del perfil. El estado de los contenedores que lo usan se recalcula en segundo plano.
+// @Tags         Thresholds
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id       path      string                true  "ID del perfil (UUID)"
+// @Param        profile  body      UpsertProfileRequest  true  "Nuevos datos del perfil"
+// @Success      202      {object}  map[string]string     "Perfil actualizado; recálculo de estados en curso"
+// @Failure      400      {object}  map[string]string     "Petición inválida o umbrales incoherentes"
+// @Failure      404      {object}  map[string]string     "Perfil no encontrado"
+// @Failure      500      {object}  map[string]string     "Error interno del servidor"
+// @Router       /threshold-profiles/{id} [put]
+func (h *Handler) UpdateProfile(c *gin.Context) {
+	var req UpsertProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile := domain.ThresholdProfile{
+		ID:         c.Param("id"),
+		Name:       req.Name,
+		Thresholds: domain.Thresholds{MediumAt: req.MediumAt, HighAt: req.HighAt},
+	}
+
+	if err := h.service.UpdateProfile(c.Request.Context(), profile); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidThresholds):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrProfileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar el perfil"})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Perfil actualizado; recalculando el estado de sus contenedores"})
+}
+
+// @Summary      Elimina un perfil de umbrales
+// @Description  Elimina el perfil. Sus contenedores vuelven a los umbrales por defecto y su estado se recalcula.
+// @Tags         Thresholds
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id   path      string  true  "ID del perfil (UUID)"
+// @Success      204  "Sin contenido"
+// @Failure      500  {object}  map[string]string "Error interno del servidor"
+// @Router       /threshold-profiles/{id} [delete]
+func (h *Handler) DeleteProfile(c *gin.Context) {
+	if err := h.service.DeleteProfile(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar el perfil"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary      Recalcula el estado de los contenedores de un perfil
+// @Description  Re-deriva de forma síncrona el estado de todos los contenedores que usan el perfil a partir de su último nivel de llenado.
+// @Tags         Thresholds
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id   path      string  true  "ID del perfil (UUID)"
+// @Success      200  {object}  map[string]int64   "Número de contenedores cuyo estado cambió"
+// @Failure      404  {object}  map[string]string  "Perfil no encontrado"
+// @Failure      500  {object}  map[string]string  "Error interno del servidor"
+// @Router       /threshold-profiles/{id}/recompute [post]
+func (h *Handler) RecomputeStatuses(c *gin.Context) {
+	changed, err := h.service.RecomputeStatuses(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ErrProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo recalcular el estado de los contenedores"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": changed})
+}
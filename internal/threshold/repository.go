@@ -0,0 +1,178 @@
+package threshold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/platform/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrProfileNotFound se devuelve cuando no existe un perfil con el ID indicado.
+var ErrProfileNotFound = errors.New("perfil de umbrales no encontrado")
+
+// statusCaseSQL replica en SQL la regla de domain.Thresholds.StatusFor, con los umbrales
+// medium y high como parámetros ($1 y $2), para recalcular muchos contenedores en una sola sentencia.
+const statusCaseSQL = `(CASE WHEN last_fill_level >= $2 THEN 'high'
+                                    WHEN last_fill_level >= $1 THEN 'medium'
+                                    ELSE 'low' END)::container_status`
+
+// Repository define las operaciones de persistencia de los perfiles de umbrales.
+type Repository interface {
+	CreateProfile(ctx context.Context, profile domain.ThresholdProfile) (domain.ThresholdProfile, error)
+	FindAllProfiles(ctx context.Context) ([]domain.ThresholdProfile, error)
+	FindProfileByID(ctx context.Context, id string) (domain.ThresholdProfile, error)
+	UpdateProfile(ctx context.Context, profile domain.ThresholdProfile) error
+	// DeleteProfile elimina el perfil y devuelve a sus contenedores a los umbrales por defecto.
+	DeleteProfile(ctx context.Context, id string) error
+	// RecomputeStatuses recalcula el estado actual de los contenedores que usan el perfil con los
+	// umbrales que tiene guardados y devuelve cuántos cambiaron de estado.
+	RecomputeStatuses(ctx context.Context, id string) (int64, error)
+}
+
+// postgresRepository es la implementación concreta de Repository para PostgreSQL.
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresRepository crea una nueva instancia del repositorio.
+func NewPostgresRepository(db *database.DB) Repository {
+	return &postgresRepository{
+		db: db.Pool,
+	}
+}
+
+func (r *postgresRepository) CreateProfile(ctx context.Context, profile domain.ThresholdProfile) (domain.ThresholdProfile, error) {
+	query := `
+        INSERT INTO threshold_profiles (name, medium_at, high_at)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(ctx, query, profile.Name, profile.MediumAt, profile.HighAt).Scan(
+		&profile.ID, &profile.CreatedAt, &profile.UpdatedAt,
+	)
+	if err != nil {
+		return domain.ThresholdProfile{}, fmt.Errorf("error al crear el perfil de umbrales: %w", err)
+	}
+	return profile, nil
+}
+
+func (r *postgresRepository) FindAllProfiles(ctx context.Context) ([]domain.ThresholdProfile, error) {
+	query := `
+        SELECT id, name, medium_at, high_at, created_at, updated_at
+        FROM threshold_profiles
+        ORDER BY name`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar los perfiles de umbrales: %w", err)
+	}
+	defer rows.Close()
+
+	var profiles []domain.ThresholdProfile
+	for rows.Next() {
+		var p domain.ThresholdProfile
+		if err := rows.Scan(&p.ID, &p.Name, &p.MediumAt, &p.HighAt, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error al escanear el perfil de umbrales: %w", err)
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+func (r *postgresRepository) FindProfileByID(ctx context.Context, id string) (domain.ThresholdProfile, error) {
+	query := `
+        SELECT id, name, medium_at, high_at, created_at, updated_at
+        FROM threshold_profiles
+        WHERE id = $1`
+
+	var p domain.ThresholdProfile
+	err := r.db.QueryRow(ctx, query, id).Scan(&p.ID, &p.Name, &p.MediumAt, &p.HighAt, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ThresholdProfile{}, ErrProfileNotFound
+		}
+		return domain.ThresholdProfile{}, fmt.Errorf("error al buscar el perfil de umbrales: %w", err)
+	}
+	return p, nil
+}
+
+func (r *postgresRepository) UpdateProfile(ctx context.Context, profile domain.ThresholdProfile) error {
+	query := `
+        UPDATE threshold_profiles
+        SET name = $1, medium_at = $2, high_at = $3, updated_at = NOW()
+        WHERE id = $4`
+
+	tag, err := r.db.Exec(ctx, query, profile.Name, profile.MediumAt, profile.HighAt, profile.ID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el perfil de umbrales: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrProfileNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepository) DeleteProfile(ctx context.Context, id string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("no se pudo iniciar la transacción: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Desasociamos los contenedores y recalculamos su estado con los umbrales por defecto
+	// en la misma transacción, para que nunca queden con un estado derivado de un perfil inexistente.
+	resetSQL := `
+        UPDATE containers
+        SET threshold_profile_id = NULL, current_status = ` + statusCaseSQL + `, updated_at = NOW()
+        WHERE threshold_profile_id = $3`
+	if _, err := tx.Exec(ctx, resetSQL, domain.DefaultThresholds.MediumAt, domain.DefaultThresholds.HighAt, id); err != nil {
+		return fmt.Errorf("error al desasociar los contenedores del perfil: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM threshold_profiles WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("error al eliminar el perfil de umbrales: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *postgresRepository) RecomputeStatuses(ctx context.Context, id string) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("no se pudo iniciar la transacción: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Leemos los umbrales bloqueando el perfil: los recálculos del mismo perfil y las modificaciones
+	// se ejecutan uno tras otro, así que el último siempre usa los umbrales vigentes aunque
+	// los recálculos terminen en otro orden.
+	var thresholds domain.Thresholds
+	err = tx.QueryRow(ctx, `SELECT medium_at, high_at FROM threshold_profiles WHERE id = $1 FOR UPDATE`, id).
+		Scan(&thresholds.MediumAt, &thresholds.HighAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrProfileNotFound
+		}
+		return 0, fmt.Errorf("error al buscar el perfil de umbrales: %w", err)
+	}
+
+	// Solo se tocan las filas cuyo estado cambia, para no generar escrituras innecesarias.
+	query := `
+        UPDATE containers
+        SET current_status = ` + statusCaseSQL + `, updated_at = NOW()
+        WHERE threshold_profile_id = $3
+          AND current_status IS DISTINCT FROM ` + statusCaseSQL
+
+	tag, err := tx.Exec(ctx, query, thresholds.MediumAt, thresholds.HighAt, id)
+	if err != nil {
+		return 0, fmt.Errorf("error al recalcular el estado de los contenedores: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("error al confirmar la transacción: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
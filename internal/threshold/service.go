@@ -0,0 +1,142 @@
+package threshold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"smart-waste-management/internal/domain"
+	"sync"
+	"time"
+)
+
+// ErrInvalidThresholds se devuelve cuando los umbrales no cumplen 0 < medium < high <= 100.
+var ErrInvalidThresholds = errors.New("los umbrales deben cumplir 0 < medium_at < high_at <= 100")
+
+// recomputeTimeout limita la duración del recálculo en segundo plano tras modificar un perfil.
+const recomputeTimeout = 5 * time.Minute
+
+// Service define la lógica de negocio de los perfiles de umbrales.
+type Service interface {
+	CreateProfile(ctx context.Context, profile domain.ThresholdProfile) (domain.ThresholdProfile, error)
+	GetAllProfiles(ctx context.Context) ([]domain.ThresholdProfile, error)
+	GetProfileByID(ctx context.Context, id string) (domain.ThresholdProfile, error)
+	// UpdateProfile guarda los nuevos umbrales y lanza en segundo plano el recálculo
+	// del estado de los contenedores que usan el perfil.
+	UpdateProfile(ctx context.Context, profile domain.ThresholdProfile) error
+	DeleteProfile(ctx context.Context, id string) error
+	// RecomputeStatuses recalcula de forma síncrona el estado de los contenedores del perfil.
+	RecomputeStatuses(ctx context.Context, id string) (int64, error)
+	// Shutdown deja de lanzar recálculos en segundo plano y espera a que terminen los que están en
+	// curso. Si 'ctx' vence antes, los cancela.
+	Shutdown(ctx context.Context) error
+}
+
+type service struct {
+	repo Repository
+
+	// Recálculos en segundo plano: 'jobs' cuenta los que están en curso y 'stop' cancela su contexto.
+	mu     sync.Mutex
+	closed bool
+	jobs   sync.WaitGroup
+	ctx    context.Context
+	stop   context.CancelFunc
+}
+
+// NewService crea una nueva instancia del servicio.
+func NewService(repo Repository) Service {
+	ctx, stop := context.WithCancel(context.Background())
+	return &service{
+		repo: repo,
+		ctx:  ctx,
+		stop: stop,
+	}
+}
+
+func (s *service) CreateProfile(ctx context.Context, profile domain.ThresholdProfile) (domain.ThresholdProfile, error) {
+	if !profile.Thresholds.IsValid() {
+		return domain.ThresholdProfile{}, ErrInvalidThresholds
+	}
+	return s.repo.CreateProfile(ctx, profile)
+}
+
+func (s *service) GetAllProfiles(ctx context.Context) ([]domain.ThresholdProfile, error) {
+	return s.repo.FindAllProfiles(ctx)
+}
+
+func (s *service) GetProfileByID(ctx context.Context, id string) (domain.ThresholdProfile, error) {
+	return s.repo.FindProfileByID(ctx, id)
+}
+
+func (s *service) UpdateProfile(ctx context.Context, profile domain.ThresholdProfile) error {
+	if !profile.Thresholds.IsValid() {
+		return ErrInvalidThresholds
+	}
+	if err := s.repo.UpdateProfile(ctx, profile); err != nil {
+		return err
+	}
+
+	// El recálculo puede afectar a miles de contenedores, así que no hacemos esperar al cliente.
+	// El job vuelve a leer el perfil, por si otra modificación se ha guardado entretanto.
+	s.startRecompute(profile.ID)
+	return nil
+}
+
+func (s *service) DeleteProfile(ctx context.Context, id string) error {
+	return s.repo.DeleteProfile(ctx, id)
+}
+
+func (s *service) RecomputeStatuses(ctx context.Context, id string) (int64, error) {
+	return s.repo.RecomputeStatuses(ctx, id)
+}
+
+// Shutdown debe llamarse después de detener el servidor HTTP y antes de cerrar la base de datos.
+func (s *service) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.jobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.stop()
+		return nil
+	case <-ctx.Done():
+		// Al cancelar, la transacción del recálculo se deshace y los estados quedan como estaban.
+		s.stop()
+		<-done
+		return fmt.Errorf("el recálculo de estados no terminó a tiempo y se ha cancelado: %w", ctx.Err())
+	}
+}
+
+// startRecompute lanza el recálculo en segundo plano, salvo si el servicio se está deteniendo.
+func (s *service) startRecompute(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		fmt.Printf("Recálculo de estados del perfil %s omitido: el servicio se está deteniendo\n", id)
+		return
+	}
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		s.recomputeInBackground(id)
+	}()
+}
+
+// recomputeInBackground es el job que re-deriva los estados tras un cambio de umbrales.
+func (s *service) recomputeInBackground(id string) {
+	ctx, cancel := context.WithTimeout(s.ctx, recomputeTimeout)
+	defer cancel()
+
+	changed, err := s.repo.RecomputeStatuses(ctx, id)
+	if err != nil {
+		fmt.Printf("Error en el recálculo de estados del perfil %s: %v\n", id, err)
+		return
+	}
+	fmt.Printf("Recálculo de estados del perfil %s completado: %d contenedores cambiaron de estado\n", id, changed)
+}
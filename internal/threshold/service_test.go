@@ -0,0 +1,95 @@
+package threshold
+
+import (
+	"context"
+	"errors"
+	"smart-waste-management/internal/domain"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingRepository bloquea el recálculo hasta que se cierra 'release' o se cancela su contexto.
+type blockingRepository struct {
+	Repository
+	started   chan struct{}
+	release   chan struct{}
+	cancelled atomic.Bool
+}
+
+func (r *blockingRepository) UpdateProfile(context.Context, domain.ThresholdProfile) error {
+	return nil
+}
+
+func (r *blockingRepository) RecomputeStatuses(ctx context.Context, _ string) (int64, error) {
+	close(r.started)
+	select {
+	case <-r.release:
+		return 1, nil
+	case <-ctx.Done():
+		r.cancelled.Store(true)
+		return 0, ctx.Err()
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	tests := []struct {
+		name          string
+		finishes      bool
+		wantErr       bool
+		wantCancelled bool
+	}{
+		{name: "espera a que termine el recálculo", finishes: true},
+		{name: "cancela el recálculo si no termina a tiempo", wantErr: true, wantCancelled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &blockingRepository{started: make(chan struct{}), release: make(chan struct{})}
+			s := NewService(repo)
+			profile := domain.ThresholdProfile{ID: "perfil", Thresholds: domain.Thresholds{MediumAt: 50, HighAt: 80}}
+			if err := s.UpdateProfile(context.Background(), profile); err != nil {
+				t.Fatalf("UpdateProfile() error inesperado: %v", err)
+			}
+			<-repo.started
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if tt.finishes {
+				go func() {
+					time.Sleep(10 * time.Millisecond)
+					close(repo.release)
+				}()
+			}
+
+			err := s.Shutdown(ctx)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("Shutdown() error = %v, se esperaba error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Shutdown() error = %v, se esperaba context.DeadlineExceeded", err)
+			}
+			if repo.cancelled.Load() != tt.wantCancelled {
+				t.Errorf("recálculo cancelado = %v, se esperaba %v", repo.cancelled.Load(), tt.wantCancelled)
+			}
+		})
+	}
+}
+
+func TestUpdateProfileAfterShutdownSkipsRecompute(t *testing.T) {
+	repo := &blockingRepository{started: make(chan struct{}), release: make(chan struct{})}
+	s := NewService(repo)
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error inesperado: %v", err)
+	}
+
+	profile := domain.ThresholdProfile{ID: "perfil", Thresholds: domain.Thresholds{MediumAt: 50, HighAt: 80}}
+	if err := s.UpdateProfile(context.Background(), profile); err != nil {
+		t.Fatalf("UpdateProfile() error inesperado: %v", err)
+	}
+	select {
+	case <-repo.started:
+		t.Error("se ha lanzado un recálculo con el servicio detenido")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
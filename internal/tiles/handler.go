@@ -0,0 +1,110 @@
+package tiles
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"smart-waste-management/internal/domain"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType es el tipo MIME de las teselas vectoriales de Mapbox.
+const ContentType = "application/vnd.mapbox-vector-tile"
+
+// DefaultMaxAge es el tiempo durante el que los clientes y las cachés pueden reutilizar una tesela
+// sin revalidarla. Es corto porque el estado de los contenedores cambia con cada lectura.
+const DefaultMaxAge = time.Minute
+
+// Handler maneja las peticiones HTTP de las teselas.
+type Handler struct {
+	service Service
+	maxAge  time.Duration
+}
+
+// NewHandler crea una nueva instancia del handler. 'maxAge' es el max-age de Cache-Control.
+func NewHandler(s Service, maxAge time.Duration) *Handler {
+	return &Handler{
+		service: s,
+		maxAge:  maxAge,
+	}
+}
+
+// RegisterRoutes registra todas las rutas de este handler en el router de Gin.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/tiles/containers/:z/:x/:y", h.GetContainerTile)
+}
+
+// @Summary      Obtiene una tesela vectorial de contenedores
+// @Description  Devuelve la tesela {z}/{x}/{y} en formato Mapbox Vector Tile, con una capa 'containers'. Hasta el zoom 14 los contenedores se agrupan en una rejilla de 8×8 celdas por tesela: cada punto es un grupo con 'point_count', 'high_count', 'medium_count', 'low_count', 'avg_fill_level', el peor 'status' y el mayor 'fill_level' ('cluster' = true; los grupos de un contenedor llevan su 'id').
+// @Description  A partir del zoom 15 cada contenedor es un punto con 'id', 'status', 'fill_level', 'fraction', 'capacity_liters' y 'last_updated'. La respuesta lleva ETag y Cache-Control para poder poner una caché de teselas delante; con 'If-None-Match' responde 304 si la tesela no ha cambiado.
+// @Tags         Tiles
+// @Produce      application/vnd.mapbox-vector-tile
+// @Param        z         path      int     true   "Nivel de zoom (0-22)"
+// @Param        x         path      int     true   "Columna de la tesela"
+// @Param        y         path      string  true   "Fila de la tesela, con la extensión .mvt (p. ej. 1547.mvt)"
+// @Param        fraction  query     string  false  "Fracción de residuo"  Enums(organic, paper, packaging, glass, residual, textile)
+// @Param        If-None-Match  header  string  false  "ETag de la tesela que ya tiene el cliente"
+// @Success      200  {file}    file  "Tesela MVT (vacía si no hay contenedores)"
+// @Success      304  {string}  string  "La tesela no ha cambiado"
+// @Failure      400  {object}  map[string]string  "Coordenadas o fracción inválidas"
+// @Failure      500  {object}  map[string]string  "Error interno del servidor"
+// @Router       /tiles/containers/{z}/{x}/{y}.mvt [get]
+func (h *Handler) GetContainerTile(c *gin.Context) {
+	// 1. Validar las coordenadas y el filtro. Gin no admite la extensión en el patrón de la ruta,
+	// así que llega en el último parámetro.
+	y, ok := strings.CutSuffix(c.Param("y"), ".mvt")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La tesela debe pedirse con la extensión .mvt"})
+		return
+	}
+	var coord Coord
+	var err error
+	for dst, value := range map[*int]string{&coord.Z: c.Param("z"), &coord.X: c.Param("x"), &coord.Y: y} {
+		if *dst, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Coordenada de tesela inválida: '%s'", value)})
+			return
+		}
+	}
+	fraction := domain.Fraction(c.Query("fraction"))
+	if fraction != "" && !fraction.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fracción desconocida: " + string(fraction)})
+		return
+	}
+
+	// 2. Generar la tesela.
+	tile, err := h.service.ContainerTile(c.Request.Context(), coord, fraction)
+	if err != nil {
+		if errors.Is(err, ErrInvalidTile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		fmt.Printf("Error al generar la tesela: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo generar la tesela"})
+		return
+	}
+
+	// 3. Responder con las cabeceras de caché; 304 si el cliente ya tiene esta versión.
+	c.Header("ETag", tile.ETag)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.maxAge.Seconds())))
+	if matchesETag(c.GetHeader("If-None-Match"), tile.ETag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, ContentType, tile.Data)
+}
+
+// matchesETag comprueba si la cabecera If-None-Match incluye el ETag (o es '*'). La comparación
+// es débil, como pide RFC 9110 para If-None-Match.
+func matchesETag(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package tiles
+
+import (
+	"context"
+	"fmt"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/platform/database"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Extent es la resolución de las teselas (unidades por lado) y Buffer, el margen en esas mismas
+// unidades que se incluye alrededor para que los símbolos de los bordes no se corten.
+const (
+	Extent = 4096
+	Buffer = 64
+)
+
+// LayerName es el nombre de la capa de contenedores dentro de la tesela.
+const LayerName = "containers"
+
+// Repository genera las teselas con PostGIS.
+type Repository interface {
+	// ContainerTile devuelve la tesela con un punto por contenedor y su estado y llenado como atributos.
+	ContainerTile(ctx context.Context, tile Coord, fraction domain.Fraction) ([]byte, error)
+	// ClusteredContainerTile devuelve la tesela con los contenedores agrupados en una rejilla de
+	// cellsPerSide × cellsPerSide celdas: un punto por celda, en el centroide de sus contenedores,
+	// con el número de contenedores de cada estado y el llenado máximo y medio.
+	ClusteredContainerTile(ctx context.Context, tile Coord, fraction domain.Fraction, cellsPerSide int) ([]byte, error)
+}
+
+// postgresRepository es la implementación concreta de Repository para PostgreSQL.
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresRepository crea una nueva instancia del repositorio.
+func NewPostgresRepository(db *database.DB) Repository {
+	return &postgresRepository{
+		db: db.Pool,
+	}
+}
+
+// fractionArg convierte la fracción en un parámetro de la consulta: NULL si no se filtra.
+func fractionArg(fraction domain.Fraction) *string {
+	if fraction == "" {
+		return nil
+	}
+	s := string(fraction)
+	return &s
+}
+
+func (r *postgresRepository) ContainerTile(ctx context.Context, tile Coord, fraction domain.Fraction) ([]byte, error) {
+	// Se incluyen también los contenedores del margen, que ST_AsMVTGeom recorta al buffer.
+	const query = `
+        WITH bounds AS (
+            SELECT ST_TileEnvelope($1, $2, $3) AS tile,
+                   ST_TileEnvelope($1, $2, $3, margin => $7) AS area
+        ),
+        features AS (
+            SELECT c.id::text AS id, c.current_status::text AS status, c.last_fill_level AS fill_level,
+                   c.fraction::text AS fraction, c.capacity_liters, c.last_updated_at AS last_updated,
+                   false AS cluster, 1 AS point_count,
+                   ST_AsMVTGeom(ST_Transform(c.location::geometry, 3857), bounds.tile, $5, $4, true) AS geom
+            FROM containers c, bounds
+            WHERE ST_Transform(c.location::geometry, 3857) && bounds.area
+              AND ($6::text IS NULL OR c.fraction::text = $6)
+        )
+        SELECT COALESCE(ST_AsMVT(features, '` + LayerName + `', $5, 'geom'), ''::bytea) FROM features`
+
+	var mvt []byte
+	margin := float64(Buffer) / Extent
+	err := r.db.QueryRow(ctx, query, tile.Z, tile.X, tile.Y, Buffer, Extent, fractionArg(fraction), margin).Scan(&mvt)
+	if err != nil {
+		return nil, fmt.Errorf("error al generar la tesela %s: %w", tile, err)
+	}
+	return mvt, nil
+}
+
+func (r *postgresRepository) ClusteredContainerTile(ctx context.Context, tile Coord, fraction domain.Fraction, cellsPerSide int) ([]byte, error) {
+	// Las celdas de la rejilla están alineadas con las teselas (su tamaño divide al de la tesela y
+	// empiezan en el origen de coordenadas), así que cada grupo cae entero en una sola tesela y no
+	// se filtra por el margen.
+	// Los grupos de un solo contenedor conservan su ID.
+	const query = `
+        WITH bounds AS (
+            SELECT ST_TileEnvelope($1, $2, $3) AS tile,
+                   (ST_XMax(ST_TileEnvelope($1, $2, $3)) - ST_XMin(ST_TileEnvelope($1, $2, $3))) / $6 AS cell
+        ),
+        points AS (
+            SELECT c.id::text AS id, c.current_status::text AS status, c.last_fill_level,
+                   ST_Transform(c.location::geometry, 3857) AS geom
+            FROM containers c, bounds
+            WHERE ST_Transform(c.location::geometry, 3857) && bounds.tile
+              AND ($5::text IS NULL OR c.fraction::text = $5)
+        ),
+        features AS (
+            SELECT CASE WHEN count(*) = 1 THEN min(p.id) END AS id,
+                   CASE WHEN bool_or(p.status = 'high') THEN 'high'
+                        WHEN bool_or(p.status = 'medium') THEN 'medium'
+                        ELSE 'low' END AS status,
+                   max(p.last_fill_level) AS fill_level,
+                   round(avg(p.last_fill_level))::int AS avg_fill_level,
+                   true AS cluster,
+                   count(*)::int AS point_count,
+                   count(*) FILTER (WHERE p.status = 'high')::int AS high_count,
+                   count(*) FILTER (WHERE p.status = 'medium')::int AS medium_count,
+                   count(*) FILTER (WHERE p.status = 'low')::int AS low_count,
+                   ST_AsMVTGeom(ST_Centroid(ST_Collect(p.geom)), bounds.tile, $4, 0, true) AS geom
+            FROM points p, bounds
+            GROUP BY floor(ST_X(p.geom) / bounds.cell), floor(ST_Y(p.geom) / bounds.cell), bounds.tile
+        )
+        SELECT COALESCE(ST_AsMVT(features, '` + LayerName + `', $4, 'geom'), ''::bytea) FROM features`
+
+	var mvt []byte
+	err := r.db.QueryRow(ctx, query, tile.Z, tile.X, tile.Y, Extent, fractionArg(fraction), float64(cellsPerSide)).Scan(&mvt)
+	if err != nil {
+		return nil, fmt.Errorf("error al generar la tesela agrupada %s: %w", tile, err)
+	}
+	return mvt, nil
+}
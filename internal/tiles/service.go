@@ -0,0 +1,89 @@
+package tiles
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"smart-waste-management/internal/domain"
+)
+
+// ErrInvalidTile se devuelve cuando las coordenadas de la tesela no existen en el nivel de zoom.
+var ErrInvalidTile = errors.New("tesela fuera de rango")
+
+const (
+	// MaxZoom es el nivel de zoom máximo que se sirve.
+	MaxZoom = 22
+	// ClusterMaxZoom es el último nivel de zoom en el que los contenedores se agrupan; a partir del
+	// siguiente, cada contenedor es un punto.
+	ClusterMaxZoom = 14
+	// clusterCellsPerSide es el número de celdas por lado de la rejilla de agrupación (celdas de
+	// 32 px en una tesela de 256 px).
+	clusterCellsPerSide = 8
+)
+
+// Coord son las coordenadas de una tesela en el esquema XYZ (el de OpenStreetMap y Mapbox).
+type Coord struct {
+	Z, X, Y int
+}
+
+func (c Coord) String() string {
+	return fmt.Sprintf("%d/%d/%d", c.Z, c.X, c.Y)
+}
+
+// IsValid comprueba que el zoom está entre 0 y MaxZoom y que x e y están entre 0 y 2^z - 1.
+func (c Coord) IsValid() bool {
+	if c.Z < 0 || c.Z > MaxZoom {
+		return false
+	}
+	n := 1 << c.Z
+	return c.X >= 0 && c.X < n && c.Y >= 0 && c.Y < n
+}
+
+// Tile es una tesela vectorial (Mapbox Vector Tile) ya codificada.
+type Tile struct {
+	Data []byte
+	// ETag identifica el contenido de la tesela: cambia solo si cambia algún contenedor de la tesela.
+	ETag string
+	// Clustered indica si los contenedores están agrupados.
+	Clustered bool
+}
+
+// Service define la lógica de negocio de las teselas.
+type Service interface {
+	// ContainerTile genera la tesela de contenedores, agrupados hasta ClusterMaxZoom.
+	ContainerTile(ctx context.Context, coord Coord, fraction domain.Fraction) (Tile, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+// NewService crea una nueva instancia del servicio.
+func NewService(repo Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+func (s *service) ContainerTile(ctx context.Context, coord Coord, fraction domain.Fraction) (Tile, error) {
+	if !coord.IsValid() {
+		return Tile{}, fmt.Errorf("%w: %s", ErrInvalidTile, coord)
+	}
+
+	var data []byte
+	var err error
+	clustered := coord.Z <= ClusterMaxZoom
+	if clustered {
+		data, err = s.repo.ClusteredContainerTile(ctx, coord, fraction, clusterCellsPerSide)
+	} else {
+		data, err = s.repo.ContainerTile(ctx, coord, fraction)
+	}
+	if err != nil {
+		return Tile{}, err
+	}
+
+	sum := sha256.Sum256(data)
+	return Tile{Data: data, ETag: `"` + hex.EncodeToString(sum[:16]) + `"`, Clustered: clustered}, nil
+}
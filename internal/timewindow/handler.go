@@ -0,0 +1,164 @@
+package timewindow
+
+import (
+	"errors"
+	"net/http"
+	"smart-waste-management/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler maneja las peticiones HTTP para los perfiles de franjas horarias.
+type Handler struct {
+	service Service
+}
+
+// UpsertProfileRequest define el cuerpo de la petición para crear o actualizar un perfil de franjas.
+type UpsertProfileRequest struct {
+	Name string `json:"name" binding:"required"`
+	// Windows son las franjas diarias (hora local, "HH:MM") en las que se permite recoger.
+	Windows domain.TimeWindows `json:"windows" binding:"required,min=1"`
+}
+
+// NewHandler crea una nueva instancia del handler.
+func NewHandler(s Service) *Handler {
+	return &Handler{
+		service: s,
+	}
+}
+
+// RegisterRoutes registra todas las rutas de este handler en el router de Gin.
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/time-window-profiles", h.GetProfiles)
+	router.POST("/time-window-profiles", h.CreateProfile)
+	router.GET("/time-window-profiles/:id", h.GetProfileByID)
+	router.PUT("/time-window-profiles/:id", h.UpdateProfile)
+	router.DELETE("/time-window-profiles/:id", h.DeleteProfile)
+}
+
+// @Summary      Obtiene los perfiles de franjas horarias
+// @Description  Devuelve las franjas de recogida definidas por zona.
+// @Tags         TimeWindows
+// @Produce      json
+// @Success      200  {object}  []domain.TimeWindowProfile
+// @Failure      500  {object}  map[string]string "Error interno del servidor"
+// @Router       /time-window-profiles [get]
+func (h *Handler) GetProfiles(c *gin.Context) {
+	profiles, err := h.service.GetAllProfiles(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener la lista de perfiles de franjas horarias"})
+		return
+	}
+	c.JSON(http.StatusOK, profiles)
+}
+
+// @Summary      Crea un perfil de franjas horarias
+// @Description  Define en qué franjas diarias (hora local) se pueden recoger los contenedores de una zona. Se asigna a los contenedores con 'time_window_profile_id'.
+// @Tags         TimeWindows
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        profile  body      UpsertProfileRequest      true  "Nombre y franjas del perfil"
+// @Success      201      {object}  domain.TimeWindowProfile  "Perfil creado"
+// @Failure      400      {object}  map[string]string         "Petición inválida o franjas incorrectas"
+// @Failure      422      {object}  map[string]string         "Idempotency-Key reutilizada con una petición distinta"
+// @Failure      500      {object}  map[string]string         "Error interno del servidor"
+// @Router       /time-window-profiles [post]
+func (h *Handler) CreateProfile(c *gin.Context) {
+	var req UpsertProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := h.service.CreateProfile(c.Request.Context(), domain.TimeWindowProfile{
+		Name:    req.Name,
+		Windows: req.Windows,
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidWindows) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo crear el perfil de franjas horarias"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// @Summary      Obtiene un perfil de franjas horarias por su ID
+// @Tags         TimeWindows
+// @Produce      json
+// @Param        id   path      string  true  "ID del perfil (UUID)"
+// @Success      200  {object}  domain.TimeWindowProfile
+// @Failure      404  {object}  map[string]string  "Perfil no encontrado"
+// @Failure      500  {object}  map[string]string  "Error interno del servidor"
+// @Router       /time-window-profiles/{id} [get]
+func (h *Handler) GetProfileByID(c *gin.Context) {
+	profile, err := h.service.GetProfileByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ErrProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al buscar el perfil de franjas horarias"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// @Summary      Actualiza un perfil de franjas horarias
+// @Description  Los cambios se aplican a las rutas que se generen a partir de ese momento.
+// @Tags         TimeWindows
+// @Accept       json
+// @Produce      json
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id       path      string                true  "ID del perfil (UUID)"
+// @Param        profile  body      UpsertProfileRequest  true  "Nuevos datos del perfil"
+// @Success      200      {object}  map[string]string     "Perfil actualizado"
+// @Failure      400      {object}  map[string]string     "Petición inválida o franjas incorrectas"
+// @Failure      404      {object}  map[string]string     "Perfil no encontrado"
+// @Failure      422      {object}  map[string]string     "Idempotency-Key reutilizada con una petición distinta"
+// @Failure      500      {object}  map[string]string     "Error interno del servidor"
+// @Router       /time-window-profiles/{id} [put]
+func (h *Handler) UpdateProfile(c *gin.Context) {
+	var req UpsertProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.service.UpdateProfile(c.Request.Context(), domain.TimeWindowProfile{
+		ID:      c.Param("id"),
+		Name:    req.Name,
+		Windows: req.Windows,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidWindows):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrProfileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo actualizar el perfil de franjas horarias"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Perfil de franjas horarias actualizado exitosamente"})
+}
+
+// @Summary      Elimina un perfil de franjas horarias
+// @Description  Los contenedores que lo usaban pasan a poder recogerse a cualquier hora.
+// @Tags         TimeWindows
+// @Param        Idempotency-Key  header  string  false  "Clave para reintentar la petición de forma segura"
+// @Param        id   path      string  true  "ID del perfil (UUID)"
+// @Success      204  "Sin contenido"
+// @Failure      500  {object}  map[string]string "Error interno del servidor"
+// @Router       /time-window-profiles/{id} [delete]
+func (h *Handler) DeleteProfile(c *gin.Context) {
+	if err := h.service.DeleteProfile(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo eliminar el perfil de franjas horarias"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,132 @@
+package timewindow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"smart-waste-management/internal/domain"
+	"smart-waste-management/internal/platform/database"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrProfileNotFound se devuelve cuando no existe un perfil de franjas con el ID indicado.
+var ErrProfileNotFound = errors.New("perfil de franjas horarias no encontrado")
+
+// Repository define las operaciones de persistencia de los perfiles de franjas horarias.
+type Repository interface {
+	CreateProfile(ctx context.Context, profile domain.TimeWindowProfile) (domain.TimeWindowProfile, error)
+	FindAllProfiles(ctx context.Context) ([]domain.TimeWindowProfile, error)
+	FindProfileByID(ctx context.Context, id string) (domain.TimeWindowProfile, error)
+	UpdateProfile(ctx context.Context, profile domain.TimeWindowProfile) error
+	// DeleteProfile elimina el perfil; sus contenedores pasan a poder recogerse a cualquier hora.
+	DeleteProfile(ctx context.Context, id string) error
+}
+
+// postgresRepository es la implementación concreta de Repository para PostgreSQL.
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresRepository crea una nueva instancia del repositorio.
+func NewPostgresRepository(db *database.DB) Repository {
+	return &postgresRepository{
+		db: db.Pool,
+	}
+}
+
+const profileColumns = `id, name, windows, created_at, updated_at`
+
+func scanProfile(row pgx.Row) (domain.TimeWindowProfile, error) {
+	var p domain.TimeWindowProfile
+	err := row.Scan(&p.ID, &p.Name, &p.Windows, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+// windowsJSON serializa las franjas para la columna JSONB.
+func windowsJSON(windows domain.TimeWindows) (string, error) {
+	if windows == nil {
+		windows = domain.TimeWindows{}
+	}
+	data, err := json.Marshal(windows)
+	return string(data), err
+}
+
+func (r *postgresRepository) CreateProfile(ctx context.Context, profile domain.TimeWindowProfile) (domain.TimeWindowProfile, error) {
+	windows, err := windowsJSON(profile.Windows)
+	if err != nil {
+		return domain.TimeWindowProfile{}, err
+	}
+	query := `
+        INSERT INTO time_window_profiles (name, windows)
+        VALUES ($1, $2::jsonb)
+        RETURNING ` + profileColumns
+
+	created, err := scanProfile(r.db.QueryRow(ctx, query, profile.Name, windows))
+	if err != nil {
+		return domain.TimeWindowProfile{}, fmt.Errorf("error al crear el perfil de franjas horarias: %w", err)
+	}
+	return created, nil
+}
+
+func (r *postgresRepository) FindAllProfiles(ctx context.Context) ([]domain.TimeWindowProfile, error) {
+	query := `SELECT ` + profileColumns + ` FROM time_window_profiles ORDER BY name`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar los perfiles de franjas horarias: %w", err)
+	}
+	defer rows.Close()
+
+	var profiles []domain.TimeWindowProfile
+	for rows.Next() {
+		p, err := scanProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error al escanear el perfil de franjas horarias: %w", err)
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+func (r *postgresRepository) FindProfileByID(ctx context.Context, id string) (domain.TimeWindowProfile, error) {
+	query := `SELECT ` + profileColumns + ` FROM time_window_profiles WHERE id = $1`
+
+	p, err := scanProfile(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.TimeWindowProfile{}, ErrProfileNotFound
+		}
+		return domain.TimeWindowProfile{}, fmt.Errorf("error al buscar el perfil de franjas horarias: %w", err)
+	}
+	return p, nil
+}
+
+func (r *postgresRepository) UpdateProfile(ctx context.Context, profile domain.TimeWindowProfile) error {
+	windows, err := windowsJSON(profile.Windows)
+	if err != nil {
+		return err
+	}
+	query := `
+        UPDATE time_window_profiles
+        SET name = $1, windows = $2::jsonb, updated_at = NOW()
+        WHERE id = $3`
+
+	tag, err := r.db.Exec(ctx, query, profile.Name, windows, profile.ID)
+	if err != nil {
+		return fmt.Errorf("error al actualizar el perfil de franjas horarias: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrProfileNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepository) DeleteProfile(ctx context.Context, id string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM time_window_profiles WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("error al eliminar el perfil de franjas horarias: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package timewindow
+
+import (
+	"context"
+	"errors"
+	"smart-waste-management/internal/domain"
+)
+
+// ErrInvalidWindows se devuelve cuando alguna franja está vacía o invertida.
+var ErrInvalidWindows = errors.New("cada franja debe cumplir start < end (las que cruzan la medianoche se indican como dos franjas)")
+
+// Service define la lógica de negocio de los perfiles de franjas horarias.
+type Service interface {
+	CreateProfile(ctx context.Context, profile domain.TimeWindowProfile) (domain.TimeWindowProfile, error)
+	GetAllProfiles(ctx context.Context) ([]domain.TimeWindowProfile, error)
+	GetProfileByID(ctx context.Context, id string) (domain.TimeWindowProfile, error)
+	UpdateProfile(ctx context.Context, profile domain.TimeWindowProfile) error
+	DeleteProfile(ctx context.Context, id string) error
+}
+
+type service struct {
+	repo Repository
+}
+
+// NewService crea una nueva instancia del servicio.
+func NewService(repo Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+func (s *service) CreateProfile(ctx context.Context, profile domain.TimeWindowProfile) (domain.TimeWindowProfile, error) {
+	if !validWindows(profile.Windows) {
+		return domain.TimeWindowProfile{}, ErrInvalidWindows
+	}
+	return s.repo.CreateProfile(ctx, profile)
+}
+
+func (s *service) GetAllProfiles(ctx context.Context) ([]domain.TimeWindowProfile, error) {
+	return s.repo.FindAllProfiles(ctx)
+}
+
+func (s *service) GetProfileByID(ctx context.Context, id string) (domain.TimeWindowProfile, error) {
+	return s.repo.FindProfileByID(ctx, id)
+}
+
+func (s *service) UpdateProfile(ctx context.Context, profile domain.TimeWindowProfile) error {
+	if !validWindows(profile.Windows) {
+		return ErrInvalidWindows
+	}
+	return s.repo.UpdateProfile(ctx, profile)
+}
+
+func (s *service) DeleteProfile(ctx context.Context, id string) error {
+	return s.repo.DeleteProfile(ctx, id)
+}
+
+func validWindows(windows domain.TimeWindows) bool {
+	for _, w := range windows {
+		if !w.IsValid() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+-- sql/02-lorawan.sql
+
+-- Sensores LoRaWAN asociados a contenedores.
+-- El DevEUI (16 dígitos hexadecimales en mayúsculas) identifica al dispositivo en el servidor de red
+-- (The Things Stack, ChirpStack). 'decoder' indica qué decodificador de payload usar para su modelo.
+CREATE TABLE IF NOT EXISTS lorawan_devices (
+    dev_eui TEXT PRIMARY KEY CHECK (dev_eui ~ '^[0-9A-F]{16}$'),
+    -- Si se elimina el contenedor, el sensor queda desasociado y se elimina también.
+    container_id UUID NOT NULL REFERENCES containers(id) ON DELETE CASCADE,
+    decoder TEXT NOT NULL,
+
+    -- Calibración del sensor ultrasónico: distancia medida con el contenedor vacío y lleno.
+    empty_distance_mm INT NOT NULL CHECK (empty_distance_mm > 0),
+    full_distance_mm INT NOT NULL DEFAULT 0 CHECK (full_distance_mm >= 0 AND full_distance_mm < empty_distance_mm),
+
+    -- Última telemetría del propio dispositivo.
+    -- Batería en mV o en porcentaje, según de cuál informe el modelo de sensor.
+    last_battery_mv INT,
+    last_battery_percent INT,
+    last_temperature_c DOUBLE PRECISION,
+    last_seen_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS lorawan_devices_container_id_idx ON lorawan_devices (container_id);
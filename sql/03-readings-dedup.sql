@@ -0,0 +1,23 @@
+-- sql/03-readings-dedup.sql
+
+-- Deduplicación de lecturas: un contenedor no puede tener dos lecturas con la misma marca de tiempo.
+-- Los gateways reenvían lecturas tras un timeout y sin esta restricción se almacenaban duplicadas.
+
+-- 1. Eliminamos los duplicados existentes, conservando la primera lectura insertada (menor id).
+DELETE FROM readings r
+USING readings d
+WHERE r.container_id = d.container_id
+  AND r.recorded_at = d.recorded_at
+  AND r.id > d.id;
+
+-- 2. Creamos la restricción única. Su índice sustituye al índice compuesto anterior,
+-- ya que cubre las mismas búsquedas por contenedor ordenadas por fecha.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'readings_container_id_recorded_at_key') THEN
+        ALTER TABLE readings
+            ADD CONSTRAINT readings_container_id_recorded_at_key UNIQUE (container_id, recorded_at);
+    END IF;
+END$$;
+
+DROP INDEX IF EXISTS readings_container_id_recorded_at_idx;
@@ -0,0 +1,21 @@
+-- sql/04-idempotency.sql
+
+-- Claves de idempotencia de las peticiones de escritura (cabecera Idempotency-Key).
+-- Guardamos la huella de la petición original y su respuesta para poder repetirla
+-- cuando un gateway reintenta la misma petición tras un timeout.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key TEXT PRIMARY KEY,
+    -- SHA-256 de método, ruta y cuerpo de la petición original.
+    request_hash TEXT NOT NULL,
+
+    -- NULL mientras la petición original se está procesando.
+    status_code INT,
+    content_type TEXT,
+    response_body BYTEA,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- Índice para purgar eficientemente las claves caducadas.
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,19 @@
+-- sql/05-threshold-profiles.sql
+
+-- Perfiles de umbrales de estado. Permiten que, por ejemplo, un contenedor orgánico pase a 'high'
+-- al 60% en verano mientras que un iglú de vidrio no lo haga hasta el 90%.
+CREATE TABLE IF NOT EXISTS threshold_profiles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    medium_at INT NOT NULL,
+    high_at INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (medium_at > 0 AND medium_at < high_at AND high_at <= 100)
+);
+
+-- Cada contenedor puede tener un perfil. Si es NULL se aplican los umbrales por defecto (40/80).
+ALTER TABLE containers
+    ADD COLUMN IF NOT EXISTS threshold_profile_id UUID REFERENCES threshold_profiles(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS containers_threshold_profile_id_idx ON containers (threshold_profile_id);
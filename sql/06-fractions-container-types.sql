@@ -0,0 +1,37 @@
+-- sql/06-fractions-container-types.sql
+
+-- Fracciones de residuo. Cada camión recoge una sola fracción.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'waste_fraction') THEN
+        CREATE TYPE waste_fraction AS ENUM ('organic', 'paper', 'packaging', 'glass', 'residual', 'textile');
+    END IF;
+END$$;
+
+-- Sistemas de elevación con los que un camión vacía un contenedor.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'lift_mechanism') THEN
+        CREATE TYPE lift_mechanism AS ENUM ('rear_loader', 'side_loader', 'front_loader', 'crane');
+    END IF;
+END$$;
+
+-- Modelos de contenedor (p. ej. "Contenur 2400L carga lateral").
+CREATE TABLE IF NOT EXISTS container_types (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    model TEXT NOT NULL UNIQUE,
+    volume_liters INT NOT NULL CHECK (volume_liters > 0),
+    lift_mechanism lift_mechanism NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Los contenedores existentes pasan a ser de la fracción resto.
+-- Un tipo de contenedor no se puede eliminar mientras haya contenedores que lo usen.
+ALTER TABLE containers
+    ADD COLUMN IF NOT EXISTS fraction waste_fraction NOT NULL DEFAULT 'residual',
+    ADD COLUMN IF NOT EXISTS container_type_id UUID REFERENCES container_types(id) ON DELETE RESTRICT;
+
+-- Las rutas y los listados se filtran por fracción y estado.
+CREATE INDEX IF NOT EXISTS containers_fraction_current_status_idx ON containers (fraction, current_status);
+CREATE INDEX IF NOT EXISTS containers_container_type_id_idx ON containers (container_type_id);
@@ -0,0 +1,17 @@
+-- sql/07-collections.sql
+
+-- Recogidas: cada vez que un camión vacía un contenedor. Pueden registrarse explícitamente
+-- (POST /containers/{id}/collections) o inferirse de una caída brusca del nivel de llenado.
+CREATE TABLE IF NOT EXISTS collections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    container_id UUID NOT NULL REFERENCES containers(id) ON DELETE CASCADE,
+    collected_at TIMESTAMPTZ NOT NULL,
+    -- Operario o camión que hizo la recogida. NULL en las recogidas inferidas.
+    collected_by TEXT,
+    estimated_volume_liters INT NOT NULL CHECK (estimated_volume_liters >= 0),
+    fill_level_before INT NOT NULL CHECK (fill_level_before >= 0 AND fill_level_before <= 100),
+    inferred BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS collections_container_id_collected_at_idx ON collections (container_id, collected_at DESC);
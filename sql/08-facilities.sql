@@ -0,0 +1,24 @@
+-- sql/08-facilities.sql
+
+-- Tipos de instalación fija: depósitos de camiones y puntos de descarga.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'facility_kind') THEN
+        CREATE TYPE facility_kind AS ENUM ('depot', 'transfer_station', 'landfill');
+    END IF;
+END$$;
+
+-- Depósitos, estaciones de transferencia y vertederos.
+-- 'fractions' son las fracciones que admite un punto de descarga; vacío significa que admite todas.
+CREATE TABLE IF NOT EXISTS facilities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    kind facility_kind NOT NULL,
+    location GEOGRAPHY(POINT, 4326) NOT NULL,
+    fractions waste_fraction[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS facilities_location_idx ON facilities USING GIST (location);
+CREATE INDEX IF NOT EXISTS facilities_kind_idx ON facilities (kind);
@@ -0,0 +1,18 @@
+-- sql/09-time-windows.sql
+
+-- Franjas horarias de recogida por zona (p. ej. "entorno escolar": no recoger de 9:00 a 14:00,
+-- o "zona comercial": recoger antes de las 8:00). 'windows' es una lista JSON de franjas
+-- diarias en hora local: [{"start": "06:00", "end": "08:00"}, ...].
+CREATE TABLE IF NOT EXISTS time_window_profiles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    windows JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Cada contenedor puede tener un perfil de franjas. Si es NULL se puede recoger a cualquier hora.
+ALTER TABLE containers
+    ADD COLUMN IF NOT EXISTS time_window_profile_id UUID REFERENCES time_window_profiles(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS containers_time_window_profile_id_idx ON containers (time_window_profile_id);
@@ -0,0 +1,63 @@
+-- sql/10-routes.sql
+
+-- Estados de ejecución de las rutas guardadas y resultado de cada parada.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'route_status') THEN
+        CREATE TYPE route_status AS ENUM ('planned', 'in_progress', 'completed');
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'route_stop_status') THEN
+        CREATE TYPE route_stop_status AS ENUM ('pending', 'collected', 'skipped', 'overflowing');
+    END IF;
+END$$;
+
+-- Rutas generadas con POST /routes, con lo planificado y su ejecución.
+CREATE TABLE IF NOT EXISTS routes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    status route_status NOT NULL DEFAULT 'planned',
+    -- Camión (p. ej. matrícula) y conductor asignados. NULL hasta que se asignan.
+    vehicle TEXT,
+    driver TEXT,
+    fraction waste_fraction,
+    depot_id UUID REFERENCES facilities(id) ON DELETE SET NULL,
+    start_point GEOGRAPHY(POINT, 4326) NOT NULL,
+    planned_start_at TIMESTAMPTZ NOT NULL,
+    planned_end_at TIMESTAMPTZ NOT NULL,
+    planned_distance_km DOUBLE PRECISION NOT NULL,
+    planned_duration_minutes DOUBLE PRECISION NOT NULL,
+    planned_load_liters DOUBLE PRECISION NOT NULL,
+    planned_load_kg DOUBLE PRECISION NOT NULL,
+    -- Capacidad del camión con la que se planificó la ruta (flota). 0 significa sin límite.
+    capacity_liters DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (capacity_liters >= 0),
+    capacity_kg DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (capacity_kg >= 0),
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS routes_status_planned_start_at_idx ON routes (status, planned_start_at DESC);
+
+-- Paradas de las rutas en el orden planificado: recogidas de contenedores y descargas.
+CREATE TABLE IF NOT EXISTS route_stops (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    route_id UUID NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    sequence INT NOT NULL CHECK (sequence > 0),
+    kind TEXT NOT NULL CHECK (kind IN ('container', 'unload')),
+    container_id UUID REFERENCES containers(id) ON DELETE SET NULL,
+    facility_id UUID REFERENCES facilities(id) ON DELETE SET NULL,
+    location GEOGRAPHY(POINT, 4326) NOT NULL,
+    planned_eta TIMESTAMPTZ,
+    estimated_load_liters DOUBLE PRECISION NOT NULL DEFAULT 0,
+    estimated_load_kg DOUBLE PRECISION NOT NULL DEFAULT 0,
+    status route_stop_status NOT NULL DEFAULT 'pending',
+    -- Motivo por el que no se recogió el contenedor: 'blocked' o 'inaccessible'.
+    skip_reason TEXT CHECK (skip_reason IN ('blocked', 'inaccessible')),
+    notes TEXT,
+    visited_at TIMESTAMPTZ,
+    -- Recogida registrada al vaciar el contenedor.
+    collection_id UUID REFERENCES collections(id) ON DELETE SET NULL,
+    UNIQUE (route_id, sequence)
+);
+
+CREATE INDEX IF NOT EXISTS route_stops_container_id_idx ON route_stops (container_id);
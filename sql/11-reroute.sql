@@ -0,0 +1,14 @@
+-- sql/11-reroute.sql
+
+-- Paradas añadidas a una ruta en curso al re-planificarla (p. ej. un contenedor que se ha llenado
+-- durante el turno). Es NULL en las paradas planificadas al generar la ruta.
+ALTER TABLE route_stops
+    ADD COLUMN IF NOT EXISTS added_at TIMESTAMPTZ;
+
+-- Las rutas re-planificadas recuerdan cuándo se cambiaron por última vez y cuánto se alargaron.
+ALTER TABLE routes
+    ADD COLUMN IF NOT EXISTS rerouted_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS added_distance_km DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Búsqueda de las paradas pendientes de las rutas activas al elegir los contenedores urgentes.
+CREATE INDEX IF NOT EXISTS route_stops_pending_container_id_idx ON route_stops (container_id) WHERE status = 'pending';
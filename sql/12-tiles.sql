@@ -0,0 +1,6 @@
+-- sql/12-tiles.sql
+
+-- Las teselas vectoriales se generan en Web Mercator (EPSG:3857). Este índice permite filtrar los
+-- contenedores de una tesela con su rectángulo exacto, sin transformar cada ubicación en la consulta.
+CREATE INDEX IF NOT EXISTS containers_location_3857_idx
+    ON containers USING GIST (ST_Transform(location::geometry, 3857));
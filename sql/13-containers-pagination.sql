@@ -0,0 +1,10 @@
+-- sql/13-containers-pagination.sql
+
+-- El listado de contenedores se pagina por clave (campo de ordenación, id). Con estos índices cada
+-- página se lee directamente a partir del cursor, en ambos sentidos, sin ordenar toda la tabla.
+CREATE INDEX IF NOT EXISTS containers_created_at_id_idx ON containers (created_at, id);
+-- Los contenedores sin lecturas (last_updated_at NULL) se ordenan como los más antiguos, así que
+-- se indexa la misma expresión por la que ordena el listado.
+CREATE INDEX IF NOT EXISTS containers_last_updated_at_id_idx ON containers ((COALESCE(last_updated_at, '-infinity'::timestamptz)), id);
+CREATE INDEX IF NOT EXISTS containers_last_fill_level_id_idx ON containers (last_fill_level, id);
+CREATE INDEX IF NOT EXISTS containers_capacity_liters_id_idx ON containers (capacity_liters, id);